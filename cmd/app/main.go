@@ -30,5 +30,7 @@ func main() {
 
 	println("Server starting")
 
-	log.Fatal(api.Start())
+	if err := api.Start(); err != nil {
+		log.Fatal(err)
+	}
 }
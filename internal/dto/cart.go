@@ -0,0 +1,42 @@
+package dto
+
+import "arabic/pkg/validator"
+
+// Владелец корзины: авторизованный пользователь или анонимный токен из cookie
+type CartOwner struct {
+	UserId int64
+	Token  string
+}
+
+type CartItemRequest struct {
+	CatalogId uint `json:"catalog_id"`
+	Quantity  int  `json:"quantity"`
+}
+
+type CartItemResponse struct {
+	CatalogId       uint    `json:"catalog_id"`
+	Name            string  `json:"name"`
+	ImageUrl        string  `json:"imageUrl"`
+	Price           float32 `json:"price"`
+	DiscountPercent float32 `json:"discount_percent"`
+	UnitPrice       float32 `json:"unit_price"`
+	Quantity        int     `json:"quantity"`
+	Available       int     `json:"available"`
+	LineTotal       float32 `json:"line_total"`
+}
+
+type CartResponse struct {
+	Items         []*CartItemResponse `json:"items"`
+	TotalQuantity int                 `json:"total_quantity"`
+	TotalWeight   float32             `json:"total_weight"`
+	Subtotal      float32             `json:"subtotal"`
+	Discount      float32             `json:"discount"`
+	Total         float32             `json:"total"`
+}
+
+func (c *CartItemRequest) IsValid() (bool, []string) {
+	v := validator.New()
+	v.CheckNumber(c.CatalogId, "CatalogId").IsMin(1)
+	v.CheckNumber(c.Quantity, "Quantity").IsMin(1).IsMax(1000)
+	return !v.HasErrors(), v.GetErrors()
+}
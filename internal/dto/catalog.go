@@ -1,18 +1,27 @@
 package dto
 
-import "arabic/pkg/validator"
+import (
+	"arabic/pkg/validator"
+	"fmt"
+	"slices"
+	"strings"
+)
 
 type CatalogResponse struct {
-	Id              uint    `json:"id"`
-	Name            string  `json:"name"`
-	Price           float32 `json:"price"`
-	Amount          int     `json:"amount"`
-	DiscountPercent float32 `json:"discount_percent"`
-	CategoryId      uint    `json:"category_id"`
-	Description     string  `json:"description"`
-	Sku             string  `json:"sku"`
-	ImageUrl        string  `json:"imageUrl"`
-	Weight          float32 `json:"weight"`
+	Id              uint           `json:"id"`
+	Name            string         `json:"name"`
+	Price           float32        `json:"price"`
+	Amount          int            `json:"amount"`
+	DiscountPercent float32        `json:"discount_percent"`
+	CategoryId      uint           `json:"category_id"`
+	Description     string         `json:"description"`
+	Sku             string         `json:"sku"`
+	ImageUrl        string         `json:"imageUrl"`
+	Weight          float32        `json:"weight"`
+	Rating          float32        `json:"rating"`
+	ReviewsCount    int            `json:"reviews_count"`
+	IsFavorite      bool           `json:"is_favorite"`
+	Tags            []*TagResponse `json:"tags"`
 }
 
 type CatalogCreateRequest struct {
@@ -23,7 +32,8 @@ type CatalogCreateRequest struct {
 	CategoryId      uint    `json:"category_id"`
 	Description     string  `json:"description"`
 	Sku             string  `json:"sku"`
-	Weight          float32 `json:"weight"`
+	// Вес единицы товара в граммах
+	Weight float32 `json:"weight"`
 }
 
 type CatalogUpdateRequest struct {
@@ -35,7 +45,8 @@ type CatalogUpdateRequest struct {
 	DiscountPercent *float32 `json:"discount_percent"`
 	Sku             *string  `json:"sku"`
 	CategoryId      *uint    `json:"category_id"`
-	Weight          *float32 `json:"weight"`
+	// Вес единицы товара в граммах
+	Weight *float32 `json:"weight"`
 }
 
 type AddImageRequest struct {
@@ -79,3 +90,99 @@ func (c *CatalogUpdateRequest) IsValid() (bool, []string) {
 
 	return !v.HasErrors(), v.GetErrors()
 }
+
+type CatalogFilterRequest struct {
+	Page       int
+	Limit      int
+	Cursor     string
+	CategoryId *uint
+	TagId      *int64
+	MinPrice   *float32
+	MaxPrice   *float32
+	InStock    bool
+	Discounted bool
+	Sort       string
+	Desc       bool
+	// Пользователь из необязательного JWT, 0 - аноним
+	UserId int64
+}
+
+type CatalogPageResponse struct {
+	Items      []*CatalogResponse `json:"items"`
+	Total      int                `json:"total"`
+	Page       int                `json:"page"`
+	Limit      int                `json:"limit"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
+
+var CatalogSortFields = []string{"id", "price", "name", "created_at", "rating"}
+
+func (c *CatalogFilterRequest) IsValid() (bool, []string) {
+	v := validator.New()
+
+	v.CheckNumber(c.Page, "Page").IsMin(1)
+	v.CheckNumber(c.Limit, "Limit").IsMin(1).IsMax(100)
+
+	if c.MinPrice != nil {
+		v.CheckNumber(*c.MinPrice, "MinPrice").IsMin(0)
+	}
+	if c.MaxPrice != nil {
+		v.CheckNumber(*c.MaxPrice, "MaxPrice").IsMin(0)
+	}
+	if c.MinPrice != nil && c.MaxPrice != nil && *c.MinPrice > *c.MaxPrice {
+		v.AddError("[MinPrice] - Must be less than or equal to MaxPrice")
+	}
+	if !slices.Contains(CatalogSortFields, c.Sort) {
+		v.AddError(fmt.Sprintf("[Sort] - Supported values: %s", strings.Join(CatalogSortFields, ", ")))
+	}
+
+	return !v.HasErrors(), v.GetErrors()
+}
+
+type CatalogSearchRequest struct {
+	Query string
+	Page  int
+	Limit int
+	// Пользователь из необязательного JWT, 0 - аноним
+	UserId int64
+}
+
+type CatalogSearchItem struct {
+	CatalogResponse
+	Rank          float32 `json:"rank"`
+	NameHighlight string  `json:"name_highlight"`
+	Snippet       string  `json:"snippet"`
+}
+
+type CatalogSearchResponse struct {
+	Items []*CatalogSearchItem `json:"items"`
+	Page  int                  `json:"page"`
+	Limit int                  `json:"limit"`
+}
+
+func (c *CatalogSearchRequest) IsValid() (bool, []string) {
+	v := validator.New()
+
+	v.CheckString(strings.TrimSpace(c.Query), "Query").IsMin(2).IsMax(100)
+	v.CheckNumber(c.Page, "Page").IsMin(1)
+	v.CheckNumber(c.Limit, "Limit").IsMin(1).IsMax(50)
+
+	return !v.HasErrors(), v.GetErrors()
+}
+
+type CatalogTagsRequest struct {
+	TagIds []int64 `json:"tag_ids"`
+}
+
+func (c *CatalogTagsRequest) IsValid(allowEmpty bool) (bool, []string) {
+	v := validator.New()
+
+	if !allowEmpty && len(c.TagIds) == 0 {
+		v.AddError("[TagIds] - Required at least one tag id")
+	}
+	for _, id := range c.TagIds {
+		v.CheckNumber(id, "TagIds").IsMin(1)
+	}
+
+	return !v.HasErrors(), v.GetErrors()
+}
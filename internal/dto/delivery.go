@@ -0,0 +1,138 @@
+package dto
+
+import (
+	"arabic/pkg/geo"
+	"arabic/pkg/validator"
+	"encoding/json"
+	"time"
+)
+
+const slotTimeLayout = "15:04"
+
+type DeliveryZoneRequest struct {
+	Name string `json:"name"`
+	// GeoJSON Polygon или MultiPolygon
+	Polygon               json.RawMessage `json:"polygon"`
+	MinOrderAmount        float32         `json:"min_order_amount"`
+	BaseFee               float32         `json:"base_fee"`
+	FreeDeliveryThreshold float32         `json:"free_delivery_threshold"`
+	PerKgFee              float32         `json:"per_kg_fee"`
+	EtaMinutes            int             `json:"eta_minutes"`
+	// Используется только при обновлении, nil - не менять
+	IsActive *bool `json:"is_active"`
+}
+
+type DeliveryZoneResponse struct {
+	Id                    int64           `json:"id"`
+	Name                  string          `json:"name"`
+	IsActive              bool            `json:"is_active"`
+	Polygon               json.RawMessage `json:"polygon"`
+	MinOrderAmount        float32         `json:"min_order_amount"`
+	BaseFee               float32         `json:"base_fee"`
+	FreeDeliveryThreshold float32         `json:"free_delivery_threshold"`
+	PerKgFee              float32         `json:"per_kg_fee"`
+	EtaMinutes            int             `json:"eta_minutes"`
+}
+
+func (z *DeliveryZoneRequest) IsValid() (bool, []string) {
+	v := validator.New()
+	v.CheckString(z.Name, "Name").IsMin(2).IsMax(100)
+	v.CheckNumber(z.MinOrderAmount, "MinOrderAmount").IsMin(0)
+	v.CheckNumber(z.BaseFee, "BaseFee").IsMin(0)
+	v.CheckNumber(z.FreeDeliveryThreshold, "FreeDeliveryThreshold").IsMin(0)
+	v.CheckNumber(z.PerKgFee, "PerKgFee").IsMin(0)
+	v.CheckNumber(z.EtaMinutes, "EtaMinutes").IsMin(1).IsMax(24 * 60)
+
+	if _, err := geo.ParseGeoJSON(z.Polygon); err != nil {
+		v.AddError("[Polygon] - " + err.Error())
+	}
+
+	return !v.HasErrors(), v.GetErrors()
+}
+
+type DeliveryQuoteRequest struct {
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+	// Необязательно: без товаров считается базовая стоимость доставки
+	Items []CartItemRequest `json:"items"`
+}
+
+type DeliveryQuoteResponse struct {
+	ZoneId                int64     `json:"zone_id"`
+	ZoneName              string    `json:"zone_name"`
+	ItemsTotal            float32   `json:"items_total"`
+	Weight                float32   `json:"weight"`
+	DeliveryFee           float32   `json:"delivery_fee"`
+	MinOrderAmount        float32   `json:"min_order_amount"`
+	MinOrderReached       bool      `json:"min_order_reached"`
+	FreeDeliveryThreshold float32   `json:"free_delivery_threshold"`
+	AmountToFreeDelivery  float32   `json:"amount_to_free_delivery"`
+	EtaMinutes            int       `json:"eta_minutes"`
+	EstimatedAt           time.Time `json:"estimated_at"`
+}
+
+func (q *DeliveryQuoteRequest) IsValid() (bool, []string) {
+	v := validator.New()
+
+	if q.Latitude == nil || q.Longitude == nil {
+		v.AddError("Latitude and Longitude are required")
+	}
+	checkCoordinates(v, q.Latitude, q.Longitude)
+
+	v.CheckNumber(len(q.Items), "Items").IsMax(100)
+	for _, item := range q.Items {
+		if ok, errs := item.IsValid(); !ok {
+			for _, err := range errs {
+				v.AddError(err)
+			}
+		}
+	}
+
+	return !v.HasErrors(), v.GetErrors()
+}
+
+type SlotTemplateRequest struct {
+	ZoneId int64 `json:"zone_id"`
+	// 0 - воскресенье
+	Weekday   int    `json:"weekday"`
+	StartTime string `json:"start_time"`
+	EndTime   string `json:"end_time"`
+	Capacity  int    `json:"capacity"`
+}
+
+type SlotTemplateResponse struct {
+	Id        int64  `json:"id"`
+	ZoneId    int64  `json:"zone_id"`
+	Weekday   int    `json:"weekday"`
+	StartTime string `json:"start_time"`
+	EndTime   string `json:"end_time"`
+	Capacity  int    `json:"capacity"`
+}
+
+func (t *SlotTemplateRequest) IsValid() (bool, []string) {
+	v := validator.New()
+	v.CheckNumber(t.ZoneId, "ZoneId").IsMin(1)
+	v.CheckNumber(t.Weekday, "Weekday").IsMin(0).IsMax(6)
+	v.CheckNumber(t.Capacity, "Capacity").IsMin(1).IsMax(10000)
+
+	start, startErr := time.Parse(slotTimeLayout, t.StartTime)
+	end, endErr := time.Parse(slotTimeLayout, t.EndTime)
+
+	if startErr != nil || endErr != nil {
+		v.AddError("StartTime and EndTime must be in HH:MM format")
+	} else if !start.Before(end) {
+		v.AddError("StartTime must be before EndTime")
+	}
+
+	return !v.HasErrors(), v.GetErrors()
+}
+
+type DeliverySlotResponse struct {
+	Id        int64  `json:"id"`
+	ZoneId    int64  `json:"zone_id"`
+	Date      string `json:"date"`
+	StartTime string `json:"start_time"`
+	EndTime   string `json:"end_time"`
+	Capacity  int    `json:"capacity"`
+	Remaining int    `json:"remaining"`
+}
@@ -0,0 +1,53 @@
+package dto
+
+import (
+	"arabic/pkg/validator"
+	"time"
+)
+
+type CourierProfileRequest struct {
+	MaxOrders int `json:"max_orders"`
+	// Килограммы
+	MaxWeight   float32 `json:"max_weight"`
+	IsAvailable bool    `json:"is_available"`
+}
+
+type CourierResponse struct {
+	UserId      int64   `json:"user_id"`
+	Username    string  `json:"username"`
+	MaxOrders   int     `json:"max_orders"`
+	MaxWeight   float32 `json:"max_weight"`
+	IsAvailable bool    `json:"is_available"`
+	Busy        bool    `json:"busy"`
+}
+
+func (c *CourierProfileRequest) IsValid() (bool, []string) {
+	v := validator.New()
+	v.CheckNumber(c.MaxOrders, "MaxOrders").IsMin(1).IsMax(100)
+	v.CheckNumber(c.MaxWeight, "MaxWeight").IsMin(1).IsMax(1000)
+	return !v.HasErrors(), v.GetErrors()
+}
+
+type RouteStopResponse struct {
+	Position int             `json:"position"`
+	OrderId  int64           `json:"order_id"`
+	Status   string          `json:"status"`
+	Address  AddressResponse `json:"address"`
+}
+
+type DeliveryRouteResponse struct {
+	Id             int64                `json:"id"`
+	CourierId      int64                `json:"courier_id"`
+	ZoneId         *int64               `json:"zone_id"`
+	DeliverySlotId *int64               `json:"delivery_slot_id"`
+	TotalWeight    float32              `json:"total_weight"`
+	Distance       float64              `json:"distance"`
+	CreatedAt      time.Time            `json:"created_at"`
+	Stops          []*RouteStopResponse `json:"stops"`
+}
+
+type DispatchResponse struct {
+	Routes []*DeliveryRouteResponse `json:"routes"`
+	// Заказы, которые не поместились ни к одному свободному курьеру
+	UnassignedOrders []int64 `json:"unassigned_orders"`
+}
@@ -0,0 +1,51 @@
+package dto
+
+import (
+	"arabic/pkg/validator"
+	"time"
+)
+
+type LoginFailureResponse struct {
+	Id        int64     `json:"id"`
+	Email     string    `json:"email"`
+	UserId    *int64    `json:"user_id"`
+	Ip        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type LoginFailureListRequest struct {
+	Email string
+	Ip    string
+	Limit int
+}
+
+func (l *LoginFailureListRequest) IsValid() (bool, []string) {
+	v := validator.New()
+	v.CheckNumber(l.Limit, "Limit").IsMin(1).IsMax(500)
+
+	return !v.HasErrors(), v.GetErrors()
+}
+
+// Снятие блокировки входа. Нужно указать email, IP или оба
+type LoginUnlockRequest struct {
+	Email string `json:"email"`
+	Ip    string `json:"ip"`
+}
+
+func (l *LoginUnlockRequest) IsValid() (bool, []string) {
+	v := validator.New()
+
+	if l.Email == "" && l.Ip == "" {
+		v.AddError("Email or Ip is required")
+	}
+	if l.Email != "" {
+		v.CheckString(l.Email, "Email").IsEmail()
+	}
+	if l.Ip != "" {
+		v.CheckString(l.Ip, "Ip").IsMax(64)
+	}
+
+	return !v.HasErrors(), v.GetErrors()
+}
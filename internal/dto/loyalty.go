@@ -0,0 +1,17 @@
+package dto
+
+import "time"
+
+type LoyaltyEntryResponse struct {
+	Type      string    `json:"type"`
+	Points    int64     `json:"points"`
+	OrderId   *int64    `json:"order_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type LoyaltyResponse struct {
+	Balance int64 `json:"balance"`
+	// Сколько стоит балл при оплате заказа
+	PointValue float64                 `json:"point_value"`
+	History    []*LoyaltyEntryResponse `json:"history"`
+}
@@ -0,0 +1,99 @@
+package dto
+
+import (
+	"arabic/pkg/validator"
+	"time"
+)
+
+type CheckoutRequest struct {
+	// Если список пуст - заказ оформляется из корзины пользователя
+	Items []CartItemRequest `json:"items"`
+	// Необязательный слот доставки из GET /delivery/slots
+	DeliverySlotId *int64 `json:"delivery_slot_id"`
+	PromoCode      string `json:"promo_code"`
+	// Сколько бонусных баллов потратить, спишется не больше допустимого для заказа
+	LoyaltyPoints int64 `json:"loyalty_points"`
+}
+
+type AddressResponse struct {
+	Apartment string   `json:"apartment"`
+	House     string   `json:"house"`
+	Street    string   `json:"street"`
+	City      string   `json:"city"`
+	Region    string   `json:"region"`
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+}
+
+type OrderItemResponse struct {
+	CatalogId       uint    `json:"catalog_id"`
+	Name            string  `json:"name"`
+	Sku             string  `json:"sku"`
+	Price           float32 `json:"price"`
+	DiscountPercent float32 `json:"discount_percent"`
+	UnitPrice       float32 `json:"unit_price"`
+	Weight          float32 `json:"weight"`
+	Quantity        int     `json:"quantity"`
+	LineTotal       float32 `json:"line_total"`
+}
+
+type OrderResponse struct {
+	Id              int64                `json:"id"`
+	Status          string               `json:"status"`
+	Subtotal        float32              `json:"subtotal"`
+	Discount        float32              `json:"discount"`
+	Total           float32              `json:"total"`
+	TotalWeight     float32              `json:"total_weight"`
+	Address         AddressResponse      `json:"address"`
+	DeliverySlotId  *int64               `json:"delivery_slot_id,omitempty"`
+	DeliveryZoneId  *int64               `json:"delivery_zone_id,omitempty"`
+	DeliveryFee     float32              `json:"delivery_fee"`
+	PromoCode       string               `json:"promo_code,omitempty"`
+	PromoDiscount   float32              `json:"promo_discount"`
+	LoyaltyPoints   int64                `json:"loyalty_points"`
+	LoyaltyDiscount float32              `json:"loyalty_discount"`
+	CreatedAt       time.Time            `json:"created_at"`
+	Items           []*OrderItemResponse `json:"items"`
+	// Платеж, созданный при оформлении
+	Payment *PaymentResponse `json:"payment,omitempty"`
+}
+
+func (c *CheckoutRequest) IsValid() (bool, []string) {
+	v := validator.New()
+
+	v.CheckNumber(len(c.Items), "Items").IsMax(100)
+	v.CheckString(c.PromoCode, "PromoCode").IsMax(32)
+	v.CheckNumber(c.LoyaltyPoints, "LoyaltyPoints").IsMin(0)
+	if c.DeliverySlotId != nil {
+		v.CheckNumber(*c.DeliverySlotId, "DeliverySlotId").IsMin(1)
+	}
+	for _, item := range c.Items {
+		if ok, errs := item.IsValid(); !ok {
+			for _, err := range errs {
+				v.AddError(err)
+			}
+		}
+	}
+
+	return !v.HasErrors(), v.GetErrors()
+}
+
+type OrderStatusRequest struct {
+	Status  string `json:"status"`
+	Comment string `json:"comment"`
+}
+
+type OrderStatusChangeResponse struct {
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	ChangedBy  int64     `json:"changed_by"`
+	Comment    string    `json:"comment"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (o *OrderStatusRequest) IsValid() (bool, []string) {
+	v := validator.New()
+	v.CheckString(o.Status, "Status").IsMin(1).IsMax(32)
+	v.CheckString(o.Comment, "Comment").IsMax(500)
+	return !v.HasErrors(), v.GetErrors()
+}
@@ -0,0 +1,19 @@
+package dto
+
+import "time"
+
+type PaymentResponse struct {
+	Id       int64  `json:"id"`
+	OrderId  int64  `json:"order_id"`
+	Provider string `json:"provider"`
+	IntentId string `json:"intent_id"`
+	// Только для платежа, ожидающего оплаты
+	ClientSecret     string    `json:"client_secret,omitempty"`
+	Currency         string    `json:"currency"`
+	Amount           float64   `json:"amount"`
+	AuthorizedAmount float64   `json:"authorized_amount"`
+	CapturedAmount   float64   `json:"captured_amount"`
+	RefundedAmount   float64   `json:"refunded_amount"`
+	Status           string    `json:"status"`
+	CreatedAt        time.Time `json:"created_at"`
+}
@@ -0,0 +1,62 @@
+package dto
+
+import (
+	"arabic/pkg/validator"
+	"time"
+)
+
+type PickLineRequest struct {
+	// picked, short, substituted
+	Status string `json:"status"`
+	// Фактически собранное количество для short и количество замены для substituted
+	Quantity            int  `json:"quantity"`
+	SubstituteCatalogId uint `json:"substitute_catalog_id"`
+}
+
+type PickLineResponse struct {
+	ItemId            int64  `json:"item_id"`
+	CatalogId         uint   `json:"catalog_id"`
+	Name              string `json:"name"`
+	Sku               string `json:"sku"`
+	Quantity          int    `json:"quantity"`
+	OrderedQuantity   int    `json:"ordered_quantity"`
+	PickStatus        string `json:"pick_status"`
+	SubstitutesItemId *int64 `json:"substitutes_item_id,omitempty"`
+}
+
+type PickingGroupResponse struct {
+	CategoryId   *int64              `json:"category_id"`
+	CategoryName string              `json:"category_name"`
+	Lines        []*PickLineResponse `json:"lines"`
+}
+
+type PickingListResponse struct {
+	OrderId        int64                   `json:"order_id"`
+	Status         string                  `json:"status"`
+	PickerId       *int64                  `json:"picker_id"`
+	DeliverySlotId *int64                  `json:"delivery_slot_id,omitempty"`
+	PendingLines   int                     `json:"pending_lines"`
+	Groups         []*PickingGroupResponse `json:"groups"`
+}
+
+type PickEventResponse struct {
+	Id               int64      `json:"id"`
+	OrderId          int64      `json:"order_id"`
+	Type             string     `json:"type"`
+	OrderItemId      int64      `json:"order_item_id"`
+	ItemName         string     `json:"item_name"`
+	SubstituteItemId *int64     `json:"substitute_item_id,omitempty"`
+	SubstituteName   string     `json:"substitute_name,omitempty"`
+	OrderedQuantity  int        `json:"ordered_quantity"`
+	PickedQuantity   int        `json:"picked_quantity"`
+	Status           string     `json:"status"`
+	CreatedAt        time.Time  `json:"created_at"`
+	ResolvedAt       *time.Time `json:"resolved_at,omitempty"`
+}
+
+func (p *PickLineRequest) IsValid() (bool, []string) {
+	v := validator.New()
+	v.CheckString(p.Status, "Status").IsMin(1).IsMax(16)
+	v.CheckNumber(p.Quantity, "Quantity").IsMin(0).IsMax(1000)
+	return !v.HasErrors(), v.GetErrors()
+}
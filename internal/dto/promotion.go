@@ -0,0 +1,126 @@
+package dto
+
+import (
+	"arabic/pkg/validator"
+	"time"
+)
+
+type PromotionRequest struct {
+	Name string `json:"name"`
+	// Пустой код - автоматическое правило
+	Code           string     `json:"code"`
+	Type           string     `json:"type"`
+	Value          float32    `json:"value"`
+	BuyQuantity    int        `json:"buy_quantity"`
+	FreeQuantity   int        `json:"free_quantity"`
+	MinOrderAmount float32    `json:"min_order_amount"`
+	UsageLimit     *int       `json:"usage_limit"`
+	PerUserLimit   *int       `json:"per_user_limit"`
+	StartsAt       *time.Time `json:"starts_at"`
+	EndsAt         *time.Time `json:"ends_at"`
+	IsActive       bool       `json:"is_active"`
+	CategoryIds    []uint     `json:"category_ids"`
+	TagIds         []int64    `json:"tag_ids"`
+}
+
+type PromotionResponse struct {
+	Id             int64      `json:"id"`
+	Name           string     `json:"name"`
+	Code           string     `json:"code,omitempty"`
+	Type           string     `json:"type"`
+	Value          float32    `json:"value"`
+	BuyQuantity    int        `json:"buy_quantity"`
+	FreeQuantity   int        `json:"free_quantity"`
+	MinOrderAmount float32    `json:"min_order_amount"`
+	UsageLimit     *int       `json:"usage_limit"`
+	PerUserLimit   *int       `json:"per_user_limit"`
+	UsedCount      int        `json:"used_count"`
+	StartsAt       *time.Time `json:"starts_at"`
+	EndsAt         *time.Time `json:"ends_at"`
+	IsActive       bool       `json:"is_active"`
+	CategoryIds    []uint     `json:"category_ids"`
+	TagIds         []int64    `json:"tag_ids"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// Тип акции проверяет сервис, здесь только границы значений
+func (p *PromotionRequest) IsValid() (bool, []string) {
+	v := validator.New()
+	v.CheckString(p.Name, "Name").IsMin(2).IsMax(100)
+	if p.Code != "" {
+		v.CheckString(p.Code, "Code").IsMin(3).IsMax(32)
+	}
+	v.CheckNumber(p.Value, "Value").IsMin(0)
+	v.CheckNumber(p.BuyQuantity, "BuyQuantity").IsMin(0).IsMax(100)
+	v.CheckNumber(p.FreeQuantity, "FreeQuantity").IsMin(0).IsMax(100)
+	v.CheckNumber(p.MinOrderAmount, "MinOrderAmount").IsMin(0)
+	if p.UsageLimit != nil {
+		v.CheckNumber(*p.UsageLimit, "UsageLimit").IsMin(1)
+	}
+	if p.PerUserLimit != nil {
+		v.CheckNumber(*p.PerUserLimit, "PerUserLimit").IsMin(1)
+	}
+	if p.StartsAt != nil && p.EndsAt != nil && !p.StartsAt.Before(*p.EndsAt) {
+		v.AddError("StartsAt must be before EndsAt")
+	}
+	v.CheckNumber(len(p.CategoryIds), "CategoryIds").IsMax(100)
+	v.CheckNumber(len(p.TagIds), "TagIds").IsMax(100)
+
+	return !v.HasErrors(), v.GetErrors()
+}
+
+type PriceRequest struct {
+	// Если список пуст - считается корзина
+	Items     []CartItemRequest `json:"items"`
+	PromoCode string            `json:"promo_code"`
+}
+
+type PriceLineResponse struct {
+	CatalogId       uint    `json:"catalog_id"`
+	Name            string  `json:"name"`
+	Price           float32 `json:"price"`
+	DiscountPercent float32 `json:"discount_percent"`
+	UnitPrice       float32 `json:"unit_price"`
+	Quantity        int     `json:"quantity"`
+	LineTotal       float32 `json:"line_total"`
+}
+
+type AppliedDiscountResponse struct {
+	PromotionId int64   `json:"promotion_id"`
+	Name        string  `json:"name"`
+	Code        string  `json:"code,omitempty"`
+	Type        string  `json:"type"`
+	Amount      float32 `json:"amount"`
+	Description string  `json:"description"`
+}
+
+type PriceBreakdownResponse struct {
+	Lines            []*PriceLineResponse       `json:"lines"`
+	Subtotal         float32                    `json:"subtotal"`
+	ItemsDiscount    float32                    `json:"items_discount"`
+	PromoDiscount    float32                    `json:"promo_discount"`
+	ItemsTotal       float32                    `json:"items_total"`
+	Weight           float32                    `json:"weight"`
+	DeliveryFee      float32                    `json:"delivery_fee"`
+	DeliveryDiscount float32                    `json:"delivery_discount"`
+	Total            float32                    `json:"total"`
+	Discounts        []*AppliedDiscountResponse `json:"discounts"`
+	PromoCode        string                     `json:"promo_code,omitempty"`
+	PromoCodeError   string                     `json:"promo_code_error,omitempty"`
+}
+
+func (p *PriceRequest) IsValid() (bool, []string) {
+	v := validator.New()
+
+	v.CheckNumber(len(p.Items), "Items").IsMax(100)
+	v.CheckString(p.PromoCode, "PromoCode").IsMax(32)
+	for _, item := range p.Items {
+		if ok, errs := item.IsValid(); !ok {
+			for _, err := range errs {
+				v.AddError(err)
+			}
+		}
+	}
+
+	return !v.HasErrors(), v.GetErrors()
+}
@@ -0,0 +1,58 @@
+package dto
+
+import (
+	"arabic/pkg/validator"
+	"time"
+)
+
+type ReviewRequest struct {
+	Score int    `json:"score"`
+	Text  string `json:"text"`
+	// Фотографии в base64 с префиксом data:image/...;base64,
+	Photos []string `json:"photos"`
+}
+
+type ReviewModerationRequest struct {
+	Comment string `json:"comment"`
+}
+
+type ReviewListRequest struct {
+	CatalogId uint
+	Page      int
+	Limit     int
+}
+
+type ReviewResponse struct {
+	Id                int64     `json:"id"`
+	CatalogId         uint      `json:"catalog_id"`
+	Username          string    `json:"username"`
+	Score             int       `json:"score"`
+	Text              string    `json:"text"`
+	Status            string    `json:"status"`
+	Photos            []string  `json:"photos"`
+	ModerationComment string    `json:"moderation_comment,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+func (r *ReviewRequest) IsValid() (bool, []string) {
+	v := validator.New()
+
+	v.CheckNumber(r.Score, "Score").IsMin(1).IsMax(5)
+	v.CheckString(r.Text, "Text").IsMax(2000)
+	v.CheckNumber(len(r.Photos), "Photos").IsMax(5)
+
+	return !v.HasErrors(), v.GetErrors()
+}
+
+func (r *ReviewModerationRequest) IsValid() (bool, []string) {
+	v := validator.New()
+	v.CheckString(r.Comment, "Comment").IsMax(500)
+	return !v.HasErrors(), v.GetErrors()
+}
+
+func (r *ReviewListRequest) IsValid() (bool, []string) {
+	v := validator.New()
+	v.CheckNumber(r.Page, "Page").IsMin(1)
+	v.CheckNumber(r.Limit, "Limit").IsMin(1).IsMax(50)
+	return !v.HasErrors(), v.GetErrors()
+}
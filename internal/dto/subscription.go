@@ -0,0 +1,10 @@
+package dto
+
+import "time"
+
+type SubscriptionResponse struct {
+	CatalogId   uint      `json:"catalog_id"`
+	CatalogName string    `json:"catalog_name"`
+	Type        string    `json:"type"`
+	CreatedAt   time.Time `json:"created_at"`
+}
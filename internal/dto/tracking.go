@@ -0,0 +1,28 @@
+package dto
+
+import "time"
+
+// События SSE-потока заказа GET /orders/{id}/events
+
+type OrderStatusEvent struct {
+	OrderId    int64     `json:"order_id"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	Comment    string    `json:"comment"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type CourierAssignedEvent struct {
+	OrderId   int64 `json:"order_id"`
+	RouteId   int64 `json:"route_id"`
+	CourierId int64 `json:"courier_id"`
+	// Номер остановки в маршруте курьера
+	Position int `json:"position"`
+}
+
+type OrderEtaEvent struct {
+	OrderId int64     `json:"order_id"`
+	Eta     time.Time `json:"eta"`
+	// Сколько заказов курьер доставит раньше
+	StopsBefore int `json:"stops_before"`
+}
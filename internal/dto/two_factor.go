@@ -0,0 +1,100 @@
+package dto
+
+import "arabic/pkg/validator"
+
+// Ответ на вход, когда нужен второй фактор. Cookie в этом случае не выставляются
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+	// Роль требует второй фактор, а он еще не подключен: сначала /user/login/2fa/setup
+	EnrollmentRequired bool `json:"enrollment_required"`
+	ExpiresIn          int  `json:"expires_in"`
+}
+
+// Итог входа: либо пользователь с токенами, либо запрос второго фактора
+type LoginResult struct {
+	User      *UserGetResponse
+	Tokens    *AuthTokens
+	Challenge *TwoFactorChallengeResponse
+	// Выдаются один раз, если второй фактор подключен прямо при входе
+	RecoveryCodes []string
+}
+
+type TwoFactorLoginResponse struct {
+	*UserGetResponse
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+// Секрет для ручного ввода и ссылка otpauth:// для QR-кода
+type TwoFactorSetupResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type TwoFactorStatusResponse struct {
+	Enabled  bool `json:"enabled"`
+	Required bool `json:"required"`
+}
+
+// Код из приложения или код восстановления, нужен один из них
+type TwoFactorCodeRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+func (t *TwoFactorCodeRequest) IsValid() (bool, []string) {
+	v := validator.New()
+
+	if t.Code == "" && t.RecoveryCode == "" {
+		v.AddError("Code or RecoveryCode is required")
+	}
+	if t.Code != "" {
+		v.CheckString(t.Code, "Code").IsMin(6).IsMax(6)
+	}
+	if t.RecoveryCode != "" {
+		v.CheckString(t.RecoveryCode, "RecoveryCode").IsMin(10).IsMax(16)
+	}
+
+	return !v.HasErrors(), v.GetErrors()
+}
+
+// Подключение подтверждается только кодом из приложения
+type TwoFactorEnableRequest struct {
+	Code string `json:"code"`
+}
+
+func (t *TwoFactorEnableRequest) IsValid() (bool, []string) {
+	v := validator.New()
+	v.CheckString(t.Code, "Code").IsMin(6).IsMax(6)
+
+	return !v.HasErrors(), v.GetErrors()
+}
+
+type TwoFactorChallengeRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+}
+
+func (t *TwoFactorChallengeRequest) IsValid() (bool, []string) {
+	v := validator.New()
+	v.CheckString(t.ChallengeToken, "ChallengeToken").IsMin(1).IsMax(64)
+
+	return !v.HasErrors(), v.GetErrors()
+}
+
+// Второй шаг входа
+type TwoFactorLoginRequest struct {
+	TwoFactorChallengeRequest
+	TwoFactorCodeRequest
+	ReturnTokens bool `json:"return_tokens"`
+}
+
+func (t *TwoFactorLoginRequest) IsValid() (bool, []string) {
+	okChallenge, challengeErrors := t.TwoFactorChallengeRequest.IsValid()
+	okCode, codeErrors := t.TwoFactorCodeRequest.IsValid()
+
+	return okChallenge && okCode, append(challengeErrors, codeErrors...)
+}
@@ -1,28 +1,88 @@
 package dto
 
-import "arabic/pkg/validator"
+import (
+	"arabic/pkg/notify"
+	"arabic/pkg/validator"
+	"slices"
+	"strings"
+	"time"
+)
 
 type UserLoginRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	// Для клиентов без cookie: токены приходят в теле ответа, cookie не выставляются
+	ReturnTokens bool `json:"return_tokens"`
 }
+
+// Refresh токен в теле запроса для клиентов без cookie
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Данные клиента, сохраняются вместе с сессией
+type SessionMeta struct {
+	UserAgent string
+	Ip        string
+}
+
+// Пара токенов, которую хендлер раскладывает по cookie или отдает в теле ответа
+type AuthTokens struct {
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+// Токены в теле ответа, access токен передается в заголовке Authorization: Bearer
+type TokenResponse struct {
+	TokenType        string    `json:"token_type"`
+	AccessToken      string    `json:"access_token"`
+	ExpiresIn        int       `json:"expires_in"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+func NewTokenResponse(tokens *AuthTokens) *TokenResponse {
+	return &TokenResponse{
+		TokenType:        "Bearer",
+		AccessToken:      tokens.AccessToken,
+		ExpiresIn:        int(time.Until(tokens.AccessExpiresAt).Seconds()),
+		RefreshToken:     tokens.RefreshToken,
+		RefreshExpiresAt: tokens.RefreshExpiresAt,
+	}
+}
+
+// Ответ на вход с return_tokens
+type LoginTokensResponse struct {
+	User          *UserGetResponse `json:"user"`
+	Tokens        *TokenResponse   `json:"tokens"`
+	RecoveryCodes []string         `json:"recovery_codes,omitempty"`
+}
+
 type UserGetResponse struct {
 	Email      string `json:"email"`
 	Username   string `json:"username"`
 	FirstName  string `json:"first_name"`
 	Id         int64  `json:"id"`
 	SecondName string `json:"second_name"`
+	Language   string `json:"language"`
+	// Подтвержден ли email по ссылке из письма
+	EmailVerified bool `json:"email_verified"`
 }
 type UserCreateRequest struct {
 	Email    string `json:"email"`
 	Username string `json:"username"`
 	Password string `json:"password"`
+	// Язык писем, по умолчанию русский
+	Language string `json:"language"`
 }
 
 type UserUpdateRequest struct {
 	Id         int64
 	FirstName  *string `json:"first_name"`
 	SecondName *string `json:"second_name"`
+	Language   *string `json:"language"`
 }
 
 func (u *UserUpdateRequest) IsValid() (bool, []string) {
@@ -34,6 +94,12 @@ func (u *UserUpdateRequest) IsValid() (bool, []string) {
 	if u.SecondName != nil {
 		v.CheckString(*u.SecondName, "SecondName").IsMin(4).IsMax(20)
 	}
+	if u.Language != nil {
+		v.CheckString(*u.Language, "Language")
+		if !IsValidLanguage(*u.Language) {
+			v.AddError(InvalidLanguageMessage)
+		}
+	}
 
 	if v.ValidatedFieldsCount() < 1 {
 		v.AddError("Required at least one field")
@@ -43,13 +109,39 @@ func (u *UserUpdateRequest) IsValid() (bool, []string) {
 	return !v.HasErrors(), v.GetErrors()
 }
 
+type PasswordForgotRequest struct {
+	Email string `json:"email"`
+}
+
+func (p *PasswordForgotRequest) IsValid() (bool, []string) {
+	v := validator.New()
+	v.CheckString(p.Email, "Email").IsEmail()
+
+	return !v.HasErrors(), v.GetErrors()
+}
+
+type PasswordResetRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func (p *PasswordResetRequest) IsValid() (bool, []string) {
+	v := validator.New()
+	v.CheckString(p.Token, "Token").IsMin(1).IsMax(64)
+	v.CheckString(p.Password, "Password").IsPassword()
+
+	return !v.HasErrors(), v.GetErrors()
+}
+
 type UserAddressUpdateRequest struct {
 	Id        int64
-	Apartment string `json:"apartment"`
-	House     string `json:"house"`
-	Street    string `json:"street"`
-	City      string `json:"city"`
-	Region    string `json:"region"`
+	Apartment string   `json:"apartment"`
+	House     string   `json:"house"`
+	Street    string   `json:"street"`
+	City      string   `json:"city"`
+	Region    string   `json:"region"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
 }
 
 func (u *UserAddressUpdateRequest) IsValid() (bool, []string) {
@@ -60,6 +152,27 @@ func (u *UserAddressUpdateRequest) IsValid() (bool, []string) {
 	v.CheckString(u.Street, "Street").IsMin(2).IsMax(173)
 	v.CheckString(u.City, "City").IsMin(3).IsMax(25)
 	v.CheckString(u.Region, "Region").IsMin(4).IsMax(25)
+	checkCoordinates(v, u.Latitude, u.Longitude)
 
 	return !v.HasErrors(), v.GetErrors()
 }
+
+// Координаты передаются только парой
+func checkCoordinates(v *validator.Validator, latitude, longitude *float64) {
+	if (latitude == nil) != (longitude == nil) {
+		v.AddError("Latitude and Longitude must be provided together")
+		return
+	}
+
+	if latitude != nil {
+		v.CheckNumber(*latitude, "Latitude").IsMin(-90).IsMax(90)
+		v.CheckNumber(*longitude, "Longitude").IsMin(-180).IsMax(180)
+	}
+}
+
+var InvalidLanguageMessage = "Language must be one of: " + strings.Join(notify.Languages, ", ")
+
+// Язык писем, для которого есть шаблоны
+func IsValidLanguage(language string) bool {
+	return slices.Contains(notify.Languages, language)
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"arabic/internal/dto"
+	"arabic/internal/service"
+	"arabic/pkg/customError"
+	"arabic/pkg/fs"
+	security "arabic/pkg/security/auth"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const cartTokenCookie = "cart_token"
+
+// Определяет владельца корзины. create = true разрешает выдать новый анонимный токен
+type CartOwnerResolver func(w http.ResponseWriter, r *http.Request, create bool) (*dto.CartOwner, error)
+
+type CartHandler struct {
+	service service.ICartService
+	fs      fs.IFileSystemImage
+}
+
+func NewCartHandler(service service.ICartService, fs fs.IFileSystemImage) *CartHandler {
+	return &CartHandler{service: service, fs: fs}
+}
+
+// Корзина авторизованного пользователя, используется на защищенных роутах
+func UserCartOwner(w http.ResponseWriter, r *http.Request, create bool) (*dto.CartOwner, error) {
+	claims, err := security.GetClaimsFromContext(r)
+	if err != nil {
+		return nil, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, err)
+	}
+
+	return &dto.CartOwner{UserId: claims.Id}, nil
+}
+
+// Анонимная корзина по токену из cookie
+func GuestCartOwner(w http.ResponseWriter, r *http.Request, create bool) (*dto.CartOwner, error) {
+	token := readCartToken(r)
+
+	if token == "" && create {
+		token = uuid.New().String()
+		http.SetCookie(w, &http.Cookie{
+			Name:     cartTokenCookie,
+			Value:    token,
+			Path:     "/",
+			HttpOnly: true,
+			MaxAge:   30 * 24 * 60 * 60,
+		})
+	}
+
+	return &dto.CartOwner{Token: token}, nil
+}
+
+func readCartToken(r *http.Request) string {
+	cookie, err := r.Cookie(cartTokenCookie)
+	if err != nil {
+		return ""
+	}
+
+	if _, err = uuid.Parse(cookie.Value); err != nil {
+		return ""
+	}
+
+	return cookie.Value
+}
+
+func clearCartToken(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     cartTokenCookie,
+		Value:    "",
+		Path:     "/",
+		HttpOnly: true,
+		MaxAge:   -1,
+	})
+}
+
+func (c *CartHandler) Get(resolveOwner CartOwnerResolver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		owner, err := resolveOwner(w, r, false)
+		if err != nil {
+			handleServiceError(w, err, "Cart: Get")
+			return
+		}
+
+		cart, err := c.service.GetCart(r.Context(), owner, c.imagePrefix())
+		if err != nil {
+			handleServiceError(w, err, "Cart: Get")
+			return
+		}
+
+		respondSuccess(w, http.StatusOK, cart)
+	}
+}
+
+func (c *CartHandler) AddItem(resolveOwner CartOwnerResolver) http.HandlerFunc {
+	return c.changeItem(resolveOwner, c.service.AddItem)
+}
+
+func (c *CartHandler) UpdateItem(resolveOwner CartOwnerResolver) http.HandlerFunc {
+	return c.changeItem(resolveOwner, c.service.UpdateItem)
+}
+
+func (c *CartHandler) RemoveItem(resolveOwner CartOwnerResolver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		catalogId, err := parseIdVar(r, "catalogId")
+		if err != nil {
+			handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorGetQueryParam, nil), "Cart: RemoveItem")
+			return
+		}
+
+		owner, err := resolveOwner(w, r, false)
+		if err != nil {
+			handleServiceError(w, err, "Cart: RemoveItem")
+			return
+		}
+
+		cart, err := c.service.RemoveItem(r.Context(), owner, uint(catalogId), c.imagePrefix())
+		if err != nil {
+			handleServiceError(w, err, "Cart: RemoveItem")
+			return
+		}
+
+		respondSuccess(w, http.StatusOK, cart)
+	}
+}
+
+func (c *CartHandler) Clear(resolveOwner CartOwnerResolver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		owner, err := resolveOwner(w, r, false)
+		if err != nil {
+			handleServiceError(w, err, "Cart: Clear")
+			return
+		}
+
+		if err = c.service.Clear(r.Context(), owner); err != nil {
+			handleServiceError(w, err, "Cart: Clear")
+			return
+		}
+
+		respondSuccess(w, http.StatusOK, nil)
+	}
+}
+
+func (c *CartHandler) changeItem(resolveOwner CartOwnerResolver, apply func(ctx context.Context, owner *dto.CartOwner, req *dto.CartItemRequest, imagePrefix string) (*dto.CartResponse, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := dto.CartItemRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorParse, nil), "Cart: Decode")
+			return
+		}
+
+		if ok, errStrings := req.IsValid(); !ok {
+			handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, strings.Join(errStrings, "; "), nil), "Cart: validation error")
+			return
+		}
+
+		owner, err := resolveOwner(w, r, true)
+		if err != nil {
+			handleServiceError(w, err, "Cart: changeItem")
+			return
+		}
+
+		cart, err := apply(r.Context(), owner, &req, c.imagePrefix())
+		if err != nil {
+			handleServiceError(w, err, "Cart: changeItem")
+			return
+		}
+
+		respondSuccess(w, http.StatusOK, cart)
+	}
+}
+
+func (c *CartHandler) imagePrefix() string {
+	return "/" + c.fs.GetPath()
+}
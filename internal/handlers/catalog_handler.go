@@ -5,6 +5,7 @@ import (
 	"arabic/internal/service"
 	"arabic/pkg/customError"
 	"arabic/pkg/fs"
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/gorilla/mux"
@@ -50,14 +51,68 @@ func (c *CatalogHandler) Delete(w http.ResponseWriter, r *http.Request) {
 
 func (c *CatalogHandler) GetAll(fs fs.IFileSystemImage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := parseCatalogFilter(r)
+
+		if err != nil {
+			handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorGetQueryParam, nil), "Catalog: GetAll parse query")
+			return
+		}
+
+		if ok, errStrings := filter.IsValid(); !ok {
+			handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, strings.Join(errStrings, "; "), nil), "Catalog: GetAll validation error")
+			return
+		}
+		filter.UserId = optionalUserId(r)
+
 		imagePrefix := "/" + fs.GetPath()
-		items, err := c.service.GetAll(r.Context(), imagePrefix)
+		page, err := c.service.GetAll(r.Context(), filter, imagePrefix)
 
 		if err != nil {
-			handleServiceError(w, err, "CategoryHandle GetManyById")
+			handleServiceError(w, err, "Catalog: GetAll")
 			return
 		}
-		respondSuccess(w, http.StatusOK, items)
+		respondSuccess(w, http.StatusOK, page)
+	}
+}
+
+func (c *CatalogHandler) Search(fs fs.IFileSystemImage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		req := &dto.CatalogSearchRequest{
+			Query:  query.Get("q"),
+			Page:   1,
+			Limit:  10,
+			UserId: optionalUserId(r),
+		}
+
+		var err error
+		if v := query.Get("page"); v != "" {
+			if req.Page, err = strconv.Atoi(v); err != nil {
+				handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorGetQueryParam, nil), "Catalog: Search parse query")
+				return
+			}
+		}
+		if v := query.Get("limit"); v != "" {
+			if req.Limit, err = strconv.Atoi(v); err != nil {
+				handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorGetQueryParam, nil), "Catalog: Search parse query")
+				return
+			}
+		}
+
+		if ok, errStrings := req.IsValid(); !ok {
+			handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, strings.Join(errStrings, "; "), nil), "Catalog: Search validation error")
+			return
+		}
+
+		imagePrefix := "/" + fs.GetPath()
+		resp, err := c.service.Search(r.Context(), req, imagePrefix)
+
+		if err != nil {
+			handleServiceError(w, err, "Catalog: Search")
+			return
+		}
+
+		respondSuccess(w, http.StatusOK, resp)
 	}
 }
 
@@ -72,7 +127,7 @@ func (c *CatalogHandler) GetById(fs fs.IFileSystemImage) http.HandlerFunc {
 		}
 
 		imagePrefix := "/" + fs.GetPath()
-		item, err := c.service.GetById(r.Context(), uint(itemId), imagePrefix)
+		item, err := c.service.GetById(r.Context(), uint(itemId), optionalUserId(r), imagePrefix)
 
 		if err != nil {
 			handleServiceError(w, err, "CategoryHandle GetManyById")
@@ -131,6 +186,61 @@ func (c *CatalogHandler) Create(w http.ResponseWriter, r *http.Request) {
 	respondSuccess(w, http.StatusCreated, fmt.Sprintf("Id: %d", id))
 }
 
+func (c *CatalogHandler) SetTags(w http.ResponseWriter, r *http.Request) {
+	c.changeTags(w, r, true, c.service.SetTags)
+}
+
+func (c *CatalogHandler) AddTags(w http.ResponseWriter, r *http.Request) {
+	c.changeTags(w, r, false, c.service.AddTags)
+}
+
+func (c *CatalogHandler) RemoveTag(w http.ResponseWriter, r *http.Request) {
+	catalogId, err := parseIdVar(r, "id")
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorGetQueryParam, nil), "Catalog: RemoveTag")
+		return
+	}
+
+	tagId, err := parseIdVar(r, "tagId")
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorGetQueryParam, nil), "Catalog: RemoveTag")
+		return
+	}
+
+	if err = c.service.RemoveTag(r.Context(), uint(catalogId), tagId); err != nil {
+		handleServiceError(w, err, "Catalog: RemoveTag")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, nil)
+}
+
+func (c *CatalogHandler) changeTags(w http.ResponseWriter, r *http.Request, allowEmpty bool, apply func(context.Context, uint, *dto.CatalogTagsRequest) error) {
+	catalogId, err := parseIdVar(r, "id")
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorGetQueryParam, nil), "Catalog: Tags")
+		return
+	}
+
+	req := dto.CatalogTagsRequest{}
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorParse, nil), "Catalog: Tags Decode")
+		return
+	}
+
+	if ok, errStrings := req.IsValid(allowEmpty); !ok {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, strings.Join(errStrings, "; "), nil), "Catalog: Tags validation error")
+		return
+	}
+
+	if err = apply(r.Context(), uint(catalogId), &req); err != nil {
+		handleServiceError(w, err, "Catalog: Tags")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, nil)
+}
+
 func (c *CatalogHandler) AddImage(fs fs.IFileSystemImage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		req := &dto.AddImageRequest{}
@@ -151,3 +261,96 @@ func (c *CatalogHandler) AddImage(fs fs.IFileSystemImage) http.HandlerFunc {
 		respondSuccess(w, 200, filename)
 	}
 }
+
+// Разбирает query параметры: page, limit, cursor, category_id, tag_id, min_price, max_price,
+// in_stock, discounted, sort (price|name|created_at|rating) и order (asc|desc)
+func parseCatalogFilter(r *http.Request) (*dto.CatalogFilterRequest, error) {
+	query := r.URL.Query()
+	filter := &dto.CatalogFilterRequest{
+		Page:   1,
+		Limit:  20,
+		Sort:   "id",
+		Cursor: query.Get("cursor"),
+	}
+
+	if v := query.Get("page"); v != "" {
+		page, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, err
+		}
+		filter.Page = page
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, err
+		}
+		filter.Limit = limit
+	}
+
+	if v := query.Get("category_id"); v != "" {
+		categoryId, err := strconv.ParseUint(v, 10, 0)
+		if err != nil {
+			return nil, err
+		}
+		id := uint(categoryId)
+		filter.CategoryId = &id
+	}
+
+	if v := query.Get("tag_id"); v != "" {
+		tagId, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		filter.TagId = &tagId
+	}
+
+	if v := query.Get("min_price"); v != "" {
+		price, err := strconv.ParseFloat(v, 32)
+		if err != nil {
+			return nil, err
+		}
+		minPrice := float32(price)
+		filter.MinPrice = &minPrice
+	}
+
+	if v := query.Get("max_price"); v != "" {
+		price, err := strconv.ParseFloat(v, 32)
+		if err != nil {
+			return nil, err
+		}
+		maxPrice := float32(price)
+		filter.MaxPrice = &maxPrice
+	}
+
+	if v := query.Get("in_stock"); v != "" {
+		inStock, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, err
+		}
+		filter.InStock = inStock
+	}
+
+	if v := query.Get("discounted"); v != "" {
+		discounted, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, err
+		}
+		filter.Discounted = discounted
+	}
+
+	if v := query.Get("sort"); v != "" {
+		filter.Sort = v
+	}
+
+	switch strings.ToLower(query.Get("order")) {
+	case "", "asc":
+	case "desc":
+		filter.Desc = true
+	default:
+		return nil, fmt.Errorf("unsupported order: %s", query.Get("order"))
+	}
+
+	return filter, nil
+}
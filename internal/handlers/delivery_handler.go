@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"arabic/internal/dto"
+	"arabic/internal/service"
+	"arabic/pkg/customError"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type DeliveryHandler struct {
+	service service.IDeliveryService
+}
+
+func NewDeliveryHandler(service service.IDeliveryService) *DeliveryHandler {
+	return &DeliveryHandler{service: service}
+}
+
+func (d *DeliveryHandler) GetZones(w http.ResponseWriter, r *http.Request) {
+	zones, err := d.service.GetZones(r.Context())
+	if err != nil {
+		handleServiceError(w, err, "Delivery: GetZones")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, zones)
+}
+
+func (d *DeliveryHandler) CreateZone(w http.ResponseWriter, r *http.Request) {
+	req := dto.DeliveryZoneRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorParse, nil), "Delivery: CreateZone Decode")
+		return
+	}
+
+	if ok, errStrings := req.IsValid(); !ok {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, strings.Join(errStrings, "; "), nil), "Delivery: CreateZone validation error")
+		return
+	}
+
+	zone, err := d.service.CreateZone(r.Context(), &req)
+	if err != nil {
+		handleServiceError(w, err, "Delivery: CreateZone")
+		return
+	}
+
+	respondSuccess(w, http.StatusCreated, zone)
+}
+
+func (d *DeliveryHandler) UpdateZone(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIdVar(r, "id")
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorGetQueryParam, nil), "Delivery: UpdateZone")
+		return
+	}
+
+	req := dto.DeliveryZoneRequest{}
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorParse, nil), "Delivery: UpdateZone Decode")
+		return
+	}
+
+	if ok, errStrings := req.IsValid(); !ok {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, strings.Join(errStrings, "; "), nil), "Delivery: UpdateZone validation error")
+		return
+	}
+
+	zone, err := d.service.UpdateZone(r.Context(), id, &req)
+	if err != nil {
+		handleServiceError(w, err, "Delivery: UpdateZone")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, zone)
+}
+
+func (d *DeliveryHandler) Quote(w http.ResponseWriter, r *http.Request) {
+	req := dto.DeliveryQuoteRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorParse, nil), "Delivery: Quote Decode")
+		return
+	}
+
+	if ok, errStrings := req.IsValid(); !ok {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, strings.Join(errStrings, "; "), nil), "Delivery: Quote validation error")
+		return
+	}
+
+	quote, err := d.service.Quote(r.Context(), &req)
+	if err != nil {
+		handleServiceError(w, err, "Delivery: Quote")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, quote)
+}
+
+// GET /delivery/slots?date=YYYY-MM-DD&zone_id=
+func (d *DeliveryHandler) GetSlots(w http.ResponseWriter, r *http.Request) {
+	zoneId, err := parseZoneIdQuery(r, false)
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorGetQueryParam, nil), "Delivery: GetSlots")
+		return
+	}
+
+	slots, err := d.service.GetSlots(r.Context(), zoneId, r.URL.Query().Get("date"))
+	if err != nil {
+		handleServiceError(w, err, "Delivery: GetSlots")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, slots)
+}
+
+func (d *DeliveryHandler) GetTemplates(w http.ResponseWriter, r *http.Request) {
+	zoneId, err := parseZoneIdQuery(r, true)
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorGetQueryParam, nil), "Delivery: GetTemplates")
+		return
+	}
+
+	templates, err := d.service.GetTemplates(r.Context(), zoneId)
+	if err != nil {
+		handleServiceError(w, err, "Delivery: GetTemplates")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, templates)
+}
+
+func (d *DeliveryHandler) CreateTemplate(w http.ResponseWriter, r *http.Request) {
+	req := dto.SlotTemplateRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorParse, nil), "Delivery: CreateTemplate Decode")
+		return
+	}
+
+	if ok, errStrings := req.IsValid(); !ok {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, strings.Join(errStrings, "; "), nil), "Delivery: CreateTemplate validation error")
+		return
+	}
+
+	template, err := d.service.CreateTemplate(r.Context(), &req)
+	if err != nil {
+		handleServiceError(w, err, "Delivery: CreateTemplate")
+		return
+	}
+
+	respondSuccess(w, http.StatusCreated, template)
+}
+
+func (d *DeliveryHandler) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIdVar(r, "id")
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorGetQueryParam, nil), "Delivery: DeleteTemplate")
+		return
+	}
+
+	if err = d.service.DeleteTemplate(r.Context(), id); err != nil {
+		handleServiceError(w, err, "Delivery: DeleteTemplate")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, nil)
+}
+
+func parseZoneIdQuery(r *http.Request, required bool) (int64, error) {
+	value := r.URL.Query().Get("zone_id")
+	if value == "" && !required {
+		return 0, nil
+	}
+
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 1 {
+		return 0, strconv.ErrSyntax
+	}
+
+	return id, nil
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"arabic/internal/dto"
+	"arabic/internal/service"
+	"arabic/pkg/customError"
+	security "arabic/pkg/security/auth"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+type DispatchHandler struct {
+	service service.IDispatchService
+}
+
+func NewDispatchHandler(service service.IDispatchService) *DispatchHandler {
+	return &DispatchHandler{service: service}
+}
+
+func (d *DispatchHandler) Run(w http.ResponseWriter, r *http.Request) {
+	claims, err := security.GetClaimsFromContext(r)
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, nil), "Dispatch: Run")
+		return
+	}
+
+	resp, err := d.service.Run(r.Context(), claims.Id)
+	if err != nil {
+		handleServiceError(w, err, "Dispatch: Run")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, resp)
+}
+
+func (d *DispatchHandler) GetRoutes(w http.ResponseWriter, r *http.Request) {
+	routes, err := d.service.GetRoutes(r.Context())
+	if err != nil {
+		handleServiceError(w, err, "Dispatch: GetRoutes")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, routes)
+}
+
+func (d *DispatchHandler) GetCouriers(w http.ResponseWriter, r *http.Request) {
+	couriers, err := d.service.GetCouriers(r.Context())
+	if err != nil {
+		handleServiceError(w, err, "Dispatch: GetCouriers")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, couriers)
+}
+
+func (d *DispatchHandler) UpdateCourier(w http.ResponseWriter, r *http.Request) {
+	courierId, err := parseIdVar(r, "id")
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorGetQueryParam, nil), "Dispatch: UpdateCourier")
+		return
+	}
+
+	req := dto.CourierProfileRequest{}
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorParse, nil), "Dispatch: UpdateCourier Decode")
+		return
+	}
+
+	if ok, errStrings := req.IsValid(); !ok {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, strings.Join(errStrings, "; "), nil), "Dispatch: UpdateCourier validation error")
+		return
+	}
+
+	if err = d.service.UpdateCourier(r.Context(), courierId, &req); err != nil {
+		handleServiceError(w, err, "Dispatch: UpdateCourier")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, nil)
+}
+
+// Текущий маршрут курьера для мобильного приложения
+func (d *DispatchHandler) GetCourierRoute(w http.ResponseWriter, r *http.Request) {
+	claims, err := security.GetClaimsFromContext(r)
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, nil), "Dispatch: GetCourierRoute")
+		return
+	}
+
+	route, err := d.service.GetCourierRoute(r.Context(), claims.Id)
+	if err != nil {
+		handleServiceError(w, err, "Dispatch: GetCourierRoute")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, route)
+}
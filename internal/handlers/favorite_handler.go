@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"arabic/internal/service"
+	"arabic/pkg/customError"
+	"arabic/pkg/fs"
+	security "arabic/pkg/security/auth"
+	"net/http"
+)
+
+type FavoriteHandler struct {
+	service service.IFavoriteService
+	fs      fs.IFileSystemImage
+}
+
+func NewFavoriteHandler(service service.IFavoriteService, fs fs.IFileSystemImage) *FavoriteHandler {
+	return &FavoriteHandler{service: service, fs: fs}
+}
+
+func (f *FavoriteHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	claims, err := security.GetClaimsFromContext(r)
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, nil), "Favorite: GetAll")
+		return
+	}
+
+	items, err := f.service.GetAll(r.Context(), claims.Id, "/"+f.fs.GetPath())
+	if err != nil {
+		handleServiceError(w, err, "Favorite: GetAll")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, items)
+}
+
+func (f *FavoriteHandler) Add(w http.ResponseWriter, r *http.Request) {
+	claims, err := security.GetClaimsFromContext(r)
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, nil), "Favorite: Add")
+		return
+	}
+
+	catalogId, err := parseIdVar(r, "catalogId")
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorGetQueryParam, nil), "Favorite: Add")
+		return
+	}
+
+	if err = f.service.Add(r.Context(), claims.Id, uint(catalogId)); err != nil {
+		handleServiceError(w, err, "Favorite: Add")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, nil)
+}
+
+func (f *FavoriteHandler) Remove(w http.ResponseWriter, r *http.Request) {
+	claims, err := security.GetClaimsFromContext(r)
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, nil), "Favorite: Remove")
+		return
+	}
+
+	catalogId, err := parseIdVar(r, "catalogId")
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorGetQueryParam, nil), "Favorite: Remove")
+		return
+	}
+
+	if err = f.service.Remove(r.Context(), claims.Id, uint(catalogId)); err != nil {
+		handleServiceError(w, err, "Favorite: Remove")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, nil)
+}
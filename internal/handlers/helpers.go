@@ -4,13 +4,17 @@ import (
 	"arabic/internal/dto"
 	"arabic/pkg/customError"
 	"arabic/pkg/logger"
+	security "arabic/pkg/security/auth"
 	"arabic/pkg/validator"
 	"encoding/json"
 	"fmt"
 	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
+	"net"
 	"net/http"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -54,6 +58,9 @@ func UserValidator(user *dto.UserCreateRequest) (bool, error) {
 	v.CheckString(user.Email, "Email").IsEmail()
 	v.CheckString(user.Username, "Username").IsValidUsername()
 	v.CheckString(user.Password, "Password").IsPassword()
+	if user.Language != "" && !dto.IsValidLanguage(user.Language) {
+		v.AddError(dto.InvalidLanguageMessage)
+	}
 
 	hasErrors, err := v.HasErrors(), v.GetErrors()
 
@@ -69,18 +76,94 @@ func handleServiceError(w http.ResponseWriter, err error, operation string) {
 	if errors.As(err, &serviceErr) {
 		respondError(w, serviceErr.Code, serviceErr.Message)
 	} else {
-		logger.Log.Error("Unexpected error type in %s: %v", operation, err)
+		logger.Log.Error(fmt.Sprintf("Unexpected error type in %s: %v", operation, err))
 		respondError(w, http.StatusInternalServerError, customError.Error500)
 	}
 }
 
-func setAuthCookie(w http.ResponseWriter, token string) {
-	http.SetCookie(w, &http.Cookie{
-		Name:     "token",
-		Value:    token,
-		Path:     "/",
-		HttpOnly: true,
-	})
+// Id пользователя на публичных роутах с необязательной авторизацией, 0 - аноним
+func optionalUserId(r *http.Request) int64 {
+	claims, err := security.GetClaimsFromContext(r)
+	if err != nil {
+		return 0
+	}
+	return claims.Id
+}
+
+// Достает числовой параметр пути, например {id}
+func parseIdVar(r *http.Request, name string) (int64, error) {
+	value, ok := mux.Vars(r)[name]
+	if !ok {
+		return 0, errors.New(name + " not provided")
+	}
+
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 1 {
+		return 0, errors.New("cannot parse " + name)
+	}
+
+	return id, nil
+}
+
+const (
+	refreshTokenCookie = "refresh_token"
+	// Refresh токен нужен только ручкам обновления и выхода
+	refreshTokenPath = "/api/v1/user"
+)
+
+// Cookie живет столько же, сколько токен
+func setAuthCookie(w http.ResponseWriter, config *security.CookieConfig, token string, expiresAt time.Time) {
+	http.SetCookie(w, config.New(security.AccessTokenCookie, token, "/", maxAgeUntil(expiresAt)))
+}
+
+func setAuthCookies(w http.ResponseWriter, config *security.CookieConfig, tokens *dto.AuthTokens) {
+	setAuthCookie(w, config, tokens.AccessToken, tokens.AccessExpiresAt)
+	http.SetCookie(w, config.NewRefresh(refreshTokenCookie, tokens.RefreshToken, refreshTokenPath, maxAgeUntil(tokens.RefreshExpiresAt)))
+}
+
+// Domain и Path должны совпадать с выставленными, иначе браузер cookie не удалит
+func clearAuthCookies(w http.ResponseWriter, config *security.CookieConfig) {
+	http.SetCookie(w, config.New(security.AccessTokenCookie, "", "/", -1))
+	http.SetCookie(w, config.NewRefresh(refreshTokenCookie, "", refreshTokenPath, -1))
+}
+
+// Refresh токен из cookie, а для клиентов без cookie - из тела запроса.
+// fromBody - новые токены тоже нужно вернуть в теле
+func readRefreshToken(r *http.Request) (token string, fromBody bool) {
+	if cookie, err := r.Cookie(refreshTokenCookie); err == nil && cookie.Value != "" {
+		return cookie.Value, false
+	}
+
+	req := dto.RefreshRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return "", false
+	}
+
+	return req.RefreshToken, req.RefreshToken != ""
+}
+
+// Max-Age 0 net/http не выставляет, поэтому истекший срок - минимум секунда
+func maxAgeUntil(expiresAt time.Time) int {
+	return max(int(time.Until(expiresAt).Seconds()), 1)
+}
+
+func sessionMeta(r *http.Request) *dto.SessionMeta {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	return &dto.SessionMeta{
+		UserAgent: truncate(r.UserAgent(), 255),
+		Ip:        truncate(ip, 64),
+	}
+}
+
+func truncate(value string, max int) string {
+	if len(value) <= max {
+		return value
+	}
+	return strings.ToValidUTF8(value[:max], "")
 }
 
 func respondSuccess(w http.ResponseWriter, status int, data interface{}) {
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"arabic/internal/dto"
+	"arabic/internal/service"
+	"arabic/pkg/customError"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type LoginSecurityHandler struct {
+	service service.ILoginSecurityService
+}
+
+func NewLoginSecurityHandler(service service.ILoginSecurityService) *LoginSecurityHandler {
+	return &LoginSecurityHandler{service: service}
+}
+
+// Фильтры ?email=&ip=&limit=
+func (h *LoginSecurityHandler) GetFailures(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req := &dto.LoginFailureListRequest{Email: query.Get("email"), Ip: query.Get("ip"), Limit: 100}
+
+	if v := query.Get("limit"); v != "" {
+		var err error
+		if req.Limit, err = strconv.Atoi(v); err != nil {
+			handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorGetQueryParam, nil), "LoginSecurity: GetFailures parse query")
+			return
+		}
+	}
+
+	if ok, errStrings := req.IsValid(); !ok {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, strings.Join(errStrings, "; "), nil), "LoginSecurity: GetFailures validation error")
+		return
+	}
+
+	failures, err := h.service.GetFailures(r.Context(), req)
+	if err != nil {
+		handleServiceError(w, err, "LoginSecurity: GetFailures")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, failures)
+}
+
+func (h *LoginSecurityHandler) Unlock(w http.ResponseWriter, r *http.Request) {
+	req := dto.LoginUnlockRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorParse, nil), "LoginSecurity: Unlock Decode")
+		return
+	}
+
+	if ok, errStrings := req.IsValid(); !ok {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, strings.Join(errStrings, "; "), nil), "LoginSecurity: Unlock validation error")
+		return
+	}
+
+	if err := h.service.Unlock(r.Context(), &req); err != nil {
+		handleServiceError(w, err, "LoginSecurity: Unlock")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, nil)
+}
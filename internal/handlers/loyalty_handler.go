@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"arabic/internal/service"
+	"arabic/pkg/customError"
+	security "arabic/pkg/security/auth"
+	"net/http"
+)
+
+type LoyaltyHandler struct {
+	service service.ILoyaltyService
+}
+
+func NewLoyaltyHandler(service service.ILoyaltyService) *LoyaltyHandler {
+	return &LoyaltyHandler{service: service}
+}
+
+func (l *LoyaltyHandler) Get(w http.ResponseWriter, r *http.Request) {
+	claims, err := security.GetClaimsFromContext(r)
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, nil), "Loyalty: Get")
+		return
+	}
+
+	account, err := l.service.GetAccount(r.Context(), claims.Id)
+	if err != nil {
+		handleServiceError(w, err, "Loyalty: Get")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, account)
+}
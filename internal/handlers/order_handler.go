@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"arabic/internal/dto"
+	"arabic/internal/model"
+	"arabic/internal/service"
+	"arabic/pkg/customError"
+	security "arabic/pkg/security/auth"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+type OrderHandler struct {
+	service service.IOrderService
+}
+
+func NewOrderHandler(service service.IOrderService) *OrderHandler {
+	return &OrderHandler{service: service}
+}
+
+func (o *OrderHandler) Checkout(w http.ResponseWriter, r *http.Request) {
+	claims, err := security.GetClaimsFromContext(r)
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, nil), "Order: Checkout")
+		return
+	}
+
+	// Пустое тело - оформляем заказ из корзины
+	req := dto.CheckoutRequest{}
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorParse, nil), "Order: Checkout Decode")
+		return
+	}
+
+	if ok, errStrings := req.IsValid(); !ok {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, strings.Join(errStrings, "; "), nil), "Order: Checkout validation error")
+		return
+	}
+
+	order, err := o.service.Checkout(r.Context(), claims.Id, &req)
+	if err != nil {
+		handleServiceError(w, err, "Order: Checkout")
+		return
+	}
+
+	respondSuccess(w, http.StatusCreated, order)
+}
+
+func (o *OrderHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	claims, err := security.GetClaimsFromContext(r)
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, nil), "Order: GetAll")
+		return
+	}
+
+	orders, err := o.service.GetUserOrders(r.Context(), claims.Id)
+	if err != nil {
+		handleServiceError(w, err, "Order: GetAll")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, orders)
+}
+
+func (o *OrderHandler) GetById(w http.ResponseWriter, r *http.Request) {
+	claims, err := security.GetClaimsFromContext(r)
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, nil), "Order: GetById")
+		return
+	}
+
+	orderId, err := parseIdVar(r, "id")
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorGetQueryParam, nil), "Order: GetById")
+		return
+	}
+
+	order, err := o.service.GetOrder(r.Context(), claims.Id, orderId)
+	if err != nil {
+		handleServiceError(w, err, "Order: GetById")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, order)
+}
+
+func (o *OrderHandler) ChangeStatus(w http.ResponseWriter, r *http.Request) {
+	req := dto.OrderStatusRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorParse, nil), "Order: ChangeStatus Decode")
+		return
+	}
+
+	o.changeStatus(w, r, &req)
+}
+
+// Ролевые действия: start-picking, pack, dispatch, deliver, cancel, return.
+// Тело с комментарием необязательно
+func (o *OrderHandler) Action(w http.ResponseWriter, r *http.Request) {
+	status, ok := model.OrderActions[mux.Vars(r)["action"]]
+	if !ok {
+		handleServiceError(w, customError.NewServiceError(http.StatusNotFound, "Unknown order action", nil), "Order: Action")
+		return
+	}
+
+	req := dto.OrderStatusRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorParse, nil), "Order: Action Decode")
+		return
+	}
+	req.Status = status
+
+	o.changeStatus(w, r, &req)
+}
+
+func (o *OrderHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	claims, err := security.GetClaimsFromContext(r)
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, nil), "Order: GetHistory")
+		return
+	}
+
+	orderId, err := parseIdVar(r, "id")
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorGetQueryParam, nil), "Order: GetHistory")
+		return
+	}
+
+	history, err := o.service.GetHistory(r.Context(), claims.Id, orderId)
+	if err != nil {
+		handleServiceError(w, err, "Order: GetHistory")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, history)
+}
+
+func (o *OrderHandler) GetQueue(w http.ResponseWriter, r *http.Request) {
+	claims, err := security.GetClaimsFromContext(r)
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, nil), "Order: GetQueue")
+		return
+	}
+
+	orders, err := o.service.GetQueue(r.Context(), claims.Id)
+	if err != nil {
+		handleServiceError(w, err, "Order: GetQueue")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, orders)
+}
+
+func (o *OrderHandler) changeStatus(w http.ResponseWriter, r *http.Request, req *dto.OrderStatusRequest) {
+	claims, err := security.GetClaimsFromContext(r)
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, nil), "Order: ChangeStatus")
+		return
+	}
+
+	orderId, err := parseIdVar(r, "id")
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorGetQueryParam, nil), "Order: ChangeStatus")
+		return
+	}
+
+	if ok, errStrings := req.IsValid(); !ok {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, strings.Join(errStrings, "; "), nil), "Order: ChangeStatus validation error")
+		return
+	}
+
+	order, err := o.service.ChangeStatus(r.Context(), claims.Id, orderId, req)
+	if err != nil {
+		handleServiceError(w, err, "Order: ChangeStatus")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, order)
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"arabic/internal/service"
+	"arabic/pkg/customError"
+	"arabic/pkg/payment"
+	security "arabic/pkg/security/auth"
+	"io"
+	"net/http"
+)
+
+// Максимальный размер тела вебхука
+const maxWebhookBody = 64 << 10
+
+type PaymentHandler struct {
+	service service.IPaymentService
+}
+
+func NewPaymentHandler(service service.IPaymentService) *PaymentHandler {
+	return &PaymentHandler{service: service}
+}
+
+func (p *PaymentHandler) Get(w http.ResponseWriter, r *http.Request) {
+	claims, err := security.GetClaimsFromContext(r)
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, nil), "Payment: Get")
+		return
+	}
+
+	orderId, err := parseIdVar(r, "id")
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorGetQueryParam, nil), "Payment: Get")
+		return
+	}
+
+	resp, err := p.service.GetPayment(r.Context(), claims.Id, orderId)
+	if err != nil {
+		handleServiceError(w, err, "Payment: Get")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, resp)
+}
+
+func (p *PaymentHandler) Retry(w http.ResponseWriter, r *http.Request) {
+	claims, err := security.GetClaimsFromContext(r)
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, nil), "Payment: Retry")
+		return
+	}
+
+	orderId, err := parseIdVar(r, "id")
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorGetQueryParam, nil), "Payment: Retry")
+		return
+	}
+
+	resp, err := p.service.Retry(r.Context(), claims.Id, orderId)
+	if err != nil {
+		handleServiceError(w, err, "Payment: Retry")
+		return
+	}
+
+	respondSuccess(w, http.StatusCreated, resp)
+}
+
+// Подпись считается по сырому телу, поэтому оно читается целиком до разбора
+func (p *PaymentHandler) Webhook(w http.ResponseWriter, r *http.Request) {
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorParse, nil), "Payment: Webhook Read")
+		return
+	}
+
+	if err = p.service.HandleWebhook(r.Context(), payload, r.Header.Get(payment.SignatureHeader)); err != nil {
+		handleServiceError(w, err, "Payment: Webhook")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, nil)
+}
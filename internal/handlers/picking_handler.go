@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"arabic/internal/dto"
+	"arabic/internal/service"
+	"arabic/pkg/customError"
+	security "arabic/pkg/security/auth"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+type PickingHandler struct {
+	service service.IPickingService
+}
+
+func NewPickingHandler(service service.IPickingService) *PickingHandler {
+	return &PickingHandler{service: service}
+}
+
+func (p *PickingHandler) Next(w http.ResponseWriter, r *http.Request) {
+	claims, err := security.GetClaimsFromContext(r)
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, nil), "Picking: Next")
+		return
+	}
+
+	list, err := p.service.Next(r.Context(), claims.Id)
+	if err != nil {
+		handleServiceError(w, err, "Picking: Next")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, list)
+}
+
+func (p *PickingHandler) GetList(w http.ResponseWriter, r *http.Request) {
+	claims, err := security.GetClaimsFromContext(r)
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, nil), "Picking: GetList")
+		return
+	}
+
+	orderId, err := parseIdVar(r, "id")
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorGetQueryParam, nil), "Picking: GetList")
+		return
+	}
+
+	list, err := p.service.GetList(r.Context(), claims.Id, orderId)
+	if err != nil {
+		handleServiceError(w, err, "Picking: GetList")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, list)
+}
+
+func (p *PickingHandler) UpdateLine(w http.ResponseWriter, r *http.Request) {
+	claims, err := security.GetClaimsFromContext(r)
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, nil), "Picking: UpdateLine")
+		return
+	}
+
+	orderId, err := parseIdVar(r, "id")
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorGetQueryParam, nil), "Picking: UpdateLine")
+		return
+	}
+
+	itemId, err := parseIdVar(r, "itemId")
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorGetQueryParam, nil), "Picking: UpdateLine")
+		return
+	}
+
+	req := dto.PickLineRequest{}
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorParse, nil), "Picking: UpdateLine Decode")
+		return
+	}
+
+	if ok, errStrings := req.IsValid(); !ok {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, strings.Join(errStrings, "; "), nil), "Picking: UpdateLine validation error")
+		return
+	}
+
+	list, err := p.service.UpdateLine(r.Context(), claims.Id, orderId, itemId, &req)
+	if err != nil {
+		handleServiceError(w, err, "Picking: UpdateLine")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, list)
+}
+
+// Недовложения и замены заказа для покупателя
+func (p *PickingHandler) GetEvents(w http.ResponseWriter, r *http.Request) {
+	claims, err := security.GetClaimsFromContext(r)
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, nil), "Picking: GetEvents")
+		return
+	}
+
+	orderId, err := parseIdVar(r, "id")
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorGetQueryParam, nil), "Picking: GetEvents")
+		return
+	}
+
+	events, err := p.service.GetEvents(r.Context(), claims.Id, orderId)
+	if err != nil {
+		handleServiceError(w, err, "Picking: GetEvents")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, events)
+}
+
+func (p *PickingHandler) ApproveEvent(w http.ResponseWriter, r *http.Request) {
+	p.resolveEvent(w, r, true)
+}
+
+func (p *PickingHandler) RejectEvent(w http.ResponseWriter, r *http.Request) {
+	p.resolveEvent(w, r, false)
+}
+
+func (p *PickingHandler) resolveEvent(w http.ResponseWriter, r *http.Request, approve bool) {
+	claims, err := security.GetClaimsFromContext(r)
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, nil), "Picking: ResolveEvent")
+		return
+	}
+
+	orderId, err := parseIdVar(r, "id")
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorGetQueryParam, nil), "Picking: ResolveEvent")
+		return
+	}
+
+	eventId, err := parseIdVar(r, "eventId")
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorGetQueryParam, nil), "Picking: ResolveEvent")
+		return
+	}
+
+	event, err := p.service.ResolveEvent(r.Context(), claims.Id, orderId, eventId, approve)
+	if err != nil {
+		handleServiceError(w, err, "Picking: ResolveEvent")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, event)
+}
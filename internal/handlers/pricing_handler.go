@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"arabic/internal/dto"
+	"arabic/internal/service"
+	"arabic/pkg/customError"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+type PricingHandler struct {
+	service service.IPricingService
+}
+
+func NewPricingHandler(service service.IPricingService) *PricingHandler {
+	return &PricingHandler{service: service}
+}
+
+// Расчет цены корзины или переданных товаров с акциями и промокодом
+func (p *PricingHandler) Price(resolveOwner CartOwnerResolver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := dto.PriceRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorParse, nil), "Pricing: Price Decode")
+			return
+		}
+
+		if ok, errStrings := req.IsValid(); !ok {
+			handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, strings.Join(errStrings, "; "), nil), "Pricing: Price validation error")
+			return
+		}
+
+		owner, err := resolveOwner(w, r, false)
+		if err != nil {
+			handleServiceError(w, err, "Pricing: Price")
+			return
+		}
+
+		price, err := p.service.Price(r.Context(), owner, &req)
+		if err != nil {
+			handleServiceError(w, err, "Pricing: Price")
+			return
+		}
+
+		respondSuccess(w, http.StatusOK, price)
+	}
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"arabic/internal/dto"
+	"arabic/internal/service"
+	"arabic/pkg/customError"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+type PromotionHandler struct {
+	service service.IPromotionService
+}
+
+func NewPromotionHandler(service service.IPromotionService) *PromotionHandler {
+	return &PromotionHandler{service: service}
+}
+
+func (p *PromotionHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	promotions, err := p.service.GetAll(r.Context())
+	if err != nil {
+		handleServiceError(w, err, "Promotion: GetAll")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, promotions)
+}
+
+func (p *PromotionHandler) GetById(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIdVar(r, "id")
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorGetQueryParam, nil), "Promotion: GetById")
+		return
+	}
+
+	promotion, err := p.service.GetById(r.Context(), id)
+	if err != nil {
+		handleServiceError(w, err, "Promotion: GetById")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, promotion)
+}
+
+func (p *PromotionHandler) Create(w http.ResponseWriter, r *http.Request) {
+	req := dto.PromotionRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorParse, nil), "Promotion: Create Decode")
+		return
+	}
+
+	if ok, errStrings := req.IsValid(); !ok {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, strings.Join(errStrings, "; "), nil), "Promotion: Create validation error")
+		return
+	}
+
+	promotion, err := p.service.Create(r.Context(), &req)
+	if err != nil {
+		handleServiceError(w, err, "Promotion: Create")
+		return
+	}
+
+	respondSuccess(w, http.StatusCreated, promotion)
+}
+
+func (p *PromotionHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIdVar(r, "id")
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorGetQueryParam, nil), "Promotion: Update")
+		return
+	}
+
+	req := dto.PromotionRequest{}
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorParse, nil), "Promotion: Update Decode")
+		return
+	}
+
+	if ok, errStrings := req.IsValid(); !ok {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, strings.Join(errStrings, "; "), nil), "Promotion: Update validation error")
+		return
+	}
+
+	promotion, err := p.service.Update(r.Context(), id, &req)
+	if err != nil {
+		handleServiceError(w, err, "Promotion: Update")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, promotion)
+}
+
+func (p *PromotionHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIdVar(r, "id")
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorGetQueryParam, nil), "Promotion: Delete")
+		return
+	}
+
+	if err = p.service.Delete(r.Context(), id); err != nil {
+		handleServiceError(w, err, "Promotion: Delete")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, nil)
+}
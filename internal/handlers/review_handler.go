@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"arabic/internal/dto"
+	"arabic/internal/service"
+	"arabic/pkg/customError"
+	"arabic/pkg/fs"
+	security "arabic/pkg/security/auth"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type ReviewHandler struct {
+	service service.IReviewService
+	fs      fs.IFileSystemImage
+}
+
+func NewReviewHandler(service service.IReviewService, fs fs.IFileSystemImage) *ReviewHandler {
+	return &ReviewHandler{service: service, fs: fs}
+}
+
+func (h *ReviewHandler) imagePrefix() string {
+	return "/" + h.fs.GetPath()
+}
+
+func (h *ReviewHandler) Create(w http.ResponseWriter, r *http.Request) {
+	claims, err := security.GetClaimsFromContext(r)
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, nil), "Review: Create")
+		return
+	}
+
+	catalogId, err := parseIdVar(r, "id")
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorGetQueryParam, nil), "Review: Create")
+		return
+	}
+
+	req := dto.ReviewRequest{}
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorParse, nil), "Review: Create Decode")
+		return
+	}
+
+	if ok, errStrings := req.IsValid(); !ok {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, strings.Join(errStrings, "; "), nil), "Review: Create validation error")
+		return
+	}
+
+	review, err := h.service.Create(r.Context(), claims.Id, uint(catalogId), &req, h.fs)
+	if err != nil {
+		handleServiceError(w, err, "Review: Create")
+		return
+	}
+
+	respondSuccess(w, http.StatusCreated, review)
+}
+
+func (h *ReviewHandler) GetByCatalog(w http.ResponseWriter, r *http.Request) {
+	catalogId, err := parseIdVar(r, "id")
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorGetQueryParam, nil), "Review: GetByCatalog")
+		return
+	}
+
+	query := r.URL.Query()
+	req := &dto.ReviewListRequest{CatalogId: uint(catalogId), Page: 1, Limit: 10}
+
+	if v := query.Get("page"); v != "" {
+		if req.Page, err = strconv.Atoi(v); err != nil {
+			handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorGetQueryParam, nil), "Review: GetByCatalog parse query")
+			return
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		if req.Limit, err = strconv.Atoi(v); err != nil {
+			handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorGetQueryParam, nil), "Review: GetByCatalog parse query")
+			return
+		}
+	}
+
+	if ok, errStrings := req.IsValid(); !ok {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, strings.Join(errStrings, "; "), nil), "Review: GetByCatalog validation error")
+		return
+	}
+
+	reviews, err := h.service.GetCatalogReviews(r.Context(), req, h.imagePrefix())
+	if err != nil {
+		handleServiceError(w, err, "Review: GetByCatalog")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, reviews)
+}
+
+func (h *ReviewHandler) GetPending(w http.ResponseWriter, r *http.Request) {
+	reviews, err := h.service.GetPending(r.Context(), h.imagePrefix())
+	if err != nil {
+		handleServiceError(w, err, "Review: GetPending")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, reviews)
+}
+
+// Одобрение или отклонение отзыва модератором, комментарий необязателен
+func (h *ReviewHandler) Moderate(status string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := security.GetClaimsFromContext(r)
+		if err != nil {
+			handleServiceError(w, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, nil), "Review: Moderate")
+			return
+		}
+
+		reviewId, err := parseIdVar(r, "id")
+		if err != nil {
+			handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorGetQueryParam, nil), "Review: Moderate")
+			return
+		}
+
+		req := dto.ReviewModerationRequest{}
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorParse, nil), "Review: Moderate Decode")
+			return
+		}
+
+		if ok, errStrings := req.IsValid(); !ok {
+			handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, strings.Join(errStrings, "; "), nil), "Review: Moderate validation error")
+			return
+		}
+
+		review, err := h.service.Moderate(r.Context(), claims.Id, reviewId, status, &req, h.imagePrefix())
+		if err != nil {
+			handleServiceError(w, err, "Review: Moderate")
+			return
+		}
+
+		respondSuccess(w, http.StatusOK, review)
+	}
+}
+
+func (h *ReviewHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	claims, err := security.GetClaimsFromContext(r)
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, nil), "Review: Delete")
+		return
+	}
+
+	reviewId, err := parseIdVar(r, "id")
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorGetQueryParam, nil), "Review: Delete")
+		return
+	}
+
+	if err = h.service.Delete(r.Context(), claims.Id, reviewId, h.fs); err != nil {
+		handleServiceError(w, err, "Review: Delete")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, nil)
+}
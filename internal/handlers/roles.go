@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"arabic/pkg/customError"
+	security "arabic/pkg/security/auth"
+	"net/http"
+	"slices"
+)
+
+// Middleware пропускает запрос только если роль из JWT входит в список roles.
+// Должен стоять после CheckJWT, так как берет claims из контекста. Ошибки в том же формате, что у обработчиков
+func RequireRoles(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, err := security.GetClaimsFromContext(r)
+			if err != nil {
+				handleServiceError(w, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, nil), "RequireRoles")
+				return
+			}
+
+			if !slices.Contains(roles, claims.Role) {
+				handleServiceError(w, customError.NewServiceError(http.StatusForbidden, "You do not have permission to perform this action", nil), "RequireRoles")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
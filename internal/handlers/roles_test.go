@@ -0,0 +1,69 @@
+package handlers_test
+
+import (
+	"arabic/internal/handlers"
+	security "arabic/pkg/security/auth"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	jwtmiddleware "github.com/auth0/go-jwt-middleware/v2"
+	"github.com/auth0/go-jwt-middleware/v2/validator"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireRoles(t *testing.T) {
+	tests := []struct {
+		name         string
+		claims       *security.CustomClaims
+		expectStatus int
+	}{
+		{
+			name:         "allowed role",
+			claims:       &security.CustomClaims{UserEmail: "a@a.com", Role: "admin"},
+			expectStatus: http.StatusOK,
+		},
+		{
+			name:         "forbidden role",
+			claims:       &security.CustomClaims{UserEmail: "a@a.com", Role: "user"},
+			expectStatus: http.StatusForbidden,
+		},
+		{
+			name:         "no claims",
+			claims:       nil,
+			expectStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := handlers.RequireRoles("admin", "moderator")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/catalog", nil)
+			if tc.claims != nil {
+				ctx := context.WithValue(req.Context(), jwtmiddleware.ContextKey{}, &validator.ValidatedClaims{CustomClaims: tc.claims})
+				req = req.WithContext(ctx)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectStatus, rec.Code)
+			if tc.expectStatus == http.StatusOK {
+				return
+			}
+
+			// Отказ в доступе в том же формате, что и ошибки обработчиков
+			var body handlers.ErrorMessage[any]
+			assert.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+			assert.Equal(t, tc.expectStatus, body.Status)
+			assert.False(t, body.Success)
+			assert.NotEmpty(t, body.Error)
+			assert.NotEmpty(t, body.Path)
+		})
+	}
+}
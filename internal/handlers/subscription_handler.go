@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"arabic/internal/service"
+	"arabic/pkg/customError"
+	security "arabic/pkg/security/auth"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type SubscriptionHandler struct {
+	service service.ISubscriptionService
+}
+
+func NewSubscriptionHandler(service service.ISubscriptionService) *SubscriptionHandler {
+	return &SubscriptionHandler{service: service}
+}
+
+func (s *SubscriptionHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	claims, err := security.GetClaimsFromContext(r)
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, nil), "Subscription: GetAll")
+		return
+	}
+
+	subscriptions, err := s.service.GetAll(r.Context(), claims.Id)
+	if err != nil {
+		handleServiceError(w, err, "Subscription: GetAll")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, subscriptions)
+}
+
+func (s *SubscriptionHandler) Subscribe(w http.ResponseWriter, r *http.Request) {
+	claims, err := security.GetClaimsFromContext(r)
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, nil), "Subscription: Subscribe")
+		return
+	}
+
+	catalogId, err := parseIdVar(r, "id")
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorGetQueryParam, nil), "Subscription: Subscribe")
+		return
+	}
+
+	if err = s.service.Subscribe(r.Context(), claims.Id, uint(catalogId), mux.Vars(r)["type"]); err != nil {
+		handleServiceError(w, err, "Subscription: Subscribe")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, nil)
+}
+
+func (s *SubscriptionHandler) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	claims, err := security.GetClaimsFromContext(r)
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, nil), "Subscription: Unsubscribe")
+		return
+	}
+
+	catalogId, err := parseIdVar(r, "id")
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorGetQueryParam, nil), "Subscription: Unsubscribe")
+		return
+	}
+
+	if err = s.service.Unsubscribe(r.Context(), claims.Id, uint(catalogId), mux.Vars(r)["type"]); err != nil {
+		handleServiceError(w, err, "Subscription: Unsubscribe")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, nil)
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"arabic/internal/service"
+	"arabic/pkg/customError"
+	"arabic/pkg/events"
+	security "arabic/pkg/security/auth"
+	"net/http"
+	"time"
+)
+
+type TrackingHandler struct {
+	service   service.ITrackingService
+	heartbeat time.Duration
+}
+
+func NewTrackingHandler(service service.ITrackingService, config *events.Config) *TrackingHandler {
+	return &TrackingHandler{service: service, heartbeat: config.Heartbeat()}
+}
+
+// SSE-поток событий заказа. Браузер переподключается сам и передает Last-Event-ID -
+// id последнего полученного перехода статуса
+func (t *TrackingHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	claims, err := security.GetClaimsFromContext(r)
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, nil), "Tracking: Stream")
+		return
+	}
+
+	orderId, err := parseIdVar(r, "id")
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorGetQueryParam, nil), "Tracking: Stream")
+		return
+	}
+
+	lastEventId := events.ParseLastEventId(r.Header.Get("Last-Event-ID"))
+
+	sub, replay, err := t.service.Open(r.Context(), claims.Id, orderId, lastEventId)
+	if err != nil {
+		handleServiceError(w, err, "Tracking: Stream")
+		return
+	}
+	defer sub.Close()
+
+	// Поток живет дольше таймаутов сервера
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	sent := lastEventId
+	send := func(event events.Event) bool {
+		// Переход мог прийти и из истории, и из подписки
+		if event.Id != 0 && event.Id <= sent {
+			return true
+		}
+		if err := events.WriteEvent(w, event); err != nil {
+			return false
+		}
+		if event.Id > sent {
+			sent = event.Id
+		}
+		return true
+	}
+
+	for _, event := range replay {
+		if !send(event) {
+			return
+		}
+	}
+	if rc.Flush() != nil {
+		return
+	}
+
+	ticker := time.NewTicker(t.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-sub.Events():
+			// Подписка закрыта: клиент не успевал читать или сервер останавливается
+			if !ok || !send(event) {
+				return
+			}
+		case <-ticker.C:
+			if events.WriteHeartbeat(w) != nil {
+				return
+			}
+		}
+
+		if rc.Flush() != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"arabic/internal/dto"
+	"arabic/internal/service"
+	"arabic/pkg/customError"
+	security "arabic/pkg/security/auth"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+type TwoFactorHandler struct {
+	service service.ITwoFactorService
+}
+
+func NewTwoFactorHandler(service service.ITwoFactorService) *TwoFactorHandler {
+	return &TwoFactorHandler{service: service}
+}
+
+func (h *TwoFactorHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	claims, err := security.GetClaimsFromContext(r)
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, nil), "TwoFactor: GetStatus")
+		return
+	}
+
+	status, err := h.service.GetStatus(r.Context(), claims.Id, claims.Role)
+	if err != nil {
+		handleServiceError(w, err, "TwoFactor: GetStatus")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, status)
+}
+
+// Новый секрет для приложения. Действовать начнет после /user/2fa/enable
+func (h *TwoFactorHandler) Setup(w http.ResponseWriter, r *http.Request) {
+	claims, err := security.GetClaimsFromContext(r)
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, nil), "TwoFactor: Setup")
+		return
+	}
+
+	setup, err := h.service.Setup(r.Context(), claims.Id, claims.UserEmail)
+	if err != nil {
+		handleServiceError(w, err, "TwoFactor: Setup")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, setup)
+}
+
+func (h *TwoFactorHandler) Enable(w http.ResponseWriter, r *http.Request) {
+	claims, err := security.GetClaimsFromContext(r)
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, nil), "TwoFactor: Enable")
+		return
+	}
+
+	req := dto.TwoFactorEnableRequest{}
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorParse, nil), "TwoFactor: Enable Decode")
+		return
+	}
+
+	if ok, errStrings := req.IsValid(); !ok {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, strings.Join(errStrings, "; "), nil), "TwoFactor: Enable validation error")
+		return
+	}
+
+	codes, err := h.service.Enable(r.Context(), claims.Id, req.Code)
+	if err != nil {
+		handleServiceError(w, err, "TwoFactor: Enable")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, codes)
+}
+
+func (h *TwoFactorHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	claims, req, ok := h.parseCodeRequest(w, r, "TwoFactor: Disable")
+	if !ok {
+		return
+	}
+
+	if err := h.service.Disable(r.Context(), claims.Id, claims.Role, req); err != nil {
+		handleServiceError(w, err, "TwoFactor: Disable")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, nil)
+}
+
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	claims, req, ok := h.parseCodeRequest(w, r, "TwoFactor: RegenerateRecoveryCodes")
+	if !ok {
+		return
+	}
+
+	codes, err := h.service.RegenerateRecoveryCodes(r.Context(), claims.Id, req)
+	if err != nil {
+		handleServiceError(w, err, "TwoFactor: RegenerateRecoveryCodes")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, codes)
+}
+
+func (h *TwoFactorHandler) parseCodeRequest(w http.ResponseWriter, r *http.Request, op string) (*security.CustomClaims, *dto.TwoFactorCodeRequest, bool) {
+	claims, err := security.GetClaimsFromContext(r)
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, nil), op)
+		return nil, nil, false
+	}
+
+	req := &dto.TwoFactorCodeRequest{}
+	if err = json.NewDecoder(r.Body).Decode(req); err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorParse, nil), op+" Decode")
+		return nil, nil, false
+	}
+
+	if ok, errStrings := req.IsValid(); !ok {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, strings.Join(errStrings, "; "), nil), op+" validation error")
+		return nil, nil, false
+	}
+
+	return claims, req, true
+}
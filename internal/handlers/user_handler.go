@@ -12,11 +12,13 @@ import (
 )
 
 type UserHandler struct {
-	service service.IUserService
+	service     service.IUserService
+	cartService service.ICartService
+	cookies     *security.CookieConfig
 }
 
-func NewUserHandler(service service.IUserService) *UserHandler {
-	return &UserHandler{service: service}
+func NewUserHandler(service service.IUserService, cartService service.ICartService, cookies *security.CookieConfig) *UserHandler {
+	return &UserHandler{service: service, cartService: cartService, cookies: cookies}
 }
 
 func (u *UserHandler) Create() http.HandlerFunc {
@@ -63,6 +65,8 @@ func (u *UserHandler) Get(w http.ResponseWriter, r *http.Request) {
 	respondSuccess(w, http.StatusOK, user)
 }
 
+// При включенном втором факторе вместо cookie возвращается токен для /user/login/2fa.
+// С return_tokens токены приходят в теле ответа вместо cookie
 func (u *UserHandler) Login() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req dto.UserLoginRequest
@@ -70,15 +74,116 @@ func (u *UserHandler) Login() http.HandlerFunc {
 			respondError(w, http.StatusBadRequest, "Invalid request payload")
 			return
 		}
-		user, token, err := u.service.Login(r.Context(), req.Email, req.Password)
+		result, err := u.service.Login(r.Context(), req.Email, req.Password, sessionMeta(r))
 
 		if err != nil {
 			handleServiceError(w, err, "Login")
 			return
 		}
-		setAuthCookie(w, token)
-		respondSuccess(w, http.StatusOK, user)
+
+		if result.Challenge != nil {
+			respondSuccess(w, http.StatusOK, result.Challenge)
+			return
+		}
+
+		u.completeLogin(w, r, result, req.ReturnTokens, result.User)
+	}
+}
+
+// Второй шаг входа: код из приложения или код восстановления
+func (u *UserHandler) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	req := dto.TwoFactorLoginRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorParse, nil), "User: Decode error")
+		return
+	}
+
+	if ok, errStrings := req.IsValid(); !ok {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, strings.Join(errStrings, "; "), nil), "User: Validation error")
+		return
 	}
+
+	result, err := u.service.LoginTwoFactor(r.Context(), &req, sessionMeta(r))
+	if err != nil {
+		handleServiceError(w, err, "User: LoginTwoFactor")
+		return
+	}
+
+	u.completeLogin(w, r, result, req.ReturnTokens, &dto.TwoFactorLoginResponse{UserGetResponse: result.User, RecoveryCodes: result.RecoveryCodes})
+}
+
+// Подключение второго фактора при входе, если роль его требует
+func (u *UserHandler) SetupTwoFactorLogin(w http.ResponseWriter, r *http.Request) {
+	req := dto.TwoFactorChallengeRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorParse, nil), "User: Decode error")
+		return
+	}
+
+	if ok, errStrings := req.IsValid(); !ok {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, strings.Join(errStrings, "; "), nil), "User: Validation error")
+		return
+	}
+
+	setup, err := u.service.SetupTwoFactorLogin(r.Context(), req.ChallengeToken)
+	if err != nil {
+		handleServiceError(w, err, "User: SetupTwoFactorLogin")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, setup)
+}
+
+// Токен из тела запроса меняется на токены в теле ответа, из cookie - на cookie
+func (u *UserHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	refreshToken, fromBody := readRefreshToken(r)
+	tokens, err := u.service.Refresh(r.Context(), refreshToken)
+
+	if err != nil {
+		if !fromBody {
+			clearAuthCookies(w, u.cookies)
+		}
+		handleServiceError(w, err, "User: Refresh")
+		return
+	}
+
+	if fromBody {
+		respondSuccess(w, http.StatusOK, dto.NewTokenResponse(tokens))
+		return
+	}
+
+	setAuthCookies(w, u.cookies, tokens)
+	respondSuccess(w, http.StatusOK, nil)
+}
+
+// Cookie очищаем в любом случае, даже если refresh токен уже недействителен
+func (u *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	refreshToken, _ := readRefreshToken(r)
+	err := u.service.Logout(r.Context(), refreshToken)
+	clearAuthCookies(w, u.cookies)
+
+	if err != nil {
+		handleServiceError(w, err, "User: Logout")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, nil)
+}
+
+func (u *UserHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	claims, err := security.GetClaimsFromContext(r)
+	if err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, nil), "User: LogoutAll")
+		return
+	}
+
+	if err = u.service.LogoutAll(r.Context(), claims.Id); err != nil {
+		handleServiceError(w, err, "User: LogoutAll")
+		return
+	}
+
+	clearAuthCookies(w, u.cookies)
+	respondSuccess(w, http.StatusOK, nil)
 }
 
 func (u *UserHandler) Update(w http.ResponseWriter, r *http.Request) {
@@ -134,3 +239,77 @@ func (u *UserHandler) UpdateAddress(w http.ResponseWriter, r *http.Request) {
 
 	respondSuccess(w, http.StatusOK, nil)
 }
+
+// 200, даже если email не зарегистрирован. 429, если запросов с этого email или IP слишком много
+func (u *UserHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	req := dto.PasswordForgotRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorParse, nil), "User: Decode error")
+		return
+	}
+
+	if ok, errStrings := req.IsValid(); !ok {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, strings.Join(errStrings, "; "), nil), "User: Validation error")
+		return
+	}
+
+	if err := u.service.ForgotPassword(r.Context(), req.Email, sessionMeta(r)); err != nil {
+		handleServiceError(w, err, "User: ForgotPassword")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, nil)
+}
+
+// Сессии сбрасываются, поэтому cookie текущего клиента тоже очищаем
+func (u *UserHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	req := dto.PasswordResetRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, customError.ErrorParse, nil), "User: Decode error")
+		return
+	}
+
+	if ok, errStrings := req.IsValid(); !ok {
+		handleServiceError(w, customError.NewServiceError(http.StatusBadRequest, strings.Join(errStrings, "; "), nil), "User: Validation error")
+		return
+	}
+
+	if err := u.service.ResetPassword(r.Context(), &req); err != nil {
+		handleServiceError(w, err, "User: ResetPassword")
+		return
+	}
+
+	clearAuthCookies(w, u.cookies)
+	respondSuccess(w, http.StatusOK, nil)
+}
+
+func (u *UserHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	if err := u.service.VerifyEmail(r.Context(), r.URL.Query().Get("token")); err != nil {
+		handleServiceError(w, err, "User: VerifyEmail")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, nil)
+}
+
+// Переносим анонимную корзину в корзину пользователя и отдаем токены: в cookie вместе с body
+// или, если клиент просил, в теле ответа. Ошибка слияния не должна мешать логину
+func (u *UserHandler) completeLogin(w http.ResponseWriter, r *http.Request, result *dto.LoginResult, returnTokens bool, body any) {
+	if cartToken := readCartToken(r); cartToken != "" {
+		if err := u.cartService.MergeGuestCart(r.Context(), cartToken, result.User.Id); err == nil {
+			clearCartToken(w)
+		}
+	}
+
+	if returnTokens {
+		respondSuccess(w, http.StatusOK, &dto.LoginTokensResponse{
+			User:          result.User,
+			Tokens:        dto.NewTokenResponse(result.Tokens),
+			RecoveryCodes: result.RecoveryCodes,
+		})
+		return
+	}
+
+	setAuthCookies(w, u.cookies, result.Tokens)
+	respondSuccess(w, http.StatusOK, body)
+}
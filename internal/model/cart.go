@@ -0,0 +1,89 @@
+package model
+
+import (
+	"arabic/internal/dto"
+	"math"
+)
+
+type CartItem struct {
+	CartId          int64   `json:"cart_id"`
+	CatalogId       uint    `json:"catalog_id"`
+	Quantity        int     `json:"quantity"`
+	Name            string  `json:"name"`
+	ImageUrl        string  `json:"image_url"`
+	Price           float32 `json:"price"`
+	DiscountPercent float32 `json:"discount_percent"`
+	Amount          int     `json:"amount"`
+	Weight          float32 `json:"weight"`
+}
+
+// Цена за единицу с учетом скидки товара
+func (c *CartItem) UnitPrice() float32 {
+	return DiscountedPrice(c.Price, c.DiscountPercent)
+}
+
+func (c *CartItem) ToResponse(imagePrefix string) *dto.CartItemResponse {
+	return &dto.CartItemResponse{
+		CatalogId:       c.CatalogId,
+		Name:            c.Name,
+		ImageUrl:        imagePrefix + c.ImageUrl,
+		Price:           c.Price,
+		DiscountPercent: c.DiscountPercent,
+		UnitPrice:       c.UnitPrice(),
+		Quantity:        c.Quantity,
+		Available:       c.Amount,
+		LineTotal:       RoundMoney(float64(c.UnitPrice()) * float64(c.Quantity)),
+	}
+}
+
+func NewCartResponse(items []*CartItem, imagePrefix string) *dto.CartResponse {
+	resp := &dto.CartResponse{
+		Items: make([]*dto.CartItemResponse, 0, len(items)),
+	}
+
+	var subtotal, total, weight float64
+	for _, item := range items {
+		itemResp := item.ToResponse(imagePrefix)
+		resp.Items = append(resp.Items, itemResp)
+
+		resp.TotalQuantity += item.Quantity
+		subtotal += float64(item.Price) * float64(item.Quantity)
+		total += float64(itemResp.LineTotal)
+		weight += float64(item.Weight) * float64(item.Quantity)
+	}
+
+	resp.Subtotal = RoundMoney(subtotal)
+	resp.Total = RoundMoney(total)
+	resp.Discount = RoundMoney(subtotal - total)
+	resp.TotalWeight = float32(weight)
+
+	return resp
+}
+
+// Количества товаров после слияния анонимной корзины с корзиной пользователя.
+// Сумма ограничивается остатком на складе, уже лежащее у пользователя не уменьшается
+func MergeCartQuantities(userItems, guestItems []*CartItem) map[uint]int {
+	current := make(map[uint]int, len(userItems))
+	for _, item := range userItems {
+		current[item.CatalogId] = item.Quantity
+	}
+
+	quantities := make(map[uint]int, len(guestItems))
+	for _, item := range guestItems {
+		quantity := min(current[item.CatalogId]+item.Quantity, item.Amount)
+		if quantity > current[item.CatalogId] {
+			quantities[item.CatalogId] = quantity
+		}
+	}
+
+	return quantities
+}
+
+func DiscountedPrice(price, discountPercent float32) float32 {
+	return RoundMoney(float64(price) * (1 - float64(discountPercent)/100))
+}
+
+// Округляет денежную сумму до копеек
+func RoundMoney(value float64) float32 {
+	return float32(math.Round(value*100) / 100)
+}
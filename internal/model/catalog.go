@@ -1,6 +1,20 @@
 package model
 
-import "arabic/internal/dto"
+import (
+	"arabic/internal/dto"
+	"html"
+	"strings"
+	"time"
+)
+
+// Границы совпадений, которые расставляет ts_headline. Управляющие символы не встречаются
+// в тексте товаров, поэтому текст экранируется целиком, а теги подсветки добавляются после
+const (
+	HighlightStart = "\x01"
+	HighlightStop  = "\x02"
+)
+
+var highlightTags = strings.NewReplacer(HighlightStart, "<mark>", HighlightStop, "</mark>")
 
 type Catalog struct {
 	Id              uint    `json:"id"`
@@ -12,10 +26,23 @@ type Catalog struct {
 	Sku             string  `json:"sku"`
 	CategoryId      uint    `json:"category_id"`
 	ImageUrl        string  `json:"image_url"`
-	Weight          float32 `json:"weight"`
+	// Вес единицы товара в граммах
+	Weight float32 `json:"weight"`
+	// Средняя оценка и число одобренных отзывов
+	Rating       float32 `json:"rating"`
+	ReviewsCount int     `json:"reviews_count"`
+	Tags         []*Tag  `json:"tags"`
+	// Товар в избранном у пользователя из запроса, для анонимного всегда false
+	IsFavorite bool      `json:"is_favorite"`
+	CreatedAt  time.Time `json:"created_at"`
 }
 
 func (c *Catalog) ToResponse(imagePrefix string) *dto.CatalogResponse {
+	tags := make([]*dto.TagResponse, 0, len(c.Tags))
+	for _, tag := range c.Tags {
+		tags = append(tags, tag.ToResponse())
+	}
+
 	return &dto.CatalogResponse{
 		Id:              c.Id,
 		Description:     c.Description,
@@ -27,5 +54,30 @@ func (c *Catalog) ToResponse(imagePrefix string) *dto.CatalogResponse {
 		DiscountPercent: c.DiscountPercent,
 		ImageUrl:        imagePrefix + c.ImageUrl,
 		Weight:          c.Weight,
+		Rating:          c.Rating,
+		ReviewsCount:    c.ReviewsCount,
+		IsFavorite:      c.IsFavorite,
+		Tags:            tags,
+	}
+}
+
+type CatalogSearchResult struct {
+	Catalog
+	Rank          float32
+	NameHighlight string
+	Snippet       string
+}
+
+// HTML подсветки: разметка из названия и описания товара выводится как текст
+func HighlightHTML(headline string) string {
+	return highlightTags.Replace(html.EscapeString(headline))
+}
+
+func (c *CatalogSearchResult) ToResponse(imagePrefix string) *dto.CatalogSearchItem {
+	return &dto.CatalogSearchItem{
+		CatalogResponse: *c.Catalog.ToResponse(imagePrefix),
+		Rank:            c.Rank,
+		NameHighlight:   c.NameHighlight,
+		Snippet:         c.Snippet,
 	}
 }
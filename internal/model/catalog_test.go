@@ -0,0 +1,14 @@
+package model_test
+
+import (
+	"arabic/internal/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHighlightHTML(t *testing.T) {
+	headline := "<img src=x onerror=alert(1)> " + model.HighlightStart + "Яблоки" + model.HighlightStop + " & груши"
+
+	assert.Equal(t, "&lt;img src=x onerror=alert(1)&gt; <mark>Яблоки</mark> &amp; груши", model.HighlightHTML(headline))
+}
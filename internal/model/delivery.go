@@ -0,0 +1,115 @@
+package model
+
+import (
+	"arabic/internal/dto"
+	"arabic/pkg/geo"
+	"encoding/json"
+	"math"
+	"time"
+)
+
+// Вес товаров (catalogs.weight, orders.total_weight) хранится в граммах, грузоподъемность курьера - в килограммах
+const gramsPerKg = 1000
+
+type DeliveryZone struct {
+	Id       int64  `json:"id"`
+	Name     string `json:"name"`
+	IsActive bool   `json:"is_active"`
+	// GeoJSON как он хранится в БД и разобранная геометрия для проверки точки
+	Polygon               json.RawMessage  `json:"polygon"`
+	Area                  geo.MultiPolygon `json:"-"`
+	MinOrderAmount        float32          `json:"min_order_amount"`
+	BaseFee               float32          `json:"base_fee"`
+	FreeDeliveryThreshold float32          `json:"free_delivery_threshold"`
+	PerKgFee              float32          `json:"per_kg_fee"`
+	EtaMinutes            int              `json:"eta_minutes"`
+	CreatedAt             time.Time        `json:"created_at"`
+}
+
+// Стоимость доставки для суммы товаров (с учетом скидок) и веса в граммах
+func (z *DeliveryZone) Fee(amount, weight float32) float32 {
+	if z.FreeDeliveryThreshold > 0 && amount >= z.FreeDeliveryThreshold {
+		return 0
+	}
+
+	return RoundMoney(float64(z.BaseFee) + float64(z.PerKgFee)*float64(weight)/gramsPerKg)
+}
+
+func (z *DeliveryZone) Contains(p geo.Point) bool {
+	return z.Area != nil && z.Area.Contains(p)
+}
+
+// Первая по порядку активная зона, в которую попадает точка
+func FindDeliveryZone(zones []*DeliveryZone, p geo.Point) (*DeliveryZone, bool) {
+	for _, zone := range zones {
+		if zone.IsActive && zone.Contains(p) {
+			return zone, true
+		}
+	}
+	return nil, false
+}
+
+// Сколько не хватает до бесплатной доставки, 0 если порог достигнут или его нет
+func (z *DeliveryZone) AmountToFreeDelivery(amount float32) float32 {
+	if z.FreeDeliveryThreshold <= 0 {
+		return 0
+	}
+	return RoundMoney(math.Max(float64(z.FreeDeliveryThreshold-amount), 0))
+}
+
+// Шаблон слота доставки зоны на день недели. Время в формате HH:MM
+type DeliverySlotTemplate struct {
+	Id        int64  `json:"id"`
+	ZoneId    int64  `json:"zone_id"`
+	Weekday   int    `json:"weekday"`
+	StartTime string `json:"start_time"`
+	EndTime   string `json:"end_time"`
+	Capacity  int    `json:"capacity"`
+}
+
+type DeliverySlot struct {
+	Id        int64     `json:"id"`
+	ZoneId    int64     `json:"zone_id"`
+	Date      time.Time `json:"date"`
+	StartTime string    `json:"start_time"`
+	EndTime   string    `json:"end_time"`
+	Capacity  int       `json:"capacity"`
+	Reserved  int       `json:"reserved"`
+}
+
+func (z *DeliveryZone) ToResponse() *dto.DeliveryZoneResponse {
+	return &dto.DeliveryZoneResponse{
+		Id:                    z.Id,
+		Name:                  z.Name,
+		IsActive:              z.IsActive,
+		Polygon:               z.Polygon,
+		MinOrderAmount:        z.MinOrderAmount,
+		BaseFee:               z.BaseFee,
+		FreeDeliveryThreshold: z.FreeDeliveryThreshold,
+		PerKgFee:              z.PerKgFee,
+		EtaMinutes:            z.EtaMinutes,
+	}
+}
+
+func (t *DeliverySlotTemplate) ToResponse() *dto.SlotTemplateResponse {
+	return &dto.SlotTemplateResponse{
+		Id:        t.Id,
+		ZoneId:    t.ZoneId,
+		Weekday:   t.Weekday,
+		StartTime: t.StartTime,
+		EndTime:   t.EndTime,
+		Capacity:  t.Capacity,
+	}
+}
+
+func (s *DeliverySlot) ToResponse() *dto.DeliverySlotResponse {
+	return &dto.DeliverySlotResponse{
+		Id:        s.Id,
+		ZoneId:    s.ZoneId,
+		Date:      s.Date.Format(time.DateOnly),
+		StartTime: s.StartTime,
+		EndTime:   s.EndTime,
+		Capacity:  s.Capacity,
+		Remaining: max(s.Capacity-s.Reserved, 0),
+	}
+}
@@ -0,0 +1,126 @@
+package model
+
+import (
+	"arabic/internal/dto"
+	"arabic/pkg/geo"
+	"time"
+)
+
+type CourierProfile struct {
+	UserId   int64  `json:"user_id"`
+	Username string `json:"username"`
+	// Максимум заказов и веса (кг) в одном маршруте
+	MaxOrders   int     `json:"max_orders"`
+	MaxWeight   float32 `json:"max_weight"`
+	IsAvailable bool    `json:"is_available"`
+	// У курьера есть маршрут с недоставленными заказами
+	Busy bool `json:"busy"`
+}
+
+// Заказ, ожидающий назначения курьера
+type DispatchOrder struct {
+	Id        int64      `json:"id"`
+	ZoneId    int64      `json:"zone_id"`
+	SlotId    *int64     `json:"slot_id"`
+	SlotStart *time.Time `json:"slot_start"`
+	Location  geo.Point  `json:"location"`
+	// Адрес доставки с теми же координатами
+	Address UserAddress `json:"address"`
+	// Граммы
+	Weight float32 `json:"weight"`
+}
+
+type DeliveryRoute struct {
+	Id          int64        `json:"id"`
+	CourierId   int64        `json:"courier_id"`
+	ZoneId      *int64       `json:"zone_id"`
+	SlotId      *int64       `json:"slot_id"`
+	TotalWeight float32      `json:"total_weight"`
+	Distance    float64      `json:"distance"`
+	CreatedBy   int64        `json:"created_by"`
+	CreatedAt   time.Time    `json:"created_at"`
+	Stops       []*RouteStop `json:"stops"`
+}
+
+type RouteStop struct {
+	Position int         `json:"position"`
+	OrderId  int64       `json:"order_id"`
+	Status   string      `json:"status"`
+	Address  UserAddress `json:"address"`
+}
+
+func (c *CourierProfile) MaxWeightGrams() float32 {
+	return c.MaxWeight * gramsPerKg
+}
+
+// Курьер может получить новый маршрут
+func (c *CourierProfile) CanTakeRoute() bool {
+	return c.IsAvailable && !c.Busy
+}
+
+func (c *CourierProfile) ToResponse() *dto.CourierResponse {
+	return &dto.CourierResponse{
+		UserId:      c.UserId,
+		Username:    c.Username,
+		MaxOrders:   c.MaxOrders,
+		MaxWeight:   c.MaxWeight,
+		IsAvailable: c.IsAvailable,
+		Busy:        c.Busy,
+	}
+}
+
+// Остановки, до которых курьер еще не доехал, и точка, откуда он к ним едет:
+// последняя завершенная остановка или склад. Остановки без координат пропускаются
+func (r *DeliveryRoute) RemainingStops(depot geo.Point) (geo.Point, []*RouteStop) {
+	from := depot
+	var remaining []*RouteStop
+
+	for _, stop := range r.Stops {
+		if stop.Address.Latitude == nil || stop.Address.Longitude == nil {
+			continue
+		}
+
+		if stop.Status == OrderStatusOutForDelivery {
+			remaining = append(remaining, stop)
+		} else {
+			from = stop.Location()
+		}
+	}
+
+	return from, remaining
+}
+
+func (s *RouteStop) Location() geo.Point {
+	return geo.Point{Lat: *s.Address.Latitude, Lon: *s.Address.Longitude}
+}
+
+func (r *DeliveryRoute) ToResponse() *dto.DeliveryRouteResponse {
+	stops := make([]*dto.RouteStopResponse, 0, len(r.Stops))
+	for _, stop := range r.Stops {
+		stops = append(stops, &dto.RouteStopResponse{
+			Position: stop.Position,
+			OrderId:  stop.OrderId,
+			Status:   stop.Status,
+			Address: dto.AddressResponse{
+				Apartment: stop.Address.Apartment,
+				House:     stop.Address.House,
+				Street:    stop.Address.Street,
+				City:      stop.Address.City,
+				Region:    stop.Address.Region,
+				Latitude:  stop.Address.Latitude,
+				Longitude: stop.Address.Longitude,
+			},
+		})
+	}
+
+	return &dto.DeliveryRouteResponse{
+		Id:             r.Id,
+		CourierId:      r.CourierId,
+		ZoneId:         r.ZoneId,
+		DeliverySlotId: r.SlotId,
+		TotalWeight:    r.TotalWeight,
+		Distance:       r.Distance,
+		CreatedAt:      r.CreatedAt,
+		Stops:          stops,
+	}
+}
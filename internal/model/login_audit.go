@@ -0,0 +1,36 @@
+package model
+
+import (
+	"arabic/internal/dto"
+	"time"
+)
+
+// Причины неудачного входа в журнале
+const (
+	LoginUnknownEmail  = "unknown_email"
+	LoginWrongPassword = "wrong_password"
+	LoginThrottled     = "throttled"
+	LoginWrongCode     = "wrong_code"
+)
+
+type LoginFailure struct {
+	Id        int64
+	Email     string
+	UserId    *int64
+	Ip        string
+	UserAgent string
+	Reason    string
+	CreatedAt time.Time
+}
+
+func (l *LoginFailure) ToResponse() *dto.LoginFailureResponse {
+	return &dto.LoginFailureResponse{
+		Id:        l.Id,
+		Email:     l.Email,
+		UserId:    l.UserId,
+		Ip:        l.Ip,
+		UserAgent: l.UserAgent,
+		Reason:    l.Reason,
+		CreatedAt: l.CreatedAt,
+	}
+}
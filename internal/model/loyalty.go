@@ -0,0 +1,38 @@
+package model
+
+import (
+	"arabic/internal/dto"
+	"time"
+)
+
+// Счета бонусной книги. Каждая проводка переводит баллы между счетом покупателя и счетом магазина
+const (
+	LoyaltyAccountCustomer = "customer"
+	LoyaltyAccountIssued   = "issued"
+	LoyaltyAccountRedeemed = "redeemed"
+)
+
+const (
+	LoyaltyAccrual            = "accrual"
+	LoyaltyRedemption         = "redemption"
+	LoyaltyAccrualReversal    = "accrual_reversal"
+	LoyaltyRedemptionReversal = "redemption_reversal"
+)
+
+// Движение по счету покупателя: points > 0 - начисление, < 0 - списание
+type LoyaltyEntry struct {
+	Id        int64     `json:"id"`
+	Type      string    `json:"type"`
+	Points    int64     `json:"points"`
+	OrderId   *int64    `json:"order_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (e *LoyaltyEntry) ToResponse() *dto.LoyaltyEntryResponse {
+	return &dto.LoyaltyEntryResponse{
+		Type:      e.Type,
+		Points:    e.Points,
+		OrderId:   e.OrderId,
+		CreatedAt: e.CreatedAt,
+	}
+}
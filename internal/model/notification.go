@@ -0,0 +1,89 @@
+package model
+
+import (
+	"arabic/internal/dto"
+	"time"
+)
+
+// Типы подписок на товар, они же типы уведомлений
+const (
+	SubscriptionBackInStock = "back_in_stock"
+	SubscriptionPriceDrop   = "price_drop"
+)
+
+var SubscriptionTypes = []string{SubscriptionBackInStock, SubscriptionPriceDrop}
+
+// Остальные типы уведомлений. Тип совпадает с именем шаблона письма
+const (
+	NotificationRegistration  = "registration"
+	NotificationOrderStatus   = "order_status"
+	NotificationPasswordReset = "password_reset"
+)
+
+const (
+	NotificationPending = "pending"
+	NotificationSent    = "sent"
+	NotificationFailed  = "failed"
+)
+
+type CatalogSubscription struct {
+	CatalogId   uint      `json:"catalog_id"`
+	CatalogName string    `json:"catalog_name"`
+	Type        string    `json:"type"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (s *CatalogSubscription) ToResponse() *dto.SubscriptionResponse {
+	return &dto.SubscriptionResponse{
+		CatalogId:   s.CatalogId,
+		CatalogName: s.CatalogName,
+		Type:        s.Type,
+		CreatedAt:   s.CreatedAt,
+	}
+}
+
+// Уведомление из очереди вместе с адресом и языком получателя.
+// Data - параметры шаблона письма
+type Notification struct {
+	Id       int64
+	UserId   int64
+	Email    string
+	Language string
+	Type     string
+	Data     map[string]any
+	Attempts int
+}
+
+// Цена и остаток товара, по которым определяется, кого уведомить
+type CatalogState struct {
+	Name            string
+	Price           float32
+	DiscountPercent float32
+	Amount          int
+}
+
+func (s *CatalogState) FinalPrice() float32 {
+	return DiscountedPrice(s.Price, s.DiscountPercent)
+}
+
+// Уведомления подписчикам по изменению товара: поступление после нулевого остатка
+// и снижение итоговой цены с учетом скидки
+func CatalogChangeNotifications(before, after *CatalogState) []*Notification {
+	var notifications []*Notification
+
+	if before.Amount <= 0 && after.Amount > 0 {
+		notifications = append(notifications, &Notification{
+			Type: SubscriptionBackInStock,
+			Data: map[string]any{"name": after.Name, "amount": after.Amount, "price": after.FinalPrice()},
+		})
+	}
+
+	if after.FinalPrice() < before.FinalPrice() {
+		notifications = append(notifications, &Notification{
+			Type: SubscriptionPriceDrop,
+			Data: map[string]any{"name": after.Name, "price": after.FinalPrice(), "old_price": before.FinalPrice()},
+		})
+	}
+
+	return notifications
+}
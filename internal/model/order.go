@@ -0,0 +1,154 @@
+package model
+
+import (
+	"arabic/internal/dto"
+	"arabic/pkg/loyalty"
+	"time"
+)
+
+type Order struct {
+	Id          int64       `json:"id"`
+	UserId      int64       `json:"user_id"`
+	Status      string      `json:"status"`
+	Subtotal    float32     `json:"subtotal"`
+	Discount    float32     `json:"discount"`
+	Total       float32     `json:"total"`
+	TotalWeight float32     `json:"total_weight"`
+	Address     UserAddress `json:"address"`
+	// Слот доставки, nil если покупатель его не выбрал
+	DeliverySlotId *int64  `json:"delivery_slot_id"`
+	DeliveryZoneId *int64  `json:"delivery_zone_id"`
+	DeliveryFee    float32 `json:"delivery_fee"`
+	// Промокод и скидка акций на товары, зафиксированные при оформлении
+	PromoCode     string  `json:"promo_code"`
+	PromoDiscount float32 `json:"promo_discount"`
+	// Баллы, которыми оплачена часть заказа, и их стоимость
+	LoyaltyPoints   int64   `json:"loyalty_points"`
+	LoyaltyDiscount float32 `json:"loyalty_discount"`
+	// Ставка начисления баллов на момент оформления
+	LoyaltyEarnRate float64 `json:"-"`
+	// Правила списания баллов при оформлении
+	Loyalty *loyalty.Config `json:"-"`
+	// Зона, по тарифам которой считается доставка при оформлении
+	Zone *DeliveryZone `json:"-"`
+	// Акции, доступные при оформлении, и результат расчета цены
+	Promotions []*Promotion    `json:"-"`
+	Pricing    *PriceBreakdown `json:"-"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+	Items      []*OrderItem    `json:"items"`
+}
+
+type OrderItem struct {
+	Id              int64   `json:"id"`
+	OrderId         int64   `json:"order_id"`
+	CatalogId       uint    `json:"catalog_id"`
+	Name            string  `json:"name"`
+	Sku             string  `json:"sku"`
+	Price           float32 `json:"price"`
+	DiscountPercent float32 `json:"discount_percent"`
+	UnitPrice       float32 `json:"unit_price"`
+	Weight          float32 `json:"weight"`
+	Quantity        int     `json:"quantity"`
+	// Для ограничений акций, заполняются при оформлении
+	CategoryId uint    `json:"-"`
+	TagIds     []int64 `json:"-"`
+}
+
+// Пересчитывает суммы заказа по снимкам позиций с учетом акций и промокода
+func (o *Order) CalculateTotals() {
+	lines := make([]*PricingLine, 0, len(o.Items))
+	for _, item := range o.Items {
+		item.UnitPrice = DiscountedPrice(item.Price, item.DiscountPercent)
+		lines = append(lines, &PricingLine{
+			CatalogId:       item.CatalogId,
+			CategoryId:      item.CategoryId,
+			TagIds:          item.TagIds,
+			Name:            item.Name,
+			Price:           item.Price,
+			DiscountPercent: item.DiscountPercent,
+			Quantity:        item.Quantity,
+			Weight:          item.Weight,
+		})
+	}
+
+	o.Pricing = CalculatePrice(lines, o.Promotions, o.PromoCode, o.Zone, time.Now())
+	o.PromoCode = o.Pricing.PromoCode
+
+	o.Subtotal = o.Pricing.Subtotal
+	o.PromoDiscount = o.Pricing.PromoDiscount
+	o.Discount = RoundMoney(float64(o.Pricing.ItemsDiscount) + float64(o.Pricing.PromoDiscount))
+	o.TotalWeight = o.Pricing.Weight
+	o.DeliveryFee = o.Pricing.DeliveryFee
+	o.Total = o.Pricing.Total
+}
+
+// Оставляет из LoyaltyPoints столько баллов, сколько можно списать по правилам Loyalty,
+// и уменьшает итог на их стоимость. Вызывается после CalculateTotals
+func (o *Order) RedeemLoyalty() {
+	points, discount := o.Loyalty.Redeemable(float64(o.ItemsTotal()), o.LoyaltyPoints)
+
+	o.LoyaltyPoints = points
+	o.LoyaltyDiscount = float32(discount)
+	o.LoyaltyEarnRate = o.Loyalty.EarnRate
+	o.Total = RoundMoney(float64(o.Total) - discount)
+}
+
+// Баллы за доставленный заказ: начисляются на сумму товаров, оплаченную деньгами
+func (o *Order) EarnedPoints() int64 {
+	return loyalty.Earned(float64(o.ItemsTotal()), o.LoyaltyEarnRate)
+}
+
+// Сумма товаров без доставки
+func (o *Order) ItemsTotal() float32 {
+	return RoundMoney(float64(o.Total) - float64(o.DeliveryFee))
+}
+
+// Сумма товаров меньше минимального заказа зоны
+func (o *Order) BelowMinOrder() bool {
+	return o.Zone != nil && o.ItemsTotal() < o.Zone.MinOrderAmount
+}
+
+func (o *Order) ToResponse() *dto.OrderResponse {
+	items := make([]*dto.OrderItemResponse, 0, len(o.Items))
+	for _, item := range o.Items {
+		items = append(items, &dto.OrderItemResponse{
+			CatalogId:       item.CatalogId,
+			Name:            item.Name,
+			Sku:             item.Sku,
+			Price:           item.Price,
+			DiscountPercent: item.DiscountPercent,
+			UnitPrice:       item.UnitPrice,
+			Weight:          item.Weight,
+			Quantity:        item.Quantity,
+			LineTotal:       RoundMoney(float64(item.UnitPrice) * float64(item.Quantity)),
+		})
+	}
+
+	return &dto.OrderResponse{
+		Id:          o.Id,
+		Status:      o.Status,
+		Subtotal:    o.Subtotal,
+		Discount:    o.Discount,
+		Total:       o.Total,
+		TotalWeight: o.TotalWeight,
+		Address: dto.AddressResponse{
+			Apartment: o.Address.Apartment,
+			House:     o.Address.House,
+			Street:    o.Address.Street,
+			City:      o.Address.City,
+			Region:    o.Address.Region,
+			Latitude:  o.Address.Latitude,
+			Longitude: o.Address.Longitude,
+		},
+		DeliveryZoneId:  o.DeliveryZoneId,
+		DeliveryFee:     o.DeliveryFee,
+		PromoCode:       o.PromoCode,
+		PromoDiscount:   o.PromoDiscount,
+		LoyaltyPoints:   o.LoyaltyPoints,
+		LoyaltyDiscount: o.LoyaltyDiscount,
+		DeliverySlotId:  o.DeliverySlotId,
+		CreatedAt:       o.CreatedAt,
+		Items:           items,
+	}
+}
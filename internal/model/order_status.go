@@ -0,0 +1,136 @@
+package model
+
+import (
+	"arabic/internal/dto"
+	"slices"
+	"time"
+)
+
+const (
+	OrderStatusCreated        = "created"
+	OrderStatusConfirmed      = "confirmed"
+	OrderStatusPicking        = "picking"
+	OrderStatusPacked         = "packed"
+	OrderStatusOutForDelivery = "out_for_delivery"
+	OrderStatusDelivered      = "delivered"
+	OrderStatusCancelled      = "cancelled"
+	OrderStatusReturned       = "returned"
+)
+
+const (
+	RoleAdmin     = "admin"
+	RoleModerator = "moderator"
+	RoleWorker    = "worker"
+	RoleCollector = "collector"
+	RoleCourier   = "courier"
+	RoleUser      = "user"
+)
+
+// Разрешенные переходы статусов заказа и роли, которые могут их выполнять.
+// Роль user может менять только свои заказы. Подтверждение заказа выполняет
+// только вебхук оплаты, поэтому ролей у перехода нет
+var orderTransitions = map[string]map[string][]string{
+	OrderStatusCreated: {
+		OrderStatusConfirmed: {},
+		OrderStatusCancelled: {RoleAdmin, RoleWorker, RoleUser},
+	},
+	OrderStatusConfirmed: {
+		OrderStatusPicking:   {RoleAdmin, RoleCollector},
+		OrderStatusCancelled: {RoleAdmin, RoleWorker, RoleUser},
+	},
+	OrderStatusPicking: {
+		OrderStatusPacked:    {RoleAdmin, RoleCollector},
+		OrderStatusCancelled: {RoleAdmin, RoleWorker},
+	},
+	OrderStatusPacked: {
+		OrderStatusOutForDelivery: {RoleAdmin, RoleCourier},
+		OrderStatusCancelled:      {RoleAdmin, RoleWorker},
+	},
+	OrderStatusOutForDelivery: {
+		OrderStatusDelivered: {RoleAdmin, RoleCourier},
+		OrderStatusReturned:  {RoleAdmin, RoleCourier},
+	},
+	OrderStatusDelivered: {
+		OrderStatusReturned: {RoleAdmin, RoleWorker},
+	},
+}
+
+// Действия для ролевых эндпоинтов: /orders/{id}/{action}
+var OrderActions = map[string]string{
+	"start-picking": OrderStatusPicking,
+	"pack":          OrderStatusPacked,
+	"dispatch":      OrderStatusOutForDelivery,
+	"deliver":       OrderStatusDelivered,
+	"cancel":        OrderStatusCancelled,
+	"return":        OrderStatusReturned,
+}
+
+type OrderStatusChange struct {
+	Id         int64     `json:"id"`
+	OrderId    int64     `json:"order_id"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	ChangedBy  int64     `json:"changed_by"`
+	Comment    string    `json:"comment"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Сотрудники склада и администратор работают со всеми заказами
+func IsOrderStaff(role string) bool {
+	return role == RoleAdmin || role == RoleWorker || role == RoleCollector
+}
+
+// Проверяет существует ли переход from -> to
+func IsValidOrderTransition(from, to string) bool {
+	_, ok := orderTransitions[from][to]
+	return ok
+}
+
+// Проверяет может ли роль выполнить переход from -> to
+func CanChangeOrderStatus(from, to, role string) bool {
+	return slices.Contains(orderTransitions[from][to], role)
+}
+
+// Статусы, из которых роль может перевести заказ дальше. Используется для очередей сотрудников
+func ActionableOrderStatuses(role string) []string {
+	var statuses []string
+	for from, targets := range orderTransitions {
+		for _, roles := range targets {
+			if slices.Contains(roles, role) {
+				statuses = append(statuses, from)
+				break
+			}
+		}
+	}
+	slices.Sort(statuses)
+	return statuses
+}
+
+// Отмена и возврат возвращают товары заказа на склад
+func IsRestockingStatus(status string) bool {
+	return status == OrderStatusCancelled || status == OrderStatusReturned
+}
+
+func (o *OrderStatusChange) ToResponse() *dto.OrderStatusChangeResponse {
+	return &dto.OrderStatusChangeResponse{
+		FromStatus: o.FromStatus,
+		ToStatus:   o.ToStatus,
+		ChangedBy:  o.ChangedBy,
+		Comment:    o.Comment,
+		CreatedAt:  o.CreatedAt,
+	}
+}
+
+// О каких статусах заказа покупатель получает письмо. Промежуточные этапы сборки не интересны
+func IsNotifiedOrderStatus(status string) bool {
+	switch status {
+	case OrderStatusConfirmed, OrderStatusOutForDelivery, OrderStatusDelivered, OrderStatusCancelled, OrderStatusReturned:
+		return true
+	}
+	return false
+}
+
+// Покупатель может отклонить недовложение или замену, пока заказ не передан в доставку
+func IsPickAdjustableStatus(status string) bool {
+	return status == OrderStatusPicking || status == OrderStatusPacked
+}
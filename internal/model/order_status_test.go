@@ -0,0 +1,21 @@
+package model_test
+
+import (
+	"arabic/internal/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsRestockingStatus(t *testing.T) {
+	// Товары отмененного и возвращенного заказа снова доступны для продажи
+	assert.True(t, model.IsRestockingStatus(model.OrderStatusCancelled))
+	assert.True(t, model.IsRestockingStatus(model.OrderStatusReturned))
+
+	assert.False(t, model.IsRestockingStatus(model.OrderStatusDelivered))
+	assert.False(t, model.IsRestockingStatus(model.OrderStatusOutForDelivery))
+
+	// Возврат доступен из доставки и после нее
+	assert.True(t, model.IsValidOrderTransition(model.OrderStatusOutForDelivery, model.OrderStatusReturned))
+	assert.True(t, model.IsValidOrderTransition(model.OrderStatusDelivered, model.OrderStatusReturned))
+}
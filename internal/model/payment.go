@@ -0,0 +1,82 @@
+package model
+
+import (
+	"arabic/internal/dto"
+	"arabic/pkg/payment"
+	"time"
+)
+
+const (
+	PaymentStatusPending    = "pending"
+	PaymentStatusAuthorized = "authorized"
+	PaymentStatusFailed     = "failed"
+	PaymentStatusCaptured   = "captured"
+	PaymentStatusRefunded   = "refunded"
+	// Авторизация отменена без списания
+	PaymentStatusCanceled = "canceled"
+)
+
+// Суммы в минимальных единицах валюты
+type Payment struct {
+	Id               int64     `json:"id"`
+	OrderId          int64     `json:"order_id"`
+	Provider         string    `json:"provider"`
+	IntentId         string    `json:"intent_id"`
+	ClientSecret     string    `json:"-"`
+	Currency         string    `json:"currency"`
+	Amount           int64     `json:"amount"`
+	AuthorizedAmount int64     `json:"authorized_amount"`
+	CapturedAmount   int64     `json:"captured_amount"`
+	RefundedAmount   int64     `json:"refunded_amount"`
+	Status           string    `json:"status"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// Проверенный вебхук шлюза. Status - статус, в который переходит платеж
+type PaymentWebhook struct {
+	EventId  string
+	Type     string
+	IntentId string
+	Status   string
+	Amount   int64
+	Payload  []byte
+}
+
+type PaymentWebhookResult struct {
+	// Событие уже обрабатывалось
+	Duplicate bool
+	Payment   *Payment
+	// Переход заказа в confirmed, nil если заказ не менялся
+	Change *OrderStatusChange
+	// Деньги авторизованы, но заказ уже не ждет оплату (отменен или оплачен другим платежом)
+	Release bool
+}
+
+// Для повторной оплаты заказа нужен новый платеж
+func (p *Payment) IsRetryable() bool {
+	return p.Status == PaymentStatusFailed || p.Status == PaymentStatusCanceled
+}
+
+func (p *Payment) ToResponse() *dto.PaymentResponse {
+	resp := &dto.PaymentResponse{
+		Id:               p.Id,
+		OrderId:          p.OrderId,
+		Provider:         p.Provider,
+		IntentId:         p.IntentId,
+		Currency:         p.Currency,
+		Amount:           payment.FromMinorUnits(p.Amount),
+		AuthorizedAmount: payment.FromMinorUnits(p.AuthorizedAmount),
+		CapturedAmount:   payment.FromMinorUnits(p.CapturedAmount),
+		RefundedAmount:   payment.FromMinorUnits(p.RefundedAmount),
+		Status:           p.Status,
+		CreatedAt:        p.CreatedAt,
+	}
+
+	// Секрет нужен клиенту только для оплаты
+	if p.Status == PaymentStatusPending {
+		resp.ClientSecret = p.ClientSecret
+	}
+
+	return resp
+}
@@ -0,0 +1,143 @@
+package model
+
+import (
+	"arabic/internal/dto"
+	"time"
+)
+
+const (
+	PickStatusPending     = "pending"
+	PickStatusPicked      = "picked"
+	PickStatusShort       = "short"
+	PickStatusSubstituted = "substituted"
+)
+
+const (
+	PickEventShort        = "short_pick"
+	PickEventSubstitution = "substitution"
+)
+
+const (
+	PickDecisionPending  = "pending"
+	PickDecisionApproved = "approved"
+	PickDecisionRejected = "rejected"
+)
+
+// Заказ в сборке со строками в порядке обхода склада
+type PickingOrder struct {
+	OrderId        int64       `json:"order_id"`
+	UserId         int64       `json:"user_id"`
+	Status         string      `json:"status"`
+	PickerId       *int64      `json:"picker_id"`
+	DeliverySlotId *int64      `json:"delivery_slot_id"`
+	Lines          []*PickLine `json:"lines"`
+}
+
+// Позиция заказа для сборщика. Категория товара используется как номер ряда склада
+type PickLine struct {
+	ItemId    int64  `json:"item_id"`
+	CatalogId uint   `json:"catalog_id"`
+	Name      string `json:"name"`
+	Sku       string `json:"sku"`
+	// nil если товар удален из каталога
+	CategoryId      *int64 `json:"category_id"`
+	CategoryName    string `json:"category_name"`
+	Quantity        int    `json:"quantity"`
+	OrderedQuantity int    `json:"ordered_quantity"`
+	PickStatus      string `json:"pick_status"`
+	// Строка-замена ссылается на замененную строку
+	SubstitutesItemId *int64 `json:"substitutes_item_id"`
+}
+
+type PickEvent struct {
+	Id               int64      `json:"id"`
+	OrderId          int64      `json:"order_id"`
+	Type             string     `json:"type"`
+	OrderItemId      int64      `json:"order_item_id"`
+	ItemName         string     `json:"item_name"`
+	SubstituteItemId *int64     `json:"substitute_item_id"`
+	SubstituteName   string     `json:"substitute_name"`
+	OrderedQuantity  int        `json:"ordered_quantity"`
+	PickedQuantity   int        `json:"picked_quantity"`
+	Status           string     `json:"status"`
+	CreatedBy        int64      `json:"created_by"`
+	CreatedAt        time.Time  `json:"created_at"`
+	ResolvedAt       *time.Time `json:"resolved_at"`
+}
+
+func (p *PickingOrder) Line(itemId int64) (*PickLine, bool) {
+	for _, line := range p.Lines {
+		if line.ItemId == itemId {
+			return line, true
+		}
+	}
+	return nil, false
+}
+
+// Количество строк, которые еще не собраны
+func (p *PickingOrder) PendingLines() int {
+	pending := 0
+	for _, line := range p.Lines {
+		if line.PickStatus == PickStatusPending {
+			pending++
+		}
+	}
+	return pending
+}
+
+// Группирует строки по категориям. Строки уже отсортированы репозиторием в порядке обхода
+func (p *PickingOrder) ToResponse() *dto.PickingListResponse {
+	resp := &dto.PickingListResponse{
+		OrderId:        p.OrderId,
+		Status:         p.Status,
+		PickerId:       p.PickerId,
+		DeliverySlotId: p.DeliverySlotId,
+		PendingLines:   p.PendingLines(),
+		Groups:         make([]*dto.PickingGroupResponse, 0),
+	}
+
+	var group *dto.PickingGroupResponse
+	for _, line := range p.Lines {
+		if group == nil || !sameCategory(group.CategoryId, line.CategoryId) {
+			group = &dto.PickingGroupResponse{CategoryId: line.CategoryId, CategoryName: line.CategoryName}
+			resp.Groups = append(resp.Groups, group)
+		}
+
+		group.Lines = append(group.Lines, &dto.PickLineResponse{
+			ItemId:            line.ItemId,
+			CatalogId:         line.CatalogId,
+			Name:              line.Name,
+			Sku:               line.Sku,
+			Quantity:          line.Quantity,
+			OrderedQuantity:   line.OrderedQuantity,
+			PickStatus:        line.PickStatus,
+			SubstitutesItemId: line.SubstitutesItemId,
+		})
+	}
+
+	return resp
+}
+
+func sameCategory(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func (e *PickEvent) ToResponse() *dto.PickEventResponse {
+	return &dto.PickEventResponse{
+		Id:               e.Id,
+		OrderId:          e.OrderId,
+		Type:             e.Type,
+		OrderItemId:      e.OrderItemId,
+		ItemName:         e.ItemName,
+		SubstituteItemId: e.SubstituteItemId,
+		SubstituteName:   e.SubstituteName,
+		OrderedQuantity:  e.OrderedQuantity,
+		PickedQuantity:   e.PickedQuantity,
+		Status:           e.Status,
+		CreatedAt:        e.CreatedAt,
+		ResolvedAt:       e.ResolvedAt,
+	}
+}
@@ -0,0 +1,272 @@
+package model
+
+import (
+	"arabic/internal/dto"
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Товар для расчета цены: цена, скидка товара и данные для ограничений акций
+type PricingLine struct {
+	CatalogId       uint
+	CategoryId      uint
+	TagIds          []int64
+	Name            string
+	Price           float32
+	DiscountPercent float32
+	Quantity        int
+	Weight          float32
+}
+
+// Примененная акция и сумма, на которую она уменьшила цену товаров или доставки
+type AppliedDiscount struct {
+	PromotionId int64
+	Name        string
+	Code        string
+	Type        string
+	Amount      float32
+	Description string
+}
+
+type PriceBreakdown struct {
+	Lines []*PricingLine
+	// По ценам без скидок
+	Subtotal float32
+	// Скидки товаров из каталога
+	ItemsDiscount float32
+	// Скидки акций на товары
+	PromoDiscount float32
+	// Товары со всеми скидками
+	ItemsTotal       float32
+	Weight           float32
+	DeliveryFee      float32
+	DeliveryDiscount float32
+	Total            float32
+	Discounts        []*AppliedDiscount
+	PromoCode        string
+	// Почему промокод не применился. Пусто, если применился или не указан
+	PromoCodeError string
+}
+
+// Порядок применения: сначала акции на отдельные товары, затем на сумму заказа, последней доставка
+var promotionStages = map[string]int{
+	PromotionBuyXGetY:     0,
+	PromotionPercent:      1,
+	PromotionFixed:        1,
+	PromotionFreeDelivery: 2,
+}
+
+// Считает цену товаров с автоматическими акциями и промокодом promoCode, который ищется среди promotions.
+// Подходящие акции суммируются, каждая следующая считается от уже сниженной цены. zone = nil - без доставки
+func CalculatePrice(lines []*PricingLine, promotions []*Promotion, promoCode string, zone *DeliveryZone, at time.Time) *PriceBreakdown {
+	result := &PriceBreakdown{Lines: lines, PromoCode: strings.ToUpper(strings.TrimSpace(promoCode))}
+
+	// Сумма каждой позиции после уже примененных скидок
+	remaining := make([]float64, len(lines))
+	var subtotal, itemsTotal, weight float64
+
+	for i, line := range lines {
+		remaining[i] = float64(DiscountedPrice(line.Price, line.DiscountPercent)) * float64(line.Quantity)
+		subtotal += float64(line.Price) * float64(line.Quantity)
+		itemsTotal += remaining[i]
+		weight += float64(line.Weight) * float64(line.Quantity)
+	}
+
+	result.Subtotal = RoundMoney(subtotal)
+	result.ItemsTotal = RoundMoney(itemsTotal)
+	result.ItemsDiscount = RoundMoney(float64(result.Subtotal) - float64(result.ItemsTotal))
+	result.Weight = float32(weight)
+
+	var freeDelivery *AppliedDiscount
+
+	for _, promotion := range result.selectPromotions(promotions, at) {
+		amount, reason := applyPromotion(promotion, lines, remaining, result.ItemsTotal)
+		if reason != "" {
+			if !promotion.IsAutomatic() {
+				result.PromoCodeError = reason
+			}
+			continue
+		}
+
+		discount := &AppliedDiscount{
+			PromotionId: promotion.Id,
+			Name:        promotion.Name,
+			Code:        promotion.Code,
+			Type:        promotion.Type,
+			Amount:      RoundMoney(amount),
+			Description: describePromotion(promotion),
+		}
+		result.Discounts = append(result.Discounts, discount)
+
+		if promotion.Type == PromotionFreeDelivery {
+			freeDelivery = discount
+		} else {
+			result.PromoDiscount = RoundMoney(float64(result.PromoDiscount) + float64(discount.Amount))
+		}
+	}
+
+	result.ItemsTotal = RoundMoney(math.Max(float64(result.ItemsTotal)-float64(result.PromoDiscount), 0))
+
+	if zone != nil {
+		result.DeliveryFee = zone.Fee(result.ItemsTotal, result.Weight)
+	}
+
+	if freeDelivery != nil {
+		freeDelivery.Amount = result.DeliveryFee
+		result.DeliveryDiscount = result.DeliveryFee
+		result.DeliveryFee = 0
+	}
+
+	result.Total = RoundMoney(float64(result.ItemsTotal) + float64(result.DeliveryFee))
+	return result
+}
+
+// Доступные автоматические акции и промокод в порядке применения. Недоступный промокод
+// записывается в PromoCodeError, недоступные автоматические акции пропускаются
+func (b *PriceBreakdown) selectPromotions(promotions []*Promotion, at time.Time) []*Promotion {
+	var selected []*Promotion
+	codeFound := false
+
+	for _, promotion := range promotions {
+		if !promotion.IsAutomatic() {
+			if b.PromoCode == "" || !strings.EqualFold(promotion.Code, b.PromoCode) {
+				continue
+			}
+			codeFound = true
+		}
+
+		if reason := promotion.Unavailable(at); reason != "" {
+			if !promotion.IsAutomatic() {
+				b.PromoCodeError = reason
+			}
+			continue
+		}
+
+		selected = append(selected, promotion)
+	}
+
+	if b.PromoCode != "" && !codeFound {
+		b.PromoCodeError = "promo code does not exist"
+	}
+
+	slices.SortStableFunc(selected, func(x, y *Promotion) int {
+		return cmp.Compare(promotionStages[x.Type], promotionStages[y.Type])
+	})
+
+	return selected
+}
+
+// Уменьшает remaining подходящих позиций и возвращает сумму скидки либо причину, по которой акция не применилась.
+// itemsTotal - сумма товаров до акций, по ней проверяется минимальный заказ
+func applyPromotion(promotion *Promotion, lines []*PricingLine, remaining []float64, itemsTotal float32) (float64, string) {
+	if itemsTotal < promotion.MinOrderAmount {
+		return 0, fmt.Sprintf("minimum order amount is %.2f", promotion.MinOrderAmount)
+	}
+
+	var eligible []int
+	var eligibleTotal float64
+	for i, line := range lines {
+		if promotion.Matches(line) && remaining[i] > 0 {
+			eligible = append(eligible, i)
+			eligibleTotal += remaining[i]
+		}
+	}
+
+	if len(eligible) == 0 {
+		return 0, "no items in the cart qualify"
+	}
+
+	var amount float64
+
+	switch promotion.Type {
+	case PromotionBuyXGetY:
+		// В пределах одной позиции: из каждых buy + free штук free бесплатно
+		set := promotion.BuyQuantity + promotion.FreeQuantity
+		for _, i := range eligible {
+			free := lines[i].Quantity / set * promotion.FreeQuantity
+			discount := math.Min(float64(DiscountedPrice(lines[i].Price, lines[i].DiscountPercent))*float64(free), remaining[i])
+			remaining[i] -= discount
+			amount += discount
+		}
+		if amount == 0 {
+			return 0, fmt.Sprintf("buy at least %d items of one product", set)
+		}
+	case PromotionPercent:
+		for _, i := range eligible {
+			discount := remaining[i] * float64(promotion.Value) / 100
+			remaining[i] -= discount
+			amount += discount
+		}
+	case PromotionFixed:
+		// Фиксированная скидка распределяется по позициям пропорционально их сумме
+		amount = math.Min(float64(promotion.Value), eligibleTotal)
+		for _, i := range eligible {
+			remaining[i] -= amount * remaining[i] / eligibleTotal
+		}
+	case PromotionFreeDelivery:
+		// Сумма скидки известна только после расчета доставки
+	}
+
+	return amount, ""
+}
+
+func describePromotion(promotion *Promotion) string {
+	switch promotion.Type {
+	case PromotionPercent:
+		return fmt.Sprintf("%g%% off", promotion.Value)
+	case PromotionFixed:
+		return fmt.Sprintf("%.2f off", promotion.Value)
+	case PromotionBuyXGetY:
+		return fmt.Sprintf("Buy %d get %d free", promotion.BuyQuantity, promotion.FreeQuantity)
+	case PromotionFreeDelivery:
+		return "Free delivery"
+	}
+	return promotion.Name
+}
+
+func (b *PriceBreakdown) ToResponse() *dto.PriceBreakdownResponse {
+	lines := make([]*dto.PriceLineResponse, 0, len(b.Lines))
+	for _, line := range b.Lines {
+		unitPrice := DiscountedPrice(line.Price, line.DiscountPercent)
+		lines = append(lines, &dto.PriceLineResponse{
+			CatalogId:       line.CatalogId,
+			Name:            line.Name,
+			Price:           line.Price,
+			DiscountPercent: line.DiscountPercent,
+			UnitPrice:       unitPrice,
+			Quantity:        line.Quantity,
+			LineTotal:       RoundMoney(float64(unitPrice) * float64(line.Quantity)),
+		})
+	}
+
+	discounts := make([]*dto.AppliedDiscountResponse, 0, len(b.Discounts))
+	for _, discount := range b.Discounts {
+		discounts = append(discounts, &dto.AppliedDiscountResponse{
+			PromotionId: discount.PromotionId,
+			Name:        discount.Name,
+			Code:        discount.Code,
+			Type:        discount.Type,
+			Amount:      discount.Amount,
+			Description: discount.Description,
+		})
+	}
+
+	return &dto.PriceBreakdownResponse{
+		Lines:            lines,
+		Subtotal:         b.Subtotal,
+		ItemsDiscount:    b.ItemsDiscount,
+		PromoDiscount:    b.PromoDiscount,
+		ItemsTotal:       b.ItemsTotal,
+		Weight:           b.Weight,
+		DeliveryFee:      b.DeliveryFee,
+		DeliveryDiscount: b.DeliveryDiscount,
+		Total:            b.Total,
+		Discounts:        discounts,
+		PromoCode:        b.PromoCode,
+		PromoCodeError:   b.PromoCodeError,
+	}
+}
@@ -0,0 +1,109 @@
+package model
+
+import (
+	"arabic/internal/dto"
+	"slices"
+	"time"
+)
+
+const (
+	PromotionPercent      = "percent"
+	PromotionFixed        = "fixed"
+	PromotionBuyXGetY     = "buy_x_get_y"
+	PromotionFreeDelivery = "free_delivery"
+)
+
+// Акция. Без кода применяется автоматически, с кодом - только если покупатель его ввел
+type Promotion struct {
+	Id   int64  `json:"id"`
+	Name string `json:"name"`
+	Code string `json:"code"`
+	Type string `json:"type"`
+	// Процент для percent, сумма для fixed
+	Value          float32 `json:"value"`
+	BuyQuantity    int     `json:"buy_quantity"`
+	FreeQuantity   int     `json:"free_quantity"`
+	MinOrderAmount float32 `json:"min_order_amount"`
+	// nil - без ограничений
+	UsageLimit   *int       `json:"usage_limit"`
+	PerUserLimit *int       `json:"per_user_limit"`
+	UsedCount    int        `json:"used_count"`
+	StartsAt     *time.Time `json:"starts_at"`
+	EndsAt       *time.Time `json:"ends_at"`
+	IsActive     bool       `json:"is_active"`
+	CategoryIds  []uint     `json:"category_ids"`
+	TagIds       []int64    `json:"tag_ids"`
+	// Сколько раз акцию использовал текущий покупатель
+	UserUsedCount int       `json:"-"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func IsValidPromotionType(promotionType string) bool {
+	switch promotionType {
+	case PromotionPercent, PromotionFixed, PromotionBuyXGetY, PromotionFreeDelivery:
+		return true
+	}
+	return false
+}
+
+func (p *Promotion) IsAutomatic() bool {
+	return p.Code == ""
+}
+
+// Причина, по которой акцию нельзя применить в момент at, пустая строка - можно
+func (p *Promotion) Unavailable(at time.Time) string {
+	switch {
+	case !p.IsActive:
+		return "it is not active"
+	case p.StartsAt != nil && at.Before(*p.StartsAt):
+		return "it has not started yet"
+	case p.EndsAt != nil && !at.Before(*p.EndsAt):
+		return "it has expired"
+	case p.UsageLimit != nil && p.UsedCount >= *p.UsageLimit:
+		return "usage limit is reached"
+	case p.PerUserLimit != nil && p.UserUsedCount >= *p.PerUserLimit:
+		return "you have already used it the maximum number of times"
+	}
+	return ""
+}
+
+// Товар подходит, если его категория или один из тегов входят в ограничения акции
+func (p *Promotion) Matches(line *PricingLine) bool {
+	if len(p.CategoryIds) == 0 && len(p.TagIds) == 0 {
+		return true
+	}
+
+	if slices.Contains(p.CategoryIds, line.CategoryId) {
+		return true
+	}
+
+	for _, tagId := range line.TagIds {
+		if slices.Contains(p.TagIds, tagId) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *Promotion) ToResponse() *dto.PromotionResponse {
+	return &dto.PromotionResponse{
+		Id:             p.Id,
+		Name:           p.Name,
+		Code:           p.Code,
+		Type:           p.Type,
+		Value:          p.Value,
+		BuyQuantity:    p.BuyQuantity,
+		FreeQuantity:   p.FreeQuantity,
+		MinOrderAmount: p.MinOrderAmount,
+		UsageLimit:     p.UsageLimit,
+		PerUserLimit:   p.PerUserLimit,
+		UsedCount:      p.UsedCount,
+		StartsAt:       p.StartsAt,
+		EndsAt:         p.EndsAt,
+		IsActive:       p.IsActive,
+		CategoryIds:    p.CategoryIds,
+		TagIds:         p.TagIds,
+		CreatedAt:      p.CreatedAt,
+	}
+}
@@ -0,0 +1,46 @@
+package model
+
+import (
+	"arabic/internal/dto"
+	"time"
+)
+
+const (
+	ReviewStatusPending  = "pending"
+	ReviewStatusApproved = "approved"
+	ReviewStatusRejected = "rejected"
+)
+
+type Review struct {
+	Id        int64  `json:"id"`
+	CatalogId uint   `json:"catalog_id"`
+	UserId    int64  `json:"user_id"`
+	Username  string `json:"username"`
+	Score     int    `json:"score"`
+	Text      string `json:"text"`
+	Status    string `json:"status"`
+	// Имена файлов в хранилище изображений
+	Photos            []string   `json:"photos"`
+	ModerationComment string     `json:"moderation_comment"`
+	ModeratedAt       *time.Time `json:"moderated_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+func (r *Review) ToResponse(imagePrefix string) *dto.ReviewResponse {
+	photos := make([]string, 0, len(r.Photos))
+	for _, photo := range r.Photos {
+		photos = append(photos, imagePrefix+photo)
+	}
+
+	return &dto.ReviewResponse{
+		Id:                r.Id,
+		CatalogId:         r.CatalogId,
+		Username:          r.Username,
+		Score:             r.Score,
+		Text:              r.Text,
+		Status:            r.Status,
+		Photos:            photos,
+		ModerationComment: r.ModerationComment,
+		CreatedAt:         r.CreatedAt,
+	}
+}
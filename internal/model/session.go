@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+type UserSession struct {
+	Id         string     `json:"id"`
+	UserId     int64      `json:"user_id"`
+	UserAgent  string     `json:"user_agent"`
+	Ip         string     `json:"ip"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
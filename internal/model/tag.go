@@ -1,5 +1,7 @@
 package model
 
+import "arabic/internal/dto"
+
 type Tag struct {
 	Id         int64  `json:"id"`
 	Name       string `json:"name"`
@@ -7,3 +9,12 @@ type Tag struct {
 	UsageCount int    `json:"usage_count"`
 	Color      string `json:"color"`
 }
+
+func (t *Tag) ToResponse() *dto.TagResponse {
+	return &dto.TagResponse{
+		Id:       t.Id,
+		Name:     t.Name,
+		Color:    t.Color,
+		IsActive: t.IsActive,
+	}
+}
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// Подключение второго фактора. Secret зашифрован, расшифровывает сервис
+type TwoFactor struct {
+	UserId    int64
+	Secret    string
+	EnabledAt *time.Time
+	LastStep  int64
+}
+
+func (t *TwoFactor) IsEnabled() bool {
+	return t != nil && t.EnabledAt != nil
+}
@@ -1,11 +1,25 @@
 package model
 
+import "time"
+
+// Назначения одноразовых токенов из писем
+const (
+	TokenVerifyEmail   = "verify_email"
+	TokenPasswordReset = "password_reset"
+	// Выдается после пароля, вход завершается кодом второго фактора
+	TokenTwoFactor = "two_factor"
+)
+
 type User struct {
 	Email    string `json:"email"`
 	Username string `json:"username"`
 	Password string `json:"password"`
 	Id       int64  `json:"id"`
 	RoleCode string `json:"role_code"`
+	// Язык писем
+	Language string `json:"language"`
+	// nil - email не подтвержден
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
 }
 
 type UserFullInfo struct {
@@ -22,4 +36,7 @@ type UserAddress struct {
 	Street    string `json:"street"`
 	City      string `json:"city"`
 	Region    string `json:"region"`
+	// Координаты нужны для определения зоны доставки
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
 }
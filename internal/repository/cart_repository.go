@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"arabic/internal/dto"
+	"arabic/internal/model"
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type CartRepository struct {
+	db *pgxpool.Pool
+}
+
+type ICartRepository interface {
+	FindCartId(ctx context.Context, owner *dto.CartOwner) (int64, bool, error)
+	FindOrCreateCart(ctx context.Context, owner *dto.CartOwner) (int64, error)
+	FindItems(ctx context.Context, cartId int64) ([]*model.CartItem, error)
+	SetItemQuantity(ctx context.Context, cartId int64, catalogId uint, quantity int) error
+	RemoveItem(ctx context.Context, cartId int64, catalogId uint) (bool, error)
+	Clear(ctx context.Context, cartId int64) error
+	MergeGuestCart(ctx context.Context, guestCartId, userCartId int64, quantities map[uint]int) error
+}
+
+func NewCartRepository(db *pgxpool.Pool) *CartRepository {
+	return &CartRepository{db: db}
+}
+
+var (
+	findCartByUser  = "SELECT id FROM public.carts WHERE user_id = $1"
+	findCartByToken = "SELECT id FROM public.carts WHERE token = $1"
+	upsertUserCart  = "INSERT INTO public.carts (user_id) VALUES ($1) ON CONFLICT (user_id) DO UPDATE SET updated_at = NOW() RETURNING id"
+	upsertTokenCart = "INSERT INTO public.carts (token) VALUES ($1) ON CONFLICT (token) DO UPDATE SET updated_at = NOW() RETURNING id"
+	findCartItems   = `
+		SELECT ci.cart_id, ci.catalog_id, ci.quantity, c.name, c.image_url, c.price, c.discount_percent, c.amount, c.weight
+		FROM public.cart_items ci
+		JOIN public.catalogs c ON c.id = ci.catalog_id
+		WHERE ci.cart_id = $1
+		ORDER BY ci.created_at, ci.catalog_id`
+	upsertCartItem = `
+		INSERT INTO public.cart_items (cart_id, catalog_id, quantity) VALUES ($1, $2, $3)
+		ON CONFLICT (cart_id, catalog_id) DO UPDATE SET quantity = EXCLUDED.quantity, updated_at = NOW()`
+	deleteCartItem  = "DELETE FROM public.cart_items WHERE cart_id = $1 AND catalog_id = $2"
+	deleteCartItems = "DELETE FROM public.cart_items WHERE cart_id = $1"
+	deleteCart      = "DELETE FROM public.carts WHERE id = $1"
+)
+
+func (c *CartRepository) FindCartId(ctx context.Context, owner *dto.CartOwner) (int64, bool, error) {
+	query, arg := findCartByUser, any(owner.UserId)
+	if owner.UserId == 0 {
+		query, arg = findCartByToken, owner.Token
+	}
+
+	var id int64
+	err := c.db.QueryRow(ctx, query, arg).Scan(&id)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+
+	return id, true, nil
+}
+
+func (c *CartRepository) FindOrCreateCart(ctx context.Context, owner *dto.CartOwner) (int64, error) {
+	query, arg := upsertUserCart, any(owner.UserId)
+	if owner.UserId == 0 {
+		query, arg = upsertTokenCart, owner.Token
+	}
+
+	var id int64
+	err := c.db.QueryRow(ctx, query, arg).Scan(&id)
+	return id, err
+}
+
+func (c *CartRepository) FindItems(ctx context.Context, cartId int64) ([]*model.CartItem, error) {
+	rows, err := c.db.Query(ctx, findCartItems, cartId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []*model.CartItem
+	for rows.Next() {
+		item := &model.CartItem{}
+		err = rows.Scan(&item.CartId, &item.CatalogId, &item.Quantity, &item.Name, &item.ImageUrl, &item.Price, &item.DiscountPercent, &item.Amount, &item.Weight)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+func (c *CartRepository) SetItemQuantity(ctx context.Context, cartId int64, catalogId uint, quantity int) error {
+	_, err := c.db.Exec(ctx, upsertCartItem, cartId, catalogId, quantity)
+	return err
+}
+
+func (c *CartRepository) RemoveItem(ctx context.Context, cartId int64, catalogId uint) (bool, error) {
+	tag, err := c.db.Exec(ctx, deleteCartItem, cartId, catalogId)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() != 0, nil
+}
+
+func (c *CartRepository) Clear(ctx context.Context, cartId int64) error {
+	_, err := c.db.Exec(ctx, deleteCartItems, cartId)
+	return err
+}
+
+// Записывает посчитанные количества в корзину пользователя и удаляет анонимную корзину.
+// Если анонимную корзину уже слили параллельным запросом, ничего не меняет
+func (c *CartRepository) MergeGuestCart(ctx context.Context, guestCartId, userCartId int64, quantities map[uint]int) error {
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, deleteCart, guestCartId)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return nil
+	}
+
+	for catalogId, quantity := range quantities {
+		if _, err = tx.Exec(ctx, upsertCartItem, userCartId, catalogId, quantity); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
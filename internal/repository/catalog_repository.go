@@ -18,9 +18,20 @@ type ICatalogRepository interface {
 	Delete(ctx context.Context, id uint) (bool, error)
 	Create(ctx context.Context, category *model.Catalog) (*model.Catalog, error)
 	Update(ctx context.Context, queryParts string, values []any) (bool, error)
+	UpdateWithNotifications(ctx context.Context, id uint, query string, values []any) (bool, error)
 	FindById(ctx context.Context, id uint) (*model.Catalog, bool, error)
+	FindMany(ctx context.Context, query string, values []any) ([]*model.Catalog, error)
+	Count(ctx context.Context, query string, values []any) (int, error)
+	Search(ctx context.Context, tsQuery string, limit, offset int) ([]*model.CatalogSearchResult, error)
+	FindTagsByCatalogIds(ctx context.Context, ids []uint) (map[uint][]*model.Tag, error)
+	SetTags(ctx context.Context, catalogId uint, tagIds []int64) error
+	AddTags(ctx context.Context, catalogId uint, tagIds []int64) error
+	RemoveTag(ctx context.Context, catalogId uint, tagId int64) (bool, error)
 }
 
+// Колонки в порядке сканирования для FindMany
+var CatalogColumns = []string{"id", "name", "price", "discount_percent", "amount", "category_id", "description", "sku", "image_url", "weight", "rating", "reviews_count", "created_at"}
+
 func NewCatalogRepository(db *pgxpool.Pool) *CatalogRepository {
 	return &CatalogRepository{
 		db: db,
@@ -37,8 +48,80 @@ func (c *CatalogRepository) Update(ctx context.Context, query string, values []a
 	return tag.RowsAffected() != 0, nil
 }
 
+var (
+	findCatalogState = "SELECT name, price, discount_percent, amount FROM public.catalogs WHERE id = $1"
+	// Блокировка до обновления, чтобы параллельное изменение не пропустило переход остатка через ноль
+	lockCatalogState = findCatalogState + " FOR UPDATE"
+	restockCatalog   = "UPDATE public.catalogs SET amount = amount + $2, updated_at = NOW() WHERE id = $1"
+)
+
+// Обновляет товар и в той же транзакции ставит в очередь уведомления подписчикам,
+// если товар снова появился в наличии или подешевел
+func (c *CatalogRepository) UpdateWithNotifications(ctx context.Context, id uint, query string, values []any) (bool, error) {
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	before := &model.CatalogState{}
+	err = tx.QueryRow(ctx, lockCatalogState, id).Scan(&before.Name, &before.Price, &before.DiscountPercent, &before.Amount)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	tag, err := tx.Exec(ctx, query, values...)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	after := &model.CatalogState{}
+	err = tx.QueryRow(ctx, findCatalogState, id).Scan(&after.Name, &after.Price, &after.DiscountPercent, &after.Amount)
+	if err != nil {
+		return false, err
+	}
+
+	if err = enqueueCatalogNotifications(ctx, tx, id, model.CatalogChangeNotifications(before, after)); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+// Возвращает товар на склад и уведомляет подписчиков, если он снова появился в наличии.
+// Общий путь для отмены и возврата заказа, недовложений и замен при сборке
+func restockCatalogItem(ctx context.Context, tx pgx.Tx, catalogId uint, quantity int) error {
+	if catalogId == 0 || quantity <= 0 {
+		return nil
+	}
+
+	before := &model.CatalogState{}
+	err := tx.QueryRow(ctx, lockCatalogState, catalogId).Scan(&before.Name, &before.Price, &before.DiscountPercent, &before.Amount)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec(ctx, restockCatalog, catalogId, quantity); err != nil {
+		return err
+	}
+
+	after := *before
+	after.Amount += quantity
+
+	return enqueueCatalogNotifications(ctx, tx, catalogId, model.CatalogChangeNotifications(before, &after))
+}
+
 func (c *CatalogRepository) FindById(ctx context.Context, id uint) (*model.Catalog, bool, error) {
-	query := "SELECT id, name, price, discount_percent,  amount, category_id, description, sku, image_url, weight FROM public.catalogs WHERE id = $1"
+	query := "SELECT id, name, price, discount_percent,  amount, category_id, description, sku, image_url, weight, rating, reviews_count FROM public.catalogs WHERE id = $1"
 
 	item := &model.Catalog{}
 
@@ -53,6 +136,8 @@ func (c *CatalogRepository) FindById(ctx context.Context, id uint) (*model.Catal
 		&item.Sku,
 		&item.ImageUrl,
 		&item.Weight,
+		&item.Rating,
+		&item.ReviewsCount,
 	)
 
 	if err != nil {
@@ -66,18 +151,30 @@ func (c *CatalogRepository) FindById(ctx context.Context, id uint) (*model.Catal
 }
 
 func (c *CatalogRepository) Delete(ctx context.Context, id uint) (bool, error) {
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Связи в catalog_tags удалятся каскадно, поэтому счетчики тегов уменьшаем заранее
+	_, err = tx.Exec(ctx, decrementTagsOfCatalog, id)
+	if err != nil {
+		return false, err
+	}
+
 	query := "delete from public.catalogs where id = $1"
-	tag, err := c.db.Exec(ctx, query, id)
+	tag, err := tx.Exec(ctx, query, id)
 
 	if err != nil {
 		return false, err
 	}
 
-	return tag.RowsAffected() != 0, nil
+	return tag.RowsAffected() != 0, tx.Commit(ctx)
 }
 
 func (c *CatalogRepository) FindAll(ctx context.Context) ([]*model.Catalog, error) {
-	query := "SELECT id, name, price, discount_percent,  amount, category_id, description, sku, image_url, weight FROM public.catalogs ORDER BY id"
+	query := "SELECT id, name, price, discount_percent,  amount, category_id, description, sku, image_url, weight, rating, reviews_count FROM public.catalogs ORDER BY id"
 	rows, err := c.db.Query(ctx, query)
 
 	if err != nil {
@@ -87,7 +184,7 @@ func (c *CatalogRepository) FindAll(ctx context.Context) ([]*model.Catalog, erro
 	var catalogItems []*model.Catalog
 	for rows.Next() {
 		item := &model.Catalog{}
-		err = rows.Scan(&item.Id, &item.Name, &item.Price, &item.DiscountPercent, &item.Amount, &item.CategoryId, &item.Description, &item.Sku, &item.ImageUrl, &item.Weight)
+		err = rows.Scan(&item.Id, &item.Name, &item.Price, &item.DiscountPercent, &item.Amount, &item.CategoryId, &item.Description, &item.Sku, &item.ImageUrl, &item.Weight, &item.Rating, &item.ReviewsCount)
 		if err != nil {
 			logger.Log.Error("Catalog repository -> FindAll -> error: " + err.Error())
 			continue
@@ -118,3 +215,64 @@ func (c *CatalogRepository) Create(ctx context.Context, ci *model.Catalog) (*mod
 	return ci, nil
 
 }
+
+func (c *CatalogRepository) FindMany(ctx context.Context, query string, values []any) ([]*model.Catalog, error) {
+	rows, err := c.db.Query(ctx, query, values...)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var catalogItems []*model.Catalog
+	for rows.Next() {
+		item := &model.Catalog{}
+		err = rows.Scan(&item.Id, &item.Name, &item.Price, &item.DiscountPercent, &item.Amount, &item.CategoryId, &item.Description, &item.Sku, &item.ImageUrl, &item.Weight, &item.Rating, &item.ReviewsCount, &item.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		catalogItems = append(catalogItems, item)
+	}
+
+	return catalogItems, rows.Err()
+}
+
+func (c *CatalogRepository) Count(ctx context.Context, query string, values []any) (int, error) {
+	var count int
+	err := c.db.QueryRow(ctx, query, values...).Scan(&count)
+	return count, err
+}
+
+var searchCatalog = `
+	SELECT c.id, c.name, c.price, c.discount_percent, c.amount, c.category_id, c.description, c.sku, c.image_url, c.weight, c.rating, c.reviews_count,
+		ts_rank(c.search_vector, q.query) AS rank,
+		ts_headline('russian', c.name, q.query, 'StartSel=` + model.HighlightStart + `, StopSel=` + model.HighlightStop + `, HighlightAll=true'),
+		ts_headline('russian', c.description, q.query, 'StartSel=` + model.HighlightStart + `, StopSel=` + model.HighlightStop + `, MaxWords=25, MinWords=10')
+	FROM public.catalogs c, to_tsquery('russian', $1) AS q(query)
+	WHERE c.search_vector @@ q.query
+	ORDER BY rank DESC, c.id
+	LIMIT $2 OFFSET $3`
+
+func (c *CatalogRepository) Search(ctx context.Context, tsQuery string, limit, offset int) ([]*model.CatalogSearchResult, error) {
+	rows, err := c.db.Query(ctx, searchCatalog, tsQuery, limit, offset)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []*model.CatalogSearchResult
+	for rows.Next() {
+		item := &model.CatalogSearchResult{}
+		err = rows.Scan(&item.Id, &item.Name, &item.Price, &item.DiscountPercent, &item.Amount, &item.CategoryId, &item.Description, &item.Sku, &item.ImageUrl, &item.Weight, &item.Rating, &item.ReviewsCount,
+			&item.Rank, &item.NameHighlight, &item.Snippet)
+		if err != nil {
+			return nil, err
+		}
+		item.NameHighlight = model.HighlightHTML(item.NameHighlight)
+		item.Snippet = model.HighlightHTML(item.Snippet)
+		results = append(results, item)
+	}
+
+	return results, rows.Err()
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"arabic/internal/model"
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Работа со связями каталог - теги (public.catalog_tags).
+// Все изменения связей и tags.usage_count выполняются в одной транзакции
+
+var (
+	findTagsByCatalogIds = `
+		SELECT ct.catalog_id, t.id, t.name, t.is_active, COALESCE(t.usage_count, 0), t.color
+		FROM public.catalog_tags ct
+		JOIN public.tags t ON t.id = ct.tag_id
+		WHERE ct.catalog_id = ANY($1)
+		ORDER BY ct.catalog_id, t.id`
+	insertCatalogTags = `
+		INSERT INTO public.catalog_tags (catalog_id, tag_id)
+		SELECT $1, unnest($2::bigint[])
+		ON CONFLICT DO NOTHING
+		RETURNING tag_id`
+	deleteCatalogTagsExcept = "DELETE FROM public.catalog_tags WHERE catalog_id = $1 AND NOT (tag_id = ANY($2)) RETURNING tag_id"
+	deleteCatalogTag        = "DELETE FROM public.catalog_tags WHERE catalog_id = $1 AND tag_id = $2 RETURNING tag_id"
+	incrementTagsUsage      = "UPDATE public.tags SET usage_count = COALESCE(usage_count, 0) + 1, updated_at = NOW() WHERE id = ANY($1)"
+	decrementTagsUsage      = "UPDATE public.tags SET usage_count = GREATEST(COALESCE(usage_count, 0) - 1, 0), updated_at = NOW() WHERE id = ANY($1)"
+	decrementTagsOfCatalog  = "UPDATE public.tags SET usage_count = GREATEST(COALESCE(usage_count, 0) - 1, 0), updated_at = NOW() WHERE id IN (SELECT tag_id FROM public.catalog_tags WHERE catalog_id = $1)"
+)
+
+func (c *CatalogRepository) FindTagsByCatalogIds(ctx context.Context, ids []uint) (map[uint][]*model.Tag, error) {
+	result := make(map[uint][]*model.Tag, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	rows, err := c.db.Query(ctx, findTagsByCatalogIds, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var catalogId uint
+		tag := &model.Tag{}
+		if err = rows.Scan(&catalogId, &tag.Id, &tag.Name, &tag.IsActive, &tag.UsageCount, &tag.Color); err != nil {
+			return nil, err
+		}
+		result[catalogId] = append(result[catalogId], tag)
+	}
+
+	return result, rows.Err()
+}
+
+func (c *CatalogRepository) SetTags(ctx context.Context, catalogId uint, tagIds []int64) error {
+	return c.inTx(ctx, func(tx pgx.Tx) error {
+		removed, err := collectTagIds(tx.Query(ctx, deleteCatalogTagsExcept, catalogId, tagIds))
+		if err != nil {
+			return err
+		}
+
+		if err = updateTagsUsage(ctx, tx, decrementTagsUsage, removed); err != nil {
+			return err
+		}
+
+		added, err := collectTagIds(tx.Query(ctx, insertCatalogTags, catalogId, tagIds))
+		if err != nil {
+			return err
+		}
+
+		return updateTagsUsage(ctx, tx, incrementTagsUsage, added)
+	})
+}
+
+func (c *CatalogRepository) AddTags(ctx context.Context, catalogId uint, tagIds []int64) error {
+	return c.inTx(ctx, func(tx pgx.Tx) error {
+		added, err := collectTagIds(tx.Query(ctx, insertCatalogTags, catalogId, tagIds))
+		if err != nil {
+			return err
+		}
+
+		return updateTagsUsage(ctx, tx, incrementTagsUsage, added)
+	})
+}
+
+func (c *CatalogRepository) RemoveTag(ctx context.Context, catalogId uint, tagId int64) (bool, error) {
+	var removed []int64
+
+	err := c.inTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		removed, err = collectTagIds(tx.Query(ctx, deleteCatalogTag, catalogId, tagId))
+		if err != nil {
+			return err
+		}
+
+		return updateTagsUsage(ctx, tx, decrementTagsUsage, removed)
+	})
+
+	return len(removed) != 0, err
+}
+
+func (c *CatalogRepository) inTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Счетчик меняем только для реально вставленных/удаленных связей (RETURNING), поэтому повторные запросы его не портят
+func updateTagsUsage(ctx context.Context, tx pgx.Tx, query string, tagIds []int64) error {
+	if len(tagIds) == 0 {
+		return nil
+	}
+	_, err := tx.Exec(ctx, query, tagIds)
+	return err
+}
+
+func collectTagIds(rows pgx.Rows, err error) ([]int64, error) {
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[int64])
+}
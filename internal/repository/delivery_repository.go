@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"arabic/internal/model"
+	"arabic/pkg/geo"
+	"arabic/pkg/logger"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type DeliveryRepository struct {
+	db *pgxpool.Pool
+}
+
+type IDeliveryRepository interface {
+	CreateZone(ctx context.Context, zone *model.DeliveryZone) error
+	UpdateZone(ctx context.Context, zone *model.DeliveryZone, isActive *bool) (bool, error)
+	FindZones(ctx context.Context) ([]*model.DeliveryZone, error)
+	CreateTemplate(ctx context.Context, template *model.DeliverySlotTemplate) error
+	FindTemplates(ctx context.Context, zoneId int64) ([]*model.DeliverySlotTemplate, error)
+	DeleteTemplate(ctx context.Context, id int64) (bool, error)
+	FindSlots(ctx context.Context, zoneId int64, date time.Time) ([]*model.DeliverySlot, error)
+	GenerateSlots(ctx context.Context, from, to time.Time) (int64, error)
+}
+
+func NewDeliveryRepository(db *pgxpool.Pool) *DeliveryRepository {
+	return &DeliveryRepository{db: db}
+}
+
+var (
+	insertDeliveryZone = `
+		INSERT INTO public.delivery_zones (name, polygon, min_order_amount, base_fee, free_delivery_threshold, per_kg_fee, eta_minutes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, is_active, created_at`
+	updateDeliveryZone = `
+		UPDATE public.delivery_zones
+		SET name = $2, polygon = $3, min_order_amount = $4, base_fee = $5, free_delivery_threshold = $6,
+		    per_kg_fee = $7, eta_minutes = $8, is_active = COALESCE($9, is_active), updated_at = NOW()
+		WHERE id = $1
+		RETURNING is_active, created_at`
+	findDeliveryZones = `
+		SELECT id, name, is_active, polygon, min_order_amount, base_fee, free_delivery_threshold, per_kg_fee, eta_minutes, created_at
+		FROM public.delivery_zones ORDER BY id`
+	insertSlotTemplate = `
+		INSERT INTO public.delivery_slot_templates (zone_id, weekday, start_time, end_time, capacity)
+		VALUES ($1, $2, $3::text::time, $4::text::time, $5)
+		RETURNING id`
+	findSlotTemplates = `
+		SELECT id, zone_id, weekday, to_char(start_time, 'HH24:MI'), to_char(end_time, 'HH24:MI'), capacity
+		FROM public.delivery_slot_templates WHERE zone_id = $1 ORDER BY weekday, start_time`
+	deleteSlotTemplate = "DELETE FROM public.delivery_slot_templates WHERE id = $1"
+	// Слоты дней с $1 по $2 из шаблонов активных зон. ON CONFLICT делает генерацию идемпотентной
+	generateDeliverySlots = `
+		INSERT INTO public.delivery_slots (zone_id, template_id, date, start_time, end_time, capacity)
+		SELECT t.zone_id, t.id, d::date, t.start_time, t.end_time, t.capacity
+		FROM generate_series($1::date, $2::date, interval '1 day') d
+		JOIN public.delivery_slot_templates t ON t.weekday = EXTRACT(DOW FROM d)
+		JOIN public.delivery_zones z ON z.id = t.zone_id
+		WHERE z.is_active
+		ON CONFLICT (zone_id, date, start_time) DO NOTHING`
+	findDeliverySlots = `
+		SELECT s.id, s.zone_id, s.date, to_char(s.start_time, 'HH24:MI'), to_char(s.end_time, 'HH24:MI'), s.capacity, s.reserved
+		FROM public.delivery_slots s
+		JOIN public.delivery_zones z ON z.id = s.zone_id
+		WHERE z.is_active AND s.date = $1::date AND ($2::bigint = 0 OR s.zone_id = $2) AND s.date + s.start_time > NOW()
+		ORDER BY s.zone_id, s.start_time`
+)
+
+func (d *DeliveryRepository) CreateZone(ctx context.Context, zone *model.DeliveryZone) error {
+	return d.db.QueryRow(ctx, insertDeliveryZone,
+		zone.Name,
+		zone.Polygon,
+		zone.MinOrderAmount,
+		zone.BaseFee,
+		zone.FreeDeliveryThreshold,
+		zone.PerKgFee,
+		zone.EtaMinutes).Scan(&zone.Id, &zone.IsActive, &zone.CreatedAt)
+}
+
+func (d *DeliveryRepository) UpdateZone(ctx context.Context, zone *model.DeliveryZone, isActive *bool) (bool, error) {
+	err := d.db.QueryRow(ctx, updateDeliveryZone,
+		zone.Id,
+		zone.Name,
+		zone.Polygon,
+		zone.MinOrderAmount,
+		zone.BaseFee,
+		zone.FreeDeliveryThreshold,
+		zone.PerKgFee,
+		zone.EtaMinutes,
+		isActive).Scan(&zone.IsActive, &zone.CreatedAt)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Возвращает зоны с разобранной геометрией. Зона без корректного полигона остается в списке,
+// но ни одна точка в нее не попадет
+func (d *DeliveryRepository) FindZones(ctx context.Context) ([]*model.DeliveryZone, error) {
+	rows, err := d.db.Query(ctx, findDeliveryZones)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (*model.DeliveryZone, error) {
+		zone := &model.DeliveryZone{}
+		err := row.Scan(
+			&zone.Id,
+			&zone.Name,
+			&zone.IsActive,
+			&zone.Polygon,
+			&zone.MinOrderAmount,
+			&zone.BaseFee,
+			&zone.FreeDeliveryThreshold,
+			&zone.PerKgFee,
+			&zone.EtaMinutes,
+			&zone.CreatedAt,
+		)
+		if err != nil || zone.Polygon == nil {
+			return zone, err
+		}
+
+		if zone.Area, err = geo.ParseGeoJSON(zone.Polygon); err != nil {
+			logger.Log.Warn(fmt.Sprintf("DeliveryRepository -> FindZones -> zone %d has invalid polygon -> %s", zone.Id, err.Error()))
+		}
+		return zone, nil
+	})
+}
+
+func (d *DeliveryRepository) CreateTemplate(ctx context.Context, template *model.DeliverySlotTemplate) error {
+	return d.db.QueryRow(ctx, insertSlotTemplate,
+		template.ZoneId,
+		template.Weekday,
+		template.StartTime,
+		template.EndTime,
+		template.Capacity).Scan(&template.Id)
+}
+
+func (d *DeliveryRepository) FindTemplates(ctx context.Context, zoneId int64) ([]*model.DeliverySlotTemplate, error) {
+	rows, err := d.db.Query(ctx, findSlotTemplates, zoneId)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (*model.DeliverySlotTemplate, error) {
+		template := &model.DeliverySlotTemplate{}
+		err := row.Scan(&template.Id, &template.ZoneId, &template.Weekday, &template.StartTime, &template.EndTime, &template.Capacity)
+		return template, err
+	})
+}
+
+func (d *DeliveryRepository) DeleteTemplate(ctx context.Context, id int64) (bool, error) {
+	tag, err := d.db.Exec(ctx, deleteSlotTemplate, id)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() != 0, nil
+}
+
+// Возвращает еще не начавшиеся слоты дня. zoneId = 0 - по всем зонам
+func (d *DeliveryRepository) FindSlots(ctx context.Context, zoneId int64, date time.Time) ([]*model.DeliverySlot, error) {
+	rows, err := d.db.Query(ctx, findDeliverySlots, date, zoneId)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (*model.DeliverySlot, error) {
+		slot := &model.DeliverySlot{}
+		err := row.Scan(&slot.Id, &slot.ZoneId, &slot.Date, &slot.StartTime, &slot.EndTime, &slot.Capacity, &slot.Reserved)
+		return slot, err
+	})
+}
+
+// Создает недостающие слоты дней с from по to включительно, возвращает сколько создано
+func (d *DeliveryRepository) GenerateSlots(ctx context.Context, from, to time.Time) (int64, error) {
+	tag, err := d.db.Exec(ctx, generateDeliverySlots, from, to)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,273 @@
+package repository
+
+import (
+	"arabic/internal/model"
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Заказ уже попал в другой маршрут или курьер занят - диспетчеризацию нужно повторить
+var ErrDispatchConflict = errors.New("dispatch conflict")
+
+// Имя advisory lock, под которым сохраняются маршруты. Ключ считается из имени через hashtext,
+// как для счетчиков попыток входа
+const dispatchLockKey = "dispatch"
+
+type DispatchRepository struct {
+	db *pgxpool.Pool
+}
+
+type IDispatchRepository interface {
+	FindCouriers(ctx context.Context) ([]*model.CourierProfile, error)
+	SaveCourierProfile(ctx context.Context, profile *model.CourierProfile) (bool, error)
+	FindDispatchOrders(ctx context.Context) ([]*model.DispatchOrder, error)
+	SaveRoutes(ctx context.Context, routes []*model.DeliveryRoute) error
+	FindActiveRoutes(ctx context.Context) ([]*model.DeliveryRoute, error)
+	FindActiveRoute(ctx context.Context, courierId int64) (*model.DeliveryRoute, bool, error)
+	FindRouteByOrder(ctx context.Context, orderId int64) (*model.DeliveryRoute, bool, error)
+}
+
+func NewDispatchRepository(db *pgxpool.Pool) *DispatchRepository {
+	return &DispatchRepository{db: db}
+}
+
+var (
+	// Маршрут курьера активен, пока в нем есть заказы в пути
+	activeRouteCondition = `
+		EXISTS (SELECT 1 FROM public.delivery_route_stops rs
+		        JOIN public.orders o ON o.id = rs.order_id
+		        WHERE rs.route_id = r.id AND o.status = $1)`
+	// Без профиля действуют значения по умолчанию из courier_profiles
+	findCouriers = `
+		SELECT u.id, u.username, COALESCE(p.max_orders, 10), COALESCE(p.max_weight, 30), COALESCE(p.is_available, TRUE),
+		       EXISTS (SELECT 1 FROM public.delivery_routes r WHERE r.courier_id = u.id AND ` + activeRouteCondition + `)
+		FROM public.users u
+		LEFT JOIN public.courier_profiles p ON p.user_id = u.id
+		WHERE u.role_code = $2
+		ORDER BY u.id`
+	upsertCourierProfile = `
+		INSERT INTO public.courier_profiles (user_id, max_orders, max_weight, is_available)
+		SELECT id, $2, $3, $4 FROM public.users WHERE id = $1 AND role_code = $5
+		ON CONFLICT (user_id) DO UPDATE
+		SET max_orders = EXCLUDED.max_orders, max_weight = EXCLUDED.max_weight, is_available = EXCLUDED.is_available, updated_at = NOW()`
+	// Заказы без координат или зоны (оформленные до геозон) маршрутизировать нельзя
+	findDispatchOrders = `
+		SELECT o.id, o.delivery_zone_id, o.delivery_slot_id, s.date + s.start_time, o.latitude, o.longitude, o.total_weight,
+		       o.apartment, o.house, o.street, o.city, o.region
+		FROM public.orders o
+		LEFT JOIN public.delivery_slots s ON s.id = o.delivery_slot_id
+		WHERE o.status = $1 AND o.latitude IS NOT NULL AND o.longitude IS NOT NULL AND o.delivery_zone_id IS NOT NULL
+		  AND NOT EXISTS (SELECT 1 FROM public.delivery_route_stops rs WHERE rs.order_id = o.id)
+		ORDER BY s.date + s.start_time NULLS LAST, o.id`
+	lockDispatch        = "SELECT pg_advisory_xact_lock(hashtext($1))"
+	isCourierBusy       = "SELECT EXISTS (SELECT 1 FROM public.delivery_routes r WHERE r.courier_id = $2 AND " + activeRouteCondition + ")"
+	insertDeliveryRoute = `
+		INSERT INTO public.delivery_routes (courier_id, zone_id, delivery_slot_id, total_weight, distance, created_by)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, 0))
+		RETURNING id, created_at`
+	// Остановка добавляется, только если заказ все еще в пути
+	insertRouteStop = `
+		INSERT INTO public.delivery_route_stops (route_id, position, order_id)
+		SELECT $1, $2, id FROM public.orders WHERE id = $3 AND status = $4`
+	routeColumns     = "r.id, r.courier_id, r.zone_id, r.delivery_slot_id, r.total_weight, r.distance, COALESCE(r.created_by, 0), r.created_at"
+	findActiveRoutes = "SELECT " + routeColumns + " FROM public.delivery_routes r WHERE " + activeRouteCondition + " ORDER BY r.id"
+	findCourierRoute = "SELECT " + routeColumns + " FROM public.delivery_routes r WHERE r.courier_id = $2 AND " + activeRouteCondition + " ORDER BY r.id DESC LIMIT 1"
+	findOrderRoute   = `
+		SELECT ` + routeColumns + ` FROM public.delivery_routes r
+		JOIN public.delivery_route_stops rs ON rs.route_id = r.id
+		WHERE rs.order_id = $1`
+	findRouteStops = `
+		SELECT rs.route_id, rs.position, o.id, o.status, o.apartment, o.house, o.street, o.city, o.region, o.latitude, o.longitude
+		FROM public.delivery_route_stops rs
+		JOIN public.orders o ON o.id = rs.order_id
+		WHERE rs.route_id = ANY($1)
+		ORDER BY rs.route_id, rs.position`
+)
+
+func (d *DispatchRepository) FindCouriers(ctx context.Context) ([]*model.CourierProfile, error) {
+	rows, err := d.db.Query(ctx, findCouriers, model.OrderStatusOutForDelivery, model.RoleCourier)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (*model.CourierProfile, error) {
+		courier := &model.CourierProfile{}
+		err := row.Scan(&courier.UserId, &courier.Username, &courier.MaxOrders, &courier.MaxWeight, &courier.IsAvailable, &courier.Busy)
+		return courier, err
+	})
+}
+
+// false - пользователь не найден или не курьер
+func (d *DispatchRepository) SaveCourierProfile(ctx context.Context, profile *model.CourierProfile) (bool, error) {
+	tag, err := d.db.Exec(ctx, upsertCourierProfile, profile.UserId, profile.MaxOrders, profile.MaxWeight, profile.IsAvailable, model.RoleCourier)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() != 0, nil
+}
+
+func (d *DispatchRepository) FindDispatchOrders(ctx context.Context) ([]*model.DispatchOrder, error) {
+	rows, err := d.db.Query(ctx, findDispatchOrders, model.OrderStatusOutForDelivery)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (*model.DispatchOrder, error) {
+		order := &model.DispatchOrder{}
+		err := row.Scan(
+			&order.Id,
+			&order.ZoneId,
+			&order.SlotId,
+			&order.SlotStart,
+			&order.Location.Lat,
+			&order.Location.Lon,
+			&order.Weight,
+			&order.Address.Apartment,
+			&order.Address.House,
+			&order.Address.Street,
+			&order.Address.City,
+			&order.Address.Region,
+		)
+		order.Address.Latitude, order.Address.Longitude = &order.Location.Lat, &order.Location.Lon
+		return order, err
+	})
+}
+
+// Сохраняет маршруты одной транзакцией. Если за время планирования заказ назначили
+// в другой маршрут, он ушел из статуса в пути или курьер получил маршрут - ErrDispatchConflict
+func (d *DispatchRepository) SaveRoutes(ctx context.Context, routes []*model.DeliveryRoute) error {
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err = tx.Exec(ctx, lockDispatch, dispatchLockKey); err != nil {
+		return err
+	}
+
+	for _, route := range routes {
+		var busy bool
+		if err = tx.QueryRow(ctx, isCourierBusy, model.OrderStatusOutForDelivery, route.CourierId).Scan(&busy); err != nil {
+			return err
+		}
+		if busy {
+			return ErrDispatchConflict
+		}
+
+		err = tx.QueryRow(ctx, insertDeliveryRoute, route.CourierId, route.ZoneId, route.SlotId, route.TotalWeight, route.Distance, route.CreatedBy).
+			Scan(&route.Id, &route.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		for _, stop := range route.Stops {
+			tag, err := tx.Exec(ctx, insertRouteStop, route.Id, stop.Position, stop.OrderId, model.OrderStatusOutForDelivery)
+
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+				return ErrDispatchConflict
+			}
+			if err != nil {
+				return err
+			}
+			if tag.RowsAffected() == 0 {
+				return ErrDispatchConflict
+			}
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (d *DispatchRepository) FindActiveRoutes(ctx context.Context) ([]*model.DeliveryRoute, error) {
+	return d.findRoutes(ctx, findActiveRoutes, model.OrderStatusOutForDelivery)
+}
+
+func (d *DispatchRepository) FindActiveRoute(ctx context.Context, courierId int64) (*model.DeliveryRoute, bool, error) {
+	routes, err := d.findRoutes(ctx, findCourierRoute, model.OrderStatusOutForDelivery, courierId)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(routes) == 0 {
+		return nil, false, nil
+	}
+
+	return routes[0], true, nil
+}
+
+func (d *DispatchRepository) FindRouteByOrder(ctx context.Context, orderId int64) (*model.DeliveryRoute, bool, error) {
+	routes, err := d.findRoutes(ctx, findOrderRoute, orderId)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(routes) == 0 {
+		return nil, false, nil
+	}
+
+	return routes[0], true, nil
+}
+
+func (d *DispatchRepository) findRoutes(ctx context.Context, query string, args ...any) ([]*model.DeliveryRoute, error) {
+	rows, err := d.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	routes, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*model.DeliveryRoute, error) {
+		route := &model.DeliveryRoute{}
+		err := row.Scan(&route.Id, &route.CourierId, &route.ZoneId, &route.SlotId, &route.TotalWeight, &route.Distance, &route.CreatedBy, &route.CreatedAt)
+		return route, err
+	})
+	if err != nil || len(routes) == 0 {
+		return routes, err
+	}
+
+	return routes, d.attachStops(ctx, routes)
+}
+
+func (d *DispatchRepository) attachStops(ctx context.Context, routes []*model.DeliveryRoute) error {
+	byId := make(map[int64]*model.DeliveryRoute, len(routes))
+	ids := make([]int64, 0, len(routes))
+	for _, route := range routes {
+		byId[route.Id] = route
+		ids = append(ids, route.Id)
+	}
+
+	rows, err := d.db.Query(ctx, findRouteStops, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var routeId int64
+		stop := &model.RouteStop{}
+		err = rows.Scan(
+			&routeId,
+			&stop.Position,
+			&stop.OrderId,
+			&stop.Status,
+			&stop.Address.Apartment,
+			&stop.Address.House,
+			&stop.Address.Street,
+			&stop.Address.City,
+			&stop.Address.Region,
+			&stop.Address.Latitude,
+			&stop.Address.Longitude,
+		)
+		if err != nil {
+			return err
+		}
+		byId[routeId].Stops = append(byId[routeId].Stops, stop)
+	}
+
+	return rows.Err()
+}
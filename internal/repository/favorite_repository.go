@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"arabic/internal/model"
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type FavoriteRepository struct {
+	db *pgxpool.Pool
+}
+
+type IFavoriteRepository interface {
+	Add(ctx context.Context, userId int64, catalogId uint) error
+	Remove(ctx context.Context, userId int64, catalogId uint) (bool, error)
+	FindAll(ctx context.Context, userId int64) ([]*model.Catalog, error)
+	FindFavoriteIds(ctx context.Context, userId int64, catalogIds []uint) (map[uint]bool, error)
+}
+
+func NewFavoriteRepository(db *pgxpool.Pool) *FavoriteRepository {
+	return &FavoriteRepository{db: db}
+}
+
+var (
+	// Повторное добавление не ошибка
+	insertFavorite = `
+		INSERT INTO public.user_favorites (user_id, catalog_id) VALUES ($1, $2)
+		ON CONFLICT (user_id, catalog_id) DO NOTHING`
+	deleteFavorite = "DELETE FROM public.user_favorites WHERE user_id = $1 AND catalog_id = $2"
+	findFavorites  = `
+		SELECT c.id, c.name, c.price, c.discount_percent, c.amount, c.category_id, c.description, c.sku, c.image_url, c.weight, c.rating, c.reviews_count
+		FROM public.user_favorites f
+		JOIN public.catalogs c ON c.id = f.catalog_id
+		WHERE f.user_id = $1
+		ORDER BY f.created_at DESC, c.id DESC`
+	findFavoriteIds = "SELECT catalog_id FROM public.user_favorites WHERE user_id = $1 AND catalog_id = ANY($2)"
+)
+
+func (f *FavoriteRepository) Add(ctx context.Context, userId int64, catalogId uint) error {
+	_, err := f.db.Exec(ctx, insertFavorite, userId, catalogId)
+	return err
+}
+
+func (f *FavoriteRepository) Remove(ctx context.Context, userId int64, catalogId uint) (bool, error) {
+	tag, err := f.db.Exec(ctx, deleteFavorite, userId, catalogId)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() != 0, nil
+}
+
+// Избранные товары, последние добавленные первыми
+func (f *FavoriteRepository) FindAll(ctx context.Context, userId int64) ([]*model.Catalog, error) {
+	rows, err := f.db.Query(ctx, findFavorites, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (*model.Catalog, error) {
+		item := &model.Catalog{IsFavorite: true}
+		err := row.Scan(&item.Id, &item.Name, &item.Price, &item.DiscountPercent, &item.Amount, &item.CategoryId, &item.Description, &item.Sku, &item.ImageUrl, &item.Weight, &item.Rating, &item.ReviewsCount)
+		return item, err
+	})
+}
+
+// Какие из переданных товаров пользователь добавил в избранное
+func (f *FavoriteRepository) FindFavoriteIds(ctx context.Context, userId int64, catalogIds []uint) (map[uint]bool, error) {
+	if len(catalogIds) == 0 {
+		return map[uint]bool{}, nil
+	}
+
+	rows, err := f.db.Query(ctx, findFavoriteIds, userId, catalogIds)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[uint])
+	if err != nil {
+		return nil, err
+	}
+
+	favorites := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		favorites[id] = true
+	}
+
+	return favorites, nil
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"arabic/pkg/security/throttle"
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Счетчики попыток входа в Postgres, реализация throttle.Store для нескольких экземпляров приложения
+type LoginAttemptRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewLoginAttemptRepository(db *pgxpool.Pool) *LoginAttemptRepository {
+	return &LoginAttemptRepository{db: db}
+}
+
+var (
+	// Попытки одного ключа проверяются и записываются по очереди
+	lockLoginAttemptKey = "SELECT pg_advisory_xact_lock(hashtext($1))"
+	insertLoginAttempt  = "INSERT INTO public.login_attempts (key, attempted_at) VALUES ($1, $2)"
+	deleteOldAttempts   = "DELETE FROM public.login_attempts WHERE key = $1 AND attempted_at <= $2"
+	deleteAttempt       = `
+		DELETE FROM public.login_attempts
+		WHERE id = (SELECT id FROM public.login_attempts WHERE key = $1 AND attempted_at = $2 LIMIT 1)`
+	deleteAttempts    = "DELETE FROM public.login_attempts WHERE key = $1"
+	findAttemptWindow = `
+		SELECT COUNT(*), COALESCE(MAX(attempted_at), 'epoch')
+		FROM public.login_attempts
+		WHERE key = $1 AND attempted_at > $2 AND attempted_at <= $3`
+)
+
+func (l *LoginAttemptRepository) Reserve(ctx context.Context, key string, at time.Time, window time.Duration, delay throttle.DelayFunc) (throttle.Window, bool, error) {
+	tx, err := l.db.Begin(ctx)
+	if err != nil {
+		return throttle.Window{}, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err = tx.Exec(ctx, lockLoginAttemptKey, key); err != nil {
+		return throttle.Window{}, false, err
+	}
+	if _, err = tx.Exec(ctx, deleteOldAttempts, key, at.Add(-window)); err != nil {
+		return throttle.Window{}, false, err
+	}
+
+	result := throttle.Window{}
+	if err = tx.QueryRow(ctx, findAttemptWindow, key, at.Add(-window), at).Scan(&result.Count, &result.Last); err != nil {
+		return throttle.Window{}, false, err
+	}
+
+	if result.Count > 0 && at.Before(result.Last.Add(delay(result.Count))) {
+		return result, false, tx.Commit(ctx)
+	}
+
+	if _, err = tx.Exec(ctx, insertLoginAttempt, key, at); err != nil {
+		return throttle.Window{}, false, err
+	}
+
+	return result, true, tx.Commit(ctx)
+}
+
+func (l *LoginAttemptRepository) Release(ctx context.Context, key string, at time.Time) error {
+	_, err := l.db.Exec(ctx, deleteAttempt, key, at)
+	return err
+}
+
+func (l *LoginAttemptRepository) Reset(ctx context.Context, key string) error {
+	_, err := l.db.Exec(ctx, deleteAttempts, key)
+	return err
+}
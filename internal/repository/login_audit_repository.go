@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"arabic/internal/model"
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type LoginAuditRepository struct {
+	db *pgxpool.Pool
+}
+
+type ILoginAuditRepository interface {
+	Record(ctx context.Context, failure *model.LoginFailure) error
+	Find(ctx context.Context, email, ip string, limit int) ([]*model.LoginFailure, error)
+}
+
+func NewLoginAuditRepository(db *pgxpool.Pool) *LoginAuditRepository {
+	return &LoginAuditRepository{db: db}
+}
+
+var (
+	insertLoginFailure = `
+		INSERT INTO public.login_audit (email, user_id, ip, user_agent, reason)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+	// Пустой фильтр не ограничивает выборку
+	findLoginFailures = `
+		SELECT id, email, user_id, ip, user_agent, reason, created_at
+		FROM public.login_audit
+		WHERE ($1::text = '' OR email = $1) AND ($2::text = '' OR ip = $2)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3`
+)
+
+func (l *LoginAuditRepository) Record(ctx context.Context, failure *model.LoginFailure) error {
+	return l.db.QueryRow(ctx, insertLoginFailure, failure.Email, failure.UserId, failure.Ip, failure.UserAgent, failure.Reason).
+		Scan(&failure.Id, &failure.CreatedAt)
+}
+
+func (l *LoginAuditRepository) Find(ctx context.Context, email, ip string, limit int) ([]*model.LoginFailure, error) {
+	rows, err := l.db.Query(ctx, findLoginFailures, email, ip, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (*model.LoginFailure, error) {
+		failure := &model.LoginFailure{}
+		err := row.Scan(&failure.Id, &failure.Email, &failure.UserId, &failure.Ip, &failure.UserAgent, &failure.Reason, &failure.CreatedAt)
+		return failure, err
+	})
+}
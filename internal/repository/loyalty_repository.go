@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"arabic/internal/model"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Возвращается при оформлении, если баллов на счете меньше, чем покупатель хочет списать
+type LoyaltyError struct {
+	Balance   int64
+	Requested int64
+}
+
+func (e *LoyaltyError) Error() string {
+	return fmt.Sprintf("loyalty balance %d is less than requested %d points", e.Balance, e.Requested)
+}
+
+type LoyaltyRepository struct {
+	db *pgxpool.Pool
+}
+
+type ILoyaltyRepository interface {
+	FindBalance(ctx context.Context, userId int64) (int64, error)
+	FindEntries(ctx context.Context, userId int64, limit int) ([]*model.LoyaltyEntry, error)
+}
+
+func NewLoyaltyRepository(db *pgxpool.Pool) *LoyaltyRepository {
+	return &LoyaltyRepository{db: db}
+}
+
+// Проводки, которые отменяются вместе с заказом, и счет магазина, на который возвращаются баллы
+var loyaltyReversals = []struct {
+	entryType string
+	reversal  string
+	account   string
+}{
+	{model.LoyaltyAccrual, model.LoyaltyAccrualReversal, model.LoyaltyAccountIssued},
+	{model.LoyaltyRedemption, model.LoyaltyRedemptionReversal, model.LoyaltyAccountRedeemed},
+}
+
+var (
+	findLoyaltyBalance = `
+		SELECT COALESCE(SUM(points), 0) FROM public.loyalty_ledger
+		WHERE user_id = $1 AND account = 'customer'`
+	findLoyaltyEntries = `
+		SELECT id, type, points, order_id, created_at FROM public.loyalty_ledger
+		WHERE user_id = $1 AND account = 'customer'
+		ORDER BY id DESC LIMIT $2`
+	// Баланс не хранится, поэтому параллельные списания одного покупателя
+	// сериализуются блокировкой его строки до пересчета суммы
+	lockLoyaltyAccount = "SELECT id FROM public.users WHERE id = $1 FOR NO KEY UPDATE"
+	// Обе строки проводки пишутся одним запросом. Повтор проводки того же типа по заказу пропускается
+	insertLoyaltyEntry = `
+		INSERT INTO public.loyalty_ledger (entry_id, account, user_id, order_id, type, points)
+		VALUES ($1, 'customer', $2, $3, $4, $5), ($1, $6, $2, $3, $4, -$5::bigint)
+		ON CONFLICT (order_id, type, account) DO NOTHING`
+	findOrderLoyaltyEntry = `
+		SELECT user_id, points FROM public.loyalty_ledger
+		WHERE order_id = $1 AND type = $2 AND account = 'customer'`
+	findOrderLoyalty = `
+		SELECT user_id, total, delivery_fee, loyalty_earn_rate FROM public.orders WHERE id = $1`
+)
+
+func (l *LoyaltyRepository) FindBalance(ctx context.Context, userId int64) (int64, error) {
+	var balance int64
+	err := l.db.QueryRow(ctx, findLoyaltyBalance, userId).Scan(&balance)
+	return balance, err
+}
+
+func (l *LoyaltyRepository) FindEntries(ctx context.Context, userId int64, limit int) ([]*model.LoyaltyEntry, error) {
+	rows, err := l.db.Query(ctx, findLoyaltyEntries, userId, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (*model.LoyaltyEntry, error) {
+		entry := &model.LoyaltyEntry{}
+		return entry, row.Scan(&entry.Id, &entry.Type, &entry.Points, &entry.OrderId, &entry.CreatedAt)
+	})
+}
+
+// Блокирует счет покупателя до конца транзакции и проверяет, что на нем хватает баллов
+func lockLoyaltyPoints(ctx context.Context, tx pgx.Tx, userId, points int64) error {
+	if _, err := tx.Exec(ctx, lockLoyaltyAccount, userId); err != nil {
+		return err
+	}
+
+	var balance int64
+	if err := tx.QueryRow(ctx, findLoyaltyBalance, userId).Scan(&balance); err != nil {
+		return err
+	}
+
+	if balance < points {
+		return &LoyaltyError{Balance: balance, Requested: points}
+	}
+
+	return nil
+}
+
+// Списывает баллы в оплату заказа. Вызывается после lockLoyaltyPoints в той же транзакции
+func redeemLoyaltyPoints(ctx context.Context, tx pgx.Tx, order *model.Order) error {
+	_, err := tx.Exec(ctx, insertLoyaltyEntry, uuid.New(), order.UserId, order.Id, model.LoyaltyRedemption, -order.LoyaltyPoints, model.LoyaltyAccountRedeemed)
+	return err
+}
+
+// Начисляет баллы за доставленный заказ по ставке, зафиксированной при оформлении
+func accrueLoyaltyPoints(ctx context.Context, tx pgx.Tx, orderId int64) error {
+	order := &model.Order{Id: orderId}
+	err := tx.QueryRow(ctx, findOrderLoyalty, orderId).Scan(&order.UserId, &order.Total, &order.DeliveryFee, &order.LoyaltyEarnRate)
+	if err != nil {
+		return err
+	}
+
+	points := order.EarnedPoints()
+	if points == 0 {
+		return nil
+	}
+
+	_, err = tx.Exec(ctx, insertLoyaltyEntry, uuid.New(), order.UserId, orderId, model.LoyaltyAccrual, points, model.LoyaltyAccountIssued)
+	return err
+}
+
+// Сторнирует начисление и списание по заказу. Если начисленные баллы уже потрачены, баланс уходит в минус
+func reverseLoyaltyPoints(ctx context.Context, tx pgx.Tx, orderId int64) error {
+	for _, r := range loyaltyReversals {
+		var userId, points int64
+		err := tx.QueryRow(ctx, findOrderLoyaltyEntry, orderId, r.entryType).Scan(&userId, &points)
+
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, err = tx.Exec(ctx, insertLoyaltyEntry, uuid.New(), userId, orderId, r.reversal, -points, r.account); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
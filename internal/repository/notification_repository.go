@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"arabic/internal/model"
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type NotificationRepository struct {
+	db *pgxpool.Pool
+}
+
+type INotificationRepository interface {
+	Enqueue(ctx context.Context, userId int64, notificationType string, data map[string]any) error
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]*model.Notification, error)
+	MarkSent(ctx context.Context, id int64) error
+	Retry(ctx context.Context, id int64, reason string, at time.Time) error
+	MarkFailed(ctx context.Context, id int64, reason string) error
+}
+
+func NewNotificationRepository(db *pgxpool.Pool) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+var (
+	insertNotification = "INSERT INTO public.notifications (user_id, type, data) VALUES ($1, $2, $3)"
+	// Уведомление получают все подписчики товара на этот тип события
+	insertCatalogNotifications = `
+		INSERT INTO public.notifications (user_id, type, data)
+		SELECT user_id, $2, $3 FROM public.catalog_subscriptions
+		WHERE catalog_id = $1 AND type = $2`
+	deleteCatalogSubscriptions = "DELETE FROM public.catalog_subscriptions WHERE catalog_id = $1 AND type = $2"
+	// Данные письма о статусе берутся из заказа в той же транзакции, что и смена статуса
+	insertOrderNotification = `
+		INSERT INTO public.notifications (user_id, type, data)
+		SELECT o.user_id, $2, jsonb_build_object(
+			'order_id', o.id,
+			'status', o.status,
+			'total', o.total,
+			'delivery_fee', o.delivery_fee,
+			'items', (
+				SELECT COALESCE(jsonb_agg(jsonb_build_object('name', oi.name, 'quantity', oi.quantity, 'unit_price', oi.unit_price) ORDER BY oi.id), '[]')
+				FROM public.order_items oi WHERE oi.order_id = o.id))
+		FROM public.orders o WHERE o.id = $1`
+	// Выбранные уведомления скрываются от других обработчиков на время аренды.
+	// Если процесс упадет до отметки об отправке, уведомление снова попадет в выборку
+	claimNotifications = `
+		UPDATE public.notifications n SET next_attempt_at = NOW() + make_interval(secs => $2), attempts = n.attempts + 1
+		FROM public.users u
+		WHERE n.id IN (
+			SELECT id FROM public.notifications
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at, id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED)
+		AND u.id = n.user_id
+		RETURNING n.id, n.user_id, u.email, u.language, n.type, n.data, n.attempts`
+	// Ссылка в письме может содержать одноразовый токен, после отправки ее не храним
+	markNotificationSent   = "UPDATE public.notifications SET status = 'sent', sent_at = NOW(), error = '', data = data - 'link' WHERE id = $1"
+	retryNotification      = "UPDATE public.notifications SET next_attempt_at = $3, error = $2 WHERE id = $1"
+	markNotificationFailed = "UPDATE public.notifications SET status = 'failed', error = $2, data = data - 'link' WHERE id = $1"
+)
+
+func (n *NotificationRepository) Enqueue(ctx context.Context, userId int64, notificationType string, data map[string]any) error {
+	_, err := n.db.Exec(ctx, insertNotification, userId, notificationType, data)
+	return err
+}
+
+func (n *NotificationRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]*model.Notification, error) {
+	rows, err := n.db.Query(ctx, claimNotifications, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (*model.Notification, error) {
+		notification := &model.Notification{}
+		var data []byte
+		err := row.Scan(&notification.Id, &notification.UserId, &notification.Email, &notification.Language, &notification.Type, &data, &notification.Attempts)
+		if err != nil {
+			return nil, err
+		}
+
+		// Числа остаются json.Number, чтобы номер заказа не превратился в 1e+06
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		return notification, decoder.Decode(&notification.Data)
+	})
+}
+
+func (n *NotificationRepository) MarkSent(ctx context.Context, id int64) error {
+	_, err := n.db.Exec(ctx, markNotificationSent, id)
+	return err
+}
+
+// Возвращает уведомление в очередь со следующей попыткой в at
+func (n *NotificationRepository) Retry(ctx context.Context, id int64, reason string, at time.Time) error {
+	_, err := n.db.Exec(ctx, retryNotification, id, reason, at)
+	return err
+}
+
+func (n *NotificationRepository) MarkFailed(ctx context.Context, id int64, reason string) error {
+	_, err := n.db.Exec(ctx, markNotificationFailed, id, reason)
+	return err
+}
+
+// Ставит уведомления об изменении товара в очередь в транзакции изменения.
+// Подписка на поступление одноразовая и удаляется после уведомления
+func enqueueCatalogNotifications(ctx context.Context, tx pgx.Tx, catalogId uint, notifications []*model.Notification) error {
+	for _, notification := range notifications {
+		_, err := tx.Exec(ctx, insertCatalogNotifications, catalogId, notification.Type, notification.Data)
+		if err != nil {
+			return err
+		}
+
+		if notification.Type == model.SubscriptionBackInStock {
+			if _, err = tx.Exec(ctx, deleteCatalogSubscriptions, catalogId, notification.Type); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Ставит письмо покупателю о смене статуса заказа, если статус из тех, о которых сообщаем
+func enqueueOrderNotification(ctx context.Context, tx pgx.Tx, change *model.OrderStatusChange) error {
+	if !model.IsNotifiedOrderStatus(change.ToStatus) {
+		return nil
+	}
+
+	_, err := tx.Exec(ctx, insertOrderNotification, change.OrderId, model.NotificationOrderStatus)
+	return err
+}
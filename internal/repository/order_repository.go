@@ -0,0 +1,548 @@
+package repository
+
+import (
+	"arabic/internal/model"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type OrderRepository struct {
+	db *pgxpool.Pool
+}
+
+type IOrderRepository interface {
+	Create(ctx context.Context, order *model.Order, cartItems []*model.CartItem) (*model.Order, error)
+	FindById(ctx context.Context, id int64) (*model.Order, bool, error)
+	FindByUser(ctx context.Context, userId int64) ([]*model.Order, error)
+	FindByStatuses(ctx context.Context, statuses []string) ([]*model.Order, error)
+	ChangeStatus(ctx context.Context, change *model.OrderStatusChange) (bool, error)
+	FindHistory(ctx context.Context, orderId int64) ([]*model.OrderStatusChange, error)
+	IsCourierOrder(ctx context.Context, orderId, courierId int64) (bool, error)
+	FindCourierQueue(ctx context.Context, courierId int64) ([]*model.Order, error)
+}
+
+// Возвращается при оформлении, если товара не хватает на складе или он удален
+type StockError struct {
+	CatalogId uint
+	Available int
+	NotFound  bool
+}
+
+func (e *StockError) Error() string {
+	if e.NotFound {
+		return fmt.Sprintf("catalog item %d not found", e.CatalogId)
+	}
+	return fmt.Sprintf("catalog item %d: only %d left in stock", e.CatalogId, e.Available)
+}
+
+// Возвращается при оформлении, если слот доставки не найден, из другой зоны, уже начался или заполнен
+type SlotError struct {
+	SlotId int64
+	Full   bool
+}
+
+func (e *SlotError) Error() string {
+	if e.Full {
+		return fmt.Sprintf("delivery slot %d is fully booked", e.SlotId)
+	}
+	return fmt.Sprintf("delivery slot %d is not available", e.SlotId)
+}
+
+// Возвращается при оформлении, если сумма товаров меньше минимального заказа зоны доставки
+type MinOrderError struct {
+	Amount    float32
+	MinAmount float32
+}
+
+func (e *MinOrderError) Error() string {
+	return fmt.Sprintf("order amount %.2f is less than minimal %.2f", e.Amount, e.MinAmount)
+}
+
+// Заказ нельзя упаковать, пока не собраны все строки
+var ErrPickingIncomplete = errors.New("order has lines that are not picked yet")
+
+func NewOrderRepository(db *pgxpool.Pool) *OrderRepository {
+	return &OrderRepository{db: db}
+}
+
+var (
+	// Списываем остаток только если его хватает, заодно получаем снимок товара
+	reserveCatalogStock = `
+		UPDATE public.catalogs SET amount = amount - $2, updated_at = NOW()
+		WHERE id = $1 AND amount >= $2
+		RETURNING name, COALESCE(sku, ''), price, discount_percent, weight, category_id,
+		          ARRAY(SELECT tag_id FROM public.catalog_tags WHERE catalog_id = $1)`
+	findCatalogAmount = "SELECT amount FROM public.catalogs WHERE id = $1"
+	// Атомарно занимаем место в слоте: UPDATE блокирует строку, параллельные оформления ждут друг друга
+	reserveDeliverySlot = `
+		UPDATE public.delivery_slots SET reserved = reserved + 1
+		WHERE id = $1 AND reserved < capacity AND date + start_time > NOW() AND ($2::bigint IS NULL OR zone_id = $2)`
+	findDeliverySlotState = `
+		SELECT reserved >= capacity FROM public.delivery_slots
+		WHERE id = $1 AND date + start_time > NOW() AND ($2::bigint IS NULL OR zone_id = $2)`
+	releaseDeliverySlot = `
+		UPDATE public.delivery_slots s SET reserved = s.reserved - 1
+		FROM public.orders o
+		WHERE o.id = $1 AND s.id = o.delivery_slot_id AND s.reserved > 0`
+	insertOrder = `
+		INSERT INTO public.orders (user_id, status, subtotal, discount, total, total_weight, apartment, house, street, city, region,
+		                           latitude, longitude, delivery_slot_id, delivery_zone_id, delivery_fee, promo_code, promo_discount,
+		                           loyalty_points, loyalty_discount, loyalty_earn_rate)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, NULLIF($17, ''), $18, $19, $20, $21)
+		RETURNING id, created_at, updated_at`
+	insertOrderItem = `
+		INSERT INTO public.order_items (order_id, catalog_id, name, sku, price, discount_percent, unit_price, weight, quantity, ordered_quantity)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8, $9, $9)
+		RETURNING id`
+	// Из корзины убирается только оформленное: добавленное во время оформления остается.
+	// Сначала удаляются позиции, заказанные полностью, затем уменьшаются увеличенные за это время
+	deleteOrderedCartItems = `
+		DELETE FROM public.cart_items ci
+		USING unnest($2::bigint[], $3::int[]) AS o(catalog_id, quantity)
+		WHERE ci.cart_id = $1 AND ci.catalog_id = o.catalog_id AND ci.quantity <= o.quantity`
+	reduceOrderedCartItems = `
+		UPDATE public.cart_items ci SET quantity = ci.quantity - o.quantity, updated_at = NOW()
+		FROM unnest($2::bigint[], $3::int[]) AS o(catalog_id, quantity)
+		WHERE ci.cart_id = $1 AND ci.catalog_id = o.catalog_id`
+	// Статус меняется только если заказ все еще в ожидаемом статусе - защита от гонок между сотрудниками
+	updateOrderStatus   = "UPDATE public.orders SET status = $3, updated_at = NOW() WHERE id = $1 AND status = $2"
+	assignOrderPicker   = "UPDATE public.orders SET picker_id = $2 WHERE id = $1"
+	hasPendingPickLines = "SELECT EXISTS (SELECT 1 FROM public.order_items WHERE order_id = $1 AND pick_status = $2)"
+	insertOrderHistory  = `
+		INSERT INTO public.order_status_history (order_id, from_status, to_status, changed_by, comment)
+		VALUES ($1, NULLIF($2, ''), $3, NULLIF($4, 0), $5)
+		RETURNING id, created_at`
+	// В порядке id товара, как при списании, чтобы не уйти в deadlock с оформлением
+	findOrderStock   = "SELECT catalog_id, quantity FROM public.order_items WHERE order_id = $1 AND catalog_id IS NOT NULL ORDER BY catalog_id"
+	findOrderHistory = `
+		SELECT id, order_id, COALESCE(from_status, ''), to_status, COALESCE(changed_by, 0), comment, created_at
+		FROM public.order_status_history WHERE order_id = $1 ORDER BY id`
+	orderColumns         = "id, user_id, status, subtotal, discount, total, total_weight, apartment, house, street, city, region, latitude, longitude, delivery_slot_id, delivery_zone_id, delivery_fee, COALESCE(promo_code, ''), promo_discount, loyalty_points, loyalty_discount, created_at, updated_at"
+	findOrderById        = "SELECT " + orderColumns + " FROM public.orders WHERE id = $1"
+	findUserOrders       = "SELECT " + orderColumns + " FROM public.orders WHERE user_id = $1 ORDER BY created_at DESC, id DESC"
+	findOrdersByStatuses = "SELECT " + orderColumns + " FROM public.orders WHERE status = ANY($1) ORDER BY created_at, id"
+	// Заказ курьера: в его маршруте или он сам забрал заказ в доставку. $2 - курьер, $3 - статус out_for_delivery
+	courierOrderCondition = `
+		(EXISTS (SELECT 1 FROM public.delivery_route_stops rs
+		         JOIN public.delivery_routes r ON r.id = rs.route_id
+		         WHERE rs.order_id = o.id AND r.courier_id = $2)
+		 OR EXISTS (SELECT 1 FROM public.order_status_history h
+		            WHERE h.order_id = o.id AND h.changed_by = $2 AND h.to_status = $3))`
+	isCourierOrder   = "SELECT EXISTS (SELECT 1 FROM public.orders o WHERE o.id = $1 AND " + courierOrderCondition + ")"
+	findCourierQueue = "SELECT " + orderColumns + " FROM public.orders o WHERE o.status = $1 OR (o.status = $3 AND " + courierOrderCondition + ") ORDER BY created_at, id"
+	findOrderItems   = `
+		SELECT id, order_id, COALESCE(catalog_id, 0), name, COALESCE(sku, ''), price, discount_percent, unit_price, weight, quantity
+		FROM public.order_items WHERE order_id = ANY($1) ORDER BY id`
+)
+
+// Создает заказ в одной транзакции: списывает остатки, фиксирует цены и убирает из корзины
+// оформленные позиции cartItems
+func (o *OrderRepository) Create(ctx context.Context, order *model.Order, cartItems []*model.CartItem) (*model.Order, error) {
+	tx, err := o.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Блокируем строки каталога всегда в одном порядке, чтобы параллельные оформления не уходили в deadlock
+	slices.SortFunc(order.Items, func(a, b *model.OrderItem) int {
+		return cmp.Compare(a.CatalogId, b.CatalogId)
+	})
+
+	for _, item := range order.Items {
+		err = tx.QueryRow(ctx, reserveCatalogStock, item.CatalogId, item.Quantity).
+			Scan(&item.Name, &item.Sku, &item.Price, &item.DiscountPercent, &item.Weight, &item.CategoryId, &item.TagIds)
+
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, stockError(ctx, tx, item.CatalogId)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	order.CalculateTotals()
+
+	if order.Pricing.PromoCodeError != "" {
+		return nil, &PromoError{Promotion: order.PromoCode, Reason: order.Pricing.PromoCodeError}
+	}
+
+	if order.BelowMinOrder() {
+		return nil, &MinOrderError{Amount: order.ItemsTotal(), MinAmount: order.Zone.MinOrderAmount}
+	}
+
+	// Баллами оплачивается часть суммы, уже прошедшей проверку минимального заказа
+	if order.Loyalty != nil {
+		order.RedeemLoyalty()
+	}
+
+	if order.LoyaltyPoints > 0 {
+		if err = lockLoyaltyPoints(ctx, tx, order.UserId, order.LoyaltyPoints); err != nil {
+			return nil, err
+		}
+	}
+
+	if order.DeliverySlotId != nil {
+		if err = o.reserveSlot(ctx, tx, *order.DeliverySlotId, order.DeliveryZoneId); err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.QueryRow(ctx, insertOrder,
+		order.UserId,
+		order.Status,
+		order.Subtotal,
+		order.Discount,
+		order.Total,
+		order.TotalWeight,
+		order.Address.Apartment,
+		order.Address.House,
+		order.Address.Street,
+		order.Address.City,
+		order.Address.Region,
+		order.Address.Latitude,
+		order.Address.Longitude,
+		order.DeliverySlotId,
+		order.DeliveryZoneId,
+		order.DeliveryFee,
+		order.PromoCode,
+		order.PromoDiscount,
+		order.LoyaltyPoints,
+		order.LoyaltyDiscount,
+		order.LoyaltyEarnRate).Scan(&order.Id, &order.CreatedAt, &order.UpdatedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = redeemPromotions(ctx, tx, order); err != nil {
+		return nil, err
+	}
+
+	if order.LoyaltyPoints > 0 {
+		if err = redeemLoyaltyPoints(ctx, tx, order); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, item := range order.Items {
+		item.OrderId = order.Id
+		err = tx.QueryRow(ctx, insertOrderItem,
+			item.OrderId,
+			item.CatalogId,
+			item.Name,
+			item.Sku,
+			item.Price,
+			item.DiscountPercent,
+			item.UnitPrice,
+			item.Weight,
+			item.Quantity).Scan(&item.Id)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err = tx.Exec(ctx, insertOrderHistory, order.Id, "", order.Status, order.UserId, ""); err != nil {
+		return nil, err
+	}
+
+	if err = removeOrderedCartItems(ctx, tx, cartItems); err != nil {
+		return nil, err
+	}
+
+	return order, tx.Commit(ctx)
+}
+
+func removeOrderedCartItems(ctx context.Context, tx pgx.Tx, cartItems []*model.CartItem) error {
+	if len(cartItems) == 0 {
+		return nil
+	}
+
+	catalogIds := make([]int64, 0, len(cartItems))
+	quantities := make([]int32, 0, len(cartItems))
+	for _, item := range cartItems {
+		catalogIds = append(catalogIds, int64(item.CatalogId))
+		quantities = append(quantities, int32(item.Quantity))
+	}
+
+	cartId := cartItems[0].CartId
+	if _, err := tx.Exec(ctx, deleteOrderedCartItems, cartId, catalogIds, quantities); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(ctx, reduceOrderedCartItems, cartId, catalogIds, quantities)
+	return err
+}
+
+func restockOrder(ctx context.Context, tx pgx.Tx, orderId int64) error {
+	rows, err := tx.Query(ctx, findOrderStock, orderId)
+	if err != nil {
+		return err
+	}
+
+	items, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*model.OrderItem, error) {
+		item := &model.OrderItem{}
+		return item, row.Scan(&item.CatalogId, &item.Quantity)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		if err = restockCatalogItem(ctx, tx, item.CatalogId, item.Quantity); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func stockError(ctx context.Context, tx pgx.Tx, catalogId uint) error {
+	stockErr := &StockError{CatalogId: catalogId}
+
+	err := tx.QueryRow(ctx, findCatalogAmount, catalogId).Scan(&stockErr.Available)
+	if errors.Is(err, pgx.ErrNoRows) {
+		stockErr.NotFound = true
+		return stockErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return stockErr
+}
+
+// Слот должен быть из зоны доставки заказа
+func (o *OrderRepository) reserveSlot(ctx context.Context, tx pgx.Tx, slotId int64, zoneId *int64) error {
+	tag, err := tx.Exec(ctx, reserveDeliverySlot, slotId, zoneId)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() != 0 {
+		return nil
+	}
+
+	slotErr := &SlotError{SlotId: slotId}
+	err = tx.QueryRow(ctx, findDeliverySlotState, slotId, zoneId).Scan(&slotErr.Full)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+
+	return slotErr
+}
+
+func (o *OrderRepository) FindById(ctx context.Context, id int64) (*model.Order, bool, error) {
+	orders, err := o.findOrders(ctx, findOrderById, id)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(orders) == 0 {
+		return nil, false, nil
+	}
+
+	return orders[0], true, nil
+}
+
+func (o *OrderRepository) FindByUser(ctx context.Context, userId int64) ([]*model.Order, error) {
+	return o.findOrders(ctx, findUserOrders, userId)
+}
+
+func (o *OrderRepository) FindByStatuses(ctx context.Context, statuses []string) ([]*model.Order, error) {
+	return o.findOrders(ctx, findOrdersByStatuses, statuses)
+}
+
+// Меняет статус и пишет историю в одной транзакции. false - заказ уже не в статусе change.FromStatus.
+// Переход в сборку назначает сборщика, упаковка возможна только после сборки всех строк
+func (o *OrderRepository) ChangeStatus(ctx context.Context, change *model.OrderStatusChange) (bool, error) {
+	tx, err := o.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, updateOrderStatus, change.OrderId, change.FromStatus, change.ToStatus)
+	if err != nil {
+		return false, err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	switch change.ToStatus {
+	case model.OrderStatusPicking:
+		if _, err = tx.Exec(ctx, assignOrderPicker, change.OrderId, change.ChangedBy); err != nil {
+			return false, err
+		}
+	case model.OrderStatusPacked:
+		var pending bool
+		if err = tx.QueryRow(ctx, hasPendingPickLines, change.OrderId, model.PickStatusPending).Scan(&pending); err != nil {
+			return false, err
+		}
+		if pending {
+			return false, ErrPickingIncomplete
+		}
+	}
+
+	if err = recordStatusChange(ctx, tx, change); err != nil {
+		return false, err
+	}
+
+	if model.IsRestockingStatus(change.ToStatus) {
+		if err = restockOrder(ctx, tx, change.OrderId); err != nil {
+			return false, err
+		}
+	}
+
+	// Слот и использование промокода освобождает только отмена: возвращенный заказ уже доставлен
+	if change.ToStatus == model.OrderStatusCancelled {
+		if _, err = tx.Exec(ctx, releaseDeliverySlot, change.OrderId); err != nil {
+			return false, err
+		}
+		if err = releasePromotions(ctx, tx, change.OrderId); err != nil {
+			return false, err
+		}
+	}
+
+	switch change.ToStatus {
+	case model.OrderStatusDelivered:
+		err = accrueLoyaltyPoints(ctx, tx, change.OrderId)
+	case model.OrderStatusCancelled, model.OrderStatusReturned:
+		// Отмена и возврат сторнируют баллы заказа вместе с возвратом денег
+		err = reverseLoyaltyPoints(ctx, tx, change.OrderId)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+func (o *OrderRepository) IsCourierOrder(ctx context.Context, orderId, courierId int64) (bool, error) {
+	var ok bool
+	err := o.db.QueryRow(ctx, isCourierOrder, orderId, courierId, model.OrderStatusOutForDelivery).Scan(&ok)
+	return ok, err
+}
+
+// Упакованные заказы, которые ждут курьера, и заказы самого курьера в доставке
+func (o *OrderRepository) FindCourierQueue(ctx context.Context, courierId int64) ([]*model.Order, error) {
+	return o.findOrders(ctx, findCourierQueue, model.OrderStatusPacked, courierId, model.OrderStatusOutForDelivery)
+}
+
+func (o *OrderRepository) FindHistory(ctx context.Context, orderId int64) ([]*model.OrderStatusChange, error) {
+	rows, err := o.db.Query(ctx, findOrderHistory, orderId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []*model.OrderStatusChange
+	for rows.Next() {
+		change := &model.OrderStatusChange{}
+		err = rows.Scan(&change.Id, &change.OrderId, &change.FromStatus, &change.ToStatus, &change.ChangedBy, &change.Comment, &change.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, change)
+	}
+
+	return history, rows.Err()
+}
+
+func (o *OrderRepository) findOrders(ctx context.Context, query string, args ...any) ([]*model.Order, error) {
+	rows, err := o.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	orders, err := pgx.CollectRows(rows, scanOrder)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = o.attachItems(ctx, orders); err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+func (o *OrderRepository) attachItems(ctx context.Context, orders []*model.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	byId := make(map[int64]*model.Order, len(orders))
+	ids := make([]int64, 0, len(orders))
+	for _, order := range orders {
+		byId[order.Id] = order
+		ids = append(ids, order.Id)
+	}
+
+	rows, err := o.db.Query(ctx, findOrderItems, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item := &model.OrderItem{}
+		err = rows.Scan(&item.Id, &item.OrderId, &item.CatalogId, &item.Name, &item.Sku, &item.Price, &item.DiscountPercent, &item.UnitPrice, &item.Weight, &item.Quantity)
+		if err != nil {
+			return err
+		}
+		byId[item.OrderId].Items = append(byId[item.OrderId].Items, item)
+	}
+
+	return rows.Err()
+}
+
+func scanOrder(row pgx.CollectableRow) (*model.Order, error) {
+	order := &model.Order{}
+	err := row.Scan(
+		&order.Id,
+		&order.UserId,
+		&order.Status,
+		&order.Subtotal,
+		&order.Discount,
+		&order.Total,
+		&order.TotalWeight,
+		&order.Address.Apartment,
+		&order.Address.House,
+		&order.Address.Street,
+		&order.Address.City,
+		&order.Address.Region,
+		&order.Address.Latitude,
+		&order.Address.Longitude,
+		&order.DeliverySlotId,
+		&order.DeliveryZoneId,
+		&order.DeliveryFee,
+		&order.PromoCode,
+		&order.PromoDiscount,
+		&order.LoyaltyPoints,
+		&order.LoyaltyDiscount,
+		&order.CreatedAt,
+		&order.UpdatedAt,
+	)
+	return order, err
+}
+
+// Пишет смену статуса в историю и ставит письмо покупателю в очередь
+func recordStatusChange(ctx context.Context, tx pgx.Tx, change *model.OrderStatusChange) error {
+	err := tx.QueryRow(ctx, insertOrderHistory, change.OrderId, change.FromStatus, change.ToStatus, change.ChangedBy, change.Comment).
+		Scan(&change.Id, &change.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return enqueueOrderNotification(ctx, tx, change)
+}
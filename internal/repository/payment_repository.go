@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"arabic/internal/model"
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	// У заказа уже есть платеж в ожидании или оплаченный
+	ErrPaymentExists = errors.New("order already has an active payment")
+	// Вебхук пришел по неизвестному платежу
+	ErrPaymentNotFound = errors.New("payment not found")
+)
+
+type PaymentRepository struct {
+	db *pgxpool.Pool
+}
+
+type IPaymentRepository interface {
+	Create(ctx context.Context, payment *model.Payment) error
+	FindLatestByOrder(ctx context.Context, orderId int64) (*model.Payment, bool, error)
+	ApplyWebhook(ctx context.Context, webhook *model.PaymentWebhook) (*model.PaymentWebhookResult, error)
+	UpdateState(ctx context.Context, payment *model.Payment, fromStatus string) (bool, error)
+}
+
+func NewPaymentRepository(db *pgxpool.Pool) *PaymentRepository {
+	return &PaymentRepository{db: db}
+}
+
+var (
+	paymentColumns = `id, order_id, provider, intent_id, client_secret, currency, amount, authorized_amount, captured_amount,
+		refunded_amount, status, created_at, updated_at`
+	// Новый платеж создается, только если у заказа нет активного
+	insertPayment = `
+		INSERT INTO public.payments (order_id, provider, intent_id, client_secret, currency, amount, status)
+		SELECT $1, $2, $3, $4, $5, $6, $7
+		WHERE NOT EXISTS (SELECT 1 FROM public.payments WHERE order_id = $1 AND status = ANY($8))
+		RETURNING id, created_at, updated_at`
+	findLatestOrderPayment = "SELECT " + paymentColumns + " FROM public.payments WHERE order_id = $1 ORDER BY id DESC LIMIT 1"
+	insertWebhookEvent     = `
+		INSERT INTO public.payment_webhook_events (event_id, type, intent_id, payload)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (event_id) DO NOTHING`
+	// Блокируем платеж и заказ: отмена заказа и вебхук не должны обгонять друг друга
+	lockPaymentByIntent = `
+		SELECT p.id, p.order_id, p.provider, p.intent_id, p.client_secret, p.currency, p.amount, p.authorized_amount,
+		       p.captured_amount, p.refunded_amount, p.status, p.created_at, p.updated_at, o.status
+		FROM public.payments p
+		JOIN public.orders o ON o.id = p.order_id
+		WHERE p.intent_id = $1
+		FOR UPDATE OF p, o`
+	applyPaymentWebhook = `
+		UPDATE public.payments SET status = $2, authorized_amount = $3, updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at`
+	updatePaymentState = `
+		UPDATE public.payments SET status = $3, captured_amount = $4, refunded_amount = $5, updated_at = NOW()
+		WHERE id = $1 AND status = $2
+		RETURNING updated_at`
+)
+
+// Активные платежи блокируют создание нового
+var activePaymentStatuses = []string{model.PaymentStatusPending, model.PaymentStatusAuthorized, model.PaymentStatusCaptured}
+
+func (p *PaymentRepository) Create(ctx context.Context, payment *model.Payment) error {
+	err := p.db.QueryRow(ctx, insertPayment,
+		payment.OrderId,
+		payment.Provider,
+		payment.IntentId,
+		payment.ClientSecret,
+		payment.Currency,
+		payment.Amount,
+		payment.Status,
+		activePaymentStatuses).Scan(&payment.Id, &payment.CreatedAt, &payment.UpdatedAt)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrPaymentExists
+	}
+
+	return err
+}
+
+func (p *PaymentRepository) FindLatestByOrder(ctx context.Context, orderId int64) (*model.Payment, bool, error) {
+	payment := &model.Payment{}
+	err := p.db.QueryRow(ctx, findLatestOrderPayment, orderId).Scan(paymentFields(payment)...)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	return payment, true, nil
+}
+
+// Применяет вебхук в одной транзакции с записью события, поэтому повторная доставка
+// ничего не меняет. Авторизация переводит заказ из created в confirmed.
+// Событие по уже обработанному платежу (например, failed после authorized) только записывается
+func (p *PaymentRepository) ApplyWebhook(ctx context.Context, webhook *model.PaymentWebhook) (*model.PaymentWebhookResult, error) {
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, insertWebhookEvent, webhook.EventId, webhook.Type, webhook.IntentId, webhook.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return &model.PaymentWebhookResult{Duplicate: true}, nil
+	}
+
+	payment := &model.Payment{}
+	var orderStatus string
+	err = tx.QueryRow(ctx, lockPaymentByIntent, webhook.IntentId).Scan(append(paymentFields(payment), &orderStatus)...)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrPaymentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := &model.PaymentWebhookResult{Payment: payment}
+
+	if payment.Status != model.PaymentStatusPending {
+		return result, tx.Commit(ctx)
+	}
+
+	payment.Status = webhook.Status
+	if webhook.Status == model.PaymentStatusAuthorized {
+		payment.AuthorizedAmount = webhook.Amount
+	}
+
+	err = tx.QueryRow(ctx, applyPaymentWebhook, payment.Id, payment.Status, payment.AuthorizedAmount).Scan(&payment.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if webhook.Status == model.PaymentStatusAuthorized {
+		if orderStatus != model.OrderStatusCreated {
+			result.Release = true
+			return result, tx.Commit(ctx)
+		}
+
+		result.Change = &model.OrderStatusChange{
+			OrderId:    payment.OrderId,
+			FromStatus: model.OrderStatusCreated,
+			ToStatus:   model.OrderStatusConfirmed,
+			Comment:    "Payment authorized",
+		}
+
+		if _, err = tx.Exec(ctx, updateOrderStatus, payment.OrderId, result.Change.FromStatus, result.Change.ToStatus); err != nil {
+			return nil, err
+		}
+
+		if err = recordStatusChange(ctx, tx, result.Change); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, tx.Commit(ctx)
+}
+
+// Сохраняет статус и суммы после списания или возврата. false - платеж уже не в статусе fromStatus
+func (p *PaymentRepository) UpdateState(ctx context.Context, payment *model.Payment, fromStatus string) (bool, error) {
+	err := p.db.QueryRow(ctx, updatePaymentState, payment.Id, fromStatus, payment.Status, payment.CapturedAmount, payment.RefundedAmount).
+		Scan(&payment.UpdatedAt)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func paymentFields(payment *model.Payment) []any {
+	return []any{
+		&payment.Id,
+		&payment.OrderId,
+		&payment.Provider,
+		&payment.IntentId,
+		&payment.ClientSecret,
+		&payment.Currency,
+		&payment.Amount,
+		&payment.AuthorizedAmount,
+		&payment.CapturedAmount,
+		&payment.RefundedAmount,
+		&payment.Status,
+		&payment.CreatedAt,
+		&payment.UpdatedAt,
+	}
+}
@@ -0,0 +1,445 @@
+package repository
+
+import (
+	"arabic/internal/model"
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Строка уже собрана или заказ больше не в сборке
+var ErrPickLineUnavailable = errors.New("pick line is not available")
+
+// Событие не найдено в заказе
+var ErrPickEventNotFound = errors.New("pick event not found")
+
+// Решение по событию уже принято или заказ уже передан в доставку
+var ErrPickEventClosed = errors.New("pick event is closed")
+
+type PickingRepository struct {
+	db *pgxpool.Pool
+}
+
+type IPickingRepository interface {
+	ClaimNext(ctx context.Context, pickerId int64) (int64, *model.OrderStatusChange, error)
+	FindPickingOrder(ctx context.Context, orderId int64) (*model.PickingOrder, bool, error)
+	MarkPicked(ctx context.Context, orderId, itemId int64) error
+	ShortPick(ctx context.Context, orderId, itemId int64, quantity int, pickerId int64) (*model.PickEvent, error)
+	Substitute(ctx context.Context, orderId, itemId int64, catalogId uint, quantity int, pickerId int64) (*model.PickEvent, error)
+	FindEvents(ctx context.Context, orderId int64) ([]*model.PickEvent, error)
+	ResolveEvent(ctx context.Context, orderId, eventId int64, approve bool) (*model.PickEvent, error)
+}
+
+func NewPickingRepository(db *pgxpool.Pool) *PickingRepository {
+	return &PickingRepository{db: db}
+}
+
+var (
+	findPickerOrder = "SELECT id FROM public.orders WHERE status = $2 AND picker_id = $1 ORDER BY updated_at, id LIMIT 1"
+	// Сначала заказы с ближайшим слотом доставки. SKIP LOCKED - параллельные сборщики получают разные заказы
+	lockNextPickingOrder = `
+		SELECT o.id FROM public.orders o
+		LEFT JOIN public.delivery_slots s ON s.id = o.delivery_slot_id
+		WHERE o.status = $1
+		ORDER BY s.date + s.start_time NULLS LAST, o.created_at, o.id
+		LIMIT 1
+		FOR UPDATE OF o SKIP LOCKED`
+	startOrderPicking = "UPDATE public.orders SET status = $3, picker_id = $2, updated_at = NOW() WHERE id = $1"
+	findPickingOrder  = "SELECT id, user_id, status, picker_id, delivery_slot_id FROM public.orders WHERE id = $1"
+	// Порядок обхода: по категориям (рядам склада), внутри ряда по названию
+	findPickLines = `
+		SELECT oi.id, COALESCE(oi.catalog_id, 0), oi.name, COALESCE(oi.sku, ''), c.category_id, COALESCE(cat.name, ''),
+		       oi.quantity, oi.ordered_quantity, oi.pick_status, oi.substitutes_item_id
+		FROM public.order_items oi
+		LEFT JOIN public.catalogs c ON c.id = oi.catalog_id
+		LEFT JOIN public.categories cat ON cat.id = c.category_id
+		WHERE oi.order_id = $1
+		ORDER BY c.category_id NULLS LAST, oi.name, oi.id`
+	// Блокируем и заказ: отмена заказа ждет, пока сборщик не изменит количество, и возвращает на склад актуальный остаток
+	lockPickLine = `
+		SELECT oi.quantity, COALESCE(oi.catalog_id, 0)
+		FROM public.order_items oi
+		JOIN public.orders o ON o.id = oi.order_id
+		WHERE oi.id = $2 AND oi.order_id = $1 AND o.status = $3 AND oi.pick_status = $4
+		FOR UPDATE OF o, oi`
+	setPickLine          = "UPDATE public.order_items SET pick_status = $2, quantity = $3 WHERE id = $1"
+	clearPickLine        = "UPDATE public.order_items SET quantity = 0 WHERE id = $1"
+	findOrderItemStock   = "SELECT COALESCE(catalog_id, 0), quantity FROM public.order_items WHERE id = $1"
+	insertSubstituteItem = `
+		INSERT INTO public.order_items (order_id, catalog_id, name, sku, price, discount_percent, unit_price, weight,
+		                                quantity, ordered_quantity, pick_status, substitutes_item_id)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8, $9, $9, $10, $11)
+		RETURNING id`
+	// Стоимость доставки, скидка акций и оплата баллами фиксируются при оформлении и при сборке не пересчитываются
+	recalculateOrderTotals = `
+		UPDATE public.orders o
+		SET subtotal = t.subtotal, discount = t.subtotal - t.total + o.promo_discount,
+		    total = GREATEST(t.total - o.promo_discount - o.loyalty_discount, 0) + o.delivery_fee,
+		    total_weight = t.weight, updated_at = NOW()
+		FROM (SELECT COALESCE(SUM(price * quantity), 0) AS subtotal,
+		             COALESCE(SUM(unit_price * quantity), 0) AS total,
+		             COALESCE(SUM(weight * quantity), 0) AS weight
+		      FROM public.order_items WHERE order_id = $1) t
+		WHERE o.id = $1`
+	insertPickEvent = `
+		INSERT INTO public.order_pick_events (order_id, type, order_item_id, substitute_item_id, ordered_quantity, picked_quantity, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, 0))
+		RETURNING id`
+	pickEventColumns = `
+		SELECT e.id, e.order_id, e.type, e.order_item_id, oi.name, e.substitute_item_id, COALESCE(si.name, ''),
+		       e.ordered_quantity, e.picked_quantity, e.status, COALESCE(e.created_by, 0), e.created_at, e.resolved_at
+		FROM public.order_pick_events e
+		JOIN public.order_items oi ON oi.id = e.order_item_id
+		LEFT JOIN public.order_items si ON si.id = e.substitute_item_id`
+	findPickEvents = pickEventColumns + " WHERE e.order_id = $1 ORDER BY e.id"
+	findPickEvent  = pickEventColumns + " WHERE e.id = $1"
+	lockPickEvent  = `
+		SELECT e.status, e.order_item_id, e.substitute_item_id, o.status
+		FROM public.order_pick_events e
+		JOIN public.orders o ON o.id = e.order_id
+		WHERE e.id = $1 AND e.order_id = $2
+		FOR UPDATE OF e, o`
+	resolvePickEvent = "UPDATE public.order_pick_events SET status = $2, resolved_at = NOW() WHERE id = $1"
+)
+
+// Возвращает заказ, который сборщик уже собирает, иначе переводит в сборку следующий подтвержденный заказ.
+// 0 - очередь пуста. Переход статуса возвращается, только если заказ взят в сборку сейчас
+func (p *PickingRepository) ClaimNext(ctx context.Context, pickerId int64) (int64, *model.OrderStatusChange, error) {
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var orderId int64
+	err = tx.QueryRow(ctx, findPickerOrder, pickerId, model.OrderStatusPicking).Scan(&orderId)
+	if err == nil {
+		return orderId, nil, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil, err
+	}
+
+	err = tx.QueryRow(ctx, lockNextPickingOrder, model.OrderStatusConfirmed).Scan(&orderId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil, nil
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if _, err = tx.Exec(ctx, startOrderPicking, orderId, pickerId, model.OrderStatusPicking); err != nil {
+		return 0, nil, err
+	}
+
+	change := &model.OrderStatusChange{
+		OrderId:    orderId,
+		FromStatus: model.OrderStatusConfirmed,
+		ToStatus:   model.OrderStatusPicking,
+		ChangedBy:  pickerId,
+	}
+
+	if err = recordStatusChange(ctx, tx, change); err != nil {
+		return 0, nil, err
+	}
+
+	return orderId, change, tx.Commit(ctx)
+}
+
+func (p *PickingRepository) FindPickingOrder(ctx context.Context, orderId int64) (*model.PickingOrder, bool, error) {
+	order := &model.PickingOrder{}
+	err := p.db.QueryRow(ctx, findPickingOrder, orderId).
+		Scan(&order.OrderId, &order.UserId, &order.Status, &order.PickerId, &order.DeliverySlotId)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	rows, err := p.db.Query(ctx, findPickLines, orderId)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		line := &model.PickLine{}
+		err = rows.Scan(&line.ItemId, &line.CatalogId, &line.Name, &line.Sku, &line.CategoryId, &line.CategoryName,
+			&line.Quantity, &line.OrderedQuantity, &line.PickStatus, &line.SubstitutesItemId)
+		if err != nil {
+			return nil, false, err
+		}
+		order.Lines = append(order.Lines, line)
+	}
+
+	return order, true, rows.Err()
+}
+
+func (p *PickingRepository) MarkPicked(ctx context.Context, orderId, itemId int64) error {
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	quantity, _, err := p.lockLine(ctx, tx, orderId, itemId)
+	if err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec(ctx, setPickLine, itemId, model.PickStatusPicked, quantity); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Недовложение: уменьшает количество, возвращает недостающее на склад и пересчитывает суммы заказа
+func (p *PickingRepository) ShortPick(ctx context.Context, orderId, itemId int64, quantity int, pickerId int64) (*model.PickEvent, error) {
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	ordered, catalogId, err := p.lockLine(ctx, tx, orderId, itemId)
+	if err != nil {
+		return nil, err
+	}
+
+	if quantity >= ordered {
+		return nil, ErrPickLineUnavailable
+	}
+
+	if err = restockCatalogItem(ctx, tx, catalogId, ordered-quantity); err != nil {
+		return nil, err
+	}
+
+	if _, err = tx.Exec(ctx, setPickLine, itemId, model.PickStatusShort, quantity); err != nil {
+		return nil, err
+	}
+
+	event := &model.PickEvent{
+		OrderId:         orderId,
+		Type:            model.PickEventShort,
+		OrderItemId:     itemId,
+		OrderedQuantity: ordered,
+		PickedQuantity:  quantity,
+		CreatedBy:       pickerId,
+	}
+
+	return p.finishPick(ctx, tx, event)
+}
+
+// Замена: исходная строка обнуляется и возвращается на склад, замена списывается со склада отдельной строкой
+func (p *PickingRepository) Substitute(ctx context.Context, orderId, itemId int64, catalogId uint, quantity int, pickerId int64) (*model.PickEvent, error) {
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	ordered, _, err := p.lockLine(ctx, tx, orderId, itemId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = restockOrderItem(ctx, tx, itemId); err != nil {
+		return nil, err
+	}
+
+	if _, err = tx.Exec(ctx, setPickLine, itemId, model.PickStatusSubstituted, 0); err != nil {
+		return nil, err
+	}
+
+	item := &model.OrderItem{OrderId: orderId, CatalogId: catalogId, Quantity: quantity}
+	err = tx.QueryRow(ctx, reserveCatalogStock, catalogId, quantity).
+		Scan(&item.Name, &item.Sku, &item.Price, &item.DiscountPercent, &item.Weight)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, stockError(ctx, tx, catalogId)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	item.UnitPrice = model.DiscountedPrice(item.Price, item.DiscountPercent)
+
+	err = tx.QueryRow(ctx, insertSubstituteItem,
+		item.OrderId,
+		item.CatalogId,
+		item.Name,
+		item.Sku,
+		item.Price,
+		item.DiscountPercent,
+		item.UnitPrice,
+		item.Weight,
+		item.Quantity,
+		model.PickStatusPicked,
+		itemId).Scan(&item.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	event := &model.PickEvent{
+		OrderId:          orderId,
+		Type:             model.PickEventSubstitution,
+		OrderItemId:      itemId,
+		SubstituteItemId: &item.Id,
+		OrderedQuantity:  ordered,
+		PickedQuantity:   quantity,
+		CreatedBy:        pickerId,
+	}
+
+	return p.finishPick(ctx, tx, event)
+}
+
+func (p *PickingRepository) FindEvents(ctx context.Context, orderId int64) ([]*model.PickEvent, error) {
+	rows, err := p.db.Query(ctx, findPickEvents, orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, scanPickEvent)
+}
+
+// Отказ покупателя обнуляет строку недовложения или замены и возвращает товар на склад
+func (p *PickingRepository) ResolveEvent(ctx context.Context, orderId, eventId int64, approve bool) (*model.PickEvent, error) {
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var status, orderStatus string
+	var itemId int64
+	var substituteItemId *int64
+
+	err = tx.QueryRow(ctx, lockPickEvent, eventId, orderId).Scan(&status, &itemId, &substituteItemId, &orderStatus)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrPickEventNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if status != model.PickDecisionPending || !model.IsPickAdjustableStatus(orderStatus) {
+		return nil, ErrPickEventClosed
+	}
+
+	decision := model.PickDecisionApproved
+	if !approve {
+		decision = model.PickDecisionRejected
+
+		if substituteItemId != nil {
+			itemId = *substituteItemId
+		}
+
+		if err = restockOrderItem(ctx, tx, itemId); err != nil {
+			return nil, err
+		}
+		if _, err = tx.Exec(ctx, clearPickLine, itemId); err != nil {
+			return nil, err
+		}
+		if _, err = tx.Exec(ctx, recalculateOrderTotals, orderId); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err = tx.Exec(ctx, resolvePickEvent, eventId, decision); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, findPickEvent, eventId)
+	if err != nil {
+		return nil, err
+	}
+
+	event, err := pgx.CollectExactlyOneRow(rows, scanPickEvent)
+	if err != nil {
+		return nil, err
+	}
+
+	return event, tx.Commit(ctx)
+}
+
+// Строка должна быть еще не собрана, а заказ - в сборке
+func (p *PickingRepository) lockLine(ctx context.Context, tx pgx.Tx, orderId, itemId int64) (int, uint, error) {
+	var quantity int
+	var catalogId uint
+
+	err := tx.QueryRow(ctx, lockPickLine, orderId, itemId, model.OrderStatusPicking, model.PickStatusPending).Scan(&quantity, &catalogId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, 0, ErrPickLineUnavailable
+	}
+
+	return quantity, catalogId, err
+}
+
+// Пересчитывает суммы заказа и сохраняет событие для покупателя
+func (p *PickingRepository) finishPick(ctx context.Context, tx pgx.Tx, event *model.PickEvent) (*model.PickEvent, error) {
+	if _, err := tx.Exec(ctx, recalculateOrderTotals, event.OrderId); err != nil {
+		return nil, err
+	}
+
+	err := tx.QueryRow(ctx, insertPickEvent,
+		event.OrderId,
+		event.Type,
+		event.OrderItemId,
+		event.SubstituteItemId,
+		event.OrderedQuantity,
+		event.PickedQuantity,
+		event.CreatedBy).Scan(&event.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, findPickEvent, event.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	event, err = pgx.CollectExactlyOneRow(rows, scanPickEvent)
+	if err != nil {
+		return nil, err
+	}
+
+	return event, tx.Commit(ctx)
+}
+
+func scanPickEvent(row pgx.CollectableRow) (*model.PickEvent, error) {
+	event := &model.PickEvent{}
+	err := row.Scan(
+		&event.Id,
+		&event.OrderId,
+		&event.Type,
+		&event.OrderItemId,
+		&event.ItemName,
+		&event.SubstituteItemId,
+		&event.SubstituteName,
+		&event.OrderedQuantity,
+		&event.PickedQuantity,
+		&event.Status,
+		&event.CreatedBy,
+		&event.CreatedAt,
+		&event.ResolvedAt,
+	)
+	return event, err
+}
+
+// Возвращает на склад текущее количество строки заказа
+func restockOrderItem(ctx context.Context, tx pgx.Tx, itemId int64) error {
+	var catalogId uint
+	var quantity int
+	if err := tx.QueryRow(ctx, findOrderItemStock, itemId).Scan(&catalogId, &quantity); err != nil {
+		return err
+	}
+
+	return restockCatalogItem(ctx, tx, catalogId, quantity)
+}
@@ -0,0 +1,362 @@
+package repository
+
+import (
+	"arabic/internal/dto"
+	"arabic/internal/model"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Возвращается при оформлении, если промокод не подходит к заказу или акция
+// исчерпала лимит использований, пока покупатель оформлял заказ
+type PromoError struct {
+	Promotion string
+	Reason    string
+}
+
+func (e *PromoError) Error() string {
+	return fmt.Sprintf("promotion %s cannot be applied: %s", e.Promotion, e.Reason)
+}
+
+type PromotionRepository struct {
+	db *pgxpool.Pool
+}
+
+type IPromotionRepository interface {
+	Create(ctx context.Context, promotion *model.Promotion) error
+	Update(ctx context.Context, promotion *model.Promotion) (bool, error)
+	Delete(ctx context.Context, id int64) (bool, error)
+	FindById(ctx context.Context, id int64) (*model.Promotion, bool, error)
+	FindAll(ctx context.Context) ([]*model.Promotion, error)
+	FindApplicable(ctx context.Context, code string, userId int64) ([]*model.Promotion, error)
+	FindPricingLines(ctx context.Context, items []dto.CartItemRequest) ([]*model.PricingLine, error)
+}
+
+func NewPromotionRepository(db *pgxpool.Pool) *PromotionRepository {
+	return &PromotionRepository{db: db}
+}
+
+var (
+	promotionColumns = `p.id, p.name, COALESCE(p.code, ''), p.type, p.value, p.buy_quantity, p.free_quantity, p.min_order_amount,
+		p.usage_limit, p.per_user_limit, p.used_count, p.starts_at, p.ends_at, p.is_active,
+		ARRAY(SELECT category_id FROM public.promotion_categories WHERE promotion_id = p.id ORDER BY category_id),
+		ARRAY(SELECT tag_id FROM public.promotion_tags WHERE promotion_id = p.id ORDER BY tag_id),
+		p.created_at`
+	insertPromotion = `
+		INSERT INTO public.promotions (name, code, type, value, buy_quantity, free_quantity, min_order_amount,
+		                               usage_limit, per_user_limit, starts_at, ends_at, is_active)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id, used_count, created_at`
+	updatePromotion = `
+		UPDATE public.promotions
+		SET name = $2, code = NULLIF($3, ''), type = $4, value = $5, buy_quantity = $6, free_quantity = $7,
+		    min_order_amount = $8, usage_limit = $9, per_user_limit = $10, starts_at = $11, ends_at = $12,
+		    is_active = $13, updated_at = NOW()
+		WHERE id = $1
+		RETURNING used_count, created_at`
+	deletePromotion           = "DELETE FROM public.promotions WHERE id = $1"
+	deletePromotionCategories = "DELETE FROM public.promotion_categories WHERE promotion_id = $1"
+	deletePromotionTags       = "DELETE FROM public.promotion_tags WHERE promotion_id = $1"
+	insertPromotionCategories = "INSERT INTO public.promotion_categories (promotion_id, category_id) SELECT $1, unnest($2::bigint[])"
+	insertPromotionTags       = "INSERT INTO public.promotion_tags (promotion_id, tag_id) SELECT $1, unnest($2::bigint[])"
+	findPromotionById         = "SELECT " + promotionColumns + " FROM public.promotions p WHERE p.id = $1"
+	findPromotions            = "SELECT " + promotionColumns + " FROM public.promotions p ORDER BY p.id DESC"
+	// Активные автоматические правила и акция с кодом code в любом состоянии,
+	// чтобы объяснить покупателю, почему промокод не подошел
+	findApplicablePromotions = `
+		SELECT ` + promotionColumns + `,
+		       (SELECT COUNT(*) FROM public.promotion_redemptions r WHERE r.promotion_id = p.id AND r.user_id = $2)
+		FROM public.promotions p
+		WHERE (p.code IS NULL AND p.is_active AND (p.starts_at IS NULL OR p.starts_at <= NOW()) AND (p.ends_at IS NULL OR p.ends_at > NOW()))
+		   OR p.code = upper($1)
+		ORDER BY p.id`
+	findPricingCatalogs = `
+		SELECT c.id, c.category_id, ARRAY(SELECT tag_id FROM public.catalog_tags WHERE catalog_id = c.id),
+		       c.name, c.price, c.discount_percent, c.weight
+		FROM public.catalogs c WHERE c.id = ANY($1)`
+	lockPromotion = `
+		SELECT name, COALESCE(code, ''), is_active, usage_limit, per_user_limit, used_count
+		FROM public.promotions WHERE id = $1 FOR UPDATE`
+	countUserRedemptions = "SELECT COUNT(*) FROM public.promotion_redemptions WHERE promotion_id = $1 AND user_id = $2"
+	insertRedemption     = `
+		INSERT INTO public.promotion_redemptions (promotion_id, order_id, user_id, discount)
+		VALUES ($1, $2, $3, $4)`
+	incrementPromotionUsage = "UPDATE public.promotions SET used_count = used_count + 1 WHERE id = $1"
+	releasePromotionUsage   = `
+		UPDATE public.promotions p SET used_count = GREATEST(p.used_count - 1, 0)
+		FROM public.promotion_redemptions r
+		WHERE r.order_id = $1 AND r.promotion_id = p.id`
+	deleteOrderRedemptions = "DELETE FROM public.promotion_redemptions WHERE order_id = $1"
+)
+
+func (p *PromotionRepository) Create(ctx context.Context, promotion *model.Promotion) error {
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, insertPromotion,
+		promotion.Name,
+		promotion.Code,
+		promotion.Type,
+		promotion.Value,
+		promotion.BuyQuantity,
+		promotion.FreeQuantity,
+		promotion.MinOrderAmount,
+		promotion.UsageLimit,
+		promotion.PerUserLimit,
+		promotion.StartsAt,
+		promotion.EndsAt,
+		promotion.IsActive).Scan(&promotion.Id, &promotion.UsedCount, &promotion.CreatedAt)
+
+	if err != nil {
+		return err
+	}
+
+	if err = savePromotionRestrictions(ctx, tx, promotion); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Ограничения по категориям и тегам заменяются целиком
+func (p *PromotionRepository) Update(ctx context.Context, promotion *model.Promotion) (bool, error) {
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, updatePromotion,
+		promotion.Id,
+		promotion.Name,
+		promotion.Code,
+		promotion.Type,
+		promotion.Value,
+		promotion.BuyQuantity,
+		promotion.FreeQuantity,
+		promotion.MinOrderAmount,
+		promotion.UsageLimit,
+		promotion.PerUserLimit,
+		promotion.StartsAt,
+		promotion.EndsAt,
+		promotion.IsActive).Scan(&promotion.UsedCount, &promotion.CreatedAt)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if _, err = tx.Exec(ctx, deletePromotionCategories, promotion.Id); err != nil {
+		return false, err
+	}
+	if _, err = tx.Exec(ctx, deletePromotionTags, promotion.Id); err != nil {
+		return false, err
+	}
+
+	if err = savePromotionRestrictions(ctx, tx, promotion); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+// Акцию, которая уже применялась в заказах, удалить нельзя - вернется ошибка внешнего ключа
+func (p *PromotionRepository) Delete(ctx context.Context, id int64) (bool, error) {
+	tag, err := p.db.Exec(ctx, deletePromotion, id)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+func (p *PromotionRepository) FindById(ctx context.Context, id int64) (*model.Promotion, bool, error) {
+	promotion := &model.Promotion{}
+	err := p.db.QueryRow(ctx, findPromotionById, id).Scan(promotionFields(promotion)...)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	return promotion, true, nil
+}
+
+func (p *PromotionRepository) FindAll(ctx context.Context) ([]*model.Promotion, error) {
+	rows, err := p.db.Query(ctx, findPromotions)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (*model.Promotion, error) {
+		promotion := &model.Promotion{}
+		return promotion, row.Scan(promotionFields(promotion)...)
+	})
+}
+
+// Акции для расчета цены покупателя userId (0 - гость) с числом его использований
+func (p *PromotionRepository) FindApplicable(ctx context.Context, code string, userId int64) ([]*model.Promotion, error) {
+	rows, err := p.db.Query(ctx, findApplicablePromotions, code, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (*model.Promotion, error) {
+		promotion := &model.Promotion{}
+		return promotion, row.Scan(append(promotionFields(promotion), &promotion.UserUsedCount)...)
+	})
+}
+
+// Позиции для расчета цены по текущим ценам каталога. Удаленные из каталога товары пропускаются
+func (p *PromotionRepository) FindPricingLines(ctx context.Context, items []dto.CartItemRequest) ([]*model.PricingLine, error) {
+	ids := make([]uint, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.CatalogId)
+	}
+
+	rows, err := p.db.Query(ctx, findPricingCatalogs, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	catalogs, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*model.PricingLine, error) {
+		line := &model.PricingLine{}
+		return line, row.Scan(&line.CatalogId, &line.CategoryId, &line.TagIds, &line.Name, &line.Price, &line.DiscountPercent, &line.Weight)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	byId := make(map[uint]*model.PricingLine, len(catalogs))
+	for _, line := range catalogs {
+		byId[line.CatalogId] = line
+	}
+
+	// Сохраняем порядок позиций запроса
+	lines := make([]*model.PricingLine, 0, len(items))
+	for _, item := range items {
+		catalog, ok := byId[item.CatalogId]
+		if !ok {
+			continue
+		}
+
+		line := *catalog
+		line.Quantity = item.Quantity
+		lines = append(lines, &line)
+	}
+
+	return lines, nil
+}
+
+func savePromotionRestrictions(ctx context.Context, tx pgx.Tx, promotion *model.Promotion) error {
+	if len(promotion.CategoryIds) > 0 {
+		if _, err := tx.Exec(ctx, insertPromotionCategories, promotion.Id, promotion.CategoryIds); err != nil {
+			return err
+		}
+	}
+
+	if len(promotion.TagIds) > 0 {
+		if _, err := tx.Exec(ctx, insertPromotionTags, promotion.Id, promotion.TagIds); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Фиксирует примененные в заказе акции. Лимиты перепроверяются под блокировкой строки акции:
+// расчет цены шел до транзакции, и параллельные заказы могли исчерпать лимит
+func redeemPromotions(ctx context.Context, tx pgx.Tx, order *model.Order) error {
+	discounts := slices.Clone(order.Pricing.Discounts)
+
+	// Блокируем акции всегда в одном порядке, чтобы параллельные оформления не уходили в deadlock
+	slices.SortFunc(discounts, func(a, b *model.AppliedDiscount) int {
+		return cmp.Compare(a.PromotionId, b.PromotionId)
+	})
+
+	for _, discount := range discounts {
+		promotion := &model.Promotion{Id: discount.PromotionId}
+		err := tx.QueryRow(ctx, lockPromotion, discount.PromotionId).
+			Scan(&promotion.Name, &promotion.Code, &promotion.IsActive, &promotion.UsageLimit, &promotion.PerUserLimit, &promotion.UsedCount)
+
+		if errors.Is(err, pgx.ErrNoRows) {
+			return &PromoError{Promotion: discount.Name, Reason: "it no longer exists"}
+		}
+		if err != nil {
+			return err
+		}
+
+		if promotion.PerUserLimit != nil {
+			if err = tx.QueryRow(ctx, countUserRedemptions, promotion.Id, order.UserId).Scan(&promotion.UserUsedCount); err != nil {
+				return err
+			}
+		}
+
+		// Окно действия уже проверено при расчете цены, здесь важны только флаг и лимиты
+		promotion.StartsAt, promotion.EndsAt = nil, nil
+		if reason := promotion.Unavailable(order.CreatedAt); reason != "" {
+			return &PromoError{Promotion: promotionLabel(promotion), Reason: reason}
+		}
+
+		if _, err = tx.Exec(ctx, insertRedemption, promotion.Id, order.Id, order.UserId, discount.Amount); err != nil {
+			return err
+		}
+		if _, err = tx.Exec(ctx, incrementPromotionUsage, promotion.Id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Отмена заказа возвращает использования акций
+func releasePromotions(ctx context.Context, tx pgx.Tx, orderId int64) error {
+	if _, err := tx.Exec(ctx, releasePromotionUsage, orderId); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(ctx, deleteOrderRedemptions, orderId)
+	return err
+}
+
+func promotionLabel(promotion *model.Promotion) string {
+	if promotion.Code != "" {
+		return promotion.Code
+	}
+	return promotion.Name
+}
+
+func promotionFields(promotion *model.Promotion) []any {
+	return []any{
+		&promotion.Id,
+		&promotion.Name,
+		&promotion.Code,
+		&promotion.Type,
+		&promotion.Value,
+		&promotion.BuyQuantity,
+		&promotion.FreeQuantity,
+		&promotion.MinOrderAmount,
+		&promotion.UsageLimit,
+		&promotion.PerUserLimit,
+		&promotion.UsedCount,
+		&promotion.StartsAt,
+		&promotion.EndsAt,
+		&promotion.IsActive,
+		&promotion.CategoryIds,
+		&promotion.TagIds,
+		&promotion.CreatedAt,
+	}
+}
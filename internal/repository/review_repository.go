@@ -0,0 +1,224 @@
+package repository
+
+import (
+	"arabic/internal/model"
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ReviewRepository struct {
+	db *pgxpool.Pool
+}
+
+type IReviewRepository interface {
+	Create(ctx context.Context, review *model.Review) error
+	IsEligible(ctx context.Context, userId int64, catalogId uint) (bool, error)
+	FindById(ctx context.Context, id int64) (*model.Review, bool, error)
+	FindApproved(ctx context.Context, catalogId uint, limit, offset int) ([]*model.Review, error)
+	FindPending(ctx context.Context, limit int) ([]*model.Review, error)
+	Moderate(ctx context.Context, review *model.Review, moderatorId int64) (bool, error)
+	Delete(ctx context.Context, id, userId int64) ([]string, bool, error)
+}
+
+func NewReviewRepository(db *pgxpool.Pool) *ReviewRepository {
+	return &ReviewRepository{db: db}
+}
+
+var (
+	reviewColumns = `r.id, r.catalog_id, r.user_id, u.username, r.score, r.text, r.status,
+		ARRAY(SELECT image_url FROM public.review_photos WHERE review_id = r.id ORDER BY position),
+		r.moderation_comment, r.moderated_at, r.created_at`
+	insertReview = `
+		INSERT INTO public.reviews (catalog_id, user_id, score, text)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, status, created_at`
+	insertReviewPhoto = "INSERT INTO public.review_photos (review_id, position, image_url) VALUES ($1, $2, $3)"
+	// Отзыв можно оставить только на товар из доставленного заказа, который покупатель получил
+	findReviewEligibility = `
+		SELECT EXISTS (
+			SELECT 1 FROM public.orders o
+			JOIN public.order_items oi ON oi.order_id = o.id
+			WHERE o.user_id = $1 AND oi.catalog_id = $2 AND o.status = 'delivered' AND oi.quantity > 0)`
+	findReviewById      = "SELECT " + reviewColumns + " FROM public.reviews r JOIN public.users u ON u.id = r.user_id WHERE r.id = $1"
+	findApprovedReviews = "SELECT " + reviewColumns + " FROM public.reviews r JOIN public.users u ON u.id = r.user_id WHERE r.catalog_id = $1 AND r.status = 'approved' ORDER BY r.id DESC LIMIT $2 OFFSET $3"
+	findPendingReviews  = "SELECT " + reviewColumns + " FROM public.reviews r JOIN public.users u ON u.id = r.user_id WHERE r.status = 'pending' ORDER BY r.id LIMIT $1"
+	moderateReview      = `
+		UPDATE public.reviews SET status = $2, moderation_comment = $3, moderated_by = $4, moderated_at = NOW()
+		WHERE id = $1 AND status = 'pending'
+		RETURNING catalog_id, moderated_at`
+	findReviewPhotos = `
+		SELECT p.image_url FROM public.review_photos p
+		JOIN public.reviews r ON r.id = p.review_id
+		WHERE r.id = $1 AND r.user_id = $2
+		ORDER BY p.position`
+	deleteReview = "DELETE FROM public.reviews WHERE id = $1 AND user_id = $2 RETURNING catalog_id"
+	// Параллельная модерация отзывов одного товара ждет на блокировке строки каталога,
+	// а пересчет идет отдельным запросом и видит уже закоммиченные изменения
+	lockCatalogRating = "SELECT id FROM public.catalogs WHERE id = $1 FOR UPDATE"
+	recalculateRating = `
+		UPDATE public.catalogs c
+		SET rating = COALESCE(r.rating, 0), reviews_count = r.count
+		FROM (SELECT ROUND(AVG(score), 2) AS rating, COUNT(*) AS count
+		      FROM public.reviews WHERE catalog_id = $1 AND status = 'approved') r
+		WHERE c.id = $1`
+)
+
+func (r *ReviewRepository) Create(ctx context.Context, review *model.Review) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, insertReview, review.CatalogId, review.UserId, review.Score, review.Text).
+		Scan(&review.Id, &review.Status, &review.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	for i, photo := range review.Photos {
+		if _, err = tx.Exec(ctx, insertReviewPhoto, review.Id, i, photo); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *ReviewRepository) IsEligible(ctx context.Context, userId int64, catalogId uint) (bool, error) {
+	var eligible bool
+	err := r.db.QueryRow(ctx, findReviewEligibility, userId, catalogId).Scan(&eligible)
+	return eligible, err
+}
+
+func (r *ReviewRepository) FindById(ctx context.Context, id int64) (*model.Review, bool, error) {
+	review := &model.Review{}
+	err := r.db.QueryRow(ctx, findReviewById, id).Scan(reviewFields(review)...)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	return review, true, nil
+}
+
+func (r *ReviewRepository) FindApproved(ctx context.Context, catalogId uint, limit, offset int) ([]*model.Review, error) {
+	rows, err := r.db.Query(ctx, findApprovedReviews, catalogId, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, scanReview)
+}
+
+// Очередь модерации, старые отзывы первыми
+func (r *ReviewRepository) FindPending(ctx context.Context, limit int) ([]*model.Review, error) {
+	rows, err := r.db.Query(ctx, findPendingReviews, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, scanReview)
+}
+
+// Одобряет или отклоняет отзыв из очереди и пересчитывает рейтинг товара в той же транзакции.
+// false - отзыва нет или он уже промодерирован
+func (r *ReviewRepository) Moderate(ctx context.Context, review *model.Review, moderatorId int64) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, moderateReview, review.Id, review.Status, review.ModerationComment, moderatorId).
+		Scan(&review.CatalogId, &review.ModeratedAt)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if err = recalculateCatalogRating(ctx, tx, review.CatalogId); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+// Удаляет отзыв автора и пересчитывает рейтинг товара. Возвращает файлы фотографий,
+// строки которых удалены вместе с отзывом
+func (r *ReviewRepository) Delete(ctx context.Context, id, userId int64) ([]string, bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, findReviewPhotos, id, userId)
+	if err != nil {
+		return nil, false, err
+	}
+
+	photos, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, false, err
+	}
+
+	var catalogId uint
+	err = tx.QueryRow(ctx, deleteReview, id, userId).Scan(&catalogId)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err = recalculateCatalogRating(ctx, tx, catalogId); err != nil {
+		return nil, false, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, false, err
+	}
+
+	return photos, true, nil
+}
+
+func recalculateCatalogRating(ctx context.Context, tx pgx.Tx, catalogId uint) error {
+	if _, err := tx.Exec(ctx, lockCatalogRating, catalogId); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(ctx, recalculateRating, catalogId)
+	return err
+}
+
+func scanReview(row pgx.CollectableRow) (*model.Review, error) {
+	review := &model.Review{}
+	return review, row.Scan(reviewFields(review)...)
+}
+
+func reviewFields(review *model.Review) []any {
+	return []any{
+		&review.Id,
+		&review.CatalogId,
+		&review.UserId,
+		&review.Username,
+		&review.Score,
+		&review.Text,
+		&review.Status,
+		&review.Photos,
+		&review.ModerationComment,
+		&review.ModeratedAt,
+		&review.CreatedAt,
+	}
+}
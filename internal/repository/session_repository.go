@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"arabic/internal/model"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	// Предъявлен уже ротированный токен - вероятна кража, сессия отозвана целиком
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+	ErrSessionExpired     = errors.New("session expired or revoked")
+)
+
+type SessionRepository struct {
+	db *pgxpool.Pool
+}
+
+type ISessionRepository interface {
+	Create(ctx context.Context, session *model.UserSession, tokenHash string) error
+	Rotate(ctx context.Context, tokenHash, newTokenHash string, expiresAt time.Time) (*model.UserSession, error)
+	RevokeByToken(ctx context.Context, tokenHash string) error
+	RevokeAll(ctx context.Context, userId int64) error
+	IsSessionActive(ctx context.Context, sessionId string) (bool, error)
+}
+
+func NewSessionRepository(db *pgxpool.Pool) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+var (
+	insertSession = `
+		INSERT INTO public.user_sessions (id, user_id, user_agent, ip, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING last_used_at, created_at`
+	insertRefreshToken     = "INSERT INTO public.user_refresh_tokens (token_hash, session_id) VALUES ($1, $2)"
+	findSessionByTokenHash = `
+		SELECT t.used_at, s.id, s.user_id, s.user_agent, s.ip, s.expires_at, s.revoked_at, s.last_used_at, s.created_at
+		FROM public.user_refresh_tokens t
+		JOIN public.user_sessions s ON s.id = t.session_id
+		WHERE t.token_hash = $1
+		FOR UPDATE OF t, s`
+	markRefreshTokenUsed = "UPDATE public.user_refresh_tokens SET used_at = NOW() WHERE token_hash = $1"
+	prolongSession       = "UPDATE public.user_sessions SET expires_at = $2, last_used_at = NOW() WHERE id = $1 RETURNING last_used_at"
+	revokeSession        = "UPDATE public.user_sessions SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL"
+	revokeSessionByToken = `
+		UPDATE public.user_sessions SET revoked_at = NOW()
+		WHERE revoked_at IS NULL AND id = (SELECT session_id FROM public.user_refresh_tokens WHERE token_hash = $1)`
+	revokeUserSessions = "UPDATE public.user_sessions SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL"
+	isSessionActive    = "SELECT EXISTS (SELECT 1 FROM public.user_sessions WHERE id = $1 AND revoked_at IS NULL AND expires_at > NOW())"
+)
+
+// Создает сессию вместе с первым refresh токеном
+func (s *SessionRepository) Create(ctx context.Context, session *model.UserSession, tokenHash string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, insertSession, session.Id, session.UserId, session.UserAgent, session.Ip, session.ExpiresAt).
+		Scan(&session.LastUsedAt, &session.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec(ctx, insertRefreshToken, tokenHash, session.Id); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Меняет refresh токен на новый и продлевает сессию до expiresAt.
+// Повторное предъявление старого токена отзывает сессию и возвращает ErrRefreshTokenReused
+func (s *SessionRepository) Rotate(ctx context.Context, tokenHash, newTokenHash string, expiresAt time.Time) (*model.UserSession, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var usedAt *time.Time
+	session := &model.UserSession{}
+	err = tx.QueryRow(ctx, findSessionByTokenHash, tokenHash).Scan(
+		&usedAt,
+		&session.Id,
+		&session.UserId,
+		&session.UserAgent,
+		&session.Ip,
+		&session.ExpiresAt,
+		&session.RevokedAt,
+		&session.LastUsedAt,
+		&session.CreatedAt,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrRefreshTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if session.RevokedAt != nil || !session.ExpiresAt.After(time.Now()) {
+		return nil, ErrSessionExpired
+	}
+
+	if usedAt != nil {
+		if _, err = tx.Exec(ctx, revokeSession, session.Id); err != nil {
+			return nil, err
+		}
+		if err = tx.Commit(ctx); err != nil {
+			return nil, err
+		}
+		return session, ErrRefreshTokenReused
+	}
+
+	if _, err = tx.Exec(ctx, markRefreshTokenUsed, tokenHash); err != nil {
+		return nil, err
+	}
+
+	if _, err = tx.Exec(ctx, insertRefreshToken, newTokenHash, session.Id); err != nil {
+		return nil, err
+	}
+
+	if err = tx.QueryRow(ctx, prolongSession, session.Id, expiresAt).Scan(&session.LastUsedAt); err != nil {
+		return nil, err
+	}
+	session.ExpiresAt = expiresAt
+
+	return session, tx.Commit(ctx)
+}
+
+func (s *SessionRepository) RevokeByToken(ctx context.Context, tokenHash string) error {
+	_, err := s.db.Exec(ctx, revokeSessionByToken, tokenHash)
+	return err
+}
+
+func (s *SessionRepository) RevokeAll(ctx context.Context, userId int64) error {
+	_, err := s.db.Exec(ctx, revokeUserSessions, userId)
+	return err
+}
+
+func (s *SessionRepository) IsSessionActive(ctx context.Context, sessionId string) (bool, error) {
+	var active bool
+	err := s.db.QueryRow(ctx, isSessionActive, sessionId).Scan(&active)
+	return active, err
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"arabic/internal/model"
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SubscriptionRepository struct {
+	db *pgxpool.Pool
+}
+
+type ISubscriptionRepository interface {
+	Subscribe(ctx context.Context, userId int64, catalogId uint, subscriptionType string) error
+	Unsubscribe(ctx context.Context, userId int64, catalogId uint, subscriptionType string) (bool, error)
+	FindByUser(ctx context.Context, userId int64) ([]*model.CatalogSubscription, error)
+}
+
+func NewSubscriptionRepository(db *pgxpool.Pool) *SubscriptionRepository {
+	return &SubscriptionRepository{db: db}
+}
+
+var (
+	// Повторная подписка не ошибка
+	insertSubscription = `
+		INSERT INTO public.catalog_subscriptions (user_id, catalog_id, type) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, catalog_id, type) DO NOTHING`
+	deleteSubscription    = "DELETE FROM public.catalog_subscriptions WHERE user_id = $1 AND catalog_id = $2 AND type = $3"
+	findUserSubscriptions = `
+		SELECT s.catalog_id, c.name, s.type, s.created_at
+		FROM public.catalog_subscriptions s
+		JOIN public.catalogs c ON c.id = s.catalog_id
+		WHERE s.user_id = $1
+		ORDER BY s.created_at DESC`
+)
+
+func (s *SubscriptionRepository) Subscribe(ctx context.Context, userId int64, catalogId uint, subscriptionType string) error {
+	_, err := s.db.Exec(ctx, insertSubscription, userId, catalogId, subscriptionType)
+	return err
+}
+
+func (s *SubscriptionRepository) Unsubscribe(ctx context.Context, userId int64, catalogId uint, subscriptionType string) (bool, error) {
+	tag, err := s.db.Exec(ctx, deleteSubscription, userId, catalogId, subscriptionType)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() != 0, nil
+}
+
+func (s *SubscriptionRepository) FindByUser(ctx context.Context, userId int64) ([]*model.CatalogSubscription, error) {
+	rows, err := s.db.Query(ctx, findUserSubscriptions, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (*model.CatalogSubscription, error) {
+		subscription := &model.CatalogSubscription{}
+		err := row.Scan(&subscription.CatalogId, &subscription.CatalogName, &subscription.Type, &subscription.CreatedAt)
+		return subscription, err
+	})
+}
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"arabic/internal/model"
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type TwoFactorRepository struct {
+	db *pgxpool.Pool
+}
+
+type ITwoFactorRepository interface {
+	Find(ctx context.Context, userId int64) (*model.TwoFactor, bool, error)
+	SaveSecret(ctx context.Context, userId int64, secret string) (bool, error)
+	Enable(ctx context.Context, userId, step int64, codeHashes []string) (bool, error)
+	UseStep(ctx context.Context, userId, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userId int64, codeHash string) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, userId int64, codeHashes []string) error
+	Disable(ctx context.Context, userId int64) error
+}
+
+func NewTwoFactorRepository(db *pgxpool.Pool) *TwoFactorRepository {
+	return &TwoFactorRepository{db: db}
+}
+
+var (
+	findTwoFactor = "SELECT user_id, secret, enabled_at, last_step FROM public.user_two_factor WHERE user_id = $1"
+	// Неподтвержденный секрет можно перевыпустить, подключенный - только после отключения
+	upsertTwoFactorSecret = `
+		INSERT INTO public.user_two_factor (user_id, secret) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, last_step = 0, created_at = NOW()
+		WHERE user_two_factor.enabled_at IS NULL`
+	enableTwoFactor = `
+		UPDATE public.user_two_factor SET enabled_at = NOW(), last_step = $2
+		WHERE user_id = $1 AND enabled_at IS NULL AND last_step < $2`
+	// Условие на last_step делает проверку атомарной: из двух одновременных запросов
+	// с одним кодом пройдет только один
+	useTwoFactorStep    = "UPDATE public.user_two_factor SET last_step = $2 WHERE user_id = $1 AND last_step < $2"
+	useRecoveryCode     = "UPDATE public.user_recovery_codes SET used_at = NOW() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL"
+	deleteRecoveryCodes = "DELETE FROM public.user_recovery_codes WHERE user_id = $1"
+	insertRecoveryCode  = "INSERT INTO public.user_recovery_codes (user_id, code_hash) VALUES ($1, $2)"
+	deleteTwoFactor     = "DELETE FROM public.user_two_factor WHERE user_id = $1"
+)
+
+func (t *TwoFactorRepository) Find(ctx context.Context, userId int64) (*model.TwoFactor, bool, error) {
+	twoFactor := &model.TwoFactor{}
+	err := t.db.QueryRow(ctx, findTwoFactor, userId).Scan(&twoFactor.UserId, &twoFactor.Secret, &twoFactor.EnabledAt, &twoFactor.LastStep)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	return twoFactor, true, nil
+}
+
+// false - второй фактор уже подключен
+func (t *TwoFactorRepository) SaveSecret(ctx context.Context, userId int64, secret string) (bool, error) {
+	tag, err := t.db.Exec(ctx, upsertTwoFactorSecret, userId, secret)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() != 0, nil
+}
+
+// Подтверждает подключение и заменяет коды восстановления.
+// false - уже подключен или код этого шага уже использован
+func (t *TwoFactorRepository) Enable(ctx context.Context, userId, step int64, codeHashes []string) (bool, error) {
+	tx, err := t.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, enableTwoFactor, userId, step)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if err = replaceRecoveryCodes(ctx, tx, userId, codeHashes); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+// false - код этого или более позднего шага уже принимался
+func (t *TwoFactorRepository) UseStep(ctx context.Context, userId, step int64) (bool, error) {
+	tag, err := t.db.Exec(ctx, useTwoFactorStep, userId, step)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() != 0, nil
+}
+
+// false - кода нет или он уже использован
+func (t *TwoFactorRepository) UseRecoveryCode(ctx context.Context, userId int64, codeHash string) (bool, error) {
+	tag, err := t.db.Exec(ctx, useRecoveryCode, userId, codeHash)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() != 0, nil
+}
+
+func (t *TwoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userId int64, codeHashes []string) error {
+	tx, err := t.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err = replaceRecoveryCodes(ctx, tx, userId, codeHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (t *TwoFactorRepository) Disable(ctx context.Context, userId int64) error {
+	tx, err := t.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err = tx.Exec(ctx, deleteRecoveryCodes, userId); err != nil {
+		return err
+	}
+	if _, err = tx.Exec(ctx, deleteTwoFactor, userId); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Прежние коды, в том числе неиспользованные, перестают действовать
+func replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userId int64, codeHashes []string) error {
+	if _, err := tx.Exec(ctx, deleteRecoveryCodes, userId); err != nil {
+		return err
+	}
+
+	for _, hash := range codeHashes {
+		if _, err := tx.Exec(ctx, insertRecoveryCode, userId, hash); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -20,26 +20,33 @@ type IUserRepository interface {
 	Create(cxt context.Context, u *model.User) error
 	FindByEmail(cxt context.Context, email string) (*model.UserFullInfo, error)
 	Update(ctx context.Context, query string, values []any) (bool, error)
+	FindById(cxt context.Context, id int64) (*model.UserFullInfo, error)
 }
 
 var (
-	insertUser        = "INSERT INTO public.users (email, username, password) VALUES ($1, $2, $3) RETURNING id"
-	searchUserByEmail = "SELECT id, username, password, email, role_code, first_name, second_name, phone_number, apartment, house, street, city, region   FROM public.users WHERE email = $1"
+	insertUser        = "INSERT INTO public.users (email, username, password, language) VALUES ($1, $2, $3, $4) RETURNING id"
+	searchUserByEmail = "SELECT id, username, password, email, role_code, language, email_verified_at, first_name, second_name, phone_number, apartment, house, street, city, region, latitude, longitude FROM public.users WHERE email = $1"
+	searchUserById    = "SELECT id, username, password, email, role_code, language, email_verified_at, first_name, second_name, phone_number, apartment, house, street, city, COALESCE(region, ''), latitude, longitude FROM public.users WHERE id = $1"
 )
 
 func (ur *UserRepository) Create(cxt context.Context, u *model.User) error {
-	user := model.User{}
-	err := ur.db.QueryRow(cxt, insertUser, u.Email, u.Username, u.Password).Scan(&user.Id)
+	return ur.db.QueryRow(cxt, insertUser, u.Email, u.Username, u.Password, u.Language).Scan(&u.Id)
+}
+
+func (ur *UserRepository) FindByEmail(cxt context.Context, email string) (*model.UserFullInfo, error) {
+	u := model.UserFullInfo{}
+	err := ur.db.QueryRow(cxt, searchUserByEmail, email).Scan(&u.Id, &u.Username, &u.Password, &u.Email, &u.RoleCode, &u.Language, &u.EmailVerifiedAt, &u.FirstName, &u.SecondName, &u.PhoneNumber, &u.Apartment, &u.House, &u.Street, &u.City, &u.Region, &u.Latitude, &u.Longitude)
+
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return &u, nil
 }
 
-func (ur *UserRepository) FindByEmail(cxt context.Context, email string) (*model.UserFullInfo, error) {
+func (ur *UserRepository) FindById(cxt context.Context, id int64) (*model.UserFullInfo, error) {
 	u := model.UserFullInfo{}
-	err := ur.db.QueryRow(cxt, searchUserByEmail, email).Scan(&u.Id, &u.Username, &u.Password, &u.Email, &u.RoleCode, &u.FirstName, &u.SecondName, &u.PhoneNumber, &u.Apartment, &u.House, &u.Street, &u.City, &u.Region)
+	err := ur.db.QueryRow(cxt, searchUserById, id).Scan(&u.Id, &u.Username, &u.Password, &u.Email, &u.RoleCode, &u.Language, &u.EmailVerifiedAt, &u.FirstName, &u.SecondName, &u.PhoneNumber, &u.Apartment, &u.House, &u.Street, &u.City, &u.Region, &u.Latitude, &u.Longitude)
 
 	if err != nil {
 		return nil, err
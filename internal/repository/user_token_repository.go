@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"arabic/internal/model"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type UserTokenRepository struct {
+	db *pgxpool.Pool
+}
+
+type IUserTokenRepository interface {
+	Create(ctx context.Context, userId int64, purpose, tokenHash string, expiresAt time.Time) error
+	VerifyEmail(ctx context.Context, tokenHash string) (bool, error)
+	ResetPassword(ctx context.Context, tokenHash, passwordHash string) (bool, error)
+	FindUser(ctx context.Context, tokenHash, purpose string) (int64, bool, error)
+	Consume(ctx context.Context, tokenHash, purpose string) (bool, error)
+}
+
+func NewUserTokenRepository(db *pgxpool.Pool) *UserTokenRepository {
+	return &UserTokenRepository{db: db}
+}
+
+var (
+	// Новый токен отменяет прежние неиспользованные того же назначения
+	expireUserTokens = "UPDATE public.user_tokens SET used_at = NOW() WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL"
+	insertUserToken  = "INSERT INTO public.user_tokens (token_hash, user_id, purpose, expires_at) VALUES ($1, $2, $3, $4)"
+	consumeUserToken = `
+		UPDATE public.user_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id`
+	findTokenUser = `
+		SELECT user_id FROM public.user_tokens
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()`
+	markEmailVerified  = "UPDATE public.users SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW() WHERE id = $1"
+	updateUserPassword = "UPDATE public.users SET password = $2, updated_at = NOW() WHERE id = $1"
+)
+
+func (u *UserTokenRepository) Create(ctx context.Context, userId int64, purpose, tokenHash string, expiresAt time.Time) error {
+	tx, err := u.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err = tx.Exec(ctx, expireUserTokens, userId, purpose); err != nil {
+		return err
+	}
+	if _, err = tx.Exec(ctx, insertUserToken, tokenHash, userId, purpose, expiresAt); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// false - токен не найден, уже использован или истек
+func (u *UserTokenRepository) VerifyEmail(ctx context.Context, tokenHash string) (bool, error) {
+	tx, err := u.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	userId, ok, err := consumeToken(ctx, tx, tokenHash, model.TokenVerifyEmail)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	if _, err = tx.Exec(ctx, markEmailVerified, userId); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+// Меняет пароль и отзывает все сессии пользователя. Письмо со ссылкой доказывает
+// владение адресом, поэтому email заодно считается подтвержденным
+func (u *UserTokenRepository) ResetPassword(ctx context.Context, tokenHash, passwordHash string) (bool, error) {
+	tx, err := u.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	userId, ok, err := consumeToken(ctx, tx, tokenHash, model.TokenPasswordReset)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	if _, err = tx.Exec(ctx, updateUserPassword, userId, passwordHash); err != nil {
+		return false, err
+	}
+	if _, err = tx.Exec(ctx, markEmailVerified, userId); err != nil {
+		return false, err
+	}
+	if _, err = tx.Exec(ctx, revokeUserSessions, userId); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+// Владелец действующего токена. Токен при этом не расходуется: после неверного
+// кода второго фактора его можно ввести еще раз
+func (u *UserTokenRepository) FindUser(ctx context.Context, tokenHash, purpose string) (int64, bool, error) {
+	var userId int64
+	err := u.db.QueryRow(ctx, findTokenUser, tokenHash, purpose).Scan(&userId)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	return userId, true, nil
+}
+
+// false - токен уже использован или истек
+func (u *UserTokenRepository) Consume(ctx context.Context, tokenHash, purpose string) (bool, error) {
+	var userId int64
+	err := u.db.QueryRow(ctx, consumeUserToken, tokenHash, purpose).Scan(&userId)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func consumeToken(ctx context.Context, tx pgx.Tx, tokenHash, purpose string) (int64, bool, error) {
+	var userId int64
+	err := tx.QueryRow(ctx, consumeUserToken, tokenHash, purpose).Scan(&userId)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	return userId, true, nil
+}
@@ -2,10 +2,17 @@ package builders
 
 import (
 	"arabic/internal/handlers"
+	"arabic/internal/model"
 	"arabic/internal/service"
 	"arabic/internal/store"
+	"arabic/pkg/events"
 	"arabic/pkg/fs"
+	"arabic/pkg/loyalty"
+	"arabic/pkg/payment"
+	"arabic/pkg/routing"
 	"arabic/pkg/security/auth"
+	"arabic/pkg/security/throttle"
+	"arabic/pkg/security/totp"
 	"fmt"
 	"net/http"
 
@@ -21,50 +28,233 @@ type Builder struct {
 	Store     *store.Store
 	JwtConfig *security.JWTConfig
 	Fs        *fs.FS
+	Dispatch  *routing.Config
+	Events    *events.Config
+	Hub       *events.Hub
+	Payment   *payment.Config
+	Provider  payment.PaymentProvider
+	Loyalty   *loyalty.Config
+	Account   *security.AccountConfig
+	Cookie    *security.CookieConfig
+	Limiter   *throttle.Limiter
+	TwoFactor *totp.Config
+	Cipher    *totp.Cipher
 }
 
 func BuildRoutes(b *Builder) {
+	// Открытые ключи для проверки access токенов другими сервисами
+	b.Router.HandleFunc("/.well-known/jwks.json", security.JWKSHandler(b.JwtConfig)).Methods("GET")
+
+	//Cart
+	cartService := service.NewCartService(b.Store.CartRepository(), b.Store.CatalogRepository())
+	cartHandler := handlers.NewCartHandler(cartService, b.Fs.Image)
+	b.Router.HandleFunc(url+"/cart/guest", cartHandler.Get(handlers.GuestCartOwner)).Methods("GET")
+	b.Router.HandleFunc(url+"/cart/guest", cartHandler.Clear(handlers.GuestCartOwner)).Methods("DELETE")
+	b.Router.HandleFunc(url+"/cart/guest/items", cartHandler.AddItem(handlers.GuestCartOwner)).Methods("POST")
+	b.Router.HandleFunc(url+"/cart/guest/items", cartHandler.UpdateItem(handlers.GuestCartOwner)).Methods("PATCH")
+	b.Router.HandleFunc(url+"/cart/guest/items/{catalogId}", cartHandler.RemoveItem(handlers.GuestCartOwner)).Methods("DELETE")
+
 	//User
-	userService := service.NewUserService(b.Store.UserRepository(), b.JwtConfig)
-	userHandler := handlers.NewUserHandler(userService)
+	twoFactorService := service.NewTwoFactorService(b.Store.TwoFactorRepository(), b.Cipher, b.TwoFactor)
+	userService := service.NewUserService(b.Store.UserRepository(), b.Store.SessionRepository(), b.Store.UserTokenRepository(), b.Store.NotificationRepository(), b.Store.LoginAuditRepository(), twoFactorService, b.Limiter, b.JwtConfig, b.Account, b.TwoFactor)
+	userHandler := handlers.NewUserHandler(userService, cartService, b.Cookie)
 	b.Router.HandleFunc(url+"/user/register", userHandler.Create()).Methods("POST")
 	b.Router.HandleFunc(url+"/user/login", userHandler.Login()).Methods("POST")
+	b.Router.HandleFunc(url+"/user/login/2fa", userHandler.LoginTwoFactor).Methods("POST")
+	b.Router.HandleFunc(url+"/user/login/2fa/setup", userHandler.SetupTwoFactorLogin).Methods("POST")
+	b.Router.HandleFunc(url+"/user/refresh", userHandler.Refresh).Methods("POST")
+	b.Router.HandleFunc(url+"/user/logout", userHandler.Logout).Methods("POST")
+	b.Router.HandleFunc(url+"/user/password/forgot", userHandler.ForgotPassword).Methods("POST")
+	b.Router.HandleFunc(url+"/user/password/reset", userHandler.ResetPassword).Methods("POST")
+	b.Router.HandleFunc(url+"/user/verify", userHandler.VerifyEmail).Methods("GET")
 
 	//Tag
 	tagService := service.NewTagService(b.Store.TagRepository())
 	tagHandler := handlers.NewTagHandler(tagService)
-	b.Router.HandleFunc(url+"/tag", tagHandler.Create()).Methods("POST")
 	b.Router.HandleFunc(url+"/tag/all", tagHandler.GetAll()).Methods("GET")
-	b.Router.HandleFunc(url+"/tag/{id}", tagHandler.Delete()).Methods("DELETE")
 
 	//Category
 	categoryService := service.NewCategoryService(b.Store.CategoryRepository())
 	categoryHandler := handlers.NewCategoryHandler(categoryService)
-	b.Router.HandleFunc(url+"/category", categoryHandler.Create()).Methods("POST")
 	b.Router.HandleFunc(url+"/category/all", categoryHandler.GetAll()).Methods("GET")
-	b.Router.HandleFunc(url+"/category/{id}", categoryHandler.Delete()).Methods("DELETE")
+
+	//Delivery
+	deliveryService := service.NewDeliveryService(b.Store.DeliveryRepository(), b.Store.CatalogRepository())
+	deliveryHandler := handlers.NewDeliveryHandler(deliveryService)
+	b.Router.HandleFunc(url+"/delivery/zones", deliveryHandler.GetZones).Methods("GET")
+	b.Router.HandleFunc(url+"/delivery/slots", deliveryHandler.GetSlots).Methods("GET")
+	b.Router.HandleFunc(url+"/delivery/quote", deliveryHandler.Quote).Methods("POST")
 
 	// Защищенные роуты
-	JWTMiddleware := security.NewJwtMiddleware(b.JwtConfig)
+	JWTMiddleware := security.NewJwtMiddleware(b.JwtConfig, b.Store.SessionRepository())
 
 	protected := b.Router.PathPrefix("/api/v1").Subrouter()
 	protected.Use(JWTMiddleware.CheckJWT)
 
+	// Роуты управления каталогом - только для администраторов и модераторов
+	staff := protected.NewRoute().Subrouter()
+	staff.Use(handlers.RequireRoles(model.RoleAdmin, model.RoleModerator))
+
+	// Настройки доставки - только для администраторов
+	admin := protected.NewRoute().Subrouter()
+	admin.Use(handlers.RequireRoles(model.RoleAdmin))
+
+	admin.HandleFunc("/delivery/zones", deliveryHandler.CreateZone).Methods("POST")
+	admin.HandleFunc("/delivery/zones/{id}", deliveryHandler.UpdateZone).Methods("PUT")
+	admin.HandleFunc("/delivery/slot-templates", deliveryHandler.GetTemplates).Methods("GET")
+	admin.HandleFunc("/delivery/slot-templates", deliveryHandler.CreateTemplate).Methods("POST")
+	admin.HandleFunc("/delivery/slot-templates/{id}", deliveryHandler.DeleteTemplate).Methods("DELETE")
+
+	// Журнал неудачных входов и снятие блокировки
+	loginSecurityService := service.NewLoginSecurityService(b.Store.LoginAuditRepository(), b.Limiter)
+	loginSecurityHandler := handlers.NewLoginSecurityHandler(loginSecurityService)
+	admin.HandleFunc("/security/login-failures", loginSecurityHandler.GetFailures).Methods("GET")
+	admin.HandleFunc("/security/unlock", loginSecurityHandler.Unlock).Methods("POST")
+
+	staff.HandleFunc("/tag", tagHandler.Create()).Methods("POST")
+	staff.HandleFunc("/tag/{id}", tagHandler.Delete()).Methods("DELETE")
+	staff.HandleFunc("/category", categoryHandler.Create()).Methods("POST")
+	staff.HandleFunc("/category/{id}", categoryHandler.Delete()).Methods("DELETE")
+
 	//Catalog
-	catalogService := service.NewCatalogService(b.Store.CatalogRepository())
+	catalogService := service.NewCatalogService(b.Store.CatalogRepository(), b.Store.FavoriteRepository())
 	catalogHandler := handlers.NewCatalogHandler(catalogService)
-	b.Router.HandleFunc(url+"/catalog/all", catalogHandler.GetAll(b.Fs.Image)).Methods("GET")
-	b.Router.HandleFunc(url+"/catalog/{id}", catalogHandler.GetById(b.Fs.Image)).Methods("GET")
 
-	protected.HandleFunc("/catalog", catalogHandler.Create).Methods("POST")
-	protected.HandleFunc("/catalog/{id}", catalogHandler.Delete).Methods("DELETE")
-	protected.HandleFunc("/catalog", catalogHandler.Update).Methods("PATCH")
-	protected.HandleFunc("/catalog/add-image", catalogHandler.AddImage(b.Fs.Image)).Methods("POST")
+	// Публичные роуты каталога: для авторизованного пользователя отмечаем избранное
+	optional := b.Router.PathPrefix("/api/v1").Subrouter()
+	optional.Use(security.NewOptionalJwtMiddleware(b.JwtConfig, b.Store.SessionRepository()))
+	optional.HandleFunc("/catalog/all", catalogHandler.GetAll(b.Fs.Image)).Methods("GET")
+	optional.HandleFunc("/catalog/search", catalogHandler.Search(b.Fs.Image)).Methods("GET")
+	optional.HandleFunc("/catalog/{id}", catalogHandler.GetById(b.Fs.Image)).Methods("GET")
+
+	staff.HandleFunc("/catalog", catalogHandler.Create).Methods("POST")
+	staff.HandleFunc("/catalog/{id}", catalogHandler.Delete).Methods("DELETE")
+	staff.HandleFunc("/catalog", catalogHandler.Update).Methods("PATCH")
+	staff.HandleFunc("/catalog/add-image", catalogHandler.AddImage(b.Fs.Image)).Methods("POST")
+	staff.HandleFunc("/catalog/{id}/tags", catalogHandler.SetTags).Methods("PUT")
+	staff.HandleFunc("/catalog/{id}/tags", catalogHandler.AddTags).Methods("POST")
+	staff.HandleFunc("/catalog/{id}/tags/{tagId}", catalogHandler.RemoveTag).Methods("DELETE")
+
+	// Reviews - отзывы покупателей, в рейтинг товара попадают после модерации
+	reviewService := service.NewReviewService(b.Store.ReviewRepository())
+	reviewHandler := handlers.NewReviewHandler(reviewService, b.Fs.Image)
+	b.Router.HandleFunc(url+"/catalog/{id}/reviews", reviewHandler.GetByCatalog).Methods("GET")
+	protected.HandleFunc("/catalog/{id}/reviews", reviewHandler.Create).Methods("POST")
+	protected.HandleFunc("/reviews/{id}", reviewHandler.Delete).Methods("DELETE")
+	staff.HandleFunc("/reviews/moderation", reviewHandler.GetPending).Methods("GET")
+	staff.HandleFunc("/reviews/{id}/approve", reviewHandler.Moderate(model.ReviewStatusApproved)).Methods("POST")
+	staff.HandleFunc("/reviews/{id}/reject", reviewHandler.Moderate(model.ReviewStatusRejected)).Methods("POST")
 
 	// User
 	protected.HandleFunc("/user/profile", userHandler.Update).Methods("PATCH")
 	protected.HandleFunc("/user/profile/address", userHandler.UpdateAddress).Methods("POST")
 	protected.HandleFunc("/user", userHandler.Get).Methods("GET")
+	protected.HandleFunc("/user/logout-all", userHandler.LogoutAll).Methods("POST")
+
+	// Two-factor - TOTP из приложения-аутентификатора и коды восстановления
+	twoFactorHandler := handlers.NewTwoFactorHandler(twoFactorService)
+	protected.HandleFunc("/user/2fa", twoFactorHandler.GetStatus).Methods("GET")
+	protected.HandleFunc("/user/2fa/setup", twoFactorHandler.Setup).Methods("POST")
+	protected.HandleFunc("/user/2fa/enable", twoFactorHandler.Enable).Methods("POST")
+	protected.HandleFunc("/user/2fa/disable", twoFactorHandler.Disable).Methods("POST")
+	protected.HandleFunc("/user/2fa/recovery-codes", twoFactorHandler.RegenerateRecoveryCodes).Methods("POST")
+
+	// Favorites
+	favoriteService := service.NewFavoriteService(b.Store.FavoriteRepository(), b.Store.CatalogRepository())
+	favoriteHandler := handlers.NewFavoriteHandler(favoriteService, b.Fs.Image)
+	protected.HandleFunc("/user/favorites", favoriteHandler.GetAll).Methods("GET")
+	protected.HandleFunc("/user/favorites/{catalogId}", favoriteHandler.Add).Methods("POST")
+	protected.HandleFunc("/user/favorites/{catalogId}", favoriteHandler.Remove).Methods("DELETE")
+
+	// Subscriptions - уведомления о поступлении товара и снижении цены
+	subscriptionService := service.NewSubscriptionService(b.Store.SubscriptionRepository())
+	subscriptionHandler := handlers.NewSubscriptionHandler(subscriptionService)
+	protected.HandleFunc("/user/subscriptions", subscriptionHandler.GetAll).Methods("GET")
+	protected.HandleFunc("/catalog/{id}/subscriptions/{type}", subscriptionHandler.Subscribe).Methods("POST")
+	protected.HandleFunc("/catalog/{id}/subscriptions/{type}", subscriptionHandler.Unsubscribe).Methods("DELETE")
+
+	// Loyalty - бонусные баллы, баланс считается по книге проводок
+	loyaltyService := service.NewLoyaltyService(b.Store.LoyaltyRepository(), b.Loyalty)
+	loyaltyHandler := handlers.NewLoyaltyHandler(loyaltyService)
+	protected.HandleFunc("/user/loyalty", loyaltyHandler.Get).Methods("GET")
+
+	// Cart
+	protected.HandleFunc("/cart", cartHandler.Get(handlers.UserCartOwner)).Methods("GET")
+	protected.HandleFunc("/cart", cartHandler.Clear(handlers.UserCartOwner)).Methods("DELETE")
+	protected.HandleFunc("/cart/items", cartHandler.AddItem(handlers.UserCartOwner)).Methods("POST")
+	protected.HandleFunc("/cart/items", cartHandler.UpdateItem(handlers.UserCartOwner)).Methods("PATCH")
+	protected.HandleFunc("/cart/items/{catalogId}", cartHandler.RemoveItem(handlers.UserCartOwner)).Methods("DELETE")
+
+	// Pricing - итоговая цена корзины с акциями и промокодом
+	pricingService := service.NewPricingService(b.Store.PromotionRepository(), b.Store.CartRepository(), b.Store.UserRepository(), b.Store.DeliveryRepository())
+	pricingHandler := handlers.NewPricingHandler(pricingService)
+	b.Router.HandleFunc(url+"/cart/guest/price", pricingHandler.Price(handlers.GuestCartOwner)).Methods("POST")
+	protected.HandleFunc("/cart/price", pricingHandler.Price(handlers.UserCartOwner)).Methods("POST")
+
+	// Promotions - промокоды и автоматические акции
+	promotionService := service.NewPromotionService(b.Store.PromotionRepository())
+	promotionHandler := handlers.NewPromotionHandler(promotionService)
+	admin.HandleFunc("/promotions", promotionHandler.GetAll).Methods("GET")
+	admin.HandleFunc("/promotions", promotionHandler.Create).Methods("POST")
+	admin.HandleFunc("/promotions/{id}", promotionHandler.GetById).Methods("GET")
+	admin.HandleFunc("/promotions/{id}", promotionHandler.Update).Methods("PUT")
+	admin.HandleFunc("/promotions/{id}", promotionHandler.Delete).Methods("DELETE")
+
+	// Tracking - события заказа для SSE
+	trackingService := service.NewTrackingService(b.Store.OrderRepository(), b.Store.UserRepository(), b.Store.DispatchRepository(), b.Hub, b.Dispatch)
+	trackingHandler := handlers.NewTrackingHandler(trackingService, b.Events)
+
+	// Payments - заказ подтверждается только подписанным вебхуком шлюза
+	paymentService := service.NewPaymentService(b.Store.PaymentRepository(), b.Store.OrderRepository(), b.Store.UserRepository(), b.Provider, b.Payment, trackingService)
+	paymentHandler := handlers.NewPaymentHandler(paymentService)
+	b.Router.HandleFunc(url+"/payments/webhook", paymentHandler.Webhook).Methods("POST")
+	protected.HandleFunc("/orders/{id}/payment", paymentHandler.Get).Methods("GET")
+	protected.HandleFunc("/orders/{id}/payment", paymentHandler.Retry).Methods("POST")
+
+	// Имитатор оплаты для локальной разработки: POST /payments/fake/{intentId}/authorize|fail
+	if fake, ok := b.Provider.(*payment.FakeProvider); ok && b.Payment.EnableFakeSimulator {
+		b.Router.PathPrefix(url + "/payments/fake/").Handler(http.StripPrefix(url+"/payments/fake", fake.Simulator()))
+	}
+
+	// Orders
+	orderService := service.NewOrderService(b.Store.OrderRepository(), b.Store.CartRepository(), b.Store.UserRepository(), b.Store.DeliveryRepository(), b.Store.PromotionRepository(), trackingService, paymentService, b.Loyalty, b.Account)
+	orderHandler := handlers.NewOrderHandler(orderService)
+	protected.HandleFunc("/orders/checkout", orderHandler.Checkout).Methods("POST")
+	protected.HandleFunc("/orders", orderHandler.GetAll).Methods("GET")
+	protected.HandleFunc("/orders/queue", orderHandler.GetQueue).Methods("GET")
+	protected.HandleFunc("/orders/{id}", orderHandler.GetById).Methods("GET")
+	protected.HandleFunc("/orders/{id}/history", orderHandler.GetHistory).Methods("GET")
+	protected.HandleFunc("/orders/{id}/events", trackingHandler.Stream).Methods("GET")
+	protected.HandleFunc("/orders/{id}/status", orderHandler.ChangeStatus).Methods("PATCH")
+
+	// Picking - сборка заказов, покупатель подтверждает недовложения и замены
+	pickingService := service.NewPickingService(b.Store.PickingRepository(), b.Store.OrderRepository(), b.Store.UserRepository(), trackingService)
+	pickingHandler := handlers.NewPickingHandler(pickingService)
+	protected.HandleFunc("/orders/{id}/pick-events", pickingHandler.GetEvents).Methods("GET")
+	protected.HandleFunc("/orders/{id}/pick-events/{eventId}/approve", pickingHandler.ApproveEvent).Methods("POST")
+	protected.HandleFunc("/orders/{id}/pick-events/{eventId}/reject", pickingHandler.RejectEvent).Methods("POST")
+	protected.HandleFunc("/orders/{id}/{action}", orderHandler.Action).Methods("POST")
+
+	// Dispatch - распределение заказов по курьерам
+	dispatchService := service.NewDispatchService(b.Store.DispatchRepository(), trackingService, b.Dispatch)
+	dispatchHandler := handlers.NewDispatchHandler(dispatchService)
+
+	dispatcher := protected.NewRoute().Subrouter()
+	dispatcher.Use(handlers.RequireRoles(model.RoleAdmin, model.RoleWorker))
+	dispatcher.HandleFunc("/dispatch/run", dispatchHandler.Run).Methods("POST")
+	dispatcher.HandleFunc("/dispatch/routes", dispatchHandler.GetRoutes).Methods("GET")
+	dispatcher.HandleFunc("/dispatch/couriers", dispatchHandler.GetCouriers).Methods("GET")
+	dispatcher.HandleFunc("/dispatch/couriers/{id}", dispatchHandler.UpdateCourier).Methods("PUT")
+
+	courier := protected.NewRoute().Subrouter()
+	courier.Use(handlers.RequireRoles(model.RoleCourier))
+	courier.HandleFunc("/courier/route", dispatchHandler.GetCourierRoute).Methods("GET")
+
+	collector := protected.NewRoute().Subrouter()
+	collector.Use(handlers.RequireRoles(model.RoleAdmin, model.RoleCollector))
+	collector.HandleFunc("/picking/next", pickingHandler.Next).Methods("POST")
+	collector.HandleFunc("/picking/orders/{id}", pickingHandler.GetList).Methods("GET")
+	collector.HandleFunc("/picking/orders/{id}/items/{itemId}", pickingHandler.UpdateLine).Methods("PATCH")
 }
 
 func BuildRoutesStatic(r *mux.Router, fsPath string) {
@@ -2,25 +2,59 @@ package server
 
 import (
 	"arabic/internal/store"
+	"arabic/pkg/events"
 	"arabic/pkg/fs"
+	"arabic/pkg/loyalty"
+	"arabic/pkg/notify"
+	"arabic/pkg/payment"
+	"arabic/pkg/routing"
 	"arabic/pkg/security/auth"
+	"arabic/pkg/security/throttle"
+	"arabic/pkg/security/totp"
 )
 
+// Окружение, в котором разрешены инструменты локальной разработки
+const EnvDev = "dev"
+
 type Config struct {
+	// dev или production
+	Env      string `toml:"env"`
 	BindAddr string `toml:"bind_addr"`
 	LogLevel string `toml:"log_level"`
 	LogDir   string `toml:"log_dir"`
 	Storage  *store.Config
 	JWT      *security.JWTConfig
 	FS       *fs.Config
+	Dispatch *routing.Config
+	Events   *events.Config
+	Payment  *payment.Config
+	Loyalty  *loyalty.Config
+	Notify   *notify.Config
+	Account  *security.AccountConfig
+	// Атрибуты cookie с токенами
+	Cookie *security.CookieConfig
+	// Ограничение попыток входа
+	LoginThrottle *throttle.Config `toml:"login_throttle"`
+	// Второй фактор входа
+	TwoFactor *totp.Config `toml:"two_factor"`
 }
 
 func NewConfig() *Config {
 	return &Config{
-		BindAddr: ":8080",
-		LogLevel: "debug",
-		Storage:  store.NewConfig(),
-		JWT:      security.NewJWTConfig(),
-		FS:       fs.NewFSConfig(),
+		Env:           "production",
+		BindAddr:      ":8080",
+		LogLevel:      "debug",
+		Storage:       store.NewConfig(),
+		JWT:           security.NewJWTConfig(),
+		FS:            fs.NewFSConfig(),
+		Dispatch:      routing.NewConfig(),
+		Events:        events.NewConfig(),
+		Payment:       payment.NewConfig(),
+		Loyalty:       loyalty.NewConfig(),
+		Notify:        notify.NewConfig(),
+		Account:       security.NewAccountConfig(),
+		Cookie:        security.NewCookieConfig(),
+		LoginThrottle: throttle.NewConfig(),
+		TwoFactor:     totp.NewConfig(),
 	}
 }
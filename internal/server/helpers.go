@@ -2,9 +2,16 @@ package server
 
 import (
 	"arabic/internal/server/builders"
+	"arabic/internal/service"
 	"arabic/internal/store"
+	"arabic/pkg/events"
 	"arabic/pkg/fs"
 	"arabic/pkg/logger"
+	"arabic/pkg/notify"
+	"arabic/pkg/payment"
+	"arabic/pkg/security/throttle"
+	"arabic/pkg/security/totp"
+	"fmt"
 	"github.com/gorilla/mux"
 	"net/http"
 )
@@ -16,6 +23,17 @@ func (a *Api) configureRouter() {
 		Store:     a.store,
 		JwtConfig: a.config.JWT,
 		Fs:        a.fs,
+		Dispatch:  a.config.Dispatch,
+		Events:    a.config.Events,
+		Hub:       a.hub,
+		Payment:   a.config.Payment,
+		Provider:  a.payment,
+		Loyalty:   a.config.Loyalty,
+		Account:   a.config.Account,
+		Cookie:    a.config.Cookie,
+		Limiter:   a.loginLimiter,
+		TwoFactor: a.config.TwoFactor,
+		Cipher:    a.twoFactorCipher,
 	}
 
 	builders.BuildRoutes(builder)
@@ -28,6 +46,84 @@ func (a *Api) configureFileSystem() {
 	a.fs = fs.New(a.config.FS)
 }
 
+func (a *Api) configureEvents() {
+	a.hub = events.NewHub(a.config.Events)
+}
+
+// Имитатор оплаты подтверждает любые платежи без авторизации, вне dev сервер с ним не стартует
+func (a *Api) configurePayment() error {
+	if err := a.config.Payment.Validate(); err != nil {
+		return err
+	}
+	if a.config.Payment.EnableFakeSimulator && a.config.Env != EnvDev {
+		return fmt.Errorf("payment enable_fake_simulator is allowed only in env=%q, got %q", EnvDev, a.config.Env)
+	}
+
+	provider, err := payment.NewProvider(a.config.Payment)
+	if err != nil {
+		return err
+	}
+	a.payment = provider
+	return nil
+}
+
+// Очередь уведомлений разбирается в фоне, пока работает сервер
+func (a *Api) configureNotifications() error {
+	notifier, err := notify.NewNotifier(a.config.Notify)
+	if err != nil {
+		return err
+	}
+	renderer, err := notify.NewRenderer()
+	if err != nil {
+		return err
+	}
+	a.notifications = service.NewNotificationService(a.store.NotificationRepository(), notifier, renderer, a.config.Notify)
+	return nil
+}
+
+// Слоты создаются фоновой задачей, запросы слотов ничего не пишут в базу
+func (a *Api) configureDeliverySlots() {
+	a.deliverySlots = service.NewDeliveryService(a.store.DeliveryRepository(), a.store.CatalogRepository())
+}
+
+// Счетчики в памяти работают только в пределах одного процесса
+func (a *Api) configureLoginLimiter() error {
+	var store throttle.Store
+
+	switch a.config.LoginThrottle.Driver {
+	case throttle.DriverMemory:
+		store = throttle.NewMemoryStore()
+	case throttle.DriverPostgres:
+		store = a.store.LoginAttemptRepository()
+	default:
+		return fmt.Errorf("unknown login throttle driver %q", a.config.LoginThrottle.Driver)
+	}
+
+	a.loginLimiter = throttle.NewLimiter(store, a.config.LoginThrottle)
+	return nil
+}
+
+// Ключи подписи читаются заранее: сервер с битым ключом или настройками cookie не должен стартовать
+func (a *Api) configureAuth() error {
+	if err := a.config.JWT.Validate(); err != nil {
+		return err
+	}
+	if err := a.config.Cookie.Validate(); err != nil {
+		return err
+	}
+	return a.config.JWT.LoadKeys()
+}
+
+// Без ключа шифрования сервер не стартует: секреты нельзя хранить открытыми
+func (a *Api) configureTwoFactor() error {
+	cipher, err := totp.NewCipher(a.config.TwoFactor.EncryptionKey)
+	if err != nil {
+		return err
+	}
+	a.twoFactorCipher = cipher
+	return nil
+}
+
 func (a *Api) configureLogger() error {
 	return logger.Init(a.config.LogLevel, a.config.LogDir)
 }
@@ -51,7 +147,7 @@ func corsMiddleware(next http.Handler) http.Handler {
 		}
 
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 
 		if r.Method == http.MethodOptions {
@@ -1,19 +1,44 @@
 package server
 
 import (
+	"arabic/internal/service"
 	"arabic/internal/store"
+	"arabic/pkg/events"
 	"arabic/pkg/fs"
 	"arabic/pkg/logger"
+	"arabic/pkg/payment"
+	"arabic/pkg/security/throttle"
+	"arabic/pkg/security/totp"
+	"context"
+	"errors"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gorilla/mux"
 )
 
+// Сколько ждать завершения активных запросов при остановке
+const shutdownTimeout = 10 * time.Second
+
 type Api struct {
 	config *Config
 	router *mux.Router
 	store  *store.Store
 	fs     *fs.FS
+	hub    *events.Hub
+	// Платежный шлюз из конфигурации
+	payment payment.PaymentProvider
+	// Фоновая доставка уведомлений
+	notifications *service.NotificationService
+	// Фоновое создание слотов доставки из шаблонов
+	deliverySlots *service.DeliveryService
+	// Ограничение попыток входа
+	loginLimiter *throttle.Limiter
+	// Шифрование секретов второго фактора
+	twoFactorCipher *totp.Cipher
 }
 
 func New(config *Config) *Api {
@@ -31,10 +56,76 @@ func (api *Api) Start() error {
 	if err := api.configureStore(); err != nil {
 		return err
 	}
+	defer api.store.Stop()
+
+	if err := api.configureAuth(); err != nil {
+		return err
+	}
 
 	api.configureFileSystem()
 
+	api.configureEvents()
+
+	if err := api.configurePayment(); err != nil {
+		return err
+	}
+
+	if err := api.configureNotifications(); err != nil {
+		return err
+	}
+
+	api.configureDeliverySlots()
+
+	if err := api.configureLoginLimiter(); err != nil {
+		return err
+	}
+
+	if err := api.configureTwoFactor(); err != nil {
+		return err
+	}
+
 	api.configureRouter()
 
-	return http.ListenAndServe(api.config.BindAddr, corsMiddleware(api.router))
+	// WriteTimeout не действует на SSE: обработчик потока снимает дедлайны своего соединения
+	srv := &http.Server{
+		Addr:              api.config.BindAddr,
+		Handler:           corsMiddleware(api.router),
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       120 * time.Second,
+	}
+
+	return api.serve(srv)
+}
+
+// Работает до SIGINT/SIGTERM, затем закрывает SSE-потоки и дожидается остальных запросов
+func (api *Api) serve(srv *http.Server) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go api.notifications.Run(ctx)
+	go api.deliverySlots.RunSlotGeneration(ctx)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	api.hub.Close()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
 }
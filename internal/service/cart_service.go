@@ -0,0 +1,198 @@
+package service
+
+import (
+	"arabic/internal/dto"
+	"arabic/internal/model"
+	"arabic/internal/repository"
+	"arabic/pkg/customError"
+	"arabic/pkg/logger"
+	"context"
+	"fmt"
+	"net/http"
+)
+
+type ICartService interface {
+	GetCart(ctx context.Context, owner *dto.CartOwner, imagePrefix string) (*dto.CartResponse, error)
+	AddItem(ctx context.Context, owner *dto.CartOwner, req *dto.CartItemRequest, imagePrefix string) (*dto.CartResponse, error)
+	UpdateItem(ctx context.Context, owner *dto.CartOwner, req *dto.CartItemRequest, imagePrefix string) (*dto.CartResponse, error)
+	RemoveItem(ctx context.Context, owner *dto.CartOwner, catalogId uint, imagePrefix string) (*dto.CartResponse, error)
+	Clear(ctx context.Context, owner *dto.CartOwner) error
+	MergeGuestCart(ctx context.Context, token string, userId int64) error
+}
+
+type CartService struct {
+	cartRepository    repository.ICartRepository
+	catalogRepository repository.ICatalogRepository
+}
+
+func NewCartService(cartRepo repository.ICartRepository, catalogRepo repository.ICatalogRepository) *CartService {
+	return &CartService{
+		cartRepository:    cartRepo,
+		catalogRepository: catalogRepo,
+	}
+}
+
+func (s *CartService) GetCart(ctx context.Context, owner *dto.CartOwner, imagePrefix string) (*dto.CartResponse, error) {
+	if owner.UserId == 0 && owner.Token == "" {
+		return model.NewCartResponse(nil, imagePrefix), nil
+	}
+
+	cartId, ok, err := s.cartRepository.FindCartId(ctx, owner)
+
+	if err != nil {
+		logger.Log.Error("CartService -> GetCart -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	if !ok {
+		return model.NewCartResponse(nil, imagePrefix), nil
+	}
+
+	return s.cartResponse(ctx, cartId, imagePrefix)
+}
+
+func (s *CartService) AddItem(ctx context.Context, owner *dto.CartOwner, req *dto.CartItemRequest, imagePrefix string) (*dto.CartResponse, error) {
+	return s.changeItem(ctx, owner, req, imagePrefix, true)
+}
+
+func (s *CartService) UpdateItem(ctx context.Context, owner *dto.CartOwner, req *dto.CartItemRequest, imagePrefix string) (*dto.CartResponse, error) {
+	return s.changeItem(ctx, owner, req, imagePrefix, false)
+}
+
+func (s *CartService) RemoveItem(ctx context.Context, owner *dto.CartOwner, catalogId uint, imagePrefix string) (*dto.CartResponse, error) {
+	if owner.UserId == 0 && owner.Token == "" {
+		return nil, customError.NewServiceError(http.StatusBadRequest, fmt.Sprintf("Catalog item %d is not in the cart", catalogId), nil)
+	}
+
+	cartId, ok, err := s.cartRepository.FindCartId(ctx, owner)
+
+	if err != nil {
+		logger.Log.Error("CartService -> RemoveItem -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	if ok {
+		ok, err = s.cartRepository.RemoveItem(ctx, cartId, catalogId)
+		if err != nil {
+			logger.Log.Error("CartService -> RemoveItem -> err -> " + err.Error())
+			return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+		}
+	}
+
+	if !ok {
+		return nil, customError.NewServiceError(http.StatusBadRequest, fmt.Sprintf("Catalog item %d is not in the cart", catalogId), nil)
+	}
+
+	return s.cartResponse(ctx, cartId, imagePrefix)
+}
+
+func (s *CartService) Clear(ctx context.Context, owner *dto.CartOwner) error {
+	if owner.UserId == 0 && owner.Token == "" {
+		return nil
+	}
+
+	cartId, ok, err := s.cartRepository.FindCartId(ctx, owner)
+
+	if err == nil && ok {
+		err = s.cartRepository.Clear(ctx, cartId)
+	}
+
+	if err != nil {
+		logger.Log.Error("CartService -> Clear -> err -> " + err.Error())
+		return customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	return nil
+}
+
+// Переносит товары анонимной корзины в корзину пользователя, не превышая остаток на складе
+func (s *CartService) MergeGuestCart(ctx context.Context, token string, userId int64) error {
+	if err := s.mergeGuestCart(ctx, token, userId); err != nil {
+		logger.Log.Error("CartService -> MergeGuestCart -> err -> " + err.Error())
+		return customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	return nil
+}
+
+func (s *CartService) mergeGuestCart(ctx context.Context, token string, userId int64) error {
+	guestCartId, ok, err := s.cartRepository.FindCartId(ctx, &dto.CartOwner{Token: token})
+	if err != nil || !ok {
+		return err
+	}
+
+	guestItems, err := s.cartRepository.FindItems(ctx, guestCartId)
+	if err != nil {
+		return err
+	}
+
+	userCartId, err := s.cartRepository.FindOrCreateCart(ctx, &dto.CartOwner{UserId: userId})
+	if err != nil {
+		return err
+	}
+
+	userItems, err := s.cartRepository.FindItems(ctx, userCartId)
+	if err != nil {
+		return err
+	}
+
+	return s.cartRepository.MergeGuestCart(ctx, guestCartId, userCartId, model.MergeCartQuantities(userItems, guestItems))
+}
+
+// increment = true прибавляет количество к уже лежащему в корзине, иначе заменяет его
+func (s *CartService) changeItem(ctx context.Context, owner *dto.CartOwner, req *dto.CartItemRequest, imagePrefix string, increment bool) (*dto.CartResponse, error) {
+	item, ok, err := s.catalogRepository.FindById(ctx, req.CatalogId)
+
+	if err != nil {
+		logger.Log.Error("CartService -> changeItem -> FindById -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	if !ok {
+		return nil, customError.NewServiceError(http.StatusBadRequest, customError.ErrorNotFoundById, nil)
+	}
+
+	cartId, err := s.cartRepository.FindOrCreateCart(ctx, owner)
+
+	if err != nil {
+		logger.Log.Error("CartService -> changeItem -> FindOrCreateCart -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	quantity := req.Quantity
+	if increment {
+		items, err := s.cartRepository.FindItems(ctx, cartId)
+		if err != nil {
+			logger.Log.Error("CartService -> changeItem -> FindItems -> err -> " + err.Error())
+			return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+		}
+
+		for _, cartItem := range items {
+			if cartItem.CatalogId == req.CatalogId {
+				quantity += cartItem.Quantity
+			}
+		}
+	}
+
+	if quantity > item.Amount {
+		return nil, customError.NewServiceError(http.StatusConflict, fmt.Sprintf("Only %d items of %s left in stock", item.Amount, item.Name), nil)
+	}
+
+	if err = s.cartRepository.SetItemQuantity(ctx, cartId, req.CatalogId, quantity); err != nil {
+		logger.Log.Error("CartService -> changeItem -> SetItemQuantity -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	return s.cartResponse(ctx, cartId, imagePrefix)
+}
+
+func (s *CartService) cartResponse(ctx context.Context, cartId int64, imagePrefix string) (*dto.CartResponse, error) {
+	items, err := s.cartRepository.FindItems(ctx, cartId)
+
+	if err != nil {
+		logger.Log.Error("CartService -> cartResponse -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	return model.NewCartResponse(items, imagePrefix), nil
+}
@@ -0,0 +1,175 @@
+package service_test
+
+import (
+	"arabic/internal/dto"
+	"arabic/internal/model"
+	"arabic/internal/service"
+	"arabic/pkg/logger"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+)
+
+type MockICartRepository struct {
+	mock.Mock
+}
+
+func (m *MockICartRepository) FindCartId(ctx context.Context, owner *dto.CartOwner) (int64, bool, error) {
+	args := m.Called(ctx, owner)
+	return args.Get(0).(int64), args.Bool(1), args.Error(2)
+}
+func (m *MockICartRepository) FindOrCreateCart(ctx context.Context, owner *dto.CartOwner) (int64, error) {
+	args := m.Called(ctx, owner)
+	return args.Get(0).(int64), args.Error(1)
+}
+func (m *MockICartRepository) FindItems(ctx context.Context, cartId int64) ([]*model.CartItem, error) {
+	args := m.Called(ctx, cartId)
+	return args.Get(0).([]*model.CartItem), args.Error(1)
+}
+func (m *MockICartRepository) SetItemQuantity(ctx context.Context, cartId int64, catalogId uint, quantity int) error {
+	args := m.Called(ctx, cartId, catalogId, quantity)
+	return args.Error(0)
+}
+func (m *MockICartRepository) RemoveItem(ctx context.Context, cartId int64, catalogId uint) (bool, error) {
+	args := m.Called(ctx, cartId, catalogId)
+	return args.Bool(0), args.Error(1)
+}
+func (m *MockICartRepository) Clear(ctx context.Context, cartId int64) error {
+	args := m.Called(ctx, cartId)
+	return args.Error(0)
+}
+func (m *MockICartRepository) MergeGuestCart(ctx context.Context, guestCartId, userCartId int64, quantities map[uint]int) error {
+	args := m.Called(ctx, guestCartId, userCartId, quantities)
+	return args.Error(0)
+}
+
+func TestCartService_AddItem(t *testing.T) {
+	logger.Init("Error", "./")
+
+	catalogItem := &model.Catalog{Id: 1, Name: "Salsa", Price: 150, DiscountPercent: 10, Amount: 5, Weight: 0.5}
+	owner := &dto.CartOwner{UserId: 1}
+
+	tests := []struct {
+		name           string
+		inCart         int
+		quantity       int
+		expectErr      bool
+		expectQuantity int
+	}{
+		{
+			name:           "new item",
+			quantity:       2,
+			expectQuantity: 2,
+		},
+		{
+			name:           "increments existing item",
+			inCart:         2,
+			quantity:       3,
+			expectQuantity: 5,
+		},
+		{
+			name:      "not enough stock",
+			inCart:    4,
+			quantity:  2,
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			catalogRepo := &MockICatalogRepository{}
+			catalogRepo.On("FindById", mock.Anything, uint(1)).Return(catalogItem, true, nil)
+
+			var current []*model.CartItem
+			if tc.inCart > 0 {
+				current = []*model.CartItem{{CartId: 7, CatalogId: 1, Quantity: tc.inCart, Price: 150, DiscountPercent: 10, Amount: 5, Weight: 0.5}}
+			}
+			updated := []*model.CartItem{{CartId: 7, CatalogId: 1, Quantity: tc.expectQuantity, Price: 150, DiscountPercent: 10, Amount: 5, Weight: 0.5}}
+
+			cartRepo := &MockICartRepository{}
+			cartRepo.On("FindOrCreateCart", mock.Anything, owner).Return(int64(7), nil)
+			cartRepo.On("FindItems", mock.Anything, int64(7)).Return(current, nil).Once()
+			cartRepo.On("FindItems", mock.Anything, int64(7)).Return(updated, nil)
+			cartRepo.On("SetItemQuantity", mock.Anything, int64(7), uint(1), tc.expectQuantity).Return(nil)
+
+			srv := service.NewCartService(cartRepo, catalogRepo)
+			cart, err := srv.AddItem(context.Background(), owner, &dto.CartItemRequest{CatalogId: 1, Quantity: tc.quantity}, "/test/")
+
+			if tc.expectErr {
+				assert.Error(t, err)
+				cartRepo.AssertNotCalled(t, "SetItemQuantity", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectQuantity, cart.TotalQuantity)
+			assert.Equal(t, float32(135), cart.Items[0].UnitPrice)
+			assert.Equal(t, float32(150*tc.expectQuantity), cart.Subtotal)
+			assert.Equal(t, float32(135*tc.expectQuantity), cart.Total)
+			assert.Equal(t, float32(15*tc.expectQuantity), cart.Discount)
+		})
+	}
+}
+
+func TestCartService_GuestWithoutToken(t *testing.T) {
+	logger.Init("Error", "./")
+
+	owner := &dto.CartOwner{}
+	cartRepo := &MockICartRepository{}
+	srv := service.NewCartService(cartRepo, &MockICatalogRepository{})
+
+	_, err := srv.RemoveItem(context.Background(), owner, 1, "/test/")
+	assert.Error(t, err)
+
+	assert.NoError(t, srv.Clear(context.Background(), owner))
+	cartRepo.AssertNotCalled(t, "FindCartId", mock.Anything, mock.Anything)
+}
+
+func TestCartService_MergeGuestCart(t *testing.T) {
+	logger.Init("Error", "./")
+
+	guest := &dto.CartOwner{Token: "guest-token"}
+	user := &dto.CartOwner{UserId: 1}
+
+	tests := []struct {
+		name       string
+		userItems  []*model.CartItem
+		guestItems []*model.CartItem
+		expect     map[uint]int
+	}{
+		{
+			name:       "adds guest items",
+			userItems:  []*model.CartItem{{CatalogId: 1, Quantity: 1, Amount: 10}},
+			guestItems: []*model.CartItem{{CatalogId: 1, Quantity: 2, Amount: 10}, {CatalogId: 2, Quantity: 1, Amount: 5}},
+			expect:     map[uint]int{1: 3, 2: 1},
+		},
+		{
+			name:       "clamps sum to stock",
+			userItems:  []*model.CartItem{{CatalogId: 1, Quantity: 3, Amount: 4}},
+			guestItems: []*model.CartItem{{CatalogId: 1, Quantity: 3, Amount: 4}, {CatalogId: 2, Quantity: 7, Amount: 5}},
+			expect:     map[uint]int{1: 4, 2: 5},
+		},
+		{
+			name:       "keeps user quantity when nothing left",
+			userItems:  []*model.CartItem{{CatalogId: 1, Quantity: 3, Amount: 2}},
+			guestItems: []*model.CartItem{{CatalogId: 1, Quantity: 1, Amount: 2}, {CatalogId: 2, Quantity: 1, Amount: 0}},
+			expect:     map[uint]int{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cartRepo := &MockICartRepository{}
+			cartRepo.On("FindCartId", mock.Anything, guest).Return(int64(5), true, nil)
+			cartRepo.On("FindItems", mock.Anything, int64(5)).Return(tc.guestItems, nil)
+			cartRepo.On("FindOrCreateCart", mock.Anything, user).Return(int64(7), nil)
+			cartRepo.On("FindItems", mock.Anything, int64(7)).Return(tc.userItems, nil)
+			cartRepo.On("MergeGuestCart", mock.Anything, int64(5), int64(7), tc.expect).Return(nil)
+
+			srv := service.NewCartService(cartRepo, &MockICatalogRepository{})
+			assert.NoError(t, srv.MergeGuestCart(context.Background(), guest.Token, user.UserId))
+			cartRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -15,20 +15,25 @@ import (
 )
 
 type ICatalogService interface {
-	GetAll(cxt context.Context, imagePrefix string) ([]*dto.CatalogResponse, error)
+	GetAll(cxt context.Context, filter *dto.CatalogFilterRequest, imagePrefix string) (*dto.CatalogPageResponse, error)
 	Create(cxt context.Context, req *dto.CatalogCreateRequest) (uint, error)
 	Delete(cxt context.Context, id uint) error
 	Update(cxt context.Context, req *dto.CatalogUpdateRequest) error
-	GetById(ctx context.Context, id uint, imagePrefix string) (*dto.CatalogResponse, error)
+	GetById(ctx context.Context, id uint, userId int64, imagePrefix string) (*dto.CatalogResponse, error)
 	AddImage(cxt context.Context, req *dto.AddImageRequest, fs fs.IFileSystemImage) (string, error)
+	Search(cxt context.Context, req *dto.CatalogSearchRequest, imagePrefix string) (*dto.CatalogSearchResponse, error)
+	SetTags(cxt context.Context, catalogId uint, req *dto.CatalogTagsRequest) error
+	AddTags(cxt context.Context, catalogId uint, req *dto.CatalogTagsRequest) error
+	RemoveTag(cxt context.Context, catalogId uint, tagId int64) error
 }
 
 type CatalogService struct {
-	CatalogRepository repository.ICatalogRepository
+	CatalogRepository  repository.ICatalogRepository
+	favoriteRepository repository.IFavoriteRepository
 }
 
-func NewCatalogService(repo repository.ICatalogRepository) *CatalogService {
-	return &CatalogService{CatalogRepository: repo}
+func NewCatalogService(repo repository.ICatalogRepository, favoriteRepo repository.IFavoriteRepository) *CatalogService {
+	return &CatalogService{CatalogRepository: repo, favoriteRepository: favoriteRepo}
 }
 
 func (c *CatalogService) Delete(ctx context.Context, id uint) error {
@@ -82,7 +87,8 @@ func (c *CatalogService) Update(cxt context.Context, req *dto.CatalogUpdateReque
 		Set("weight", req.Weight)
 
 	query, values := qb.BuildUpdateQuery("public.catalogs", "id", req.Id)
-	ok, err := c.CatalogRepository.Update(cxt, query, values)
+	// Подписчики узнают о поступлении товара и снижении цены
+	ok, err := c.CatalogRepository.UpdateWithNotifications(cxt, req.Id, query, values)
 
 	if err != nil {
 		logger.Log.Error("CatalogService -> Update -> err -> " + err.Error())
@@ -97,7 +103,7 @@ func (c *CatalogService) Update(cxt context.Context, req *dto.CatalogUpdateReque
 	return nil
 }
 
-func (c *CatalogService) GetById(ctx context.Context, id uint, imagePrefix string) (*dto.CatalogResponse, error) {
+func (c *CatalogService) GetById(ctx context.Context, id uint, userId int64, imagePrefix string) (*dto.CatalogResponse, error) {
 	item, ok, err := c.CatalogRepository.FindById(ctx, id)
 
 	if err != nil {
@@ -109,6 +115,16 @@ func (c *CatalogService) GetById(ctx context.Context, id uint, imagePrefix strin
 		return nil, customError.NewServiceError(http.StatusBadRequest, customError.ErrorNotFoundById, nil)
 	}
 
+	if err = attachTags(ctx, c.CatalogRepository, []*model.Catalog{item}); err != nil {
+		logger.Log.Error("CatalogService -> GetById -> attachTags -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	if err = c.attachFavorites(ctx, userId, []*model.Catalog{item}); err != nil {
+		logger.Log.Error("CatalogService -> GetById -> attachFavorites -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
 	resp := item.ToResponse(imagePrefix)
 
 	return resp, nil
@@ -117,27 +133,9 @@ func (c *CatalogService) GetById(ctx context.Context, id uint, imagePrefix strin
 // TODO Реализовать функционал удаления предыдущего изображения
 
 func (c *CatalogService) AddImage(cxt context.Context, req *dto.AddImageRequest, fs fs.IFileSystemImage) (string, error) {
-	// Вытаскиваем расширение файла
-	extension, err := fs.GetImageExtension(&req.Image)
-
-	if err != nil {
-		logger.Log.Error(err.Error())
-		return "", customError.NewServiceError(http.StatusBadRequest, "Image extension not found. Provide correct data", nil)
-	}
-
-	// Проверяем входит ли данное расширение в список поддерживаемых
-	ok := fs.IsSupportingExtension(extension)
-
-	if !ok {
-		logger.Log.Error(fmt.Sprintf("Not supporting image extension %s", extension))
-		return "", customError.NewServiceError(http.StatusBadRequest, fmt.Sprintf("Extension of image %s not support, pls provide correct one", extension), nil)
-	}
-
-	// Сохраняем файл в хранилище
-	filename, err := fs.SafeImageToStorage(extension, &req.Image)
+	filename, err := saveImage(fs, &req.Image)
 	if err != nil {
-		logger.Log.Error(fmt.Sprintf("Image saving error: %s", err.Error()))
-		return "", customError.NewServiceError(http.StatusBadRequest, "Something went wrong while saving image. Check provided data or try later...", nil)
+		return "", err
 	}
 
 	// Формируем sql запрос для обновления изображения
@@ -146,7 +144,7 @@ func (c *CatalogService) AddImage(cxt context.Context, req *dto.AddImageRequest,
 
 	query, values := qb.BuildUpdateQuery("public.catalogs", "id", req.Id)
 
-	ok, err = c.CatalogRepository.Update(cxt, query, values)
+	ok, err := c.CatalogRepository.Update(cxt, query, values)
 
 	if err != nil {
 		logger.Log.Error("CatalogService -> AddImage -> err -> " + err.Error())
@@ -161,22 +159,236 @@ func (c *CatalogService) AddImage(cxt context.Context, req *dto.AddImageRequest,
 	return filename, nil
 }
 
-func (c *CatalogService) GetAll(cxt context.Context, imagePrefix string) ([]*dto.CatalogResponse, error) {
+// Выражения сортировки
+var catalogSortExpressions = map[string]string{
+	"id":         "id",
+	"price":      "price",
+	"name":       "name",
+	"created_at": "created_at",
+	"rating":     "rating",
+}
+
+// Типы ключей сортировки: значение из курсора приходит строкой
+var catalogSortTypes = map[string]string{
+	"id":         "bigint",
+	"price":      "numeric",
+	"name":       "text",
+	"created_at": "timestamp",
+	"rating":     "numeric",
+}
+
+func (c *CatalogService) GetAll(cxt context.Context, filter *dto.CatalogFilterRequest, imagePrefix string) (*dto.CatalogPageResponse, error) {
+	var cursor *catalogCursor
+	if filter.Cursor != "" {
+		var err error
+		cursor, err = decodeCatalogCursor(filter.Cursor, filter.Sort)
+		if err != nil {
+			return nil, customError.NewServiceError(http.StatusBadRequest, "Invalid cursor", err)
+		}
+	}
+
+	sb := buildCatalogFilter(filter)
 
-	catalogItems, err := c.CatalogRepository.FindAll(cxt)
+	countQuery, countValues := sb.BuildCountQuery()
+	total, err := c.CatalogRepository.Count(cxt, countQuery, countValues)
+
+	if err != nil {
+		logger.Log.Error("CatalogService -> GetAll -> Count -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	sortExpression := catalogSortExpressions[filter.Sort]
+	comparator := ">"
+	if filter.Desc {
+		comparator = "<"
+	}
+
+	// Keyset пагинация: продолжаем после ключа сортировки и id из курсора.
+	// Значения берутся из самого курсора, поэтому удаленная запись не обрывает выдачу
+	if cursor != nil {
+		sb.Where(fmt.Sprintf("(%s, id) %s (CAST(? AS %s), ?)", sortExpression, comparator, catalogSortTypes[filter.Sort]), cursor.Value, cursor.Id)
+	} else {
+		sb.Offset((filter.Page - 1) * filter.Limit)
+	}
+
+	sb.OrderBy(sortExpression, filter.Desc).
+		Limit(filter.Limit + 1)
+
+	if sortExpression != "id" {
+		sb.OrderBy("id", filter.Desc)
+	}
+
+	query, values := sb.BuildSelectQuery()
+	catalogItems, err := c.CatalogRepository.FindMany(cxt, query, values)
 
 	if err != nil {
 		logger.Log.Error("CatalogService -> GetAll -> err -> " + err.Error())
 		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
 	}
 
-	var catalogResp []*dto.CatalogResponse
+	resp := &dto.CatalogPageResponse{
+		Items: []*dto.CatalogResponse{},
+		Total: total,
+		Page:  filter.Page,
+		Limit: filter.Limit,
+	}
+
+	// Запрашиваем на одну запись больше, чтобы понять есть ли следующая страница
+	if len(catalogItems) > filter.Limit {
+		catalogItems = catalogItems[:filter.Limit]
+		resp.NextCursor = encodeCatalogCursor(catalogItems[len(catalogItems)-1], filter.Sort)
+	}
+
+	if err = attachTags(cxt, c.CatalogRepository, catalogItems); err != nil {
+		logger.Log.Error("CatalogService -> GetAll -> attachTags -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	if err = c.attachFavorites(cxt, filter.UserId, catalogItems); err != nil {
+		logger.Log.Error("CatalogService -> GetAll -> attachFavorites -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
 
 	for _, item := range catalogItems {
-		catalogResp = append(catalogResp, item.ToResponse(imagePrefix))
+		resp.Items = append(resp.Items, item.ToResponse(imagePrefix))
+	}
+
+	return resp, nil
+}
+
+func (c *CatalogService) Search(cxt context.Context, req *dto.CatalogSearchRequest, imagePrefix string) (*dto.CatalogSearchResponse, error) {
+	tsQuery := buildPrefixTsQuery(req.Query)
+
+	if tsQuery == "" {
+		return nil, customError.NewServiceError(http.StatusBadRequest, "Search query must contain letters or digits", nil)
+	}
+
+	results, err := c.CatalogRepository.Search(cxt, tsQuery, req.Limit, (req.Page-1)*req.Limit)
+
+	if err != nil {
+		logger.Log.Error("CatalogService -> Search -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	items := make([]*model.Catalog, 0, len(results))
+	for _, item := range results {
+		items = append(items, &item.Catalog)
+	}
+
+	if err = attachTags(cxt, c.CatalogRepository, items); err != nil {
+		logger.Log.Error("CatalogService -> Search -> attachTags -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
 	}
 
-	return catalogResp, nil
+	if err = c.attachFavorites(cxt, req.UserId, items); err != nil {
+		logger.Log.Error("CatalogService -> Search -> attachFavorites -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	resp := &dto.CatalogSearchResponse{
+		Items: []*dto.CatalogSearchItem{},
+		Page:  req.Page,
+		Limit: req.Limit,
+	}
+
+	for _, item := range results {
+		resp.Items = append(resp.Items, item.ToResponse(imagePrefix))
+	}
+
+	return resp, nil
+}
+
+func (c *CatalogService) SetTags(cxt context.Context, catalogId uint, req *dto.CatalogTagsRequest) error {
+	err := c.CatalogRepository.SetTags(cxt, catalogId, uniqueIds(req.TagIds))
+
+	if err != nil {
+		return c.handleTagsError(err, "SetTags")
+	}
+
+	return nil
+}
+
+func (c *CatalogService) AddTags(cxt context.Context, catalogId uint, req *dto.CatalogTagsRequest) error {
+	err := c.CatalogRepository.AddTags(cxt, catalogId, uniqueIds(req.TagIds))
+
+	if err != nil {
+		return c.handleTagsError(err, "AddTags")
+	}
+
+	return nil
+}
+
+func (c *CatalogService) RemoveTag(cxt context.Context, catalogId uint, tagId int64) error {
+	ok, err := c.CatalogRepository.RemoveTag(cxt, catalogId, tagId)
+
+	if err != nil {
+		return c.handleTagsError(err, "RemoveTag")
+	}
+
+	if !ok {
+		return customError.NewServiceError(http.StatusBadRequest, fmt.Sprintf("Tag %d is not attached to catalog item %d", tagId, catalogId), nil)
+	}
+
+	return nil
+}
+
+func (c *CatalogService) handleTagsError(err error, operation string) error {
+	if isForeignKeyError(err) {
+		return customError.NewServiceError(http.StatusBadRequest, "Catalog item or tag not found, please check provided ids", err)
+	}
+
+	logger.Log.Error("CatalogService -> " + operation + " -> err -> " + err.Error())
+	return customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+}
+
+// Подгружает теги для списка товаров одним запросом
+func attachTags(cxt context.Context, repo repository.ICatalogRepository, items []*model.Catalog) error {
+	tags, err := repo.FindTagsByCatalogIds(cxt, catalogIds(items))
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		item.Tags = tags[item.Id]
+	}
+
+	return nil
+}
+
+// Отмечает товары из избранного пользователя, анонимному ничего не отмечаем
+func (c *CatalogService) attachFavorites(cxt context.Context, userId int64, items []*model.Catalog) error {
+	if userId == 0 || len(items) == 0 {
+		return nil
+	}
+
+	favorites, err := c.favoriteRepository.FindFavoriteIds(cxt, userId, catalogIds(items))
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		item.IsFavorite = favorites[item.Id]
+	}
+
+	return nil
+}
+
+func catalogIds(items []*model.Catalog) []uint {
+	ids := make([]uint, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.Id)
+	}
+	return ids
+}
+
+func buildCatalogFilter(filter *dto.CatalogFilterRequest) *queryBuilder.SelectBuilder {
+	return queryBuilder.NewSelectBuilder("public.catalogs", true, repository.CatalogColumns...).
+		Where("category_id = ?", filter.CategoryId).
+		Where("EXISTS (SELECT 1 FROM public.catalog_tags ct WHERE ct.catalog_id = catalogs.id AND ct.tag_id = ?)", filter.TagId).
+		Where("price >= ?", filter.MinPrice).
+		Where("price <= ?", filter.MaxPrice).
+		WhereIf(filter.InStock, "amount > 0").
+		WhereIf(filter.Discounted, "discount_percent > 0")
 }
 
 func (c *CatalogService) getCatalogUniqFieldError(err error, catalog *model.Catalog) error {
@@ -4,6 +4,7 @@ import (
 	"arabic/internal/dto"
 	"arabic/internal/model"
 	"arabic/internal/service"
+	"arabic/pkg/customError"
 	"arabic/pkg/logger"
 	"context"
 	"errors"
@@ -32,6 +33,38 @@ func (m *MockICatalogRepository) Update(ctx context.Context, query string, value
 	args := m.Called(ctx, query, values)
 	return args.Get(0).(bool), args.Error(1)
 }
+func (m *MockICatalogRepository) UpdateWithNotifications(ctx context.Context, id uint, query string, values []any) (bool, error) {
+	args := m.Called(ctx, id, query, values)
+	return args.Get(0).(bool), args.Error(1)
+}
+func (m *MockICatalogRepository) FindMany(ctx context.Context, query string, values []any) ([]*model.Catalog, error) {
+	args := m.Called(ctx, query, values)
+	return args.Get(0).([]*model.Catalog), args.Error(1)
+}
+func (m *MockICatalogRepository) Count(ctx context.Context, query string, values []any) (int, error) {
+	args := m.Called(ctx, query, values)
+	return args.Int(0), args.Error(1)
+}
+func (m *MockICatalogRepository) Search(ctx context.Context, tsQuery string, limit, offset int) ([]*model.CatalogSearchResult, error) {
+	args := m.Called(ctx, tsQuery, limit, offset)
+	return args.Get(0).([]*model.CatalogSearchResult), args.Error(1)
+}
+func (m *MockICatalogRepository) FindTagsByCatalogIds(ctx context.Context, ids []uint) (map[uint][]*model.Tag, error) {
+	args := m.Called(ctx, ids)
+	return args.Get(0).(map[uint][]*model.Tag), args.Error(1)
+}
+func (m *MockICatalogRepository) SetTags(ctx context.Context, catalogId uint, tagIds []int64) error {
+	args := m.Called(ctx, catalogId, tagIds)
+	return args.Error(0)
+}
+func (m *MockICatalogRepository) AddTags(ctx context.Context, catalogId uint, tagIds []int64) error {
+	args := m.Called(ctx, catalogId, tagIds)
+	return args.Error(0)
+}
+func (m *MockICatalogRepository) RemoveTag(ctx context.Context, catalogId uint, tagId int64) (bool, error) {
+	args := m.Called(ctx, catalogId, tagId)
+	return args.Bool(0), args.Error(1)
+}
 func (m *MockICatalogRepository) FindById(ctx context.Context, id uint) (*model.Catalog, bool, error) {
 	args := m.Called(ctx, id)
 	return args.Get(0).(*model.Catalog), args.Get(1).(bool), args.Error(2)
@@ -156,7 +189,7 @@ func TestCatalogService_Update(t *testing.T) {
 
 			srv := service.CatalogService{CatalogRepository: mockRepo}
 
-			mockRepo.On("Update", mock.Anything, mock.Anything, mock.Anything).Return(tc.mockValue, tc.mockError)
+			mockRepo.On("UpdateWithNotifications", mock.Anything, mockData.Id, mock.Anything, mock.Anything).Return(tc.mockValue, tc.mockError)
 
 			err := srv.Update(context.Background(), mockData)
 
@@ -166,8 +199,8 @@ func TestCatalogService_Update(t *testing.T) {
 				assert.Nil(t, err)
 			}
 
-			mockRepo.AssertCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
-			mockRepo.AssertNumberOfCalls(t, "Update", 1)
+			mockRepo.AssertCalled(t, "UpdateWithNotifications", mock.Anything, mockData.Id, mock.Anything, mock.Anything)
+			mockRepo.AssertNumberOfCalls(t, "UpdateWithNotifications", 1)
 		})
 	}
 
@@ -180,48 +213,105 @@ func TestCatalogService_GetAll(t *testing.T) {
 	mockData := []*model.Catalog{generator(), generator(), generator()}
 
 	tests := []struct {
-		name       string
-		mockReturn []*model.Catalog
-		mockError  error
-		expectErr  bool
+		name           string
+		limit          int
+		cursor         string
+		mockReturn     []*model.Catalog
+		mockError      error
+		expectErr      bool
+		expectLen      int
+		expectNextPage bool
 	}{
 		{
 			name:       "success",
+			limit:      20,
 			mockReturn: mockData,
 			mockError:  nil,
 			expectErr:  false,
+			expectLen:  3,
+		},
+		{
+			name:           "has next page",
+			limit:          2,
+			mockReturn:     mockData,
+			expectLen:      2,
+			expectNextPage: true,
 		},
 		{
 			name:       "repo error",
+			limit:      20,
 			mockReturn: nil,
 			mockError:  errors.New("error case"),
 			expectErr:  true,
 		},
+		{
+			name:      "invalid cursor",
+			limit:     20,
+			cursor:    "@@@",
+			expectErr: true,
+		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			mockRepo := &MockICatalogRepository{}
-			mockRepo.On("FindAll", mock.Anything).Return(tc.mockReturn, tc.mockError)
+			mockRepo.On("Count", mock.Anything, mock.Anything, mock.Anything).Return(len(tc.mockReturn), nil)
+			mockRepo.On("FindMany", mock.Anything, mock.Anything, mock.Anything).Return(tc.mockReturn, tc.mockError)
+			mockRepo.On("FindTagsByCatalogIds", mock.Anything, mock.Anything).Return(map[uint][]*model.Tag{}, nil)
 
 			srv := &service.CatalogService{CatalogRepository: mockRepo}
-			result, err := srv.GetAll(context.Background(), "/test/")
+			result, err := srv.GetAll(context.Background(), &dto.CatalogFilterRequest{
+				Page:   1,
+				Limit:  tc.limit,
+				Sort:   "price",
+				Cursor: tc.cursor,
+			}, "/test/")
 
 			if tc.expectErr {
 				assert.Error(t, err)
 				assert.Nil(t, result)
-			} else {
-				assert.NoError(t, err)
-				assert.Len(t, result, len(tc.mockReturn))
-				assert.Equal(t, tc.mockReturn[0].Id, result[0].Id)
-				assert.IsType(t, &dto.CatalogResponse{}, result[0])
+				return
 			}
 
-			mockRepo.AssertCalled(t, "FindAll", mock.Anything)
+			assert.NoError(t, err)
+			assert.Len(t, result.Items, tc.expectLen)
+			assert.Equal(t, tc.mockReturn[0].Id, result.Items[0].Id)
+			assert.Equal(t, len(tc.mockReturn), result.Total)
+			assert.Equal(t, tc.expectNextPage, result.NextCursor != "")
+			mockRepo.AssertCalled(t, "FindMany", mock.Anything, mock.Anything, mock.Anything)
 		})
 	}
 }
 
+func TestCatalogService_GetAllCursor(t *testing.T) {
+	logger.Init("Error", "./")
+
+	generator := generateMockCatalogItems()
+	mockData := []*model.Catalog{generator(), generator(), generator()}
+
+	mockRepo := &MockICatalogRepository{}
+	mockRepo.On("Count", mock.Anything, mock.Anything, mock.Anything).Return(3, nil)
+	mockRepo.On("FindMany", mock.Anything, mock.Anything, mock.Anything).Return(mockData, nil)
+	mockRepo.On("FindTagsByCatalogIds", mock.Anything, mock.Anything).Return(map[uint][]*model.Tag{}, nil)
+
+	srv := &service.CatalogService{CatalogRepository: mockRepo}
+	first, err := srv.GetAll(context.Background(), &dto.CatalogFilterRequest{Page: 1, Limit: 2, Sort: "price"}, "/test/")
+	assert.NoError(t, err)
+
+	// Следующая страница строится по цене и id из курсора, без поиска последней записи в базе
+	_, err = srv.GetAll(context.Background(), &dto.CatalogFilterRequest{Page: 1, Limit: 2, Sort: "price", Cursor: first.NextCursor}, "/test/")
+	assert.NoError(t, err)
+
+	call := mockRepo.Calls[len(mockRepo.Calls)-2]
+	assert.Equal(t, "FindMany", call.Method)
+	assert.Contains(t, call.Arguments.String(1), "(price, id) > (CAST($1 AS numeric), $2)")
+	assert.Equal(t, []any{"150", uint(2)}, call.Arguments.Get(2))
+
+	// Курсор другой сортировки отклоняется
+	_, err = srv.GetAll(context.Background(), &dto.CatalogFilterRequest{Page: 1, Limit: 2, Sort: "name", Cursor: first.NextCursor}, "/test/")
+	assert.Error(t, err)
+}
+
 func TestCatalogService_Delete(t *testing.T) {
 	logger.Init("Error", "./")
 	generator := generateMockCatalogItems()
@@ -278,6 +368,27 @@ func TestCatalogService_Delete(t *testing.T) {
 
 }
 
+type MockIFavoriteRepository struct {
+	mock.Mock
+}
+
+func (m *MockIFavoriteRepository) Add(ctx context.Context, userId int64, catalogId uint) error {
+	args := m.Called(ctx, userId, catalogId)
+	return args.Error(0)
+}
+func (m *MockIFavoriteRepository) Remove(ctx context.Context, userId int64, catalogId uint) (bool, error) {
+	args := m.Called(ctx, userId, catalogId)
+	return args.Bool(0), args.Error(1)
+}
+func (m *MockIFavoriteRepository) FindAll(ctx context.Context, userId int64) ([]*model.Catalog, error) {
+	args := m.Called(ctx, userId)
+	return args.Get(0).([]*model.Catalog), args.Error(1)
+}
+func (m *MockIFavoriteRepository) FindFavoriteIds(ctx context.Context, userId int64, catalogIds []uint) (map[uint]bool, error) {
+	args := m.Called(ctx, userId, catalogIds)
+	return args.Get(0).(map[uint]bool), args.Error(1)
+}
+
 func TestCatalogService_GetById(t *testing.T) {
 	logger.Init("Error", "./")
 
@@ -290,6 +401,9 @@ func TestCatalogService_GetById(t *testing.T) {
 		mockError  error
 		mockOk     bool
 		expectErr  bool
+		// Пользователь из необязательного JWT
+		userId         int64
+		expectFavorite bool
 	}{
 		{
 			name:       "success",
@@ -298,6 +412,13 @@ func TestCatalogService_GetById(t *testing.T) {
 			expectErr:  false,
 			mockOk:     true,
 		},
+		{
+			name:           "success favorite of authorized user",
+			mockReturn:     mockData,
+			mockOk:         true,
+			userId:         7,
+			expectFavorite: true,
+		},
 		{
 			name:       "repo error",
 			mockReturn: nil,
@@ -317,12 +438,13 @@ func TestCatalogService_GetById(t *testing.T) {
 	for _, tc := range test {
 		t.Run(tc.name, func(t *testing.T) {
 			mockRepo := &MockICatalogRepository{}
-			srv := service.CatalogService{
-				CatalogRepository: mockRepo,
-			}
+			favoriteRepo := &MockIFavoriteRepository{}
+			srv := service.NewCatalogService(mockRepo, favoriteRepo)
 			mockRepo.On("FindById", mock.Anything, mock.Anything).Return(tc.mockReturn, tc.mockOk, tc.mockError)
+			mockRepo.On("FindTagsByCatalogIds", mock.Anything, mock.Anything).Return(map[uint][]*model.Tag{mockData.Id: {{Id: 1, Name: "Новинка"}}}, nil)
+			favoriteRepo.On("FindFavoriteIds", mock.Anything, int64(7), []uint{mockData.Id}).Return(map[uint]bool{mockData.Id: true}, nil)
 
-			item, err := srv.GetById(context.Background(), mockData.Id, "/test/")
+			item, err := srv.GetById(context.Background(), mockData.Id, tc.userId, "/test/")
 
 			if tc.expectErr {
 				assert.Error(t, err)
@@ -331,10 +453,130 @@ func TestCatalogService_GetById(t *testing.T) {
 				assert.NoError(t, err)
 				assert.Equal(t, item.Id, mockData.Id)
 				assert.IsType(t, &dto.CatalogResponse{}, item)
+				assert.Len(t, item.Tags, 1)
+				assert.Equal(t, tc.expectFavorite, item.IsFavorite)
 			}
 
 			mockRepo.AssertCalled(t, "FindById", mock.Anything, mock.Anything)
 			mockRepo.AssertNumberOfCalls(t, "FindById", 1)
+			if tc.userId == 0 {
+				favoriteRepo.AssertNotCalled(t, "FindFavoriteIds", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestCatalogService_Search(t *testing.T) {
+	logger.Init("Error", "./")
+
+	generator := generateMockCatalogItems()
+	mockData := []*model.CatalogSearchResult{{Catalog: *generator(), Rank: 0.5, NameHighlight: "<mark>Яблоки</mark>"}}
+
+	tests := []struct {
+		name        string
+		query       string
+		expectQuery string
+		mockError   error
+		expectErr   bool
+	}{
+		{
+			name:        "prefix query",
+			query:       "Яблок  зелён!",
+			expectQuery: "яблок:* & зелён:*",
+		},
+		{
+			name:      "only special characters",
+			query:     "&|!:*",
+			expectErr: true,
+		},
+		{
+			name:        "repo error",
+			query:       "яблок",
+			expectQuery: "яблок:*",
+			mockError:   errors.New("error case"),
+			expectErr:   true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockRepo := &MockICatalogRepository{}
+			mockRepo.On("Search", mock.Anything, tc.expectQuery, 10, 0).Return(mockData, tc.mockError)
+			mockRepo.On("FindTagsByCatalogIds", mock.Anything, mock.Anything).Return(map[uint][]*model.Tag{}, nil)
+
+			srv := &service.CatalogService{CatalogRepository: mockRepo}
+			result, err := srv.Search(context.Background(), &dto.CatalogSearchRequest{Query: tc.query, Page: 1, Limit: 10}, "/test/")
+
+			if tc.expectErr {
+				assert.Error(t, err)
+				assert.Nil(t, result)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Len(t, result.Items, 1)
+			assert.Equal(t, mockData[0].NameHighlight, result.Items[0].NameHighlight)
+			mockRepo.AssertCalled(t, "Search", mock.Anything, tc.expectQuery, 10, 0)
+		})
+	}
+}
+
+func TestCatalogService_SetTags(t *testing.T) {
+	logger.Init("Error", "./")
+
+	tests := []struct {
+		name       string
+		tagIds     []int64
+		expectIds  []int64
+		mockError  error
+		expectErr  bool
+		expectCode int
+	}{
+		{
+			name:      "deduplicates ids",
+			tagIds:    []int64{3, 1, 3},
+			expectIds: []int64{1, 3},
+		},
+		{
+			name:      "clear tags",
+			tagIds:    nil,
+			expectIds: []int64{},
+		},
+		{
+			name:       "unknown tag",
+			tagIds:     []int64{99},
+			expectIds:  []int64{99},
+			mockError:  errors.New(`violates foreign key constraint "fk_tag"`),
+			expectErr:  true,
+			expectCode: 400,
+		},
+		{
+			name:       "repo error",
+			tagIds:     []int64{1},
+			expectIds:  []int64{1},
+			mockError:  errors.New("error case"),
+			expectErr:  true,
+			expectCode: 500,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockRepo := &MockICatalogRepository{}
+			mockRepo.On("SetTags", mock.Anything, uint(1), tc.expectIds).Return(tc.mockError)
+
+			srv := &service.CatalogService{CatalogRepository: mockRepo}
+			err := srv.SetTags(context.Background(), 1, &dto.CatalogTagsRequest{TagIds: tc.tagIds})
+
+			if tc.expectErr {
+				var serviceErr *customError.ServiceError
+				assert.ErrorAs(t, err, &serviceErr)
+				assert.Equal(t, tc.expectCode, serviceErr.Code)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			mockRepo.AssertCalled(t, "SetTags", mock.Anything, uint(1), tc.expectIds)
 		})
 	}
 }
@@ -0,0 +1,270 @@
+package service
+
+import (
+	"arabic/internal/dto"
+	"arabic/internal/model"
+	"arabic/internal/repository"
+	"arabic/pkg/customError"
+	"arabic/pkg/geo"
+	"arabic/pkg/logger"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// На сколько дней вперед можно смотреть и бронировать слоты
+const maxSlotDaysAhead = 30
+
+// Как часто фоновая задача дополняет слоты на maxSlotDaysAhead дней вперед
+const slotGenerationInterval = time.Hour
+
+type IDeliveryService interface {
+	CreateZone(ctx context.Context, req *dto.DeliveryZoneRequest) (*dto.DeliveryZoneResponse, error)
+	UpdateZone(ctx context.Context, id int64, req *dto.DeliveryZoneRequest) (*dto.DeliveryZoneResponse, error)
+	Quote(ctx context.Context, req *dto.DeliveryQuoteRequest) (*dto.DeliveryQuoteResponse, error)
+	GetZones(ctx context.Context) ([]*dto.DeliveryZoneResponse, error)
+	CreateTemplate(ctx context.Context, req *dto.SlotTemplateRequest) (*dto.SlotTemplateResponse, error)
+	GetTemplates(ctx context.Context, zoneId int64) ([]*dto.SlotTemplateResponse, error)
+	DeleteTemplate(ctx context.Context, id int64) error
+	GetSlots(ctx context.Context, zoneId int64, date string) ([]*dto.DeliverySlotResponse, error)
+}
+
+type DeliveryService struct {
+	deliveryRepository repository.IDeliveryRepository
+	catalogRepository  repository.ICatalogRepository
+}
+
+func NewDeliveryService(deliveryRepo repository.IDeliveryRepository, catalogRepo repository.ICatalogRepository) *DeliveryService {
+	return &DeliveryService{
+		deliveryRepository: deliveryRepo,
+		catalogRepository:  catalogRepo,
+	}
+}
+
+func (s *DeliveryService) CreateZone(ctx context.Context, req *dto.DeliveryZoneRequest) (*dto.DeliveryZoneResponse, error) {
+	zone := newDeliveryZone(req)
+
+	if err := s.deliveryRepository.CreateZone(ctx, zone); err != nil {
+		if isDuplicateError(err) {
+			return nil, customError.NewServiceError(http.StatusConflict, fmt.Sprintf("Delivery zone %s already exists", req.Name), err)
+		}
+		logger.Log.Error("DeliveryService -> CreateZone -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	return zone.ToResponse(), nil
+}
+
+func (s *DeliveryService) UpdateZone(ctx context.Context, id int64, req *dto.DeliveryZoneRequest) (*dto.DeliveryZoneResponse, error) {
+	zone := newDeliveryZone(req)
+	zone.Id = id
+
+	ok, err := s.deliveryRepository.UpdateZone(ctx, zone, req.IsActive)
+
+	if err != nil {
+		if isDuplicateError(err) {
+			return nil, customError.NewServiceError(http.StatusConflict, fmt.Sprintf("Delivery zone %s already exists", req.Name), err)
+		}
+		logger.Log.Error("DeliveryService -> UpdateZone -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	if !ok {
+		return nil, customError.NewServiceError(http.StatusNotFound, customError.ErrorNotFoundById, nil)
+	}
+
+	return zone.ToResponse(), nil
+}
+
+// Определяет зону по координатам и считает стоимость доставки для переданных товаров
+func (s *DeliveryService) Quote(ctx context.Context, req *dto.DeliveryQuoteRequest) (*dto.DeliveryQuoteResponse, error) {
+	zones, err := s.deliveryRepository.FindZones(ctx)
+
+	if err != nil {
+		logger.Log.Error("DeliveryService -> Quote -> FindZones -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	zone, ok := model.FindDeliveryZone(zones, geo.Point{Lat: *req.Latitude, Lon: *req.Longitude})
+	if !ok {
+		return nil, customError.NewServiceError(http.StatusNotFound, "Sorry, we do not deliver to this address yet", nil)
+	}
+
+	var amount, weight float64
+	for _, item := range mergeOrderItems(req.Items) {
+		catalog, ok, err := s.catalogRepository.FindById(ctx, item.CatalogId)
+
+		if err != nil {
+			logger.Log.Error("DeliveryService -> Quote -> FindById -> err -> " + err.Error())
+			return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+		}
+
+		if !ok {
+			return nil, customError.NewServiceError(http.StatusBadRequest, fmt.Sprintf("Catalog item %d not found", item.CatalogId), nil)
+		}
+
+		amount += float64(model.DiscountedPrice(catalog.Price, catalog.DiscountPercent)) * float64(item.Quantity)
+		weight += float64(catalog.Weight) * float64(item.Quantity)
+	}
+
+	itemsTotal := model.RoundMoney(amount)
+
+	return &dto.DeliveryQuoteResponse{
+		ZoneId:                zone.Id,
+		ZoneName:              zone.Name,
+		ItemsTotal:            itemsTotal,
+		Weight:                float32(weight),
+		DeliveryFee:           zone.Fee(itemsTotal, float32(weight)),
+		MinOrderAmount:        zone.MinOrderAmount,
+		MinOrderReached:       itemsTotal >= zone.MinOrderAmount,
+		FreeDeliveryThreshold: zone.FreeDeliveryThreshold,
+		AmountToFreeDelivery:  zone.AmountToFreeDelivery(itemsTotal),
+		EtaMinutes:            zone.EtaMinutes,
+		EstimatedAt:           time.Now().Add(time.Duration(zone.EtaMinutes) * time.Minute),
+	}, nil
+}
+
+func (s *DeliveryService) GetZones(ctx context.Context) ([]*dto.DeliveryZoneResponse, error) {
+	zones, err := s.deliveryRepository.FindZones(ctx)
+
+	if err != nil {
+		logger.Log.Error("DeliveryService -> GetZones -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	resp := make([]*dto.DeliveryZoneResponse, 0, len(zones))
+	for _, zone := range zones {
+		resp = append(resp, zone.ToResponse())
+	}
+
+	return resp, nil
+}
+
+func (s *DeliveryService) CreateTemplate(ctx context.Context, req *dto.SlotTemplateRequest) (*dto.SlotTemplateResponse, error) {
+	template := &model.DeliverySlotTemplate{
+		ZoneId:    req.ZoneId,
+		Weekday:   req.Weekday,
+		StartTime: req.StartTime,
+		EndTime:   req.EndTime,
+		Capacity:  req.Capacity,
+	}
+
+	if err := s.deliveryRepository.CreateTemplate(ctx, template); err != nil {
+		if isForeignKeyError(err) {
+			return nil, customError.NewServiceError(http.StatusBadRequest, fmt.Sprintf("Delivery zone %d not found", req.ZoneId), err)
+		}
+		if isDuplicateError(err) {
+			return nil, customError.NewServiceError(http.StatusConflict, "Slot with this start time already exists for the zone and weekday", err)
+		}
+		logger.Log.Error("DeliveryService -> CreateTemplate -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	// Слоты нового шаблона доступны сразу, не дожидаясь фоновой задачи
+	_ = s.GenerateSlots(ctx)
+
+	return template.ToResponse(), nil
+}
+
+func (s *DeliveryService) GetTemplates(ctx context.Context, zoneId int64) ([]*dto.SlotTemplateResponse, error) {
+	templates, err := s.deliveryRepository.FindTemplates(ctx, zoneId)
+
+	if err != nil {
+		logger.Log.Error("DeliveryService -> GetTemplates -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	resp := make([]*dto.SlotTemplateResponse, 0, len(templates))
+	for _, template := range templates {
+		resp = append(resp, template.ToResponse())
+	}
+
+	return resp, nil
+}
+
+// Уже созданные по шаблону слоты и их брони остаются
+func (s *DeliveryService) DeleteTemplate(ctx context.Context, id int64) error {
+	ok, err := s.deliveryRepository.DeleteTemplate(ctx, id)
+
+	if err != nil {
+		logger.Log.Error("DeliveryService -> DeleteTemplate -> err -> " + err.Error())
+		return customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	if !ok {
+		return customError.NewServiceError(http.StatusNotFound, customError.ErrorNotFoundById, nil)
+	}
+
+	return nil
+}
+
+// Слоты на дату с оставшейся вместимостью. zoneId = 0 - по всем зонам
+func (s *DeliveryService) GetSlots(ctx context.Context, zoneId int64, date string) ([]*dto.DeliverySlotResponse, error) {
+	day, err := time.ParseInLocation(time.DateOnly, date, time.Local)
+	if err != nil {
+		return nil, customError.NewServiceError(http.StatusBadRequest, "Date must be in YYYY-MM-DD format", err)
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+
+	if day.Before(today) || day.After(today.AddDate(0, 0, maxSlotDaysAhead)) {
+		return nil, customError.NewServiceError(http.StatusBadRequest, fmt.Sprintf("Date must be within the next %d days", maxSlotDaysAhead), nil)
+	}
+
+	slots, err := s.deliveryRepository.FindSlots(ctx, zoneId, day)
+
+	if err != nil {
+		logger.Log.Error("DeliveryService -> GetSlots -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	resp := make([]*dto.DeliverySlotResponse, 0, len(slots))
+	for _, slot := range slots {
+		resp = append(resp, slot.ToResponse())
+	}
+
+	return resp, nil
+}
+
+// Создает слоты из шаблонов, пока не отменен ctx. GET слотов только читает созданное здесь
+func (s *DeliveryService) RunSlotGeneration(ctx context.Context) {
+	ticker := time.NewTicker(slotGenerationInterval)
+	defer ticker.Stop()
+
+	for {
+		_ = s.GenerateSlots(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Дополняет слоты с сегодняшнего дня на maxSlotDaysAhead дней вперед
+func (s *DeliveryService) GenerateSlots(ctx context.Context) error {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+
+	if _, err := s.deliveryRepository.GenerateSlots(ctx, today, today.AddDate(0, 0, maxSlotDaysAhead)); err != nil {
+		logger.Log.Error("DeliveryService -> GenerateSlots -> err -> " + err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func newDeliveryZone(req *dto.DeliveryZoneRequest) *model.DeliveryZone {
+	return &model.DeliveryZone{
+		Name:                  req.Name,
+		Polygon:               req.Polygon,
+		MinOrderAmount:        req.MinOrderAmount,
+		BaseFee:               req.BaseFee,
+		FreeDeliveryThreshold: req.FreeDeliveryThreshold,
+		PerKgFee:              req.PerKgFee,
+		EtaMinutes:            req.EtaMinutes,
+	}
+}
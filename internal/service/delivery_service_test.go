@@ -0,0 +1,162 @@
+package service_test
+
+import (
+	"arabic/internal/dto"
+	"arabic/internal/model"
+	"arabic/internal/service"
+	"arabic/pkg/customError"
+	"arabic/pkg/geo"
+	"arabic/pkg/logger"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+type MockIDeliveryRepository struct {
+	mock.Mock
+}
+
+func (m *MockIDeliveryRepository) CreateZone(ctx context.Context, zone *model.DeliveryZone) error {
+	args := m.Called(ctx, zone)
+	return args.Error(0)
+}
+func (m *MockIDeliveryRepository) UpdateZone(ctx context.Context, zone *model.DeliveryZone, isActive *bool) (bool, error) {
+	args := m.Called(ctx, zone, isActive)
+	return args.Bool(0), args.Error(1)
+}
+func (m *MockIDeliveryRepository) FindZones(ctx context.Context) ([]*model.DeliveryZone, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*model.DeliveryZone), args.Error(1)
+}
+func (m *MockIDeliveryRepository) CreateTemplate(ctx context.Context, template *model.DeliverySlotTemplate) error {
+	args := m.Called(ctx, template)
+	return args.Error(0)
+}
+func (m *MockIDeliveryRepository) FindTemplates(ctx context.Context, zoneId int64) ([]*model.DeliverySlotTemplate, error) {
+	args := m.Called(ctx, zoneId)
+	return args.Get(0).([]*model.DeliverySlotTemplate), args.Error(1)
+}
+func (m *MockIDeliveryRepository) DeleteTemplate(ctx context.Context, id int64) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+func (m *MockIDeliveryRepository) FindSlots(ctx context.Context, zoneId int64, date time.Time) ([]*model.DeliverySlot, error) {
+	args := m.Called(ctx, zoneId, date)
+	return args.Get(0).([]*model.DeliverySlot), args.Error(1)
+}
+func (m *MockIDeliveryRepository) GenerateSlots(ctx context.Context, from, to time.Time) (int64, error) {
+	args := m.Called(ctx, from, to)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// Зона вокруг центра Грозного: доставка 100 + 20 за кг, бесплатно от 2000
+func testDeliveryZone(t *testing.T) *model.DeliveryZone {
+	polygon := []byte(`{"type": "Polygon", "coordinates": [[[45.60, 43.25], [45.80, 43.25], [45.80, 43.40], [45.60, 43.40], [45.60, 43.25]]]}`)
+	area, err := geo.ParseGeoJSON(polygon)
+	assert.NoError(t, err)
+
+	return &model.DeliveryZone{
+		Id:                    3,
+		Name:                  "Center",
+		IsActive:              true,
+		Polygon:               polygon,
+		Area:                  area,
+		MinOrderAmount:        500,
+		BaseFee:               100,
+		FreeDeliveryThreshold: 2000,
+		PerKgFee:              20,
+		EtaMinutes:            45,
+	}
+}
+
+func TestDeliveryService_Quote(t *testing.T) {
+	logger.Init("Error", "./")
+
+	lat, lon, farLat := 43.30, 45.65, 44.0
+
+	tests := []struct {
+		name           string
+		latitude       float64
+		quantity       int
+		expectFee      float32
+		expectMinOrder bool
+		expectCode     int
+	}{
+		{
+			name:           "below min order pays base fee and weight",
+			latitude:       lat,
+			quantity:       1,
+			expectFee:      140,
+			expectMinOrder: false,
+		},
+		{
+			name:           "free delivery over threshold",
+			latitude:       lat,
+			quantity:       5,
+			expectFee:      0,
+			expectMinOrder: true,
+		},
+		{
+			name:       "outside zones",
+			latitude:   farLat,
+			quantity:   1,
+			expectCode: 404,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			deliveryRepo := &MockIDeliveryRepository{}
+			deliveryRepo.On("FindZones", mock.Anything).Return([]*model.DeliveryZone{testDeliveryZone(t)}, nil)
+
+			// 450 за штуку, 2 кг
+			catalogRepo := &MockICatalogRepository{}
+			catalogRepo.On("FindById", mock.Anything, uint(7)).Return(&model.Catalog{Id: 7, Price: 500, DiscountPercent: 10, Weight: 2000}, true, nil)
+
+			srv := service.NewDeliveryService(deliveryRepo, catalogRepo)
+			quote, err := srv.Quote(context.Background(), &dto.DeliveryQuoteRequest{
+				Latitude:  &tc.latitude,
+				Longitude: &lon,
+				Items:     []dto.CartItemRequest{{CatalogId: 7, Quantity: tc.quantity}},
+			})
+
+			if tc.expectCode != 0 {
+				var serviceErr *customError.ServiceError
+				assert.ErrorAs(t, err, &serviceErr)
+				assert.Equal(t, tc.expectCode, serviceErr.Code)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, int64(3), quote.ZoneId)
+			assert.Equal(t, tc.expectFee, quote.DeliveryFee)
+			assert.Equal(t, tc.expectMinOrder, quote.MinOrderReached)
+			assert.Equal(t, 45, quote.EtaMinutes)
+		})
+	}
+}
+
+func TestDeliveryService_GenerateSlots(t *testing.T) {
+	logger.Init("Error", "./")
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+
+	deliveryRepo := &MockIDeliveryRepository{}
+	deliveryRepo.On("GenerateSlots", mock.Anything, today, today.AddDate(0, 0, 30)).Return(int64(4), nil)
+	deliveryRepo.On("CreateTemplate", mock.Anything, mock.Anything).Return(nil)
+	deliveryRepo.On("FindSlots", mock.Anything, int64(3), today).Return([]*model.DeliverySlot{}, nil)
+
+	srv := service.NewDeliveryService(deliveryRepo, &MockICatalogRepository{})
+
+	// Новый шаблон сразу дополняет слоты, а чтение слотов ничего не создает
+	_, err := srv.CreateTemplate(context.Background(), &dto.SlotTemplateRequest{ZoneId: 3, Weekday: 1, StartTime: "10:00", EndTime: "12:00", Capacity: 5})
+	assert.NoError(t, err)
+	deliveryRepo.AssertNumberOfCalls(t, "GenerateSlots", 1)
+
+	_, err = srv.GetSlots(context.Background(), 3, today.Format(time.DateOnly))
+	assert.NoError(t, err)
+	deliveryRepo.AssertNumberOfCalls(t, "GenerateSlots", 1)
+}
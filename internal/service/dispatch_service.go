@@ -0,0 +1,254 @@
+package service
+
+import (
+	"arabic/internal/dto"
+	"arabic/internal/model"
+	"arabic/internal/repository"
+	"arabic/pkg/customError"
+	"arabic/pkg/geo"
+	"arabic/pkg/logger"
+	"arabic/pkg/routing"
+	"context"
+	"errors"
+	"net/http"
+)
+
+type IDispatchService interface {
+	Run(ctx context.Context, dispatcherId int64) (*dto.DispatchResponse, error)
+	GetRoutes(ctx context.Context) ([]*dto.DeliveryRouteResponse, error)
+	GetCouriers(ctx context.Context) ([]*dto.CourierResponse, error)
+	UpdateCourier(ctx context.Context, courierId int64, req *dto.CourierProfileRequest) error
+	GetCourierRoute(ctx context.Context, courierId int64) (*dto.DeliveryRouteResponse, error)
+}
+
+type DispatchService struct {
+	dispatchRepository repository.IDispatchRepository
+	tracker            IOrderTracker
+	depot              geo.Point
+}
+
+func NewDispatchService(dispatchRepo repository.IDispatchRepository, tracker IOrderTracker, config *routing.Config) *DispatchService {
+	return &DispatchService{
+		dispatchRepository: dispatchRepo,
+		tracker:            tracker,
+		depot:              config.Depot(),
+	}
+}
+
+// Распределяет заказы в пути без курьера по свободным курьерам и строит маршруты
+func (s *DispatchService) Run(ctx context.Context, dispatcherId int64) (*dto.DispatchResponse, error) {
+	orders, err := s.dispatchRepository.FindDispatchOrders(ctx)
+	if err != nil {
+		logger.Log.Error("DispatchService -> Run -> FindDispatchOrders -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	couriers, err := s.dispatchRepository.FindCouriers(ctx)
+	if err != nil {
+		logger.Log.Error("DispatchService -> Run -> FindCouriers -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	routes, unassigned := planRoutes(s.depot, orders, couriers)
+	for _, route := range routes {
+		route.CreatedBy = dispatcherId
+	}
+
+	if len(routes) > 0 {
+		err = s.dispatchRepository.SaveRoutes(ctx, routes)
+
+		if errors.Is(err, repository.ErrDispatchConflict) {
+			return nil, customError.NewServiceError(http.StatusConflict, "Orders or couriers were changed during dispatch, please run it again", err)
+		}
+		if err != nil {
+			logger.Log.Error("DispatchService -> Run -> SaveRoutes -> err -> " + err.Error())
+			return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+		}
+
+		s.tracker.RoutesCreated(ctx, routes)
+	}
+
+	resp := &dto.DispatchResponse{
+		Routes:           make([]*dto.DeliveryRouteResponse, 0, len(routes)),
+		UnassignedOrders: unassigned,
+	}
+	for _, route := range routes {
+		resp.Routes = append(resp.Routes, route.ToResponse())
+	}
+
+	return resp, nil
+}
+
+func (s *DispatchService) GetRoutes(ctx context.Context) ([]*dto.DeliveryRouteResponse, error) {
+	routes, err := s.dispatchRepository.FindActiveRoutes(ctx)
+
+	if err != nil {
+		logger.Log.Error("DispatchService -> GetRoutes -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	resp := make([]*dto.DeliveryRouteResponse, 0, len(routes))
+	for _, route := range routes {
+		resp = append(resp, route.ToResponse())
+	}
+
+	return resp, nil
+}
+
+func (s *DispatchService) GetCouriers(ctx context.Context) ([]*dto.CourierResponse, error) {
+	couriers, err := s.dispatchRepository.FindCouriers(ctx)
+
+	if err != nil {
+		logger.Log.Error("DispatchService -> GetCouriers -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	resp := make([]*dto.CourierResponse, 0, len(couriers))
+	for _, courier := range couriers {
+		resp = append(resp, courier.ToResponse())
+	}
+
+	return resp, nil
+}
+
+func (s *DispatchService) UpdateCourier(ctx context.Context, courierId int64, req *dto.CourierProfileRequest) error {
+	ok, err := s.dispatchRepository.SaveCourierProfile(ctx, &model.CourierProfile{
+		UserId:      courierId,
+		MaxOrders:   req.MaxOrders,
+		MaxWeight:   req.MaxWeight,
+		IsAvailable: req.IsAvailable,
+	})
+
+	if err != nil {
+		logger.Log.Error("DispatchService -> UpdateCourier -> err -> " + err.Error())
+		return customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	if !ok {
+		return customError.NewServiceError(http.StatusNotFound, "Courier not found", nil)
+	}
+
+	return nil
+}
+
+func (s *DispatchService) GetCourierRoute(ctx context.Context, courierId int64) (*dto.DeliveryRouteResponse, error) {
+	route, ok, err := s.dispatchRepository.FindActiveRoute(ctx, courierId)
+
+	if err != nil {
+		logger.Log.Error("DispatchService -> GetCourierRoute -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	if !ok {
+		return nil, customError.NewServiceError(http.StatusNotFound, "You have no active route", nil)
+	}
+
+	return route.ToResponse(), nil
+}
+
+// Группирует заказы по зоне и слоту (раньше начинающиеся слоты - первыми) и набирает
+// каждому свободному курьеру по одному маршруту в пределах его вместимости.
+// Возвращает маршруты и id заказов, которые не удалось назначить
+func planRoutes(depot geo.Point, orders []*model.DispatchOrder, couriers []*model.CourierProfile) ([]*model.DeliveryRoute, []int64) {
+	type batchKey struct {
+		zoneId int64
+		slotId int64
+	}
+
+	var keys []batchKey
+	batches := make(map[batchKey][]*model.DispatchOrder)
+	for _, order := range orders {
+		key := batchKey{zoneId: order.ZoneId}
+		if order.SlotId != nil {
+			key.slotId = *order.SlotId
+		}
+		if _, ok := batches[key]; !ok {
+			keys = append(keys, key)
+		}
+		batches[key] = append(batches[key], order)
+	}
+
+	used := make([]bool, len(couriers))
+	var routes []*model.DeliveryRoute
+	unassigned := make([]int64, 0)
+
+	for _, key := range keys {
+		remaining := batches[key]
+
+		for i, courier := range couriers {
+			if len(remaining) == 0 {
+				break
+			}
+			if used[i] || !courier.CanTakeRoute() {
+				continue
+			}
+
+			var taken []*model.DispatchOrder
+			taken, remaining = fillCourier(depot, remaining, courier)
+			if len(taken) == 0 {
+				continue
+			}
+
+			used[i] = true
+			routes = append(routes, buildRoute(depot, courier, taken))
+		}
+
+		for _, order := range remaining {
+			unassigned = append(unassigned, order.Id)
+		}
+	}
+
+	return routes, unassigned
+}
+
+// Набирает заказы в порядке объезда, пропуская те, что не влезают по количеству или весу
+func fillCourier(depot geo.Point, orders []*model.DispatchOrder, courier *model.CourierProfile) ([]*model.DispatchOrder, []*model.DispatchOrder) {
+	var taken, rest []*model.DispatchOrder
+	var weight float32
+
+	for _, idx := range routing.PlanRoute(depot, orderPoints(orders)) {
+		order := orders[idx]
+		if len(taken) < courier.MaxOrders && weight+order.Weight <= courier.MaxWeightGrams() {
+			taken = append(taken, order)
+			weight += order.Weight
+			continue
+		}
+		rest = append(rest, order)
+	}
+
+	return taken, rest
+}
+
+func buildRoute(depot geo.Point, courier *model.CourierProfile, orders []*model.DispatchOrder) *model.DeliveryRoute {
+	points := orderPoints(orders)
+	sequence := routing.PlanRoute(depot, points)
+
+	route := &model.DeliveryRoute{
+		CourierId: courier.UserId,
+		ZoneId:    &orders[0].ZoneId,
+		SlotId:    orders[0].SlotId,
+		Distance:  routing.Distance(depot, points, sequence),
+		Stops:     make([]*model.RouteStop, 0, len(orders)),
+	}
+
+	for position, idx := range sequence {
+		order := orders[idx]
+		route.TotalWeight += order.Weight
+		route.Stops = append(route.Stops, &model.RouteStop{
+			Position: position + 1,
+			OrderId:  order.Id,
+			Status:   model.OrderStatusOutForDelivery,
+			Address:  order.Address,
+		})
+	}
+
+	return route
+}
+
+func orderPoints(orders []*model.DispatchOrder) []geo.Point {
+	points := make([]geo.Point, 0, len(orders))
+	for _, order := range orders {
+		points = append(points, order.Location)
+	}
+	return points
+}
@@ -0,0 +1,152 @@
+package service_test
+
+import (
+	"arabic/internal/model"
+	"arabic/internal/repository"
+	"arabic/internal/service"
+	"arabic/pkg/customError"
+	"arabic/pkg/geo"
+	"arabic/pkg/logger"
+	"arabic/pkg/routing"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+)
+
+type MockIDispatchRepository struct {
+	mock.Mock
+}
+
+func (m *MockIDispatchRepository) FindCouriers(ctx context.Context) ([]*model.CourierProfile, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*model.CourierProfile), args.Error(1)
+}
+func (m *MockIDispatchRepository) SaveCourierProfile(ctx context.Context, profile *model.CourierProfile) (bool, error) {
+	args := m.Called(ctx, profile)
+	return args.Bool(0), args.Error(1)
+}
+func (m *MockIDispatchRepository) FindDispatchOrders(ctx context.Context) ([]*model.DispatchOrder, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*model.DispatchOrder), args.Error(1)
+}
+func (m *MockIDispatchRepository) SaveRoutes(ctx context.Context, routes []*model.DeliveryRoute) error {
+	args := m.Called(ctx, routes)
+	return args.Error(0)
+}
+func (m *MockIDispatchRepository) FindActiveRoutes(ctx context.Context) ([]*model.DeliveryRoute, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*model.DeliveryRoute), args.Error(1)
+}
+func (m *MockIDispatchRepository) FindActiveRoute(ctx context.Context, courierId int64) (*model.DeliveryRoute, bool, error) {
+	args := m.Called(ctx, courierId)
+	if args.Get(0) == nil {
+		return nil, args.Bool(1), args.Error(2)
+	}
+	return args.Get(0).(*model.DeliveryRoute), args.Bool(1), args.Error(2)
+}
+
+func (m *MockIDispatchRepository) FindRouteByOrder(ctx context.Context, orderId int64) (*model.DeliveryRoute, bool, error) {
+	args := m.Called(ctx, orderId)
+	if args.Get(0) == nil {
+		return nil, args.Bool(1), args.Error(2)
+	}
+	return args.Get(0).(*model.DeliveryRoute), args.Bool(1), args.Error(2)
+}
+
+func TestDispatchService_Run(t *testing.T) {
+	logger.Init("Error", "./")
+
+	slotA, slotB := int64(1), int64(2)
+	newOrder := func(id int64, slot *int64, lon float64, weight float32) *model.DispatchOrder {
+		return &model.DispatchOrder{Id: id, ZoneId: 3, SlotId: slot, Location: geo.Point{Lat: 43.30, Lon: lon}, Weight: weight}
+	}
+
+	tests := []struct {
+		name             string
+		orders           []*model.DispatchOrder
+		couriers         []*model.CourierProfile
+		saveError        error
+		expectRoutes     [][]int64
+		expectUnassigned []int64
+		expectCode       int
+	}{
+		{
+			name: "orders are split by slot and sorted by distance",
+			orders: []*model.DispatchOrder{
+				newOrder(10, &slotA, 45.63, 1000),
+				newOrder(11, &slotA, 45.61, 1000),
+				newOrder(12, &slotB, 45.62, 1000),
+			},
+			couriers: []*model.CourierProfile{
+				{UserId: 5, MaxOrders: 10, MaxWeight: 30, IsAvailable: true},
+				{UserId: 6, MaxOrders: 10, MaxWeight: 30, IsAvailable: true},
+			},
+			expectRoutes:     [][]int64{{11, 10}, {12}},
+			expectUnassigned: []int64{},
+		},
+		{
+			name: "courier capacity by count and weight",
+			orders: []*model.DispatchOrder{
+				newOrder(10, &slotA, 45.61, 1000),
+				newOrder(11, &slotA, 45.62, 25000),
+				newOrder(12, &slotA, 45.63, 1000),
+				newOrder(13, &slotA, 45.64, 1000),
+			},
+			couriers: []*model.CourierProfile{
+				{UserId: 5, MaxOrders: 2, MaxWeight: 20, IsAvailable: true},
+			},
+			expectRoutes:     [][]int64{{10, 12}},
+			expectUnassigned: []int64{11, 13},
+		},
+		{
+			name:   "busy and unavailable couriers are skipped",
+			orders: []*model.DispatchOrder{newOrder(10, nil, 45.61, 1000)},
+			couriers: []*model.CourierProfile{
+				{UserId: 5, MaxOrders: 10, MaxWeight: 30, IsAvailable: true, Busy: true},
+				{UserId: 6, MaxOrders: 10, MaxWeight: 30, IsAvailable: false},
+			},
+			expectRoutes:     nil,
+			expectUnassigned: []int64{10},
+		},
+		{
+			name:   "conflict on save",
+			orders: []*model.DispatchOrder{newOrder(10, nil, 45.61, 1000)},
+			couriers: []*model.CourierProfile{
+				{UserId: 5, MaxOrders: 10, MaxWeight: 30, IsAvailable: true},
+			},
+			saveError:  repository.ErrDispatchConflict,
+			expectCode: 409,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &MockIDispatchRepository{}
+			repo.On("FindDispatchOrders", mock.Anything).Return(tc.orders, nil)
+			repo.On("FindCouriers", mock.Anything).Return(tc.couriers, nil)
+			repo.On("SaveRoutes", mock.Anything, mock.Anything).Return(tc.saveError)
+
+			srv := service.NewDispatchService(repo, &NoopOrderTracker{}, &routing.Config{DepotLatitude: 43.30, DepotLongitude: 45.60})
+			resp, err := srv.Run(context.Background(), 1)
+
+			if tc.expectCode != 0 {
+				var serviceErr *customError.ServiceError
+				assert.ErrorAs(t, err, &serviceErr)
+				assert.Equal(t, tc.expectCode, serviceErr.Code)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Len(t, resp.Routes, len(tc.expectRoutes))
+			for i, route := range resp.Routes {
+				var ids []int64
+				for _, stop := range route.Stops {
+					ids = append(ids, stop.OrderId)
+				}
+				assert.Equal(t, tc.expectRoutes[i], ids)
+			}
+			assert.Equal(t, tc.expectUnassigned, resp.UnassignedOrders)
+		})
+	}
+}
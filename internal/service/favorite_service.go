@@ -0,0 +1,74 @@
+package service
+
+import (
+	"arabic/internal/dto"
+	"arabic/internal/repository"
+	"arabic/pkg/customError"
+	"arabic/pkg/logger"
+	"context"
+	"net/http"
+)
+
+type IFavoriteService interface {
+	Add(ctx context.Context, userId int64, catalogId uint) error
+	Remove(ctx context.Context, userId int64, catalogId uint) error
+	GetAll(ctx context.Context, userId int64, imagePrefix string) ([]*dto.CatalogResponse, error)
+}
+
+type FavoriteService struct {
+	favoriteRepository repository.IFavoriteRepository
+	catalogRepository  repository.ICatalogRepository
+}
+
+func NewFavoriteService(favoriteRepo repository.IFavoriteRepository, catalogRepo repository.ICatalogRepository) *FavoriteService {
+	return &FavoriteService{
+		favoriteRepository: favoriteRepo,
+		catalogRepository:  catalogRepo,
+	}
+}
+
+func (s *FavoriteService) Add(ctx context.Context, userId int64, catalogId uint) error {
+	if err := s.favoriteRepository.Add(ctx, userId, catalogId); err != nil {
+		if isForeignKeyError(err) {
+			return customError.NewServiceError(http.StatusNotFound, customError.ErrorNotFoundById, err)
+		}
+		logger.Log.Error("FavoriteService -> Add -> err -> " + err.Error())
+		return customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	return nil
+}
+
+func (s *FavoriteService) Remove(ctx context.Context, userId int64, catalogId uint) error {
+	ok, err := s.favoriteRepository.Remove(ctx, userId, catalogId)
+	if err != nil {
+		logger.Log.Error("FavoriteService -> Remove -> err -> " + err.Error())
+		return customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	if !ok {
+		return customError.NewServiceError(http.StatusNotFound, "Product is not in favorites", nil)
+	}
+
+	return nil
+}
+
+func (s *FavoriteService) GetAll(ctx context.Context, userId int64, imagePrefix string) ([]*dto.CatalogResponse, error) {
+	items, err := s.favoriteRepository.FindAll(ctx, userId)
+	if err != nil {
+		logger.Log.Error("FavoriteService -> GetAll -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	if err = attachTags(ctx, s.catalogRepository, items); err != nil {
+		logger.Log.Error("FavoriteService -> GetAll -> attachTags -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	resp := make([]*dto.CatalogResponse, 0, len(items))
+	for _, item := range items {
+		resp = append(resp, item.ToResponse(imagePrefix))
+	}
+
+	return resp, nil
+}
@@ -1,7 +1,129 @@
 package service
 
-import "strings"
+import (
+	"arabic/internal/model"
+	"arabic/pkg/customError"
+	"arabic/pkg/fs"
+	"arabic/pkg/logger"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Проверяет расширение изображения в base64 и сохраняет его в хранилище, возвращает имя файла
+func saveImage(fs fs.IFileSystemImage, base64Image *string) (string, error) {
+	// Вытаскиваем расширение файла
+	extension, err := fs.GetImageExtension(base64Image)
+
+	if err != nil {
+		logger.Log.Error(err.Error())
+		return "", customError.NewServiceError(http.StatusBadRequest, "Image extension not found. Provide correct data", nil)
+	}
+
+	// Проверяем входит ли данное расширение в список поддерживаемых
+	if !fs.IsSupportingExtension(extension) {
+		logger.Log.Error(fmt.Sprintf("Not supporting image extension %s", extension))
+		return "", customError.NewServiceError(http.StatusBadRequest, fmt.Sprintf("Extension of image %s not support, pls provide correct one", extension), nil)
+	}
+
+	// Сохраняем файл в хранилище
+	filename, err := fs.SafeImageToStorage(extension, base64Image)
+	if err != nil {
+		logger.Log.Error(fmt.Sprintf("Image saving error: %s", err.Error()))
+		return "", customError.NewServiceError(http.StatusBadRequest, "Something went wrong while saving image. Check provided data or try later...", nil)
+	}
+
+	return filename, nil
+}
+
+// Удаляет сохраненные изображения, если запись, к которой они относятся, не создалась
+func deleteImages(fs fs.IFileSystemImage, filenames []string) {
+	for _, filename := range filenames {
+		if !fs.DeleteImage(filename) {
+			logger.Log.Error(fmt.Sprintf("Image %s was not deleted", filename))
+		}
+	}
+}
 
 func isDuplicateError(err error) bool {
 	return strings.Contains(err.Error(), "duplicate")
 }
+
+func isForeignKeyError(err error) bool {
+	return strings.Contains(err.Error(), "foreign key")
+}
+
+func uniqueIds[T int64 | uint](ids []T) []T {
+	result := append([]T{}, ids...)
+	slices.Sort(result)
+	return slices.Compact(result)
+}
+
+// Курсор пагинации каталога - ключ сортировки и id последней записи страницы, JSON в base64
+type catalogCursor struct {
+	Sort  string `json:"sort"`
+	Value string `json:"value"`
+	Id    uint   `json:"id"`
+}
+
+// Формат created_at в курсоре, без часового пояса, как TIMESTAMP в базе
+const cursorTimeLayout = "2006-01-02 15:04:05.999999"
+
+func encodeCatalogCursor(item *model.Catalog, sort string) string {
+	cursor := &catalogCursor{Sort: sort, Id: item.Id}
+
+	switch sort {
+	case "price":
+		cursor.Value = strconv.FormatFloat(float64(item.Price), 'f', -1, 32)
+	case "name":
+		cursor.Value = item.Name
+	case "created_at":
+		cursor.Value = item.CreatedAt.Format(cursorTimeLayout)
+	case "rating":
+		cursor.Value = strconv.FormatFloat(float64(item.Rating), 'f', -1, 32)
+	default:
+		cursor.Value = strconv.FormatUint(uint64(item.Id), 10)
+	}
+
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// Курсор другой сортировки не подходит: ключ из него сравнивался бы не с тем полем
+func decodeCatalogCursor(encoded, sort string) (*catalogCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := &catalogCursor{}
+	if err = json.Unmarshal(raw, cursor); err != nil {
+		return nil, err
+	}
+
+	if cursor.Sort != sort {
+		return nil, fmt.Errorf("cursor is for sort %q, not %q", cursor.Sort, sort)
+	}
+
+	return cursor, nil
+}
+
+// Строит tsquery для поиска по префиксу: "яблок зел" -> "яблок:* & зел:*".
+// Все символы кроме букв и цифр отбрасываются, чтобы пользовательский ввод не ломал синтаксис tsquery
+func buildPrefixTsQuery(input string) string {
+	words := strings.FieldsFunc(strings.ToLower(input), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		terms = append(terms, word+":*")
+	}
+
+	return strings.Join(terms, " & ")
+}
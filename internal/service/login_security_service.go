@@ -0,0 +1,54 @@
+package service
+
+import (
+	"arabic/internal/dto"
+	"arabic/internal/repository"
+	"arabic/pkg/customError"
+	"arabic/pkg/logger"
+	"arabic/pkg/security/throttle"
+	"context"
+	"net/http"
+	"strings"
+)
+
+type ILoginSecurityService interface {
+	GetFailures(ctx context.Context, req *dto.LoginFailureListRequest) ([]*dto.LoginFailureResponse, error)
+	Unlock(ctx context.Context, req *dto.LoginUnlockRequest) error
+}
+
+// Журнал неудачных входов и ручное снятие блокировок для администратора
+type LoginSecurityService struct {
+	loginAuditRepository repository.ILoginAuditRepository
+	loginLimiter         *throttle.Limiter
+}
+
+func NewLoginSecurityService(loginAuditRepo repository.ILoginAuditRepository, loginLimiter *throttle.Limiter) *LoginSecurityService {
+	return &LoginSecurityService{
+		loginAuditRepository: loginAuditRepo,
+		loginLimiter:         loginLimiter,
+	}
+}
+
+func (s *LoginSecurityService) GetFailures(ctx context.Context, req *dto.LoginFailureListRequest) ([]*dto.LoginFailureResponse, error) {
+	failures, err := s.loginAuditRepository.Find(ctx, strings.ToLower(strings.TrimSpace(req.Email)), req.Ip, req.Limit)
+	if err != nil {
+		logger.Log.Error("LoginSecurityService -> GetFailures -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	resp := make([]*dto.LoginFailureResponse, 0, len(failures))
+	for _, failure := range failures {
+		resp = append(resp, failure.ToResponse())
+	}
+
+	return resp, nil
+}
+
+func (s *LoginSecurityService) Unlock(ctx context.Context, req *dto.LoginUnlockRequest) error {
+	if err := s.loginLimiter.Unlock(ctx, req.Email, req.Ip); err != nil {
+		logger.Log.Error("LoginSecurityService -> Unlock -> err -> " + err.Error())
+		return customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"arabic/internal/dto"
+	"arabic/internal/repository"
+	"arabic/pkg/customError"
+	"arabic/pkg/logger"
+	"arabic/pkg/loyalty"
+	"context"
+	"net/http"
+)
+
+// Сколько последних движений по счету показывать покупателю
+const loyaltyHistoryLimit = 100
+
+type ILoyaltyService interface {
+	GetAccount(ctx context.Context, userId int64) (*dto.LoyaltyResponse, error)
+}
+
+type LoyaltyService struct {
+	loyaltyRepository repository.ILoyaltyRepository
+	config            *loyalty.Config
+}
+
+func NewLoyaltyService(loyaltyRepo repository.ILoyaltyRepository, config *loyalty.Config) *LoyaltyService {
+	return &LoyaltyService{
+		loyaltyRepository: loyaltyRepo,
+		config:            config,
+	}
+}
+
+// Баланс считается по книге проводок, история - последние движения по счету покупателя
+func (s *LoyaltyService) GetAccount(ctx context.Context, userId int64) (*dto.LoyaltyResponse, error) {
+	balance, err := s.loyaltyRepository.FindBalance(ctx, userId)
+	if err != nil {
+		logger.Log.Error("LoyaltyService -> GetAccount -> FindBalance -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	entries, err := s.loyaltyRepository.FindEntries(ctx, userId, loyaltyHistoryLimit)
+	if err != nil {
+		logger.Log.Error("LoyaltyService -> GetAccount -> FindEntries -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	history := make([]*dto.LoyaltyEntryResponse, 0, len(entries))
+	for _, entry := range entries {
+		history = append(history, entry.ToResponse())
+	}
+
+	return &dto.LoyaltyResponse{
+		Balance:    balance,
+		PointValue: s.config.PointValue,
+		History:    history,
+	}, nil
+}
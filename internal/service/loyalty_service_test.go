@@ -0,0 +1,85 @@
+package service_test
+
+import (
+	"arabic/internal/model"
+	"arabic/internal/service"
+	"arabic/pkg/logger"
+	"arabic/pkg/loyalty"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+type MockILoyaltyRepository struct {
+	mock.Mock
+}
+
+func (m *MockILoyaltyRepository) FindBalance(ctx context.Context, userId int64) (int64, error) {
+	args := m.Called(ctx, userId)
+	return args.Get(0).(int64), args.Error(1)
+}
+func (m *MockILoyaltyRepository) FindEntries(ctx context.Context, userId int64, limit int) ([]*model.LoyaltyEntry, error) {
+	args := m.Called(ctx, userId, limit)
+	return args.Get(0).([]*model.LoyaltyEntry), args.Error(1)
+}
+
+func TestLoyaltyService_GetAccount(t *testing.T) {
+	logger.Init("Error", "./")
+
+	orderId := int64(7)
+	entries := []*model.LoyaltyEntry{
+		{Id: 3, Type: model.LoyaltyAccrualReversal, Points: -24, OrderId: &orderId, CreatedAt: time.Now()},
+		{Id: 1, Type: model.LoyaltyAccrual, Points: 24, OrderId: &orderId, CreatedAt: time.Now()},
+	}
+
+	tests := []struct {
+		name          string
+		balance       int64
+		balanceErr    error
+		expectBalance int64
+		expectHistory int
+		expectErr     bool
+	}{
+		{
+			name:          "balance from ledger with history",
+			balance:       0,
+			expectBalance: 0,
+			expectHistory: 2,
+		},
+		{
+			name:          "negative balance after return of spent points",
+			balance:       -10,
+			expectBalance: -10,
+			expectHistory: 2,
+		},
+		{
+			name:       "database error",
+			balanceErr: errors.New("connection refused"),
+			expectErr:  true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			loyaltyRepo := &MockILoyaltyRepository{}
+			loyaltyRepo.On("FindBalance", mock.Anything, int64(1)).Return(tc.balance, tc.balanceErr)
+			loyaltyRepo.On("FindEntries", mock.Anything, int64(1), mock.Anything).Return(entries, nil)
+
+			srv := service.NewLoyaltyService(loyaltyRepo, loyalty.NewConfig())
+			account, err := srv.GetAccount(context.Background(), 1)
+
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectBalance, account.Balance)
+			assert.Equal(t, float64(1), account.PointValue)
+			assert.Len(t, account.History, tc.expectHistory)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"arabic/internal/model"
+	"arabic/internal/repository"
+	"arabic/pkg/logger"
+	"arabic/pkg/notify"
+	"context"
+	"time"
+)
+
+type INotificationService interface {
+	Run(ctx context.Context)
+	Dispatch(ctx context.Context) (int, error)
+}
+
+// Фоновая доставка уведомлений из очереди через настроенный канал
+type NotificationService struct {
+	notificationRepository repository.INotificationRepository
+	notifier               notify.Notifier
+	renderer               *notify.Renderer
+	config                 *notify.Config
+}
+
+func NewNotificationService(notificationRepo repository.INotificationRepository, notifier notify.Notifier, renderer *notify.Renderer, config *notify.Config) *NotificationService {
+	return &NotificationService{
+		notificationRepository: notificationRepo,
+		notifier:               notifier,
+		renderer:               renderer,
+		config:                 config,
+	}
+}
+
+// Забирает очередь с периодом PollInterval до отмены ctx
+func (s *NotificationService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.PollInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Полная пачка - в очереди, вероятно, есть еще, забираем без ожидания
+			for {
+				sent, err := s.Dispatch(ctx)
+				if err != nil || sent < s.config.BatchSize || ctx.Err() != nil {
+					break
+				}
+			}
+		}
+	}
+}
+
+// Отправляет одну пачку уведомлений, возвращает сколько выбрано из очереди
+func (s *NotificationService) Dispatch(ctx context.Context) (int, error) {
+	notifications, err := s.notificationRepository.Claim(ctx, s.config.BatchSize, s.config.Lease())
+	if err != nil {
+		logger.Log.Error("NotificationService -> Dispatch -> Claim -> err -> " + err.Error())
+		return 0, err
+	}
+
+	for _, notification := range notifications {
+		if err = s.deliver(ctx, notification); err != nil {
+			logger.Log.Error("NotificationService -> Dispatch -> Mark -> err -> " + err.Error())
+		}
+	}
+
+	return len(notifications), nil
+}
+
+// Письмо, которое не удалось собрать, повторять бесполезно. Ошибка отправки повторяется
+// с растущей паузой, пока не кончатся попытки
+func (s *NotificationService) deliver(ctx context.Context, notification *model.Notification) error {
+	msg, err := s.renderer.Render(notification.Type, notification.Language, notification.Data)
+	if err != nil {
+		logger.Log.Error("NotificationService -> deliver -> Render -> err -> " + err.Error())
+		return s.notificationRepository.MarkFailed(ctx, notification.Id, err.Error())
+	}
+	msg.Recipient = notification.Email
+
+	if err = s.notifier.Send(ctx, msg); err == nil {
+		return s.notificationRepository.MarkSent(ctx, notification.Id)
+	}
+
+	logger.Log.Error("NotificationService -> deliver -> Send -> err -> " + err.Error())
+	if notification.Attempts >= s.config.MaxAttempts {
+		return s.notificationRepository.MarkFailed(ctx, notification.Id, err.Error())
+	}
+
+	return s.notificationRepository.Retry(ctx, notification.Id, err.Error(), time.Now().Add(s.config.Backoff(notification.Attempts)))
+}
@@ -0,0 +1,138 @@
+package service_test
+
+import (
+	"arabic/internal/model"
+	"arabic/internal/service"
+	"arabic/pkg/logger"
+	"arabic/pkg/notify"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+type MockINotificationRepository struct {
+	mock.Mock
+}
+
+func (m *MockINotificationRepository) Enqueue(ctx context.Context, userId int64, notificationType string, data map[string]any) error {
+	args := m.Called(ctx, userId, notificationType, data)
+	return args.Error(0)
+}
+func (m *MockINotificationRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]*model.Notification, error) {
+	args := m.Called(ctx, limit, lease)
+	return args.Get(0).([]*model.Notification), args.Error(1)
+}
+func (m *MockINotificationRepository) MarkSent(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+func (m *MockINotificationRepository) Retry(ctx context.Context, id int64, reason string, at time.Time) error {
+	args := m.Called(ctx, id, reason, at)
+	return args.Error(0)
+}
+func (m *MockINotificationRepository) MarkFailed(ctx context.Context, id int64, reason string) error {
+	args := m.Called(ctx, id, reason)
+	return args.Error(0)
+}
+
+// Канал, который не может доставить письма на адрес из списка
+type failingNotifier struct {
+	notify.MemoryNotifier
+	fail string
+}
+
+func (f *failingNotifier) Send(ctx context.Context, msg *notify.Message) error {
+	if msg.Recipient == f.fail {
+		return errors.New("mailbox unavailable")
+	}
+	return f.MemoryNotifier.Send(ctx, msg)
+}
+
+func TestNotificationService_Dispatch(t *testing.T) {
+	logger.Init("Error", "./")
+
+	renderer, err := notify.NewRenderer()
+	assert.NoError(t, err)
+
+	data := map[string]any{"name": "Dates", "amount": 5, "price": 100}
+	notifications := []*model.Notification{
+		{Id: 1, UserId: 1, Email: "a@a.com", Language: "en", Type: model.SubscriptionBackInStock, Data: data, Attempts: 1},
+		{Id: 2, UserId: 2, Email: "b@b.com", Language: "ru", Type: model.SubscriptionBackInStock, Data: data, Attempts: 1},
+		{Id: 3, UserId: 2, Email: "b@b.com", Language: "ru", Type: model.SubscriptionBackInStock, Data: data, Attempts: 5},
+		{Id: 4, UserId: 1, Email: "a@a.com", Language: "ru", Type: "unknown", Data: data, Attempts: 1},
+	}
+
+	config := notify.NewConfig()
+	notificationRepo := &MockINotificationRepository{}
+	notificationRepo.On("Claim", mock.Anything, config.BatchSize, config.Lease()).Return(notifications, nil)
+	notificationRepo.On("MarkSent", mock.Anything, int64(1)).Return(nil)
+	notificationRepo.On("Retry", mock.Anything, int64(2), "mailbox unavailable", mock.MatchedBy(func(at time.Time) bool {
+		return at.After(time.Now().Add(config.Backoff(1) - time.Minute))
+	})).Return(nil)
+	notificationRepo.On("MarkFailed", mock.Anything, int64(3), "mailbox unavailable").Return(nil)
+	notificationRepo.On("MarkFailed", mock.Anything, int64(4), mock.Anything).Return(nil)
+
+	notifier := &failingNotifier{fail: "b@b.com"}
+	srv := service.NewNotificationService(notificationRepo, notifier, renderer, config)
+
+	claimed, err := srv.Dispatch(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 4, claimed)
+	assert.Len(t, notifier.Messages(), 1)
+	assert.Equal(t, "a@a.com", notifier.Messages()[0].Recipient)
+	assert.Equal(t, "Dates is back in stock", notifier.Messages()[0].Subject)
+	notificationRepo.AssertExpectations(t)
+}
+
+func TestCatalogChangeNotifications(t *testing.T) {
+	tests := []struct {
+		name        string
+		before      model.CatalogState
+		after       model.CatalogState
+		expectTypes []string
+	}{
+		{
+			name:        "back in stock",
+			before:      model.CatalogState{Name: "Dates", Price: 100, Amount: 0},
+			after:       model.CatalogState{Name: "Dates", Price: 100, Amount: 5},
+			expectTypes: []string{model.SubscriptionBackInStock},
+		},
+		{
+			name:   "restock of available product",
+			before: model.CatalogState{Name: "Dates", Price: 100, Amount: 2},
+			after:  model.CatalogState{Name: "Dates", Price: 100, Amount: 5},
+		},
+		{
+			name:        "discount lowers final price",
+			before:      model.CatalogState{Name: "Dates", Price: 100, Amount: 2},
+			after:       model.CatalogState{Name: "Dates", Price: 100, DiscountPercent: 10, Amount: 2},
+			expectTypes: []string{model.SubscriptionPriceDrop},
+		},
+		{
+			name:   "price raised while discount added",
+			before: model.CatalogState{Name: "Dates", Price: 100, Amount: 2},
+			after:  model.CatalogState{Name: "Dates", Price: 120, DiscountPercent: 10, Amount: 2},
+		},
+		{
+			name:        "back in stock cheaper",
+			before:      model.CatalogState{Name: "Dates", Price: 100, Amount: 0},
+			after:       model.CatalogState{Name: "Dates", Price: 90, Amount: 1},
+			expectTypes: []string{model.SubscriptionBackInStock, model.SubscriptionPriceDrop},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var types []string
+			for _, notification := range model.CatalogChangeNotifications(&tc.before, &tc.after) {
+				types = append(types, notification.Type)
+			}
+
+			assert.Equal(t, tc.expectTypes, types)
+		})
+	}
+}
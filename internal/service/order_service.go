@@ -0,0 +1,401 @@
+package service
+
+import (
+	"arabic/internal/dto"
+	"arabic/internal/model"
+	"arabic/internal/repository"
+	"arabic/pkg/customError"
+	"arabic/pkg/geo"
+	"arabic/pkg/logger"
+	"arabic/pkg/loyalty"
+	"arabic/pkg/security/auth"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+type IOrderService interface {
+	Checkout(ctx context.Context, userId int64, req *dto.CheckoutRequest) (*dto.OrderResponse, error)
+	GetOrder(ctx context.Context, userId, orderId int64) (*dto.OrderResponse, error)
+	GetUserOrders(ctx context.Context, userId int64) ([]*dto.OrderResponse, error)
+	ChangeStatus(ctx context.Context, userId, orderId int64, req *dto.OrderStatusRequest) (*dto.OrderResponse, error)
+	GetHistory(ctx context.Context, userId, orderId int64) ([]*dto.OrderStatusChangeResponse, error)
+	GetQueue(ctx context.Context, userId int64) ([]*dto.OrderResponse, error)
+}
+
+type OrderService struct {
+	orderRepository     repository.IOrderRepository
+	cartRepository      repository.ICartRepository
+	userRepository      repository.IUserRepository
+	deliveryRepository  repository.IDeliveryRepository
+	promotionRepository repository.IPromotionRepository
+	tracker             IOrderTracker
+	payments            IOrderPayments
+	loyalty             *loyalty.Config
+	account             *security.AccountConfig
+}
+
+func NewOrderService(orderRepo repository.IOrderRepository, cartRepo repository.ICartRepository, userRepo repository.IUserRepository, deliveryRepo repository.IDeliveryRepository, promotionRepo repository.IPromotionRepository, tracker IOrderTracker, payments IOrderPayments, loyaltyConfig *loyalty.Config, accountConfig *security.AccountConfig) *OrderService {
+	return &OrderService{
+		orderRepository:     orderRepo,
+		cartRepository:      cartRepo,
+		userRepository:      userRepo,
+		deliveryRepository:  deliveryRepo,
+		promotionRepository: promotionRepo,
+		tracker:             tracker,
+		payments:            payments,
+		loyalty:             loyaltyConfig,
+		account:             accountConfig,
+	}
+}
+
+func (s *OrderService) Checkout(ctx context.Context, userId int64, req *dto.CheckoutRequest) (*dto.OrderResponse, error) {
+	user, err := s.userRepository.FindById(ctx, userId)
+
+	if err != nil {
+		logger.Log.Error("OrderService -> Checkout -> FindById -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusBadRequest, customError.ErrorAuthorize, err)
+	}
+
+	if s.account.BlocksCheckout() && user.EmailVerifiedAt == nil {
+		return nil, customError.NewServiceError(http.StatusForbidden, customError.ErrorEmailNotVerified, nil)
+	}
+
+	if user.Street == "" || user.House == "" || user.City == "" {
+		return nil, customError.NewServiceError(http.StatusBadRequest, "Please fill in the delivery address in your profile before checkout", nil)
+	}
+
+	zone, err := s.deliveryZone(ctx, &user.UserAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	items := req.Items
+	var cartItems []*model.CartItem
+
+	// Без явного списка оформляем заказ из корзины и убираем оформленное из нее в той же транзакции
+	if len(items) == 0 {
+		cartItems, items, err = s.cartItems(ctx, userId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	promotions, err := s.promotionRepository.FindApplicable(ctx, req.PromoCode, userId)
+	if err != nil {
+		logger.Log.Error("OrderService -> Checkout -> FindApplicable -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	order := &model.Order{
+		UserId:         userId,
+		Status:         model.OrderStatusCreated,
+		Address:        user.UserAddress,
+		DeliverySlotId: req.DeliverySlotId,
+		DeliveryZoneId: &zone.Id,
+		Zone:           zone,
+		PromoCode:      req.PromoCode,
+		Promotions:     promotions,
+		LoyaltyPoints:  req.LoyaltyPoints,
+		Loyalty:        s.loyalty,
+		Items:          mergeOrderItems(items),
+	}
+
+	order, err = s.orderRepository.Create(ctx, order, cartItems)
+
+	if err != nil {
+		var stockErr *repository.StockError
+		if errors.As(err, &stockErr) {
+			return nil, stockServiceError(stockErr)
+		}
+
+		var minOrderErr *repository.MinOrderError
+		if errors.As(err, &minOrderErr) {
+			return nil, customError.NewServiceError(http.StatusBadRequest, fmt.Sprintf("Minimal order amount for your delivery zone is %.2f, current amount is %.2f", minOrderErr.MinAmount, minOrderErr.Amount), err)
+		}
+
+		var promoErr *repository.PromoError
+		if errors.As(err, &promoErr) {
+			return nil, customError.NewServiceError(http.StatusBadRequest, fmt.Sprintf("Promotion %s cannot be applied: %s", promoErr.Promotion, promoErr.Reason), err)
+		}
+
+		var loyaltyErr *repository.LoyaltyError
+		if errors.As(err, &loyaltyErr) {
+			return nil, customError.NewServiceError(http.StatusBadRequest, fmt.Sprintf("Not enough loyalty points, your balance is %d", loyaltyErr.Balance), err)
+		}
+
+		var slotErr *repository.SlotError
+		if errors.As(err, &slotErr) {
+			if slotErr.Full {
+				return nil, customError.NewServiceError(http.StatusConflict, "Selected delivery slot is fully booked, please choose another one", err)
+			}
+			return nil, customError.NewServiceError(http.StatusBadRequest, "Selected delivery slot is not available", err)
+		}
+
+		logger.Log.Error("OrderService -> Checkout -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	resp := order.ToResponse()
+
+	// Заказ уже оформлен и остатки списаны: без платежа покупатель повторит оплату через POST /orders/{id}/payment
+	payment, err := s.payments.StartPayment(ctx, order)
+	if err != nil {
+		logger.Log.Error("OrderService -> Checkout -> StartPayment -> err -> " + err.Error())
+		return resp, nil
+	}
+
+	resp.Payment = payment.ToResponse()
+	return resp, nil
+}
+
+func (s *OrderService) GetOrder(ctx context.Context, userId, orderId int64) (*dto.OrderResponse, error) {
+	_, order, err := s.findAccessibleOrder(ctx, userId, orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	return order.ToResponse(), nil
+}
+
+func (s *OrderService) GetUserOrders(ctx context.Context, userId int64) ([]*dto.OrderResponse, error) {
+	orders, err := s.orderRepository.FindByUser(ctx, userId)
+
+	if err != nil {
+		logger.Log.Error("OrderService -> GetUserOrders -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	resp := make([]*dto.OrderResponse, 0, len(orders))
+	for _, order := range orders {
+		resp = append(resp, order.ToResponse())
+	}
+
+	return resp, nil
+}
+
+func (s *OrderService) ChangeStatus(ctx context.Context, userId, orderId int64, req *dto.OrderStatusRequest) (*dto.OrderResponse, error) {
+	role, order, err := s.findAccessibleOrder(ctx, userId, orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !model.IsValidOrderTransition(order.Status, req.Status) {
+		return nil, customError.NewServiceError(http.StatusConflict, fmt.Sprintf("Cannot change order status from %s to %s", order.Status, req.Status), nil)
+	}
+
+	if !model.CanChangeOrderStatus(order.Status, req.Status, role) {
+		return nil, customError.NewServiceError(http.StatusForbidden, fmt.Sprintf("Role %s cannot change order status from %s to %s", role, order.Status, req.Status), nil)
+	}
+
+	change := &model.OrderStatusChange{
+		OrderId:    order.Id,
+		FromStatus: order.Status,
+		ToStatus:   req.Status,
+		ChangedBy:  userId,
+		Comment:    req.Comment,
+	}
+
+	ok, err := s.orderRepository.ChangeStatus(ctx, change)
+
+	if errors.Is(err, repository.ErrPickingIncomplete) {
+		return nil, customError.NewServiceError(http.StatusConflict, "All order lines must be picked before packing", err)
+	}
+
+	if err != nil {
+		logger.Log.Error("OrderService -> ChangeStatus -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	if !ok {
+		return nil, customError.NewServiceError(http.StatusConflict, "Order status was changed by someone else, please reload the order", nil)
+	}
+
+	s.tracker.StatusChanged(ctx, change)
+	s.payments.StatusChanged(ctx, change)
+
+	order.Status = change.ToStatus
+	return order.ToResponse(), nil
+}
+
+func (s *OrderService) GetHistory(ctx context.Context, userId, orderId int64) ([]*dto.OrderStatusChangeResponse, error) {
+	if _, _, err := s.findAccessibleOrder(ctx, userId, orderId); err != nil {
+		return nil, err
+	}
+
+	history, err := s.orderRepository.FindHistory(ctx, orderId)
+
+	if err != nil {
+		logger.Log.Error("OrderService -> GetHistory -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	resp := make([]*dto.OrderStatusChangeResponse, 0, len(history))
+	for _, change := range history {
+		resp = append(resp, change.ToResponse())
+	}
+
+	return resp, nil
+}
+
+// Заказы, которые сотрудник может перевести в следующий статус
+func (s *OrderService) GetQueue(ctx context.Context, userId int64) ([]*dto.OrderResponse, error) {
+	role, err := s.userRole(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	var orders []*model.Order
+	switch {
+	case role == model.RoleCourier:
+		orders, err = s.orderRepository.FindCourierQueue(ctx, userId)
+	case model.IsOrderStaff(role):
+		orders, err = s.orderRepository.FindByStatuses(ctx, model.ActionableOrderStatuses(role))
+	default:
+		return nil, customError.NewServiceError(http.StatusForbidden, "Order queue is available only for staff", nil)
+	}
+
+	if err != nil {
+		logger.Log.Error("OrderService -> GetQueue -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	resp := make([]*dto.OrderResponse, 0, len(orders))
+	for _, order := range orders {
+		resp = append(resp, order.ToResponse())
+	}
+
+	return resp, nil
+}
+
+// Возвращает заказ и роль пользователя. Недоступный заказ неотличим от несуществующего
+func (s *OrderService) findAccessibleOrder(ctx context.Context, userId, orderId int64) (string, *model.Order, error) {
+	role, err := s.userRole(ctx, userId)
+	if err != nil {
+		return "", nil, err
+	}
+
+	order, ok, err := s.orderRepository.FindById(ctx, orderId)
+
+	if err != nil {
+		logger.Log.Error("OrderService -> findAccessibleOrder -> err -> " + err.Error())
+		return "", nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	if ok {
+		ok, err = canAccessOrder(ctx, s.orderRepository, userId, role, order)
+		if err != nil {
+			logger.Log.Error("OrderService -> findAccessibleOrder -> IsCourierOrder -> err -> " + err.Error())
+			return "", nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+		}
+	}
+
+	if !ok {
+		return "", nil, customError.NewServiceError(http.StatusNotFound, customError.ErrorNotFoundById, nil)
+	}
+
+	return role, order, nil
+}
+
+// Свои заказы видны всем. Склад и администратор видят любые заказы, курьер - ждущие
+// передачи в доставку и свои. Остальным ролям чужие заказы не нужны
+func canAccessOrder(ctx context.Context, orderRepo repository.IOrderRepository, userId int64, role string, order *model.Order) (bool, error) {
+	switch {
+	case order.UserId == userId, model.IsOrderStaff(role):
+		return true, nil
+	case role == model.RoleCourier && order.Status == model.OrderStatusPacked:
+		return true, nil
+	case role == model.RoleCourier:
+		return orderRepo.IsCourierOrder(ctx, order.Id, userId)
+	}
+
+	return false, nil
+}
+
+func (s *OrderService) userRole(ctx context.Context, userId int64) (string, error) {
+	user, err := s.userRepository.FindById(ctx, userId)
+
+	if err != nil {
+		logger.Log.Error("OrderService -> userRole -> err -> " + err.Error())
+		return "", customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, nil)
+	}
+
+	return user.RoleCode, nil
+}
+
+// Зона доставки по координатам адреса
+func (s *OrderService) deliveryZone(ctx context.Context, address *model.UserAddress) (*model.DeliveryZone, error) {
+	if address.Latitude == nil || address.Longitude == nil {
+		return nil, customError.NewServiceError(http.StatusBadRequest, "Please set the location of your delivery address before checkout", nil)
+	}
+
+	zones, err := s.deliveryRepository.FindZones(ctx)
+	if err != nil {
+		logger.Log.Error("OrderService -> deliveryZone -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	zone, ok := model.FindDeliveryZone(zones, geo.Point{Lat: *address.Latitude, Lon: *address.Longitude})
+	if !ok {
+		return nil, customError.NewServiceError(http.StatusBadRequest, "Sorry, we do not deliver to this address yet", nil)
+	}
+
+	return zone, nil
+}
+
+func (s *OrderService) cartItems(ctx context.Context, userId int64) ([]*model.CartItem, []dto.CartItemRequest, error) {
+	cartId, ok, err := s.cartRepository.FindCartId(ctx, &dto.CartOwner{UserId: userId})
+
+	if err != nil {
+		logger.Log.Error("OrderService -> cartItems -> FindCartId -> err -> " + err.Error())
+		return nil, nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	var cartItems []*model.CartItem
+	if ok {
+		cartItems, err = s.cartRepository.FindItems(ctx, cartId)
+		if err != nil {
+			logger.Log.Error("OrderService -> cartItems -> FindItems -> err -> " + err.Error())
+			return nil, nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+		}
+	}
+
+	if len(cartItems) == 0 {
+		return nil, nil, customError.NewServiceError(http.StatusBadRequest, "Cart is empty", nil)
+	}
+
+	items := make([]dto.CartItemRequest, 0, len(cartItems))
+	for _, item := range cartItems {
+		items = append(items, dto.CartItemRequest{CatalogId: item.CatalogId, Quantity: item.Quantity})
+	}
+
+	return cartItems, items, nil
+}
+
+// Схлопывает повторяющиеся товары в одну позицию
+func mergeOrderItems(items []dto.CartItemRequest) []*model.OrderItem {
+	byCatalog := make(map[uint]*model.OrderItem, len(items))
+	result := make([]*model.OrderItem, 0, len(items))
+
+	for _, item := range items {
+		if existing, ok := byCatalog[item.CatalogId]; ok {
+			existing.Quantity += item.Quantity
+			continue
+		}
+
+		orderItem := &model.OrderItem{CatalogId: item.CatalogId, Quantity: item.Quantity}
+		byCatalog[item.CatalogId] = orderItem
+		result = append(result, orderItem)
+	}
+
+	return result
+}
+
+func stockServiceError(err *repository.StockError) error {
+	if err.NotFound {
+		return customError.NewServiceError(http.StatusBadRequest, fmt.Sprintf("Catalog item %d not found", err.CatalogId), err)
+	}
+
+	return customError.NewServiceError(http.StatusConflict, fmt.Sprintf("Not enough stock for catalog item %d, available: %d", err.CatalogId, err.Available), err)
+}
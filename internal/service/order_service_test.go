@@ -0,0 +1,401 @@
+package service_test
+
+import (
+	"arabic/internal/dto"
+	"arabic/internal/model"
+	"arabic/internal/repository"
+	"arabic/internal/service"
+	"arabic/pkg/customError"
+	"arabic/pkg/logger"
+	"arabic/pkg/loyalty"
+	security "arabic/pkg/security/auth"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+)
+
+type MockIOrderRepository struct {
+	mock.Mock
+}
+
+func (m *MockIOrderRepository) Create(ctx context.Context, order *model.Order, cartItems []*model.CartItem) (*model.Order, error) {
+	args := m.Called(ctx, order, cartItems)
+	if fn, ok := args.Get(0).(func(*model.Order) *model.Order); ok {
+		return fn(order), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+func (m *MockIOrderRepository) FindById(ctx context.Context, id int64) (*model.Order, bool, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*model.Order), args.Bool(1), args.Error(2)
+}
+func (m *MockIOrderRepository) FindByUser(ctx context.Context, userId int64) ([]*model.Order, error) {
+	args := m.Called(ctx, userId)
+	return args.Get(0).([]*model.Order), args.Error(1)
+}
+
+func (m *MockIOrderRepository) FindByStatuses(ctx context.Context, statuses []string) ([]*model.Order, error) {
+	args := m.Called(ctx, statuses)
+	return args.Get(0).([]*model.Order), args.Error(1)
+}
+func (m *MockIOrderRepository) ChangeStatus(ctx context.Context, change *model.OrderStatusChange) (bool, error) {
+	args := m.Called(ctx, change)
+	return args.Bool(0), args.Error(1)
+}
+func (m *MockIOrderRepository) FindHistory(ctx context.Context, orderId int64) ([]*model.OrderStatusChange, error) {
+	args := m.Called(ctx, orderId)
+	return args.Get(0).([]*model.OrderStatusChange), args.Error(1)
+}
+func (m *MockIOrderRepository) IsCourierOrder(ctx context.Context, orderId, courierId int64) (bool, error) {
+	args := m.Called(ctx, orderId, courierId)
+	return args.Bool(0), args.Error(1)
+}
+func (m *MockIOrderRepository) FindCourierQueue(ctx context.Context, courierId int64) ([]*model.Order, error) {
+	args := m.Called(ctx, courierId)
+	return args.Get(0).([]*model.Order), args.Error(1)
+}
+
+type MockIUserRepository struct {
+	mock.Mock
+}
+
+func (m *MockIUserRepository) Create(ctx context.Context, u *model.User) error {
+	args := m.Called(ctx, u)
+	return args.Error(0)
+}
+func (m *MockIUserRepository) FindByEmail(ctx context.Context, email string) (*model.UserFullInfo, error) {
+	args := m.Called(ctx, email)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.UserFullInfo), args.Error(1)
+}
+func (m *MockIUserRepository) FindById(ctx context.Context, id int64) (*model.UserFullInfo, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.UserFullInfo), args.Error(1)
+}
+func (m *MockIUserRepository) Update(ctx context.Context, query string, values []any) (bool, error) {
+	args := m.Called(ctx, query, values)
+	return args.Bool(0), args.Error(1)
+}
+
+func TestOrderService_Checkout(t *testing.T) {
+	logger.Init("Error", "./")
+
+	lat, lon, farLat := 43.30, 45.65, 44.0
+	withAddress := &model.UserFullInfo{
+		User:        model.User{Id: 1},
+		UserAddress: model.UserAddress{House: "1", Street: "Lenina", City: "Grozny", Latitude: &lat, Longitude: &lon},
+	}
+
+	tests := []struct {
+		name            string
+		user            *model.UserFullInfo
+		items           []dto.CartItemRequest
+		requireVerified bool
+		mockError       error
+		expectCode      int
+	}{
+		{
+			name:  "success with merged items",
+			user:  withAddress,
+			items: []dto.CartItemRequest{{CatalogId: 2, Quantity: 1}, {CatalogId: 2, Quantity: 2}},
+		},
+		{
+			name:            "email not verified",
+			user:            withAddress,
+			items:           []dto.CartItemRequest{{CatalogId: 2, Quantity: 1}},
+			requireVerified: true,
+			expectCode:      403,
+		},
+		{
+			name:       "no address",
+			user:       &model.UserFullInfo{User: model.User{Id: 1}},
+			items:      []dto.CartItemRequest{{CatalogId: 2, Quantity: 1}},
+			expectCode: 400,
+		},
+		{
+			name:       "oversell",
+			user:       withAddress,
+			items:      []dto.CartItemRequest{{CatalogId: 2, Quantity: 10}},
+			mockError:  &repository.StockError{CatalogId: 2, Available: 3},
+			expectCode: 409,
+		},
+		{
+			name: "outside delivery zones",
+			user: &model.UserFullInfo{
+				User:        model.User{Id: 1},
+				UserAddress: model.UserAddress{House: "1", Street: "Lenina", City: "Grozny", Latitude: &farLat, Longitude: &lon},
+			},
+			items:      []dto.CartItemRequest{{CatalogId: 2, Quantity: 1}},
+			expectCode: 400,
+		},
+		{
+			name: "address without location",
+			user: &model.UserFullInfo{
+				User:        model.User{Id: 1},
+				UserAddress: model.UserAddress{House: "1", Street: "Lenina", City: "Grozny"},
+			},
+			items:      []dto.CartItemRequest{{CatalogId: 2, Quantity: 1}},
+			expectCode: 400,
+		},
+		{
+			name:       "below min order",
+			user:       withAddress,
+			items:      []dto.CartItemRequest{{CatalogId: 2, Quantity: 1}},
+			mockError:  &repository.MinOrderError{Amount: 100, MinAmount: 500},
+			expectCode: 400,
+		},
+		{
+			name:       "slot fully booked",
+			user:       withAddress,
+			items:      []dto.CartItemRequest{{CatalogId: 2, Quantity: 1}},
+			mockError:  &repository.SlotError{SlotId: 5, Full: true},
+			expectCode: 409,
+		},
+		{
+			name:       "promo code limit reached during checkout",
+			user:       withAddress,
+			items:      []dto.CartItemRequest{{CatalogId: 2, Quantity: 1}},
+			mockError:  &repository.PromoError{Promotion: "SPRING10", Reason: "usage limit is reached"},
+			expectCode: 400,
+		},
+		{
+			name:       "not enough loyalty points",
+			user:       withAddress,
+			items:      []dto.CartItemRequest{{CatalogId: 2, Quantity: 1}},
+			mockError:  &repository.LoyaltyError{Balance: 10, Requested: 50},
+			expectCode: 400,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			userRepo := &MockIUserRepository{}
+			userRepo.On("FindById", mock.Anything, int64(1)).Return(tc.user, nil)
+
+			orderRepo := &MockIOrderRepository{}
+			orderRepo.On("Create", mock.Anything, mock.Anything, []*model.CartItem(nil)).Return(func(order *model.Order) *model.Order {
+				if tc.mockError != nil {
+					return nil
+				}
+				order.Id = 10
+				return order
+			}, tc.mockError)
+
+			deliveryRepo := &MockIDeliveryRepository{}
+			deliveryRepo.On("FindZones", mock.Anything).Return([]*model.DeliveryZone{testDeliveryZone(t)}, nil)
+
+			promotionRepo := &MockIPromotionRepository{}
+			promotionRepo.On("FindApplicable", mock.Anything, "SPRING10", int64(1)).Return([]*model.Promotion{}, nil)
+
+			payments := &MockIOrderPayments{}
+			payments.On("StartPayment", mock.Anything, mock.Anything).Return(&model.Payment{Id: 1, IntentId: "pi_1", ClientSecret: "secret", Status: model.PaymentStatusPending}, nil)
+
+			account := security.NewAccountConfig()
+			if tc.requireVerified {
+				account.RequireVerifiedEmail = security.VerifiedEmailCheckout
+			}
+
+			srv := service.NewOrderService(orderRepo, &MockICartRepository{}, userRepo, deliveryRepo, promotionRepo, &NoopOrderTracker{}, payments, loyalty.NewConfig(), account)
+			order, err := srv.Checkout(context.Background(), 1, &dto.CheckoutRequest{Items: tc.items, PromoCode: "SPRING10"})
+
+			if tc.expectCode != 0 {
+				var serviceErr *customError.ServiceError
+				assert.ErrorAs(t, err, &serviceErr)
+				assert.Equal(t, tc.expectCode, serviceErr.Code)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, int64(10), order.Id)
+			assert.Len(t, order.Items, 1)
+			assert.Equal(t, 3, order.Items[0].Quantity)
+			assert.Equal(t, "Lenina", order.Address.Street)
+			assert.Equal(t, int64(3), *order.DeliveryZoneId)
+			assert.Equal(t, "secret", order.Payment.ClientSecret)
+		})
+	}
+}
+
+func TestOrderService_CheckoutFromCart(t *testing.T) {
+	logger.Init("Error", "./")
+
+	lat, lon := 43.30, 45.65
+	userRepo := &MockIUserRepository{}
+	userRepo.On("FindById", mock.Anything, int64(1)).Return(&model.UserFullInfo{
+		User:        model.User{Id: 1},
+		UserAddress: model.UserAddress{House: "1", Street: "Lenina", City: "Grozny", Latitude: &lat, Longitude: &lon},
+	}, nil)
+
+	cartItems := []*model.CartItem{{CartId: 4, CatalogId: 2, Quantity: 3}, {CartId: 4, CatalogId: 5, Quantity: 1}}
+	cartRepo := &MockICartRepository{}
+	cartRepo.On("FindCartId", mock.Anything, &dto.CartOwner{UserId: 1}).Return(int64(4), true, nil)
+	cartRepo.On("FindItems", mock.Anything, int64(4)).Return(cartItems, nil)
+
+	// Из корзины убираются ровно прочитанные позиции, а не вся корзина
+	orderRepo := &MockIOrderRepository{}
+	orderRepo.On("Create", mock.Anything, mock.Anything, cartItems).Return(func(order *model.Order) *model.Order {
+		order.Id = 10
+		return order
+	}, nil)
+
+	deliveryRepo := &MockIDeliveryRepository{}
+	deliveryRepo.On("FindZones", mock.Anything).Return([]*model.DeliveryZone{testDeliveryZone(t)}, nil)
+
+	promotionRepo := &MockIPromotionRepository{}
+	promotionRepo.On("FindApplicable", mock.Anything, "", int64(1)).Return([]*model.Promotion{}, nil)
+
+	payments := &MockIOrderPayments{}
+	payments.On("StartPayment", mock.Anything, mock.Anything).Return(&model.Payment{Id: 1, IntentId: "pi_1", Status: model.PaymentStatusPending}, nil)
+
+	srv := service.NewOrderService(orderRepo, cartRepo, userRepo, deliveryRepo, promotionRepo, &NoopOrderTracker{}, payments, loyalty.NewConfig(), security.NewAccountConfig())
+	order, err := srv.Checkout(context.Background(), 1, &dto.CheckoutRequest{})
+
+	assert.NoError(t, err)
+	assert.Len(t, order.Items, 2)
+	orderRepo.AssertCalled(t, "Create", mock.Anything, mock.Anything, cartItems)
+}
+
+func TestOrderService_ChangeStatus(t *testing.T) {
+	logger.Init("Error", "./")
+
+	tests := []struct {
+		name        string
+		role        string
+		userId      int64
+		orderStatus string
+		toStatus    string
+		repoOk      bool
+		// Заказ в маршруте курьера
+		assigned   bool
+		expectCode int
+	}{
+		{
+			name:        "collector starts picking",
+			role:        model.RoleCollector,
+			userId:      5,
+			orderStatus: model.OrderStatusConfirmed,
+			toStatus:    model.OrderStatusPicking,
+			repoOk:      true,
+		},
+		{
+			name:        "owner cancels created order",
+			role:        model.RoleUser,
+			userId:      1,
+			orderStatus: model.OrderStatusCreated,
+			toStatus:    model.OrderStatusCancelled,
+			repoOk:      true,
+		},
+		{
+			name:        "staff cannot confirm without payment",
+			role:        model.RoleAdmin,
+			userId:      5,
+			orderStatus: model.OrderStatusCreated,
+			toStatus:    model.OrderStatusConfirmed,
+			expectCode:  403,
+		},
+		{
+			name:        "skipping states is a conflict",
+			role:        model.RoleAdmin,
+			userId:      5,
+			orderStatus: model.OrderStatusCreated,
+			toStatus:    model.OrderStatusDelivered,
+			expectCode:  409,
+		},
+		{
+			name:        "courier cannot pick",
+			role:        model.RoleCourier,
+			userId:      5,
+			orderStatus: model.OrderStatusConfirmed,
+			toStatus:    model.OrderStatusPicking,
+			assigned:    true,
+			expectCode:  403,
+		},
+		{
+			name:        "foreign order is not found",
+			role:        model.RoleUser,
+			userId:      2,
+			orderStatus: model.OrderStatusCreated,
+			toStatus:    model.OrderStatusCancelled,
+			expectCode:  404,
+		},
+		{
+			name:        "moderator does not see foreign orders",
+			role:        model.RoleModerator,
+			userId:      5,
+			orderStatus: model.OrderStatusCreated,
+			toStatus:    model.OrderStatusCancelled,
+			expectCode:  404,
+		},
+		{
+			name:        "courier takes packed order",
+			role:        model.RoleCourier,
+			userId:      5,
+			orderStatus: model.OrderStatusPacked,
+			toStatus:    model.OrderStatusOutForDelivery,
+			repoOk:      true,
+		},
+		{
+			name:        "courier delivers own order",
+			role:        model.RoleCourier,
+			userId:      5,
+			orderStatus: model.OrderStatusOutForDelivery,
+			toStatus:    model.OrderStatusDelivered,
+			repoOk:      true,
+			assigned:    true,
+		},
+		{
+			name:        "courier does not see orders of other couriers",
+			role:        model.RoleCourier,
+			userId:      5,
+			orderStatus: model.OrderStatusOutForDelivery,
+			toStatus:    model.OrderStatusDelivered,
+			expectCode:  404,
+		},
+		{
+			name:        "concurrent change",
+			role:        model.RoleCollector,
+			userId:      5,
+			orderStatus: model.OrderStatusConfirmed,
+			toStatus:    model.OrderStatusPicking,
+			repoOk:      false,
+			expectCode:  409,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			userRepo := &MockIUserRepository{}
+			userRepo.On("FindById", mock.Anything, tc.userId).Return(&model.UserFullInfo{User: model.User{Id: tc.userId, RoleCode: tc.role}}, nil)
+
+			orderRepo := &MockIOrderRepository{}
+			orderRepo.On("FindById", mock.Anything, int64(10)).Return(&model.Order{Id: 10, UserId: 1, Status: tc.orderStatus}, true, nil)
+			orderRepo.On("ChangeStatus", mock.Anything, mock.Anything).Return(tc.repoOk, nil)
+			orderRepo.On("IsCourierOrder", mock.Anything, int64(10), tc.userId).Return(tc.assigned, nil)
+
+			payments := &MockIOrderPayments{}
+			payments.On("StatusChanged", mock.Anything, mock.Anything).Return()
+
+			srv := service.NewOrderService(orderRepo, &MockICartRepository{}, userRepo, &MockIDeliveryRepository{}, &MockIPromotionRepository{}, &NoopOrderTracker{}, payments, loyalty.NewConfig(), security.NewAccountConfig())
+			order, err := srv.ChangeStatus(context.Background(), tc.userId, 10, &dto.OrderStatusRequest{Status: tc.toStatus})
+
+			if tc.expectCode != 0 {
+				var serviceErr *customError.ServiceError
+				assert.ErrorAs(t, err, &serviceErr)
+				assert.Equal(t, tc.expectCode, serviceErr.Code)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.toStatus, order.Status)
+			orderRepo.AssertCalled(t, "ChangeStatus", mock.Anything, mock.MatchedBy(func(change *model.OrderStatusChange) bool {
+				return change.FromStatus == tc.orderStatus && change.ToStatus == tc.toStatus && change.ChangedBy == tc.userId
+			}))
+		})
+	}
+}
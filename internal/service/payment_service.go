@@ -0,0 +1,292 @@
+package service
+
+import (
+	"arabic/internal/dto"
+	"arabic/internal/model"
+	"arabic/internal/repository"
+	"arabic/pkg/customError"
+	"arabic/pkg/logger"
+	"arabic/pkg/payment"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Оплата заказа для OrderService: платеж при оформлении и списание/возврат при смене статуса.
+// Ошибки шлюза на смене статуса только логируются - заказ уже изменен
+type IOrderPayments interface {
+	StartPayment(ctx context.Context, order *model.Order) (*model.Payment, error)
+	StatusChanged(ctx context.Context, change *model.OrderStatusChange)
+}
+
+type IPaymentService interface {
+	IOrderPayments
+	GetPayment(ctx context.Context, userId, orderId int64) (*dto.PaymentResponse, error)
+	Retry(ctx context.Context, userId, orderId int64) (*dto.PaymentResponse, error)
+	HandleWebhook(ctx context.Context, payload []byte, signature string) error
+}
+
+type PaymentService struct {
+	paymentRepository repository.IPaymentRepository
+	orderRepository   repository.IOrderRepository
+	userRepository    repository.IUserRepository
+	provider          payment.PaymentProvider
+	currency          string
+	tracker           IOrderTracker
+}
+
+func NewPaymentService(paymentRepo repository.IPaymentRepository, orderRepo repository.IOrderRepository, userRepo repository.IUserRepository, provider payment.PaymentProvider, config *payment.Config, tracker IOrderTracker) *PaymentService {
+	return &PaymentService{
+		paymentRepository: paymentRepo,
+		orderRepository:   orderRepo,
+		userRepository:    userRepo,
+		provider:          provider,
+		currency:          config.Currency,
+		tracker:           tracker,
+	}
+}
+
+// Создает платеж на сумму заказа у шлюза и сохраняет его
+func (s *PaymentService) StartPayment(ctx context.Context, order *model.Order) (*model.Payment, error) {
+	intent, err := s.provider.CreateIntent(ctx, &payment.IntentRequest{
+		OrderId:  order.Id,
+		Amount:   payment.MinorUnits(float64(order.Total)),
+		Currency: s.currency,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p := &model.Payment{
+		OrderId:      order.Id,
+		Provider:     s.provider.Name(),
+		IntentId:     intent.Id,
+		ClientSecret: intent.ClientSecret,
+		Currency:     intent.Currency,
+		Amount:       intent.Amount,
+		Status:       model.PaymentStatusPending,
+	}
+
+	if err = s.paymentRepository.Create(ctx, p); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (s *PaymentService) GetPayment(ctx context.Context, userId, orderId int64) (*dto.PaymentResponse, error) {
+	if _, err := s.accessibleOrder(ctx, userId, orderId); err != nil {
+		return nil, err
+	}
+
+	p, ok, err := s.paymentRepository.FindLatestByOrder(ctx, orderId)
+
+	if err != nil {
+		logger.Log.Error("PaymentService -> GetPayment -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	if !ok {
+		return nil, customError.NewServiceError(http.StatusNotFound, "Order has no payments", nil)
+	}
+
+	return p.ToResponse(), nil
+}
+
+// Новый платеж для заказа, ожидающего оплату, после неудачной попытки
+func (s *PaymentService) Retry(ctx context.Context, userId, orderId int64) (*dto.PaymentResponse, error) {
+	order, err := s.accessibleOrder(ctx, userId, orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.Status != model.OrderStatusCreated {
+		return nil, customError.NewServiceError(http.StatusConflict, fmt.Sprintf("Order is %s and does not wait for payment", order.Status), nil)
+	}
+
+	p, err := s.StartPayment(ctx, order)
+
+	if errors.Is(err, repository.ErrPaymentExists) {
+		return nil, customError.NewServiceError(http.StatusConflict, "Order already has an active payment", err)
+	}
+
+	if err != nil {
+		logger.Log.Error("PaymentService -> Retry -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusBadGateway, "Payment provider is unavailable, please try again later", nil)
+	}
+
+	return p.ToResponse(), nil
+}
+
+// Проверяет подпись и применяет событие шлюза. Повторная доставка события - не ошибка,
+// иначе шлюз продолжит ее повторять
+func (s *PaymentService) HandleWebhook(ctx context.Context, payload []byte, signature string) error {
+	event, err := s.provider.VerifyWebhook(payload, signature)
+	if err != nil {
+		logger.Log.Warn("PaymentService -> HandleWebhook -> VerifyWebhook -> err -> " + err.Error())
+		return customError.NewServiceError(http.StatusBadRequest, "Invalid webhook signature", err)
+	}
+
+	var status string
+	switch event.Type {
+	case payment.EventAuthorized:
+		status = model.PaymentStatusAuthorized
+	case payment.EventFailed:
+		status = model.PaymentStatusFailed
+	default:
+		// Неизвестные события подтверждаем, чтобы шлюз не повторял их
+		return nil
+	}
+
+	result, err := s.paymentRepository.ApplyWebhook(ctx, &model.PaymentWebhook{
+		EventId:  event.Id,
+		Type:     event.Type,
+		IntentId: event.IntentId,
+		Status:   status,
+		Amount:   event.Amount,
+		Payload:  payload,
+	})
+
+	if errors.Is(err, repository.ErrPaymentNotFound) {
+		return customError.NewServiceError(http.StatusNotFound, "Payment not found", err)
+	}
+
+	if err != nil {
+		logger.Log.Error("PaymentService -> HandleWebhook -> err -> " + err.Error())
+		return customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	if result.Duplicate {
+		return nil
+	}
+
+	if result.Change != nil {
+		s.tracker.StatusChanged(ctx, result.Change)
+	}
+
+	if result.Release {
+		s.refund(ctx, result.Payment)
+	}
+
+	return nil
+}
+
+// Списание при передаче в доставку, когда сборка завершена и сумма заказа окончательная.
+// Возврат денег при отмене и возврате заказа
+func (s *PaymentService) StatusChanged(ctx context.Context, change *model.OrderStatusChange) {
+	switch change.ToStatus {
+	case model.OrderStatusOutForDelivery, model.OrderStatusCancelled, model.OrderStatusReturned:
+	default:
+		return
+	}
+
+	p, ok, err := s.paymentRepository.FindLatestByOrder(ctx, change.OrderId)
+	if err != nil {
+		logger.Log.Error("PaymentService -> StatusChanged -> FindLatestByOrder -> err -> " + err.Error())
+		return
+	}
+
+	// Заказы, оформленные до подключения оплаты
+	if !ok {
+		return
+	}
+
+	if change.ToStatus == model.OrderStatusOutForDelivery {
+		s.capture(ctx, p)
+		return
+	}
+
+	s.refund(ctx, p)
+}
+
+func (s *PaymentService) capture(ctx context.Context, p *model.Payment) {
+	if p.Status != model.PaymentStatusAuthorized {
+		return
+	}
+
+	order, ok, err := s.orderRepository.FindById(ctx, p.OrderId)
+	if err != nil || !ok {
+		logger.Log.Error(fmt.Sprintf("PaymentService -> capture -> order %d not loaded -> err -> %v", p.OrderId, err))
+		return
+	}
+
+	// Недовложения и замены могли уменьшить сумму, списываем не больше авторизованного
+	amount := min(payment.MinorUnits(float64(order.Total)), p.AuthorizedAmount)
+
+	if err = s.provider.Capture(ctx, p.IntentId, amount); err != nil {
+		logger.Log.Error("PaymentService -> capture -> Capture -> err -> " + err.Error())
+		return
+	}
+
+	p.Status = model.PaymentStatusCaptured
+	p.CapturedAmount = amount
+	s.updateState(ctx, p, model.PaymentStatusAuthorized)
+}
+
+// Отменяет авторизацию или возвращает списанные деньги
+func (s *PaymentService) refund(ctx context.Context, p *model.Payment) {
+	fromStatus := p.Status
+
+	switch p.Status {
+	case model.PaymentStatusAuthorized:
+		if err := s.provider.Refund(ctx, p.IntentId, p.AuthorizedAmount); err != nil {
+			logger.Log.Error("PaymentService -> refund -> release -> err -> " + err.Error())
+			return
+		}
+		p.Status = model.PaymentStatusCanceled
+	case model.PaymentStatusCaptured:
+		amount := p.CapturedAmount - p.RefundedAmount
+		if err := s.provider.Refund(ctx, p.IntentId, amount); err != nil {
+			logger.Log.Error("PaymentService -> refund -> Refund -> err -> " + err.Error())
+			return
+		}
+		p.Status = model.PaymentStatusRefunded
+		p.RefundedAmount += amount
+	default:
+		return
+	}
+
+	s.updateState(ctx, p, fromStatus)
+}
+
+func (s *PaymentService) updateState(ctx context.Context, p *model.Payment, fromStatus string) {
+	ok, err := s.paymentRepository.UpdateState(ctx, p, fromStatus)
+	if err != nil {
+		logger.Log.Error("PaymentService -> updateState -> err -> " + err.Error())
+		return
+	}
+
+	if !ok {
+		logger.Log.Warn(fmt.Sprintf("PaymentService -> updateState -> payment %d is no longer %s", p.Id, fromStatus))
+	}
+}
+
+// Доступ к платежу такой же, как к самому заказу
+func (s *PaymentService) accessibleOrder(ctx context.Context, userId, orderId int64) (*model.Order, error) {
+	user, err := s.userRepository.FindById(ctx, userId)
+	if err != nil {
+		logger.Log.Error("PaymentService -> accessibleOrder -> FindById -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, nil)
+	}
+
+	order, ok, err := s.orderRepository.FindById(ctx, orderId)
+	if err != nil {
+		logger.Log.Error("PaymentService -> accessibleOrder -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	if ok {
+		ok, err = canAccessOrder(ctx, s.orderRepository, userId, user.RoleCode, order)
+		if err != nil {
+			logger.Log.Error("PaymentService -> accessibleOrder -> IsCourierOrder -> err -> " + err.Error())
+			return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+		}
+	}
+
+	if !ok {
+		return nil, customError.NewServiceError(http.StatusNotFound, customError.ErrorNotFoundById, nil)
+	}
+
+	return order, nil
+}
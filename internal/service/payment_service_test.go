@@ -0,0 +1,321 @@
+package service_test
+
+import (
+	"arabic/internal/model"
+	"arabic/internal/repository"
+	"arabic/internal/service"
+	"arabic/pkg/customError"
+	"arabic/pkg/logger"
+	"arabic/pkg/payment"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+)
+
+type MockIOrderPayments struct {
+	mock.Mock
+}
+
+func (m *MockIOrderPayments) StartPayment(ctx context.Context, order *model.Order) (*model.Payment, error) {
+	args := m.Called(ctx, order)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Payment), args.Error(1)
+}
+func (m *MockIOrderPayments) StatusChanged(ctx context.Context, change *model.OrderStatusChange) {
+	m.Called(ctx, change)
+}
+
+type MockIPaymentRepository struct {
+	mock.Mock
+}
+
+func (m *MockIPaymentRepository) Create(ctx context.Context, p *model.Payment) error {
+	args := m.Called(ctx, p)
+	return args.Error(0)
+}
+func (m *MockIPaymentRepository) FindLatestByOrder(ctx context.Context, orderId int64) (*model.Payment, bool, error) {
+	args := m.Called(ctx, orderId)
+	if args.Get(0) == nil {
+		return nil, args.Bool(1), args.Error(2)
+	}
+	return args.Get(0).(*model.Payment), args.Bool(1), args.Error(2)
+}
+func (m *MockIPaymentRepository) ApplyWebhook(ctx context.Context, webhook *model.PaymentWebhook) (*model.PaymentWebhookResult, error) {
+	args := m.Called(ctx, webhook)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.PaymentWebhookResult), args.Error(1)
+}
+func (m *MockIPaymentRepository) UpdateState(ctx context.Context, p *model.Payment, fromStatus string) (bool, error) {
+	args := m.Called(ctx, p, fromStatus)
+	return args.Bool(0), args.Error(1)
+}
+
+type MockPaymentProvider struct {
+	mock.Mock
+}
+
+func (m *MockPaymentProvider) Name() string {
+	return "mock"
+}
+func (m *MockPaymentProvider) CreateIntent(ctx context.Context, req *payment.IntentRequest) (*payment.Intent, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*payment.Intent), args.Error(1)
+}
+func (m *MockPaymentProvider) Capture(ctx context.Context, intentId string, amount int64) error {
+	args := m.Called(ctx, intentId, amount)
+	return args.Error(0)
+}
+func (m *MockPaymentProvider) Refund(ctx context.Context, intentId string, amount int64) error {
+	args := m.Called(ctx, intentId, amount)
+	return args.Error(0)
+}
+func (m *MockPaymentProvider) VerifyWebhook(payload []byte, signature string) (*payment.WebhookEvent, error) {
+	args := m.Called(payload, signature)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*payment.WebhookEvent), args.Error(1)
+}
+
+// Запоминает опубликованные переходы статусов
+type RecordingOrderTracker struct {
+	NoopOrderTracker
+	changes []*model.OrderStatusChange
+}
+
+func (r *RecordingOrderTracker) StatusChanged(ctx context.Context, change *model.OrderStatusChange) {
+	r.changes = append(r.changes, change)
+}
+
+func TestPaymentService_HandleWebhook(t *testing.T) {
+	logger.Init("Error", "./")
+
+	authorized := &payment.WebhookEvent{Id: "evt_1", Type: payment.EventAuthorized, IntentId: "pi_1", Amount: 10_000}
+	confirm := &model.OrderStatusChange{Id: 3, OrderId: 7, FromStatus: model.OrderStatusCreated, ToStatus: model.OrderStatusConfirmed}
+
+	tests := []struct {
+		name          string
+		event         *payment.WebhookEvent
+		verifyErr     error
+		result        *model.PaymentWebhookResult
+		repoErr       error
+		expectCode    int
+		expectChanges int
+		expectRelease bool
+	}{
+		{
+			name:          "authorization confirms order",
+			event:         authorized,
+			result:        &model.PaymentWebhookResult{Payment: &model.Payment{Id: 1, IntentId: "pi_1", Status: model.PaymentStatusAuthorized, AuthorizedAmount: 10_000}, Change: confirm},
+			expectChanges: 1,
+		},
+		{
+			name:   "duplicate delivery is acknowledged without changes",
+			event:  authorized,
+			result: &model.PaymentWebhookResult{Duplicate: true},
+		},
+		{
+			name:          "authorization of cancelled order is released",
+			event:         authorized,
+			result:        &model.PaymentWebhookResult{Payment: &model.Payment{Id: 1, IntentId: "pi_1", Status: model.PaymentStatusAuthorized, AuthorizedAmount: 10_000}, Release: true},
+			expectRelease: true,
+		},
+		{
+			name:       "invalid signature",
+			verifyErr:  payment.ErrInvalidSignature,
+			expectCode: 400,
+		},
+		{
+			name:       "unknown payment",
+			event:      authorized,
+			repoErr:    repository.ErrPaymentNotFound,
+			expectCode: 404,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			provider := &MockPaymentProvider{}
+			provider.On("VerifyWebhook", mock.Anything, "sig").Return(tc.event, tc.verifyErr)
+			provider.On("Refund", mock.Anything, "pi_1", int64(10_000)).Return(nil)
+
+			paymentRepo := &MockIPaymentRepository{}
+			paymentRepo.On("ApplyWebhook", mock.Anything, mock.Anything).Return(tc.result, tc.repoErr)
+			paymentRepo.On("UpdateState", mock.Anything, mock.Anything, model.PaymentStatusAuthorized).Return(true, nil)
+
+			tracker := &RecordingOrderTracker{}
+			srv := service.NewPaymentService(paymentRepo, &MockIOrderRepository{}, &MockIUserRepository{}, provider, payment.NewConfig(), tracker)
+			err := srv.HandleWebhook(context.Background(), []byte(`{}`), "sig")
+
+			if tc.expectCode != 0 {
+				var serviceErr *customError.ServiceError
+				assert.ErrorAs(t, err, &serviceErr)
+				assert.Equal(t, tc.expectCode, serviceErr.Code)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Len(t, tracker.changes, tc.expectChanges)
+			if tc.expectRelease {
+				provider.AssertNumberOfCalls(t, "Refund", 1)
+				assert.Equal(t, model.PaymentStatusCanceled, tc.result.Payment.Status)
+			} else {
+				provider.AssertNotCalled(t, "Refund", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestPaymentService_StatusChanged(t *testing.T) {
+	logger.Init("Error", "./")
+
+	tests := []struct {
+		name          string
+		toStatus      string
+		payment       *model.Payment
+		orderTotal    float32
+		expectCapture int64
+		expectRefund  int64
+		expectStatus  string
+	}{
+		{
+			name:          "dispatch captures reduced total",
+			toStatus:      model.OrderStatusOutForDelivery,
+			payment:       &model.Payment{Id: 1, OrderId: 7, IntentId: "pi_1", Status: model.PaymentStatusAuthorized, AuthorizedAmount: 10_000},
+			orderTotal:    80.5,
+			expectCapture: 8_050,
+			expectStatus:  model.PaymentStatusCaptured,
+		},
+		{
+			name:          "capture never exceeds authorization",
+			toStatus:      model.OrderStatusOutForDelivery,
+			payment:       &model.Payment{Id: 1, OrderId: 7, IntentId: "pi_1", Status: model.PaymentStatusAuthorized, AuthorizedAmount: 10_000},
+			orderTotal:    120,
+			expectCapture: 10_000,
+			expectStatus:  model.PaymentStatusCaptured,
+		},
+		{
+			name:         "cancel releases authorization",
+			toStatus:     model.OrderStatusCancelled,
+			payment:      &model.Payment{Id: 1, OrderId: 7, IntentId: "pi_1", Status: model.PaymentStatusAuthorized, AuthorizedAmount: 10_000},
+			expectRefund: 10_000,
+			expectStatus: model.PaymentStatusCanceled,
+		},
+		{
+			name:         "return refunds captured amount",
+			toStatus:     model.OrderStatusReturned,
+			payment:      &model.Payment{Id: 1, OrderId: 7, IntentId: "pi_1", Status: model.PaymentStatusCaptured, AuthorizedAmount: 10_000, CapturedAmount: 8_050},
+			expectRefund: 8_050,
+			expectStatus: model.PaymentStatusRefunded,
+		},
+		{
+			name:         "failed payment is left as is",
+			toStatus:     model.OrderStatusCancelled,
+			payment:      &model.Payment{Id: 1, OrderId: 7, IntentId: "pi_1", Status: model.PaymentStatusFailed},
+			expectStatus: model.PaymentStatusFailed,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			provider := &MockPaymentProvider{}
+			provider.On("Capture", mock.Anything, "pi_1", tc.expectCapture).Return(nil)
+			provider.On("Refund", mock.Anything, "pi_1", tc.expectRefund).Return(nil)
+
+			paymentRepo := &MockIPaymentRepository{}
+			paymentRepo.On("FindLatestByOrder", mock.Anything, int64(7)).Return(tc.payment, true, nil)
+			paymentRepo.On("UpdateState", mock.Anything, tc.payment, mock.Anything).Return(true, nil)
+
+			orderRepo := &MockIOrderRepository{}
+			orderRepo.On("FindById", mock.Anything, int64(7)).Return(&model.Order{Id: 7, Total: tc.orderTotal}, true, nil)
+
+			srv := service.NewPaymentService(paymentRepo, orderRepo, &MockIUserRepository{}, provider, payment.NewConfig(), &NoopOrderTracker{})
+			srv.StatusChanged(context.Background(), &model.OrderStatusChange{OrderId: 7, ToStatus: tc.toStatus})
+
+			assert.Equal(t, tc.expectStatus, tc.payment.Status)
+			if tc.expectCapture == 0 {
+				provider.AssertNotCalled(t, "Capture", mock.Anything, mock.Anything, mock.Anything)
+			}
+			if tc.expectRefund == 0 {
+				provider.AssertNotCalled(t, "Refund", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestPaymentService_GetPayment(t *testing.T) {
+	logger.Init("Error", "./")
+
+	tests := []struct {
+		name       string
+		role       string
+		userId     int64
+		assigned   bool
+		expectCode int
+	}{
+		{
+			name:   "owner sees payment",
+			role:   model.RoleUser,
+			userId: 1,
+		},
+		{
+			name:   "collector sees any payment",
+			role:   model.RoleCollector,
+			userId: 5,
+		},
+		{
+			name:     "courier sees payment of own order",
+			role:     model.RoleCourier,
+			userId:   5,
+			assigned: true,
+		},
+		{
+			name:       "courier does not see orders of other couriers",
+			role:       model.RoleCourier,
+			userId:     5,
+			expectCode: 404,
+		},
+		{
+			name:       "moderator does not see foreign payments",
+			role:       model.RoleModerator,
+			userId:     5,
+			expectCode: 404,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			userRepo := &MockIUserRepository{}
+			userRepo.On("FindById", mock.Anything, tc.userId).Return(&model.UserFullInfo{User: model.User{Id: tc.userId, RoleCode: tc.role}}, nil)
+
+			orderRepo := &MockIOrderRepository{}
+			orderRepo.On("FindById", mock.Anything, int64(7)).Return(&model.Order{Id: 7, UserId: 1, Status: model.OrderStatusOutForDelivery}, true, nil)
+			orderRepo.On("IsCourierOrder", mock.Anything, int64(7), tc.userId).Return(tc.assigned, nil)
+
+			paymentRepo := &MockIPaymentRepository{}
+			paymentRepo.On("FindLatestByOrder", mock.Anything, int64(7)).Return(&model.Payment{Id: 1, OrderId: 7, ClientSecret: "secret", Status: model.PaymentStatusPending}, true, nil)
+
+			srv := service.NewPaymentService(paymentRepo, orderRepo, userRepo, &MockPaymentProvider{}, payment.NewConfig(), &NoopOrderTracker{})
+			resp, err := srv.GetPayment(context.Background(), tc.userId, 7)
+
+			if tc.expectCode != 0 {
+				var serviceErr *customError.ServiceError
+				assert.ErrorAs(t, err, &serviceErr)
+				assert.Equal(t, tc.expectCode, serviceErr.Code)
+				paymentRepo.AssertNotCalled(t, "FindLatestByOrder", mock.Anything, mock.Anything)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, int64(1), resp.Id)
+		})
+	}
+}
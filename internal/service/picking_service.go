@@ -0,0 +1,253 @@
+package service
+
+import (
+	"arabic/internal/dto"
+	"arabic/internal/model"
+	"arabic/internal/repository"
+	"arabic/pkg/customError"
+	"arabic/pkg/logger"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+type IPickingService interface {
+	Next(ctx context.Context, pickerId int64) (*dto.PickingListResponse, error)
+	GetList(ctx context.Context, userId, orderId int64) (*dto.PickingListResponse, error)
+	UpdateLine(ctx context.Context, userId, orderId, itemId int64, req *dto.PickLineRequest) (*dto.PickingListResponse, error)
+	GetEvents(ctx context.Context, userId, orderId int64) ([]*dto.PickEventResponse, error)
+	ResolveEvent(ctx context.Context, userId, orderId, eventId int64, approve bool) (*dto.PickEventResponse, error)
+}
+
+type PickingService struct {
+	pickingRepository repository.IPickingRepository
+	orderRepository   repository.IOrderRepository
+	userRepository    repository.IUserRepository
+	tracker           IOrderTracker
+}
+
+func NewPickingService(pickingRepo repository.IPickingRepository, orderRepo repository.IOrderRepository, userRepo repository.IUserRepository, tracker IOrderTracker) *PickingService {
+	return &PickingService{
+		pickingRepository: pickingRepo,
+		orderRepository:   orderRepo,
+		userRepository:    userRepo,
+		tracker:           tracker,
+	}
+}
+
+// Текущий заказ сборщика или следующий подтвержденный заказ из очереди
+func (s *PickingService) Next(ctx context.Context, pickerId int64) (*dto.PickingListResponse, error) {
+	orderId, started, err := s.pickingRepository.ClaimNext(ctx, pickerId)
+
+	if err != nil {
+		logger.Log.Error("PickingService -> Next -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	if orderId == 0 {
+		return nil, customError.NewServiceError(http.StatusNotFound, "No orders are waiting for picking", nil)
+	}
+
+	if started != nil {
+		s.tracker.StatusChanged(ctx, started)
+	}
+
+	order, err := s.findOrder(ctx, orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	return order.ToResponse(), nil
+}
+
+func (s *PickingService) GetList(ctx context.Context, userId, orderId int64) (*dto.PickingListResponse, error) {
+	order, err := s.pickerOrder(ctx, userId, orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	return order.ToResponse(), nil
+}
+
+func (s *PickingService) UpdateLine(ctx context.Context, userId, orderId, itemId int64, req *dto.PickLineRequest) (*dto.PickingListResponse, error) {
+	order, err := s.pickerOrder(ctx, userId, orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.Status != model.OrderStatusPicking {
+		return nil, customError.NewServiceError(http.StatusConflict, fmt.Sprintf("Order is %s, only orders in picking can be changed", order.Status), nil)
+	}
+
+	line, ok := order.Line(itemId)
+	if !ok {
+		return nil, customError.NewServiceError(http.StatusNotFound, customError.ErrorNotFoundById, nil)
+	}
+
+	if line.PickStatus != model.PickStatusPending {
+		return nil, customError.NewServiceError(http.StatusConflict, fmt.Sprintf("Line is already marked as %s", line.PickStatus), nil)
+	}
+
+	var event *model.PickEvent
+
+	switch req.Status {
+	case model.PickStatusPicked:
+		err = s.pickingRepository.MarkPicked(ctx, orderId, itemId)
+	case model.PickStatusShort:
+		if req.Quantity >= line.Quantity {
+			return nil, customError.NewServiceError(http.StatusBadRequest, fmt.Sprintf("Short pick quantity must be less than %d", line.Quantity), nil)
+		}
+		event, err = s.pickingRepository.ShortPick(ctx, orderId, itemId, req.Quantity, userId)
+	case model.PickStatusSubstituted:
+		if req.SubstituteCatalogId == 0 || req.SubstituteCatalogId == line.CatalogId || req.Quantity < 1 {
+			return nil, customError.NewServiceError(http.StatusBadRequest, "Substitution requires another catalog item and quantity of at least 1", nil)
+		}
+		event, err = s.pickingRepository.Substitute(ctx, orderId, itemId, req.SubstituteCatalogId, req.Quantity, userId)
+	default:
+		return nil, customError.NewServiceError(http.StatusBadRequest, fmt.Sprintf("Unknown pick status %s", req.Status), nil)
+	}
+
+	if err != nil {
+		if errors.Is(err, repository.ErrPickLineUnavailable) {
+			return nil, customError.NewServiceError(http.StatusConflict, "Line or order was changed by someone else, please reload the picking list", err)
+		}
+
+		var stockErr *repository.StockError
+		if errors.As(err, &stockErr) {
+			return nil, stockServiceError(stockErr)
+		}
+
+		logger.Log.Error("PickingService -> UpdateLine -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	if event != nil {
+		s.tracker.PickEventChanged(ctx, event)
+	}
+
+	order, err = s.findOrder(ctx, orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	return order.ToResponse(), nil
+}
+
+// Недовложения и замены заказа. Доступ такой же, как к самому заказу
+func (s *PickingService) GetEvents(ctx context.Context, userId, orderId int64) ([]*dto.PickEventResponse, error) {
+	if _, err := s.customerOrder(ctx, userId, orderId, true); err != nil {
+		return nil, err
+	}
+
+	events, err := s.pickingRepository.FindEvents(ctx, orderId)
+
+	if err != nil {
+		logger.Log.Error("PickingService -> GetEvents -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	resp := make([]*dto.PickEventResponse, 0, len(events))
+	for _, event := range events {
+		resp = append(resp, event.ToResponse())
+	}
+
+	return resp, nil
+}
+
+func (s *PickingService) ResolveEvent(ctx context.Context, userId, orderId, eventId int64, approve bool) (*dto.PickEventResponse, error) {
+	if _, err := s.customerOrder(ctx, userId, orderId, false); err != nil {
+		return nil, err
+	}
+
+	event, err := s.pickingRepository.ResolveEvent(ctx, orderId, eventId, approve)
+
+	if err != nil {
+		if errors.Is(err, repository.ErrPickEventNotFound) {
+			return nil, customError.NewServiceError(http.StatusNotFound, customError.ErrorNotFoundById, err)
+		}
+		if errors.Is(err, repository.ErrPickEventClosed) {
+			return nil, customError.NewServiceError(http.StatusConflict, "This change is already resolved or the order is already on its way", err)
+		}
+
+		logger.Log.Error("PickingService -> ResolveEvent -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	s.tracker.PickEventChanged(ctx, event)
+
+	return event.ToResponse(), nil
+}
+
+// Заказ доступен назначенному сборщику и администратору
+func (s *PickingService) pickerOrder(ctx context.Context, userId, orderId int64) (*model.PickingOrder, error) {
+	role, err := s.userRole(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := s.findOrder(ctx, orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	if role != model.RoleAdmin && (order.PickerId == nil || *order.PickerId != userId) {
+		return nil, customError.NewServiceError(http.StatusForbidden, "Order is assigned to another collector", nil)
+	}
+
+	return order, nil
+}
+
+// Чужой заказ неотличим от несуществующего. allowStaff открывает заказ тем, кому доступен сам заказ
+func (s *PickingService) customerOrder(ctx context.Context, userId, orderId int64, allowStaff bool) (*model.PickingOrder, error) {
+	role, err := s.userRole(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := s.findOrder(ctx, orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	ok := order.UserId == userId
+	if !ok && allowStaff {
+		ok, err = canAccessOrder(ctx, s.orderRepository, userId, role, &model.Order{Id: order.OrderId, UserId: order.UserId, Status: order.Status})
+		if err != nil {
+			logger.Log.Error("PickingService -> customerOrder -> IsCourierOrder -> err -> " + err.Error())
+			return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+		}
+	}
+
+	if !ok {
+		return nil, customError.NewServiceError(http.StatusNotFound, customError.ErrorNotFoundById, nil)
+	}
+
+	return order, nil
+}
+
+func (s *PickingService) findOrder(ctx context.Context, orderId int64) (*model.PickingOrder, error) {
+	order, ok, err := s.pickingRepository.FindPickingOrder(ctx, orderId)
+
+	if err != nil {
+		logger.Log.Error("PickingService -> findOrder -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	if !ok {
+		return nil, customError.NewServiceError(http.StatusNotFound, customError.ErrorNotFoundById, nil)
+	}
+
+	return order, nil
+}
+
+func (s *PickingService) userRole(ctx context.Context, userId int64) (string, error) {
+	user, err := s.userRepository.FindById(ctx, userId)
+
+	if err != nil {
+		logger.Log.Error("PickingService -> userRole -> err -> " + err.Error())
+		return "", customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, nil)
+	}
+
+	return user.RoleCode, nil
+}
@@ -0,0 +1,277 @@
+package service_test
+
+import (
+	"arabic/internal/dto"
+	"arabic/internal/model"
+	"arabic/internal/repository"
+	"arabic/internal/service"
+	"arabic/pkg/customError"
+	"arabic/pkg/logger"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+)
+
+type MockIPickingRepository struct {
+	mock.Mock
+}
+
+func (m *MockIPickingRepository) ClaimNext(ctx context.Context, pickerId int64) (int64, *model.OrderStatusChange, error) {
+	args := m.Called(ctx, pickerId)
+	if args.Get(1) == nil {
+		return args.Get(0).(int64), nil, args.Error(2)
+	}
+	return args.Get(0).(int64), args.Get(1).(*model.OrderStatusChange), args.Error(2)
+}
+func (m *MockIPickingRepository) FindPickingOrder(ctx context.Context, orderId int64) (*model.PickingOrder, bool, error) {
+	args := m.Called(ctx, orderId)
+	if args.Get(0) == nil {
+		return nil, args.Bool(1), args.Error(2)
+	}
+	return args.Get(0).(*model.PickingOrder), args.Bool(1), args.Error(2)
+}
+func (m *MockIPickingRepository) MarkPicked(ctx context.Context, orderId, itemId int64) error {
+	args := m.Called(ctx, orderId, itemId)
+	return args.Error(0)
+}
+func (m *MockIPickingRepository) ShortPick(ctx context.Context, orderId, itemId int64, quantity int, pickerId int64) (*model.PickEvent, error) {
+	args := m.Called(ctx, orderId, itemId, quantity, pickerId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.PickEvent), args.Error(1)
+}
+func (m *MockIPickingRepository) Substitute(ctx context.Context, orderId, itemId int64, catalogId uint, quantity int, pickerId int64) (*model.PickEvent, error) {
+	args := m.Called(ctx, orderId, itemId, catalogId, quantity, pickerId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.PickEvent), args.Error(1)
+}
+func (m *MockIPickingRepository) FindEvents(ctx context.Context, orderId int64) ([]*model.PickEvent, error) {
+	args := m.Called(ctx, orderId)
+	return args.Get(0).([]*model.PickEvent), args.Error(1)
+}
+func (m *MockIPickingRepository) ResolveEvent(ctx context.Context, orderId, eventId int64, approve bool) (*model.PickEvent, error) {
+	args := m.Called(ctx, orderId, eventId, approve)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.PickEvent), args.Error(1)
+}
+
+func testPickingOrder(pickerId int64) *model.PickingOrder {
+	fruits, dairy := int64(1), int64(2)
+	return &model.PickingOrder{
+		OrderId:  7,
+		UserId:   1,
+		Status:   model.OrderStatusPicking,
+		PickerId: &pickerId,
+		Lines: []*model.PickLine{
+			{ItemId: 20, CatalogId: 4, Name: "Apple", CategoryId: &fruits, Quantity: 3, OrderedQuantity: 3, PickStatus: model.PickStatusPending},
+			{ItemId: 21, CatalogId: 5, Name: "Banana", CategoryId: &fruits, Quantity: 1, OrderedQuantity: 1, PickStatus: model.PickStatusPicked},
+			{ItemId: 22, CatalogId: 6, Name: "Milk", CategoryId: &dairy, Quantity: 2, OrderedQuantity: 2, PickStatus: model.PickStatusPending},
+		},
+	}
+}
+
+func TestPickingService_UpdateLine(t *testing.T) {
+	logger.Init("Error", "./")
+
+	tests := []struct {
+		name       string
+		userId     int64
+		role       string
+		req        *dto.PickLineRequest
+		repoError  error
+		expectCall string
+		expectCode int
+	}{
+		{
+			name:       "picked",
+			userId:     9,
+			role:       model.RoleCollector,
+			req:        &dto.PickLineRequest{Status: model.PickStatusPicked},
+			expectCall: "MarkPicked",
+		},
+		{
+			name:       "short pick",
+			userId:     9,
+			role:       model.RoleCollector,
+			req:        &dto.PickLineRequest{Status: model.PickStatusShort, Quantity: 1},
+			expectCall: "ShortPick",
+		},
+		{
+			name:       "short pick cannot exceed ordered quantity",
+			userId:     9,
+			role:       model.RoleCollector,
+			req:        &dto.PickLineRequest{Status: model.PickStatusShort, Quantity: 3},
+			expectCode: 400,
+		},
+		{
+			name:       "substitution with the same item",
+			userId:     9,
+			role:       model.RoleCollector,
+			req:        &dto.PickLineRequest{Status: model.PickStatusSubstituted, Quantity: 3, SubstituteCatalogId: 4},
+			expectCode: 400,
+		},
+		{
+			name:       "substitute out of stock",
+			userId:     9,
+			role:       model.RoleCollector,
+			req:        &dto.PickLineRequest{Status: model.PickStatusSubstituted, Quantity: 3, SubstituteCatalogId: 8},
+			repoError:  &repository.StockError{CatalogId: 8, Available: 1},
+			expectCall: "Substitute",
+			expectCode: 409,
+		},
+		{
+			name:       "another collector",
+			userId:     10,
+			role:       model.RoleCollector,
+			req:        &dto.PickLineRequest{Status: model.PickStatusPicked},
+			expectCode: 403,
+		},
+		{
+			name:       "admin can pick any order",
+			userId:     10,
+			role:       model.RoleAdmin,
+			req:        &dto.PickLineRequest{Status: model.PickStatusPicked},
+			expectCall: "MarkPicked",
+		},
+		{
+			name:       "line changed concurrently",
+			userId:     9,
+			role:       model.RoleCollector,
+			req:        &dto.PickLineRequest{Status: model.PickStatusPicked},
+			repoError:  repository.ErrPickLineUnavailable,
+			expectCall: "MarkPicked",
+			expectCode: 409,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pickingRepo := &MockIPickingRepository{}
+			userRepo := &MockIUserRepository{}
+
+			user := &model.UserFullInfo{User: model.User{Id: tc.userId, RoleCode: tc.role}}
+			userRepo.On("FindById", mock.Anything, tc.userId).Return(user, nil)
+			pickingRepo.On("FindPickingOrder", mock.Anything, int64(7)).Return(testPickingOrder(9), true, nil)
+			pickingRepo.On("MarkPicked", mock.Anything, int64(7), int64(20)).Return(tc.repoError)
+			pickingRepo.On("ShortPick", mock.Anything, int64(7), int64(20), tc.req.Quantity, tc.userId).Return(&model.PickEvent{}, tc.repoError)
+			pickingRepo.On("Substitute", mock.Anything, int64(7), int64(20), tc.req.SubstituteCatalogId, tc.req.Quantity, tc.userId).Return(nil, tc.repoError)
+
+			srv := service.NewPickingService(pickingRepo, &MockIOrderRepository{}, userRepo, &NoopOrderTracker{})
+			resp, err := srv.UpdateLine(context.Background(), tc.userId, 7, 20, tc.req)
+
+			if tc.expectCall != "" {
+				pickingRepo.AssertNumberOfCalls(t, tc.expectCall, 1)
+			}
+
+			if tc.expectCode != 0 {
+				var serviceErr *customError.ServiceError
+				assert.ErrorAs(t, err, &serviceErr)
+				assert.Equal(t, tc.expectCode, serviceErr.Code)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Len(t, resp.Groups, 2)
+			assert.Equal(t, 2, resp.PendingLines)
+		})
+	}
+}
+
+func TestPickingService_ResolveEvent(t *testing.T) {
+	logger.Init("Error", "./")
+
+	tests := []struct {
+		name       string
+		userId     int64
+		repoError  error
+		expectCode int
+	}{
+		{name: "owner rejects substitution", userId: 1},
+		{name: "foreign order looks missing", userId: 2, expectCode: 404},
+		{name: "already resolved", userId: 1, repoError: repository.ErrPickEventClosed, expectCode: 409},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pickingRepo := &MockIPickingRepository{}
+			userRepo := &MockIUserRepository{}
+
+			user := &model.UserFullInfo{User: model.User{Id: tc.userId, RoleCode: model.RoleUser}}
+			userRepo.On("FindById", mock.Anything, tc.userId).Return(user, nil)
+			pickingRepo.On("FindPickingOrder", mock.Anything, int64(7)).Return(testPickingOrder(9), true, nil)
+
+			event := &model.PickEvent{Id: 3, OrderId: 7, Type: model.PickEventSubstitution, Status: model.PickDecisionRejected}
+			if tc.repoError != nil {
+				pickingRepo.On("ResolveEvent", mock.Anything, int64(7), int64(3), false).Return(nil, tc.repoError)
+			} else {
+				pickingRepo.On("ResolveEvent", mock.Anything, int64(7), int64(3), false).Return(event, nil)
+			}
+
+			srv := service.NewPickingService(pickingRepo, &MockIOrderRepository{}, userRepo, &NoopOrderTracker{})
+			resp, err := srv.ResolveEvent(context.Background(), tc.userId, 7, 3, false)
+
+			if tc.expectCode != 0 {
+				var serviceErr *customError.ServiceError
+				assert.ErrorAs(t, err, &serviceErr)
+				assert.Equal(t, tc.expectCode, serviceErr.Code)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, model.PickDecisionRejected, resp.Status)
+		})
+	}
+}
+
+func TestPickingService_GetEvents(t *testing.T) {
+	logger.Init("Error", "./")
+
+	tests := []struct {
+		name       string
+		role       string
+		userId     int64
+		assigned   bool
+		expectCode int
+	}{
+		{name: "owner sees events", role: model.RoleUser, userId: 1},
+		{name: "collector sees events", role: model.RoleCollector, userId: 9},
+		{name: "courier sees events of own order", role: model.RoleCourier, userId: 5, assigned: true},
+		{name: "courier does not see orders of other couriers", role: model.RoleCourier, userId: 5, expectCode: 404},
+		{name: "moderator does not see foreign orders", role: model.RoleModerator, userId: 5, expectCode: 404},
+		{name: "foreign order looks missing", role: model.RoleUser, userId: 2, expectCode: 404},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pickingRepo := &MockIPickingRepository{}
+			userRepo := &MockIUserRepository{}
+			orderRepo := &MockIOrderRepository{}
+
+			user := &model.UserFullInfo{User: model.User{Id: tc.userId, RoleCode: tc.role}}
+			userRepo.On("FindById", mock.Anything, tc.userId).Return(user, nil)
+			pickingRepo.On("FindPickingOrder", mock.Anything, int64(7)).Return(testPickingOrder(9), true, nil)
+			pickingRepo.On("FindEvents", mock.Anything, int64(7)).Return([]*model.PickEvent{{Id: 3, OrderId: 7}}, nil)
+			orderRepo.On("IsCourierOrder", mock.Anything, int64(7), tc.userId).Return(tc.assigned, nil)
+
+			srv := service.NewPickingService(pickingRepo, orderRepo, userRepo, &NoopOrderTracker{})
+			resp, err := srv.GetEvents(context.Background(), tc.userId, 7)
+
+			if tc.expectCode != 0 {
+				var serviceErr *customError.ServiceError
+				assert.ErrorAs(t, err, &serviceErr)
+				assert.Equal(t, tc.expectCode, serviceErr.Code)
+				pickingRepo.AssertNotCalled(t, "FindEvents", mock.Anything, mock.Anything)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Len(t, resp, 1)
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"arabic/internal/dto"
+	"arabic/internal/model"
+	"arabic/internal/repository"
+	"arabic/pkg/customError"
+	"arabic/pkg/geo"
+	"arabic/pkg/logger"
+	"context"
+	"net/http"
+	"time"
+)
+
+type IPricingService interface {
+	Price(ctx context.Context, owner *dto.CartOwner, req *dto.PriceRequest) (*dto.PriceBreakdownResponse, error)
+}
+
+type PricingService struct {
+	promotionRepository repository.IPromotionRepository
+	cartRepository      repository.ICartRepository
+	userRepository      repository.IUserRepository
+	deliveryRepository  repository.IDeliveryRepository
+}
+
+func NewPricingService(promotionRepo repository.IPromotionRepository, cartRepo repository.ICartRepository, userRepo repository.IUserRepository, deliveryRepo repository.IDeliveryRepository) *PricingService {
+	return &PricingService{
+		promotionRepository: promotionRepo,
+		cartRepository:      cartRepo,
+		userRepository:      userRepo,
+		deliveryRepository:  deliveryRepo,
+	}
+}
+
+// Итоговая цена переданных товаров или корзины с акциями и промокодом. Доставка считается
+// только для покупателя с адресом в зоне доставки. Неподходящий промокод не ошибка - причина в PromoCodeError
+func (s *PricingService) Price(ctx context.Context, owner *dto.CartOwner, req *dto.PriceRequest) (*dto.PriceBreakdownResponse, error) {
+	items := req.Items
+
+	if len(items) == 0 {
+		var err error
+		items, err = s.cartItems(ctx, owner)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	lines, err := s.promotionRepository.FindPricingLines(ctx, items)
+	if err != nil {
+		logger.Log.Error("PricingService -> Price -> FindPricingLines -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	promotions, err := s.promotionRepository.FindApplicable(ctx, req.PromoCode, owner.UserId)
+	if err != nil {
+		logger.Log.Error("PricingService -> Price -> FindApplicable -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	zone, err := s.deliveryZone(ctx, owner.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	return model.CalculatePrice(lines, promotions, req.PromoCode, zone, time.Now()).ToResponse(), nil
+}
+
+func (s *PricingService) cartItems(ctx context.Context, owner *dto.CartOwner) ([]dto.CartItemRequest, error) {
+	if owner.UserId == 0 && owner.Token == "" {
+		return nil, nil
+	}
+
+	cartId, ok, err := s.cartRepository.FindCartId(ctx, owner)
+	if err != nil {
+		logger.Log.Error("PricingService -> cartItems -> FindCartId -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	if !ok {
+		return nil, nil
+	}
+
+	cartItems, err := s.cartRepository.FindItems(ctx, cartId)
+	if err != nil {
+		logger.Log.Error("PricingService -> cartItems -> FindItems -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	items := make([]dto.CartItemRequest, 0, len(cartItems))
+	for _, item := range cartItems {
+		items = append(items, dto.CartItemRequest{CatalogId: item.CatalogId, Quantity: item.Quantity})
+	}
+
+	return items, nil
+}
+
+// Зона доставки по адресу покупателя, nil - гость или адрес вне зон доставки
+func (s *PricingService) deliveryZone(ctx context.Context, userId int64) (*model.DeliveryZone, error) {
+	if userId == 0 {
+		return nil, nil
+	}
+
+	user, err := s.userRepository.FindById(ctx, userId)
+	if err != nil {
+		logger.Log.Error("PricingService -> deliveryZone -> FindById -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, nil)
+	}
+
+	if user.Latitude == nil || user.Longitude == nil {
+		return nil, nil
+	}
+
+	zones, err := s.deliveryRepository.FindZones(ctx)
+	if err != nil {
+		logger.Log.Error("PricingService -> deliveryZone -> FindZones -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	zone, _ := model.FindDeliveryZone(zones, geo.Point{Lat: *user.Latitude, Lon: *user.Longitude})
+	return zone, nil
+}
@@ -0,0 +1,206 @@
+package service_test
+
+import (
+	"arabic/internal/dto"
+	"arabic/internal/model"
+	"arabic/internal/service"
+	"arabic/pkg/logger"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+type MockIPromotionRepository struct {
+	mock.Mock
+}
+
+func (m *MockIPromotionRepository) Create(ctx context.Context, promotion *model.Promotion) error {
+	args := m.Called(ctx, promotion)
+	return args.Error(0)
+}
+func (m *MockIPromotionRepository) Update(ctx context.Context, promotion *model.Promotion) (bool, error) {
+	args := m.Called(ctx, promotion)
+	return args.Bool(0), args.Error(1)
+}
+func (m *MockIPromotionRepository) Delete(ctx context.Context, id int64) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+func (m *MockIPromotionRepository) FindById(ctx context.Context, id int64) (*model.Promotion, bool, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Bool(1), args.Error(2)
+	}
+	return args.Get(0).(*model.Promotion), args.Bool(1), args.Error(2)
+}
+func (m *MockIPromotionRepository) FindAll(ctx context.Context) ([]*model.Promotion, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*model.Promotion), args.Error(1)
+}
+func (m *MockIPromotionRepository) FindApplicable(ctx context.Context, code string, userId int64) ([]*model.Promotion, error) {
+	args := m.Called(ctx, code, userId)
+	return args.Get(0).([]*model.Promotion), args.Error(1)
+}
+func (m *MockIPromotionRepository) FindPricingLines(ctx context.Context, items []dto.CartItemRequest) ([]*model.PricingLine, error) {
+	args := m.Called(ctx, items)
+	return args.Get(0).([]*model.PricingLine), args.Error(1)
+}
+
+func TestPricingService_Price(t *testing.T) {
+	logger.Init("Error", "./")
+
+	lat, lon := 43.30, 45.65
+	user := &model.UserFullInfo{
+		User:        model.User{Id: 1},
+		UserAddress: model.UserAddress{House: "1", Street: "Lenina", City: "Grozny", Latitude: &lat, Longitude: &lon},
+	}
+
+	// 3 x 100 из категории 5 и 1 x 200 со скидкой 10% с тегом 9: товары на 480, доставка 100 + 20 * 2.5 кг = 150
+	lines := func() []*model.PricingLine {
+		return []*model.PricingLine{
+			{CatalogId: 1, CategoryId: 5, Name: "Dates", Price: 100, Quantity: 3, Weight: 500},
+			{CatalogId: 2, CategoryId: 7, TagIds: []int64{9}, Name: "Honey", Price: 200, DiscountPercent: 10, Quantity: 1, Weight: 1000},
+		}
+	}
+
+	past := time.Now().Add(-time.Hour)
+	one := 1
+
+	tests := []struct {
+		name            string
+		promotions      []*model.Promotion
+		code            string
+		expectPromo     float32
+		expectDelivery  float32
+		expectTotal     float32
+		expectDiscounts int
+		expectCodeError string
+	}{
+		{
+			name:           "no promotions",
+			expectDelivery: 150,
+			expectTotal:    630,
+		},
+		{
+			name:            "percent code restricted to category",
+			promotions:      []*model.Promotion{{Id: 1, Code: "SAVE10", Type: model.PromotionPercent, Value: 10, CategoryIds: []uint{5}, IsActive: true}},
+			code:            "save10",
+			expectPromo:     30,
+			expectDelivery:  150,
+			expectTotal:     600,
+			expectDiscounts: 1,
+		},
+		{
+			name:            "percent code restricted to tag",
+			promotions:      []*model.Promotion{{Id: 1, Code: "HONEY", Type: model.PromotionPercent, Value: 50, TagIds: []int64{9}, IsActive: true}},
+			code:            "HONEY",
+			expectPromo:     90,
+			expectDelivery:  150,
+			expectTotal:     540,
+			expectDiscounts: 1,
+		},
+		{
+			name:            "fixed code",
+			promotions:      []*model.Promotion{{Id: 1, Code: "MINUS50", Type: model.PromotionFixed, Value: 50, IsActive: true}},
+			code:            "MINUS50",
+			expectPromo:     50,
+			expectDelivery:  150,
+			expectTotal:     580,
+			expectDiscounts: 1,
+		},
+		{
+			name:            "automatic buy 2 get 1",
+			promotions:      []*model.Promotion{{Id: 1, Type: model.PromotionBuyXGetY, BuyQuantity: 2, FreeQuantity: 1, IsActive: true}},
+			expectPromo:     100,
+			expectDelivery:  150,
+			expectTotal:     530,
+			expectDiscounts: 1,
+		},
+		{
+			name:            "automatic free delivery over amount",
+			promotions:      []*model.Promotion{{Id: 1, Type: model.PromotionFreeDelivery, MinOrderAmount: 400, IsActive: true}},
+			expectTotal:     480,
+			expectDiscounts: 1,
+		},
+		{
+			name: "automatic rule and code stack",
+			promotions: []*model.Promotion{
+				{Id: 1, Code: "SAVE10", Type: model.PromotionPercent, Value: 10, IsActive: true},
+				{Id: 2, Type: model.PromotionBuyXGetY, BuyQuantity: 2, FreeQuantity: 1, IsActive: true},
+			},
+			code:            "SAVE10",
+			expectPromo:     138,
+			expectDelivery:  150,
+			expectTotal:     492,
+			expectDiscounts: 2,
+		},
+		{
+			name:            "automatic rule below min order is skipped silently",
+			promotions:      []*model.Promotion{{Id: 1, Type: model.PromotionFreeDelivery, MinOrderAmount: 1000, IsActive: true}},
+			expectDelivery:  150,
+			expectTotal:     630,
+			expectDiscounts: 0,
+		},
+		{
+			name:            "expired code",
+			promotions:      []*model.Promotion{{Id: 1, Code: "OLD", Type: model.PromotionPercent, Value: 10, EndsAt: &past, IsActive: true}},
+			code:            "OLD",
+			expectDelivery:  150,
+			expectTotal:     630,
+			expectCodeError: "it has expired",
+		},
+		{
+			name:            "code below min order amount",
+			promotions:      []*model.Promotion{{Id: 1, Code: "BIG", Type: model.PromotionFixed, Value: 100, MinOrderAmount: 1000, IsActive: true}},
+			code:            "BIG",
+			expectDelivery:  150,
+			expectTotal:     630,
+			expectCodeError: "minimum order amount is 1000.00",
+		},
+		{
+			name:            "code used by customer",
+			promotions:      []*model.Promotion{{Id: 1, Code: "ONCE", Type: model.PromotionFixed, Value: 100, PerUserLimit: &one, UserUsedCount: 1, IsActive: true}},
+			code:            "ONCE",
+			expectDelivery:  150,
+			expectTotal:     630,
+			expectCodeError: "you have already used it the maximum number of times",
+		},
+		{
+			name:            "unknown code",
+			code:            "NOPE",
+			expectDelivery:  150,
+			expectTotal:     630,
+			expectCodeError: "promo code does not exist",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			items := []dto.CartItemRequest{{CatalogId: 1, Quantity: 3}, {CatalogId: 2, Quantity: 1}}
+
+			promotionRepo := &MockIPromotionRepository{}
+			promotionRepo.On("FindPricingLines", mock.Anything, items).Return(lines(), nil)
+			promotionRepo.On("FindApplicable", mock.Anything, tc.code, int64(1)).Return(tc.promotions, nil)
+
+			userRepo := &MockIUserRepository{}
+			userRepo.On("FindById", mock.Anything, int64(1)).Return(user, nil)
+
+			deliveryRepo := &MockIDeliveryRepository{}
+			deliveryRepo.On("FindZones", mock.Anything).Return([]*model.DeliveryZone{testDeliveryZone(t)}, nil)
+
+			srv := service.NewPricingService(promotionRepo, &MockICartRepository{}, userRepo, deliveryRepo)
+			price, err := srv.Price(context.Background(), &dto.CartOwner{UserId: 1}, &dto.PriceRequest{Items: items, PromoCode: tc.code})
+
+			assert.NoError(t, err)
+			assert.Equal(t, float32(500), price.Subtotal)
+			assert.Equal(t, float32(20), price.ItemsDiscount)
+			assert.Equal(t, tc.expectPromo, price.PromoDiscount)
+			assert.Equal(t, tc.expectDelivery, price.DeliveryFee)
+			assert.Equal(t, tc.expectTotal, price.Total)
+			assert.Len(t, price.Discounts, tc.expectDiscounts)
+			assert.Equal(t, tc.expectCodeError, price.PromoCodeError)
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"arabic/internal/dto"
+	"arabic/internal/model"
+	"arabic/internal/repository"
+	"arabic/pkg/customError"
+	"arabic/pkg/logger"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type IPromotionService interface {
+	Create(ctx context.Context, req *dto.PromotionRequest) (*dto.PromotionResponse, error)
+	Update(ctx context.Context, id int64, req *dto.PromotionRequest) (*dto.PromotionResponse, error)
+	Delete(ctx context.Context, id int64) error
+	GetById(ctx context.Context, id int64) (*dto.PromotionResponse, error)
+	GetAll(ctx context.Context) ([]*dto.PromotionResponse, error)
+}
+
+type PromotionService struct {
+	promotionRepository repository.IPromotionRepository
+}
+
+func NewPromotionService(promotionRepo repository.IPromotionRepository) *PromotionService {
+	return &PromotionService{promotionRepository: promotionRepo}
+}
+
+func (s *PromotionService) Create(ctx context.Context, req *dto.PromotionRequest) (*dto.PromotionResponse, error) {
+	promotion, err := newPromotion(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.promotionRepository.Create(ctx, promotion); err != nil {
+		return nil, promotionSaveError("Create", promotion, err)
+	}
+
+	return promotion.ToResponse(), nil
+}
+
+func (s *PromotionService) Update(ctx context.Context, id int64, req *dto.PromotionRequest) (*dto.PromotionResponse, error) {
+	promotion, err := newPromotion(req)
+	if err != nil {
+		return nil, err
+	}
+	promotion.Id = id
+
+	ok, err := s.promotionRepository.Update(ctx, promotion)
+
+	if err != nil {
+		return nil, promotionSaveError("Update", promotion, err)
+	}
+
+	if !ok {
+		return nil, customError.NewServiceError(http.StatusNotFound, customError.ErrorNotFoundById, nil)
+	}
+
+	return promotion.ToResponse(), nil
+}
+
+func (s *PromotionService) Delete(ctx context.Context, id int64) error {
+	ok, err := s.promotionRepository.Delete(ctx, id)
+
+	if err != nil {
+		if isForeignKeyError(err) {
+			return customError.NewServiceError(http.StatusConflict, "Promotion has already been used in orders, deactivate it instead", err)
+		}
+		logger.Log.Error("PromotionService -> Delete -> err -> " + err.Error())
+		return customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	if !ok {
+		return customError.NewServiceError(http.StatusNotFound, customError.ErrorNotFoundById, nil)
+	}
+
+	return nil
+}
+
+func (s *PromotionService) GetById(ctx context.Context, id int64) (*dto.PromotionResponse, error) {
+	promotion, ok, err := s.promotionRepository.FindById(ctx, id)
+
+	if err != nil {
+		logger.Log.Error("PromotionService -> GetById -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	if !ok {
+		return nil, customError.NewServiceError(http.StatusNotFound, customError.ErrorNotFoundById, nil)
+	}
+
+	return promotion.ToResponse(), nil
+}
+
+func (s *PromotionService) GetAll(ctx context.Context) ([]*dto.PromotionResponse, error) {
+	promotions, err := s.promotionRepository.FindAll(ctx)
+
+	if err != nil {
+		logger.Log.Error("PromotionService -> GetAll -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	resp := make([]*dto.PromotionResponse, 0, len(promotions))
+	for _, promotion := range promotions {
+		resp = append(resp, promotion.ToResponse())
+	}
+
+	return resp, nil
+}
+
+// Проверяет параметры, обязательные для типа акции. Лишние параметры обнуляются
+func newPromotion(req *dto.PromotionRequest) (*model.Promotion, error) {
+	if !model.IsValidPromotionType(req.Type) {
+		return nil, customError.NewServiceError(http.StatusBadRequest, fmt.Sprintf("Unknown promotion type %s", req.Type), nil)
+	}
+
+	promotion := &model.Promotion{
+		Name:           req.Name,
+		Code:           strings.ToUpper(strings.TrimSpace(req.Code)),
+		Type:           req.Type,
+		MinOrderAmount: req.MinOrderAmount,
+		UsageLimit:     req.UsageLimit,
+		PerUserLimit:   req.PerUserLimit,
+		StartsAt:       req.StartsAt,
+		EndsAt:         req.EndsAt,
+		IsActive:       req.IsActive,
+		CategoryIds:    uniqueIds(req.CategoryIds),
+		TagIds:         uniqueIds(req.TagIds),
+	}
+
+	switch req.Type {
+	case model.PromotionPercent:
+		if req.Value <= 0 || req.Value > 100 {
+			return nil, customError.NewServiceError(http.StatusBadRequest, "Percent promotion value must be between 0 and 100", nil)
+		}
+		promotion.Value = req.Value
+	case model.PromotionFixed:
+		if req.Value <= 0 {
+			return nil, customError.NewServiceError(http.StatusBadRequest, "Fixed promotion value must be positive", nil)
+		}
+		promotion.Value = req.Value
+	case model.PromotionBuyXGetY:
+		if req.BuyQuantity < 1 || req.FreeQuantity < 1 {
+			return nil, customError.NewServiceError(http.StatusBadRequest, "Buy and free quantities must be at least 1", nil)
+		}
+		promotion.BuyQuantity = req.BuyQuantity
+		promotion.FreeQuantity = req.FreeQuantity
+	}
+
+	return promotion, nil
+}
+
+func promotionSaveError(method string, promotion *model.Promotion, err error) error {
+	if isDuplicateError(err) {
+		return customError.NewServiceError(http.StatusConflict, fmt.Sprintf("Promo code %s already exists", promotion.Code), err)
+	}
+	if isForeignKeyError(err) {
+		return customError.NewServiceError(http.StatusBadRequest, "Category or tag does not exist", err)
+	}
+	logger.Log.Error("PromotionService -> " + method + " -> err -> " + err.Error())
+	return customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+}
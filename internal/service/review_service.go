@@ -0,0 +1,150 @@
+package service
+
+import (
+	"arabic/internal/dto"
+	"arabic/internal/model"
+	"arabic/internal/repository"
+	"arabic/pkg/customError"
+	"arabic/pkg/fs"
+	"arabic/pkg/logger"
+	"context"
+	"net/http"
+)
+
+// Размер очереди модерации за один запрос
+const reviewModerationLimit = 50
+
+type IReviewService interface {
+	Create(ctx context.Context, userId int64, catalogId uint, req *dto.ReviewRequest, fs fs.IFileSystemImage) (*dto.ReviewResponse, error)
+	GetCatalogReviews(ctx context.Context, req *dto.ReviewListRequest, imagePrefix string) ([]*dto.ReviewResponse, error)
+	GetPending(ctx context.Context, imagePrefix string) ([]*dto.ReviewResponse, error)
+	Moderate(ctx context.Context, moderatorId, reviewId int64, status string, req *dto.ReviewModerationRequest, imagePrefix string) (*dto.ReviewResponse, error)
+	Delete(ctx context.Context, userId, reviewId int64, fs fs.IFileSystemImage) error
+}
+
+type ReviewService struct {
+	reviewRepository repository.IReviewRepository
+}
+
+func NewReviewService(reviewRepo repository.IReviewRepository) *ReviewService {
+	return &ReviewService{reviewRepository: reviewRepo}
+}
+
+// Отзыв попадает в очередь модерации и в рейтинге товара учитывается после одобрения
+func (s *ReviewService) Create(ctx context.Context, userId int64, catalogId uint, req *dto.ReviewRequest, fs fs.IFileSystemImage) (*dto.ReviewResponse, error) {
+	eligible, err := s.reviewRepository.IsEligible(ctx, userId, catalogId)
+	if err != nil {
+		logger.Log.Error("ReviewService -> Create -> IsEligible -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	if !eligible {
+		return nil, customError.NewServiceError(http.StatusForbidden, "You can review only products from your delivered orders", nil)
+	}
+
+	review := &model.Review{
+		CatalogId: catalogId,
+		UserId:    userId,
+		Score:     req.Score,
+		Text:      req.Text,
+		Photos:    make([]string, 0, len(req.Photos)),
+	}
+
+	for i := range req.Photos {
+		filename, err := saveImage(fs, &req.Photos[i])
+		if err != nil {
+			deleteImages(fs, review.Photos)
+			return nil, err
+		}
+		review.Photos = append(review.Photos, filename)
+	}
+
+	if err = s.reviewRepository.Create(ctx, review); err != nil {
+		deleteImages(fs, review.Photos)
+
+		if isDuplicateError(err) {
+			return nil, customError.NewServiceError(http.StatusConflict, "You have already reviewed this product", err)
+		}
+		if isForeignKeyError(err) {
+			return nil, customError.NewServiceError(http.StatusNotFound, customError.ErrorNotFoundById, err)
+		}
+		logger.Log.Error("ReviewService -> Create -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	return review.ToResponse("/" + fs.GetPath()), nil
+}
+
+// Одобренные отзывы товара, новые первыми
+func (s *ReviewService) GetCatalogReviews(ctx context.Context, req *dto.ReviewListRequest, imagePrefix string) ([]*dto.ReviewResponse, error) {
+	reviews, err := s.reviewRepository.FindApproved(ctx, req.CatalogId, req.Limit, (req.Page-1)*req.Limit)
+	if err != nil {
+		logger.Log.Error("ReviewService -> GetCatalogReviews -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	return reviewsResponse(reviews, imagePrefix), nil
+}
+
+func (s *ReviewService) GetPending(ctx context.Context, imagePrefix string) ([]*dto.ReviewResponse, error) {
+	reviews, err := s.reviewRepository.FindPending(ctx, reviewModerationLimit)
+	if err != nil {
+		logger.Log.Error("ReviewService -> GetPending -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	return reviewsResponse(reviews, imagePrefix), nil
+}
+
+// Одобряет или отклоняет отзыв, рейтинг товара пересчитывается в той же транзакции
+func (s *ReviewService) Moderate(ctx context.Context, moderatorId, reviewId int64, status string, req *dto.ReviewModerationRequest, imagePrefix string) (*dto.ReviewResponse, error) {
+	review, ok, err := s.reviewRepository.FindById(ctx, reviewId)
+	if err != nil {
+		logger.Log.Error("ReviewService -> Moderate -> FindById -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	if !ok {
+		return nil, customError.NewServiceError(http.StatusNotFound, customError.ErrorNotFoundById, nil)
+	}
+
+	review.Status = status
+	review.ModerationComment = req.Comment
+
+	ok, err = s.reviewRepository.Moderate(ctx, review, moderatorId)
+	if err != nil {
+		logger.Log.Error("ReviewService -> Moderate -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	if !ok {
+		return nil, customError.NewServiceError(http.StatusConflict, "Review has already been moderated", nil)
+	}
+
+	return review.ToResponse(imagePrefix), nil
+}
+
+// Автор может удалить свой отзыв, рейтинг товара пересчитывается
+// Файлы фотографий удаляются только после удаления отзыва из базы
+func (s *ReviewService) Delete(ctx context.Context, userId, reviewId int64, fs fs.IFileSystemImage) error {
+	photos, ok, err := s.reviewRepository.Delete(ctx, reviewId, userId)
+	if err != nil {
+		logger.Log.Error("ReviewService -> Delete -> err -> " + err.Error())
+		return customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	if !ok {
+		return customError.NewServiceError(http.StatusNotFound, customError.ErrorNotFoundById, nil)
+	}
+
+	deleteImages(fs, photos)
+	return nil
+}
+
+func reviewsResponse(reviews []*model.Review, imagePrefix string) []*dto.ReviewResponse {
+	resp := make([]*dto.ReviewResponse, 0, len(reviews))
+	for _, review := range reviews {
+		resp = append(resp, review.ToResponse(imagePrefix))
+	}
+	return resp
+}
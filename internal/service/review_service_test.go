@@ -0,0 +1,217 @@
+package service_test
+
+import (
+	"arabic/internal/dto"
+	"arabic/internal/model"
+	"arabic/internal/service"
+	"arabic/pkg/customError"
+	"arabic/pkg/logger"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+)
+
+type MockIReviewRepository struct {
+	mock.Mock
+}
+
+func (m *MockIReviewRepository) Create(ctx context.Context, review *model.Review) error {
+	args := m.Called(ctx, review)
+	return args.Error(0)
+}
+func (m *MockIReviewRepository) IsEligible(ctx context.Context, userId int64, catalogId uint) (bool, error) {
+	args := m.Called(ctx, userId, catalogId)
+	return args.Bool(0), args.Error(1)
+}
+func (m *MockIReviewRepository) FindById(ctx context.Context, id int64) (*model.Review, bool, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Bool(1), args.Error(2)
+	}
+	return args.Get(0).(*model.Review), args.Bool(1), args.Error(2)
+}
+func (m *MockIReviewRepository) FindApproved(ctx context.Context, catalogId uint, limit, offset int) ([]*model.Review, error) {
+	args := m.Called(ctx, catalogId, limit, offset)
+	return args.Get(0).([]*model.Review), args.Error(1)
+}
+func (m *MockIReviewRepository) FindPending(ctx context.Context, limit int) ([]*model.Review, error) {
+	args := m.Called(ctx, limit)
+	return args.Get(0).([]*model.Review), args.Error(1)
+}
+func (m *MockIReviewRepository) Moderate(ctx context.Context, review *model.Review, moderatorId int64) (bool, error) {
+	args := m.Called(ctx, review, moderatorId)
+	return args.Bool(0), args.Error(1)
+}
+func (m *MockIReviewRepository) Delete(ctx context.Context, id, userId int64) ([]string, bool, error) {
+	args := m.Called(ctx, id, userId)
+	if args.Get(0) == nil {
+		return nil, args.Bool(1), args.Error(2)
+	}
+	return args.Get(0).([]string), args.Bool(1), args.Error(2)
+}
+
+// Хранилище изображений, которое принимает только png
+type MockImageStorage struct {
+	saved   int
+	deleted int
+}
+
+func (m *MockImageStorage) GetImageExtension(base64Image *string) (string, error) {
+	if *base64Image == "" {
+		return "", errors.New("empty image")
+	}
+	return *base64Image, nil
+}
+func (m *MockImageStorage) IsSupportingExtension(extension string) bool {
+	return extension == "png"
+}
+func (m *MockImageStorage) SafeImageToStorage(extension string, base64Image *string) (string, error) {
+	m.saved++
+	return "photo." + extension, nil
+}
+func (m *MockImageStorage) DeleteImage(filename string) bool {
+	m.deleted++
+	return true
+}
+func (m *MockImageStorage) GetPath() string {
+	return "images/"
+}
+
+func TestReviewService_Create(t *testing.T) {
+	logger.Init("Error", "./")
+
+	tests := []struct {
+		name        string
+		eligible    bool
+		photos      []string
+		createErr   error
+		expectCode  int
+		expectSaved int
+	}{
+		{
+			name:        "review with photos",
+			eligible:    true,
+			photos:      []string{"png", "png"},
+			expectSaved: 2,
+		},
+		{
+			name:       "product was not delivered to customer",
+			eligible:   false,
+			expectCode: 403,
+		},
+		{
+			name:        "unsupported photo",
+			eligible:    true,
+			photos:      []string{"png", "bmp"},
+			expectCode:  400,
+			expectSaved: 1,
+		},
+		{
+			name:        "second review of the same product",
+			eligible:    true,
+			photos:      []string{"png", "png"},
+			createErr:   errors.New("duplicate key value violates unique constraint"),
+			expectCode:  409,
+			expectSaved: 2,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			reviewRepo := &MockIReviewRepository{}
+			reviewRepo.On("IsEligible", mock.Anything, int64(1), uint(5)).Return(tc.eligible, nil)
+			reviewRepo.On("Create", mock.Anything, mock.Anything).Return(tc.createErr)
+
+			storage := &MockImageStorage{}
+			srv := service.NewReviewService(reviewRepo)
+			review, err := srv.Create(context.Background(), 1, 5, &dto.ReviewRequest{Score: 4, Text: "Fresh", Photos: tc.photos}, storage)
+
+			if tc.expectCode != 0 {
+				var serviceErr *customError.ServiceError
+				assert.ErrorAs(t, err, &serviceErr)
+				assert.Equal(t, tc.expectCode, serviceErr.Code)
+				// Фото отзыва, который не создался, не остаются на диске
+				assert.Equal(t, tc.expectSaved, storage.saved)
+				assert.Equal(t, storage.saved, storage.deleted)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, 4, review.Score)
+			assert.Equal(t, tc.expectSaved, storage.saved)
+			assert.Zero(t, storage.deleted)
+			assert.Equal(t, []string{"/images/photo.png", "/images/photo.png"}, review.Photos)
+		})
+	}
+}
+
+func TestReviewService_Moderate(t *testing.T) {
+	logger.Init("Error", "./")
+
+	tests := []struct {
+		name       string
+		review     *model.Review
+		moderated  bool
+		expectCode int
+	}{
+		{
+			name:      "approve pending review",
+			review:    &model.Review{Id: 3, CatalogId: 5, Score: 5, Status: model.ReviewStatusPending},
+			moderated: true,
+		},
+		{
+			name:       "review not found",
+			expectCode: 404,
+		},
+		{
+			name:       "review moderated concurrently",
+			review:     &model.Review{Id: 3, CatalogId: 5, Score: 5, Status: model.ReviewStatusPending},
+			moderated:  false,
+			expectCode: 409,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			reviewRepo := &MockIReviewRepository{}
+			reviewRepo.On("FindById", mock.Anything, int64(3)).Return(tc.review, tc.review != nil, nil)
+			reviewRepo.On("Moderate", mock.Anything, mock.Anything, int64(2)).Return(tc.moderated, nil)
+
+			srv := service.NewReviewService(reviewRepo)
+			review, err := srv.Moderate(context.Background(), 2, 3, model.ReviewStatusApproved, &dto.ReviewModerationRequest{Comment: "ok"}, "/images/")
+
+			if tc.expectCode != 0 {
+				var serviceErr *customError.ServiceError
+				assert.ErrorAs(t, err, &serviceErr)
+				assert.Equal(t, tc.expectCode, serviceErr.Code)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, model.ReviewStatusApproved, review.Status)
+			assert.Equal(t, "ok", review.ModerationComment)
+		})
+	}
+}
+
+func TestReviewService_Delete(t *testing.T) {
+	logger.Init("Error", "./")
+
+	reviewRepo := &MockIReviewRepository{}
+	reviewRepo.On("Delete", mock.Anything, int64(3), int64(1)).Return([]string{"a.png", "b.png"}, true, nil)
+	reviewRepo.On("Delete", mock.Anything, int64(4), int64(1)).Return(nil, false, nil)
+
+	storage := &MockImageStorage{}
+	srv := service.NewReviewService(reviewRepo)
+
+	// Вместе с отзывом удаляются файлы его фотографий
+	assert.NoError(t, srv.Delete(context.Background(), 1, 3, storage))
+	assert.Equal(t, 2, storage.deleted)
+
+	var serviceErr *customError.ServiceError
+	assert.ErrorAs(t, srv.Delete(context.Background(), 1, 4, storage), &serviceErr)
+	assert.Equal(t, 404, serviceErr.Code)
+	assert.Equal(t, 2, storage.deleted)
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"arabic/internal/dto"
+	"arabic/internal/model"
+	"arabic/internal/repository"
+	"arabic/pkg/customError"
+	"arabic/pkg/logger"
+	"context"
+	"net/http"
+	"slices"
+)
+
+type ISubscriptionService interface {
+	Subscribe(ctx context.Context, userId int64, catalogId uint, subscriptionType string) error
+	Unsubscribe(ctx context.Context, userId int64, catalogId uint, subscriptionType string) error
+	GetAll(ctx context.Context, userId int64) ([]*dto.SubscriptionResponse, error)
+}
+
+type SubscriptionService struct {
+	subscriptionRepository repository.ISubscriptionRepository
+}
+
+func NewSubscriptionService(subscriptionRepo repository.ISubscriptionRepository) *SubscriptionService {
+	return &SubscriptionService{subscriptionRepository: subscriptionRepo}
+}
+
+func (s *SubscriptionService) Subscribe(ctx context.Context, userId int64, catalogId uint, subscriptionType string) error {
+	if !slices.Contains(model.SubscriptionTypes, subscriptionType) {
+		return customError.NewServiceError(http.StatusNotFound, "Unknown subscription type", nil)
+	}
+
+	if err := s.subscriptionRepository.Subscribe(ctx, userId, catalogId, subscriptionType); err != nil {
+		if isForeignKeyError(err) {
+			return customError.NewServiceError(http.StatusNotFound, customError.ErrorNotFoundById, err)
+		}
+		logger.Log.Error("SubscriptionService -> Subscribe -> err -> " + err.Error())
+		return customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	return nil
+}
+
+func (s *SubscriptionService) Unsubscribe(ctx context.Context, userId int64, catalogId uint, subscriptionType string) error {
+	ok, err := s.subscriptionRepository.Unsubscribe(ctx, userId, catalogId, subscriptionType)
+	if err != nil {
+		logger.Log.Error("SubscriptionService -> Unsubscribe -> err -> " + err.Error())
+		return customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	if !ok {
+		return customError.NewServiceError(http.StatusNotFound, "Subscription not found", nil)
+	}
+
+	return nil
+}
+
+func (s *SubscriptionService) GetAll(ctx context.Context, userId int64) ([]*dto.SubscriptionResponse, error) {
+	subscriptions, err := s.subscriptionRepository.FindByUser(ctx, userId)
+	if err != nil {
+		logger.Log.Error("SubscriptionService -> GetAll -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	resp := make([]*dto.SubscriptionResponse, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		resp = append(resp, subscription.ToResponse())
+	}
+
+	return resp, nil
+}
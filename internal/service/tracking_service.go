@@ -0,0 +1,235 @@
+package service
+
+import (
+	"arabic/internal/dto"
+	"arabic/internal/model"
+	"arabic/internal/repository"
+	"arabic/pkg/customError"
+	"arabic/pkg/events"
+	"arabic/pkg/geo"
+	"arabic/pkg/logger"
+	"arabic/pkg/routing"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Типы событий SSE-потока заказа
+const (
+	TrackingEventStatus  = "status"
+	TrackingEventCourier = "courier"
+	TrackingEventEta     = "eta"
+	TrackingEventPick    = "pick"
+)
+
+// Сервисы сообщают об изменениях заказа после коммита. Ошибки трекинга
+// не должны влиять на основную операцию, поэтому методы ничего не возвращают
+type IOrderTracker interface {
+	StatusChanged(ctx context.Context, change *model.OrderStatusChange)
+	RoutesCreated(ctx context.Context, routes []*model.DeliveryRoute)
+	PickEventChanged(ctx context.Context, event *model.PickEvent)
+}
+
+type ITrackingService interface {
+	IOrderTracker
+	Open(ctx context.Context, userId, orderId, lastEventId int64) (*events.Subscription, []events.Event, error)
+}
+
+type TrackingService struct {
+	orderRepository    repository.IOrderRepository
+	userRepository     repository.IUserRepository
+	dispatchRepository repository.IDispatchRepository
+	hub                *events.Hub
+	routing            *routing.Config
+}
+
+func NewTrackingService(orderRepo repository.IOrderRepository, userRepo repository.IUserRepository, dispatchRepo repository.IDispatchRepository, hub *events.Hub, config *routing.Config) *TrackingService {
+	return &TrackingService{
+		orderRepository:    orderRepo,
+		userRepository:     userRepo,
+		dispatchRepository: dispatchRepo,
+		hub:                hub,
+		routing:            config,
+	}
+}
+
+// Подписывает на события заказа и возвращает то, что клиент пропустил: переходы статусов
+// после lastEventId и текущие курьера и ETA. Подписка оформляется до чтения истории,
+// поэтому события не теряются, а дубли отбрасываются по id
+func (s *TrackingService) Open(ctx context.Context, userId, orderId, lastEventId int64) (*events.Subscription, []events.Event, error) {
+	order, err := s.accessibleOrder(ctx, userId, orderId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sub := s.hub.Subscribe(orderTopic(orderId))
+
+	history, err := s.orderRepository.FindHistory(ctx, orderId)
+	if err != nil {
+		sub.Close()
+		logger.Log.Error("TrackingService -> Open -> FindHistory -> err -> " + err.Error())
+		return nil, nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	var replay []events.Event
+	for _, change := range history {
+		if change.Id > lastEventId {
+			replay = append(replay, statusEvent(change))
+		}
+	}
+
+	if order.Status == model.OrderStatusOutForDelivery {
+		route, ok, err := s.dispatchRepository.FindRouteByOrder(ctx, orderId)
+		if err != nil {
+			logger.Log.Error("TrackingService -> Open -> FindRouteByOrder -> err -> " + err.Error())
+		}
+
+		if ok {
+			for _, stop := range route.Stops {
+				if stop.OrderId == orderId {
+					replay = append(replay, courierEvent(route, stop))
+				}
+			}
+			for _, event := range s.etaEvents(route, time.Now()) {
+				if event.orderId == orderId {
+					replay = append(replay, event.Event)
+				}
+			}
+		}
+	}
+
+	return sub, replay, nil
+}
+
+// Доставка или возврат сдвигает ETA оставшихся заказов маршрута
+func (s *TrackingService) StatusChanged(ctx context.Context, change *model.OrderStatusChange) {
+	s.hub.Publish(orderTopic(change.OrderId), statusEvent(change))
+
+	if change.ToStatus != model.OrderStatusDelivered && change.ToStatus != model.OrderStatusReturned {
+		return
+	}
+
+	route, ok, err := s.dispatchRepository.FindRouteByOrder(ctx, change.OrderId)
+	if err != nil {
+		logger.Log.Error("TrackingService -> StatusChanged -> err -> " + err.Error())
+		return
+	}
+
+	if ok {
+		s.publishEtas(route, time.Now())
+	}
+}
+
+func (s *TrackingService) RoutesCreated(ctx context.Context, routes []*model.DeliveryRoute) {
+	now := time.Now()
+
+	for _, route := range routes {
+		for _, stop := range route.Stops {
+			s.hub.Publish(orderTopic(stop.OrderId), courierEvent(route, stop))
+		}
+		s.publishEtas(route, now)
+	}
+}
+
+func (s *TrackingService) PickEventChanged(ctx context.Context, event *model.PickEvent) {
+	s.hub.Publish(orderTopic(event.OrderId), newEvent(0, TrackingEventPick, event.ToResponse()))
+}
+
+func (s *TrackingService) publishEtas(route *model.DeliveryRoute, at time.Time) {
+	for _, event := range s.etaEvents(route, at) {
+		s.hub.Publish(orderTopic(event.orderId), event.Event)
+	}
+}
+
+type orderEvent struct {
+	events.Event
+	orderId int64
+}
+
+func (s *TrackingService) etaEvents(route *model.DeliveryRoute, at time.Time) []orderEvent {
+	from, stops := route.RemainingStops(s.routing.Depot())
+
+	points := make([]geo.Point, 0, len(stops))
+	for _, stop := range stops {
+		points = append(points, stop.Location())
+	}
+
+	arrivals := s.routing.EstimateArrivals(from, points, at)
+
+	result := make([]orderEvent, 0, len(stops))
+	for i, stop := range stops {
+		result = append(result, orderEvent{
+			Event: newEvent(0, TrackingEventEta, &dto.OrderEtaEvent{
+				OrderId:     stop.OrderId,
+				Eta:         arrivals[i],
+				StopsBefore: i,
+			}),
+			orderId: stop.OrderId,
+		})
+	}
+
+	return result
+}
+
+// Доступ к потоку такой же, как к самому заказу
+func (s *TrackingService) accessibleOrder(ctx context.Context, userId, orderId int64) (*model.Order, error) {
+	user, err := s.userRepository.FindById(ctx, userId)
+	if err != nil {
+		logger.Log.Error("TrackingService -> accessibleOrder -> FindById -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, nil)
+	}
+
+	order, ok, err := s.orderRepository.FindById(ctx, orderId)
+	if err != nil {
+		logger.Log.Error("TrackingService -> accessibleOrder -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	if ok {
+		ok, err = canAccessOrder(ctx, s.orderRepository, userId, user.RoleCode, order)
+		if err != nil {
+			logger.Log.Error("TrackingService -> accessibleOrder -> IsCourierOrder -> err -> " + err.Error())
+			return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+		}
+	}
+
+	if !ok {
+		return nil, customError.NewServiceError(http.StatusNotFound, customError.ErrorNotFoundById, nil)
+	}
+
+	return order, nil
+}
+
+func orderTopic(orderId int64) string {
+	return "order:" + strconv.FormatInt(orderId, 10)
+}
+
+// id события статуса - id записи истории, по нему клиент продолжает поток через Last-Event-ID
+func statusEvent(change *model.OrderStatusChange) events.Event {
+	return newEvent(change.Id, TrackingEventStatus, &dto.OrderStatusEvent{
+		OrderId:    change.OrderId,
+		FromStatus: change.FromStatus,
+		ToStatus:   change.ToStatus,
+		Comment:    change.Comment,
+		CreatedAt:  change.CreatedAt,
+	})
+}
+
+func courierEvent(route *model.DeliveryRoute, stop *model.RouteStop) events.Event {
+	return newEvent(0, TrackingEventCourier, &dto.CourierAssignedEvent{
+		OrderId:   stop.OrderId,
+		RouteId:   route.Id,
+		CourierId: route.CourierId,
+		Position:  stop.Position,
+	})
+}
+
+func newEvent(id int64, eventType string, payload any) events.Event {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		logger.Log.Error("TrackingService -> newEvent -> err -> " + err.Error())
+	}
+	return events.Event{Id: id, Type: eventType, Data: data}
+}
@@ -0,0 +1,147 @@
+package service_test
+
+import (
+	"arabic/internal/model"
+	"arabic/internal/service"
+	"arabic/pkg/customError"
+	"arabic/pkg/events"
+	"arabic/pkg/logger"
+	"arabic/pkg/routing"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+)
+
+// Трекинг без подписчиков для тестов остальных сервисов
+type NoopOrderTracker struct{}
+
+func (n *NoopOrderTracker) StatusChanged(ctx context.Context, change *model.OrderStatusChange) {}
+func (n *NoopOrderTracker) RoutesCreated(ctx context.Context, routes []*model.DeliveryRoute)   {}
+func (n *NoopOrderTracker) PickEventChanged(ctx context.Context, event *model.PickEvent)       {}
+
+func testRoute() *model.DeliveryRoute {
+	lat1, lon1, lat2, lon2 := 43.31, 45.60, 43.32, 45.60
+	return &model.DeliveryRoute{
+		Id:        4,
+		CourierId: 8,
+		Stops: []*model.RouteStop{
+			{Position: 1, OrderId: 7, Status: model.OrderStatusOutForDelivery, Address: model.UserAddress{Latitude: &lat1, Longitude: &lon1}},
+			{Position: 2, OrderId: 9, Status: model.OrderStatusOutForDelivery, Address: model.UserAddress{Latitude: &lat2, Longitude: &lon2}},
+		},
+	}
+}
+
+func TestTrackingService_Open(t *testing.T) {
+	logger.Init("Error", "./")
+
+	history := []*model.OrderStatusChange{
+		{Id: 11, OrderId: 7, ToStatus: model.OrderStatusCreated},
+		{Id: 12, OrderId: 7, FromStatus: model.OrderStatusCreated, ToStatus: model.OrderStatusConfirmed},
+		{Id: 15, OrderId: 7, FromStatus: model.OrderStatusPacked, ToStatus: model.OrderStatusOutForDelivery},
+	}
+
+	tests := []struct {
+		name        string
+		userId      int64
+		status      string
+		lastEventId int64
+		expectTypes []string
+		expectCode  int
+	}{
+		{
+			name:        "full history",
+			userId:      1,
+			status:      model.OrderStatusConfirmed,
+			expectTypes: []string{service.TrackingEventStatus, service.TrackingEventStatus, service.TrackingEventStatus},
+		},
+		{
+			name:        "resume after last event with courier snapshot",
+			userId:      1,
+			status:      model.OrderStatusOutForDelivery,
+			lastEventId: 12,
+			expectTypes: []string{service.TrackingEventStatus, service.TrackingEventCourier, service.TrackingEventEta},
+		},
+		{
+			name:       "foreign order",
+			userId:     2,
+			status:     model.OrderStatusConfirmed,
+			expectCode: 404,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			orderRepo := &MockIOrderRepository{}
+			userRepo := &MockIUserRepository{}
+			dispatchRepo := &MockIDispatchRepository{}
+
+			user := &model.UserFullInfo{User: model.User{Id: tc.userId, RoleCode: model.RoleUser}}
+			userRepo.On("FindById", mock.Anything, tc.userId).Return(user, nil)
+			orderRepo.On("FindById", mock.Anything, int64(7)).Return(&model.Order{Id: 7, UserId: 1, Status: tc.status}, true, nil)
+			orderRepo.On("FindHistory", mock.Anything, int64(7)).Return(history, nil)
+			dispatchRepo.On("FindRouteByOrder", mock.Anything, int64(7)).Return(testRoute(), true, nil)
+
+			hub := events.NewHub(events.NewConfig())
+			srv := service.NewTrackingService(orderRepo, userRepo, dispatchRepo, hub, &routing.Config{DepotLatitude: 43.30, DepotLongitude: 45.60, AverageSpeedKmh: 30})
+			sub, replay, err := srv.Open(context.Background(), tc.userId, 7, tc.lastEventId)
+
+			if tc.expectCode != 0 {
+				var serviceErr *customError.ServiceError
+				assert.ErrorAs(t, err, &serviceErr)
+				assert.Equal(t, tc.expectCode, serviceErr.Code)
+				return
+			}
+
+			assert.NoError(t, err)
+			defer sub.Close()
+
+			var types []string
+			for _, event := range replay {
+				types = append(types, event.Type)
+				if event.Type == service.TrackingEventStatus {
+					assert.Greater(t, event.Id, tc.lastEventId)
+				}
+			}
+			assert.Equal(t, tc.expectTypes, types)
+		})
+	}
+}
+
+func TestTrackingService_StatusChanged(t *testing.T) {
+	logger.Init("Error", "./")
+
+	// Первый заказ маршрута доставлен - ETA пересчитывается для второго от точки первого
+	route := testRoute()
+	route.Stops[0].Status = model.OrderStatusDelivered
+
+	dispatchRepo := &MockIDispatchRepository{}
+	dispatchRepo.On("FindRouteByOrder", mock.Anything, int64(7)).Return(route, true, nil)
+
+	hub := events.NewHub(events.NewConfig())
+	srv := service.NewTrackingService(&MockIOrderRepository{}, &MockIUserRepository{}, dispatchRepo, hub, &routing.Config{DepotLatitude: 43.30, DepotLongitude: 45.60, AverageSpeedKmh: 30})
+
+	delivered := hub.Subscribe("order:7")
+	next := hub.Subscribe("order:9")
+	defer delivered.Close()
+	defer next.Close()
+
+	srv.StatusChanged(context.Background(), &model.OrderStatusChange{Id: 20, OrderId: 7, FromStatus: model.OrderStatusOutForDelivery, ToStatus: model.OrderStatusDelivered})
+
+	status := <-delivered.Events()
+	assert.Equal(t, int64(20), status.Id)
+	assert.Equal(t, service.TrackingEventStatus, status.Type)
+	assert.Len(t, delivered.Events(), 0)
+
+	eta := <-next.Events()
+	assert.Equal(t, service.TrackingEventEta, eta.Type)
+
+	var payload struct {
+		OrderId     int64 `json:"order_id"`
+		StopsBefore int   `json:"stops_before"`
+	}
+	assert.NoError(t, json.Unmarshal(eta.Data, &payload))
+	assert.Equal(t, int64(9), payload.OrderId)
+	assert.Equal(t, 0, payload.StopsBefore)
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"arabic/internal/dto"
+	"arabic/internal/repository"
+	"arabic/pkg/customError"
+	"arabic/pkg/logger"
+	"arabic/pkg/security/auth"
+	"arabic/pkg/security/totp"
+	"context"
+	"net/http"
+	"time"
+)
+
+type ITwoFactorService interface {
+	GetStatus(ctx context.Context, userId int64, role string) (*dto.TwoFactorStatusResponse, error)
+	Setup(ctx context.Context, userId int64, email string) (*dto.TwoFactorSetupResponse, error)
+	Enable(ctx context.Context, userId int64, code string) (*dto.RecoveryCodesResponse, error)
+	Disable(ctx context.Context, userId int64, role string, req *dto.TwoFactorCodeRequest) error
+	RegenerateRecoveryCodes(ctx context.Context, userId int64, req *dto.TwoFactorCodeRequest) (*dto.RecoveryCodesResponse, error)
+	IsEnabled(ctx context.Context, userId int64) (bool, error)
+	Verify(ctx context.Context, userId int64, req *dto.TwoFactorCodeRequest) (bool, error)
+}
+
+// Второй фактор входа: TOTP из приложения-аутентификатора и коды восстановления
+type TwoFactorService struct {
+	twoFactorRepository repository.ITwoFactorRepository
+	cipher              *totp.Cipher
+	config              *totp.Config
+}
+
+func NewTwoFactorService(twoFactorRepo repository.ITwoFactorRepository, cipher *totp.Cipher, config *totp.Config) *TwoFactorService {
+	return &TwoFactorService{
+		twoFactorRepository: twoFactorRepo,
+		cipher:              cipher,
+		config:              config,
+	}
+}
+
+func (s *TwoFactorService) GetStatus(ctx context.Context, userId int64, role string) (*dto.TwoFactorStatusResponse, error) {
+	enabled, err := s.IsEnabled(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.TwoFactorStatusResponse{Enabled: enabled, Required: s.config.IsRequired(role)}, nil
+}
+
+// Выпускает новый секрет. Второй фактор начнет действовать после подтверждения кодом
+func (s *TwoFactorService) Setup(ctx context.Context, userId int64, email string) (*dto.TwoFactorSetupResponse, error) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		logger.Log.Error("TwoFactorService -> Setup -> GenerateSecret -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	encrypted, err := s.cipher.Encrypt(secret)
+	if err != nil {
+		logger.Log.Error("TwoFactorService -> Setup -> Encrypt -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	ok, err := s.twoFactorRepository.SaveSecret(ctx, userId, encrypted)
+	if err != nil {
+		logger.Log.Error("TwoFactorService -> Setup -> SaveSecret -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	if !ok {
+		return nil, customError.NewServiceError(http.StatusConflict, "Two-factor authentication is already enabled", nil)
+	}
+
+	return &dto.TwoFactorSetupResponse{
+		Secret: secret,
+		URI:    totp.URI(s.config.Issuer, email, secret),
+	}, nil
+}
+
+// Подтверждает подключение кодом из приложения и выдает коды восстановления
+func (s *TwoFactorService) Enable(ctx context.Context, userId int64, code string) (*dto.RecoveryCodesResponse, error) {
+	twoFactor, found, err := s.twoFactorRepository.Find(ctx, userId)
+	if err != nil {
+		logger.Log.Error("TwoFactorService -> Enable -> Find -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	if !found {
+		return nil, customError.NewServiceError(http.StatusBadRequest, "Start two-factor authentication setup first", nil)
+	}
+	if twoFactor.IsEnabled() {
+		return nil, customError.NewServiceError(http.StatusConflict, "Two-factor authentication is already enabled", nil)
+	}
+
+	step, ok, err := s.validate(twoFactor.Secret, code)
+	if err != nil {
+		logger.Log.Error("TwoFactorService -> Enable -> validate -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+	if !ok {
+		return nil, customError.NewServiceError(http.StatusBadRequest, customError.ErrorInvalidCode, nil)
+	}
+
+	codes, hashes, err := s.newRecoveryCodes()
+	if err != nil {
+		logger.Log.Error("TwoFactorService -> Enable -> newRecoveryCodes -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	// Параллельный запрос успел подключить второй фактор или использовать этот код
+	ok, err = s.twoFactorRepository.Enable(ctx, userId, step, hashes)
+	if err != nil {
+		logger.Log.Error("TwoFactorService -> Enable -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+	if !ok {
+		return nil, customError.NewServiceError(http.StatusBadRequest, customError.ErrorInvalidCode, nil)
+	}
+
+	return &dto.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// Ролям, для которых второй фактор обязателен, отключить его нельзя
+func (s *TwoFactorService) Disable(ctx context.Context, userId int64, role string, req *dto.TwoFactorCodeRequest) error {
+	if s.config.IsRequired(role) {
+		return customError.NewServiceError(http.StatusForbidden, "Two-factor authentication is required for your role", nil)
+	}
+
+	if err := s.requireCode(ctx, userId, req); err != nil {
+		return err
+	}
+
+	if err := s.twoFactorRepository.Disable(ctx, userId); err != nil {
+		logger.Log.Error("TwoFactorService -> Disable -> err -> " + err.Error())
+		return customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	return nil
+}
+
+// Новый набор кодов восстановления, прежние перестают действовать
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, userId int64, req *dto.TwoFactorCodeRequest) (*dto.RecoveryCodesResponse, error) {
+	if err := s.requireCode(ctx, userId, req); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := s.newRecoveryCodes()
+	if err != nil {
+		logger.Log.Error("TwoFactorService -> RegenerateRecoveryCodes -> newRecoveryCodes -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	if err = s.twoFactorRepository.ReplaceRecoveryCodes(ctx, userId, hashes); err != nil {
+		logger.Log.Error("TwoFactorService -> RegenerateRecoveryCodes -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	return &dto.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+func (s *TwoFactorService) IsEnabled(ctx context.Context, userId int64) (bool, error) {
+	twoFactor, _, err := s.twoFactorRepository.Find(ctx, userId)
+	if err != nil {
+		logger.Log.Error("TwoFactorService -> IsEnabled -> err -> " + err.Error())
+		return false, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	return twoFactor.IsEnabled(), nil
+}
+
+// Проверяет код из приложения или код восстановления. Оба одноразовые:
+// принятый код TOTP нельзя повторить до следующего шага, код восстановления - никогда
+func (s *TwoFactorService) Verify(ctx context.Context, userId int64, req *dto.TwoFactorCodeRequest) (bool, error) {
+	twoFactor, _, err := s.twoFactorRepository.Find(ctx, userId)
+	if err != nil {
+		logger.Log.Error("TwoFactorService -> Verify -> Find -> err -> " + err.Error())
+		return false, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	if !twoFactor.IsEnabled() {
+		return false, nil
+	}
+
+	if req.Code == "" {
+		ok, err := s.twoFactorRepository.UseRecoveryCode(ctx, userId, security.HashToken(totp.NormalizeRecoveryCode(req.RecoveryCode)))
+		if err != nil {
+			logger.Log.Error("TwoFactorService -> Verify -> UseRecoveryCode -> err -> " + err.Error())
+			return false, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+		}
+		return ok, nil
+	}
+
+	step, ok, err := s.validate(twoFactor.Secret, req.Code)
+	if err != nil {
+		logger.Log.Error("TwoFactorService -> Verify -> validate -> err -> " + err.Error())
+		return false, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+	if !ok {
+		return false, nil
+	}
+
+	ok, err = s.twoFactorRepository.UseStep(ctx, userId, step)
+	if err != nil {
+		logger.Log.Error("TwoFactorService -> Verify -> UseStep -> err -> " + err.Error())
+		return false, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	return ok, nil
+}
+
+func (s *TwoFactorService) requireCode(ctx context.Context, userId int64, req *dto.TwoFactorCodeRequest) error {
+	enabled, err := s.IsEnabled(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return customError.NewServiceError(http.StatusBadRequest, "Two-factor authentication is not enabled", nil)
+	}
+
+	ok, err := s.Verify(ctx, userId, req)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return customError.NewServiceError(http.StatusBadRequest, customError.ErrorInvalidCode, nil)
+	}
+
+	return nil
+}
+
+// Расшифровывает секрет и проверяет код с допуском из конфигурации
+func (s *TwoFactorService) validate(encrypted, code string) (int64, bool, error) {
+	secret, err := s.cipher.Decrypt(encrypted)
+	if err != nil {
+		return 0, false, err
+	}
+
+	step, ok := totp.Validate(secret, code, time.Now(), s.config.Skew)
+	return step, ok, nil
+}
+
+// Коды для пользователя и их хеши для БД
+func (s *TwoFactorService) newRecoveryCodes() ([]string, []string, error) {
+	codes, err := totp.GenerateRecoveryCodes(s.config.RecoveryCodes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, security.HashToken(totp.NormalizeRecoveryCode(code)))
+	}
+
+	return codes, hashes, nil
+}
@@ -0,0 +1,220 @@
+package service_test
+
+import (
+	"arabic/internal/dto"
+	"arabic/internal/model"
+	"arabic/internal/service"
+	"arabic/pkg/customError"
+	"arabic/pkg/logger"
+	security "arabic/pkg/security/auth"
+	"arabic/pkg/security/totp"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockITwoFactorRepository struct {
+	mock.Mock
+}
+
+func (m *MockITwoFactorRepository) Find(ctx context.Context, userId int64) (*model.TwoFactor, bool, error) {
+	args := m.Called(ctx, userId)
+	if args.Get(0) == nil {
+		return nil, args.Bool(1), args.Error(2)
+	}
+	return args.Get(0).(*model.TwoFactor), args.Bool(1), args.Error(2)
+}
+func (m *MockITwoFactorRepository) SaveSecret(ctx context.Context, userId int64, secret string) (bool, error) {
+	args := m.Called(ctx, userId, secret)
+	return args.Bool(0), args.Error(1)
+}
+func (m *MockITwoFactorRepository) Enable(ctx context.Context, userId, step int64, codeHashes []string) (bool, error) {
+	args := m.Called(ctx, userId, step, codeHashes)
+	return args.Bool(0), args.Error(1)
+}
+func (m *MockITwoFactorRepository) UseStep(ctx context.Context, userId, step int64) (bool, error) {
+	args := m.Called(ctx, userId, step)
+	return args.Bool(0), args.Error(1)
+}
+func (m *MockITwoFactorRepository) UseRecoveryCode(ctx context.Context, userId int64, codeHash string) (bool, error) {
+	args := m.Called(ctx, userId, codeHash)
+	return args.Bool(0), args.Error(1)
+}
+func (m *MockITwoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userId int64, codeHashes []string) error {
+	args := m.Called(ctx, userId, codeHashes)
+	return args.Error(0)
+}
+func (m *MockITwoFactorRepository) Disable(ctx context.Context, userId int64) error {
+	args := m.Called(ctx, userId)
+	return args.Error(0)
+}
+
+const testTwoFactorSecret = "JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"
+
+func newTwoFactorConfig() *totp.Config {
+	config := totp.NewConfig()
+	config.EncryptionKey = "test-key"
+	config.RequiredRoles = []string{model.RoleAdmin}
+	return config
+}
+
+func newTwoFactorService(repo *MockITwoFactorRepository) *service.TwoFactorService {
+	config := newTwoFactorConfig()
+	cipher, _ := totp.NewCipher(config.EncryptionKey)
+	return service.NewTwoFactorService(repo, cipher, config)
+}
+
+// Второй фактор не подключен ни у кого
+func newTwoFactorStub() *service.TwoFactorService {
+	repo := &MockITwoFactorRepository{}
+	repo.On("Find", mock.Anything, mock.Anything).Return(nil, false, nil)
+	return newTwoFactorService(repo)
+}
+
+// Подключение с тестовым секретом, зашифрованным ключом из newTwoFactorConfig
+func newTwoFactor(t *testing.T, enabled bool) *model.TwoFactor {
+	cipher, err := totp.NewCipher(newTwoFactorConfig().EncryptionKey)
+	require.NoError(t, err)
+	encrypted, err := cipher.Encrypt(testTwoFactorSecret)
+	require.NoError(t, err)
+
+	twoFactor := &model.TwoFactor{UserId: 1, Secret: encrypted}
+	if enabled {
+		now := time.Now()
+		twoFactor.EnabledAt = &now
+	}
+	return twoFactor
+}
+
+func currentCode(t *testing.T) string {
+	code, err := totp.Code(testTwoFactorSecret, time.Now())
+	require.NoError(t, err)
+	return code
+}
+
+func TestTwoFactorService_Enable(t *testing.T) {
+	logger.Init("Error", "./")
+
+	tests := []struct {
+		name       string
+		found      bool
+		enabled    bool
+		wrongCode  bool
+		expectCode int
+	}{
+		{
+			name:  "success",
+			found: true,
+		},
+		{
+			name:       "setup not started",
+			expectCode: 400,
+		},
+		{
+			name:       "already enabled",
+			found:      true,
+			enabled:    true,
+			expectCode: 409,
+		},
+		{
+			name:       "wrong code",
+			found:      true,
+			wrongCode:  true,
+			expectCode: 400,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &MockITwoFactorRepository{}
+			if tc.found {
+				repo.On("Find", mock.Anything, int64(1)).Return(newTwoFactor(t, tc.enabled), true, nil)
+			} else {
+				repo.On("Find", mock.Anything, int64(1)).Return(nil, false, nil)
+			}
+			repo.On("Enable", mock.Anything, int64(1), mock.Anything, mock.Anything).Return(true, nil)
+
+			code := currentCode(t)
+			if tc.wrongCode {
+				code = "000000"
+				if currentCode(t) == code {
+					code = "111111"
+				}
+			}
+
+			resp, err := newTwoFactorService(repo).Enable(context.Background(), 1, code)
+
+			if tc.expectCode != 0 {
+				var serviceErr *customError.ServiceError
+				assert.ErrorAs(t, err, &serviceErr)
+				assert.Equal(t, tc.expectCode, serviceErr.Code)
+				repo.AssertNotCalled(t, "Enable", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Len(t, resp.RecoveryCodes, 10)
+
+			// В БД уходят только хеши кодов
+			hashes := repo.Calls[1].Arguments.Get(3).([]string)
+			assert.Equal(t, security.HashToken(totp.NormalizeRecoveryCode(resp.RecoveryCodes[0])), hashes[0])
+		})
+	}
+}
+
+func TestTwoFactorService_Verify(t *testing.T) {
+	logger.Init("Error", "./")
+
+	t.Run("code is accepted once", func(t *testing.T) {
+		repo := &MockITwoFactorRepository{}
+		repo.On("Find", mock.Anything, int64(1)).Return(newTwoFactor(t, true), true, nil)
+		repo.On("UseStep", mock.Anything, int64(1), mock.Anything).Return(true, nil).Once()
+		repo.On("UseStep", mock.Anything, int64(1), mock.Anything).Return(false, nil)
+		srv := newTwoFactorService(repo)
+		req := &dto.TwoFactorCodeRequest{Code: currentCode(t)}
+
+		ok, err := srv.Verify(context.Background(), 1, req)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		ok, err = srv.Verify(context.Background(), 1, req)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("recovery code", func(t *testing.T) {
+		repo := &MockITwoFactorRepository{}
+		repo.On("Find", mock.Anything, int64(1)).Return(newTwoFactor(t, true), true, nil)
+		repo.On("UseRecoveryCode", mock.Anything, int64(1), security.HashToken("abcde23456")).Return(true, nil)
+
+		ok, err := newTwoFactorService(repo).Verify(context.Background(), 1, &dto.TwoFactorCodeRequest{RecoveryCode: "ABCDE-23456"})
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("not enabled", func(t *testing.T) {
+		repo := &MockITwoFactorRepository{}
+		repo.On("Find", mock.Anything, int64(1)).Return(newTwoFactor(t, false), true, nil)
+
+		ok, err := newTwoFactorService(repo).Verify(context.Background(), 1, &dto.TwoFactorCodeRequest{Code: currentCode(t)})
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		repo.AssertNotCalled(t, "UseStep", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestTwoFactorService_Disable_RequiredRole(t *testing.T) {
+	logger.Init("Error", "./")
+
+	repo := &MockITwoFactorRepository{}
+	err := newTwoFactorService(repo).Disable(context.Background(), 1, model.RoleAdmin, &dto.TwoFactorCodeRequest{Code: "123456"})
+
+	var serviceErr *customError.ServiceError
+	assert.ErrorAs(t, err, &serviceErr)
+	assert.Equal(t, 403, serviceErr.Code)
+	repo.AssertNotCalled(t, "Disable", mock.Anything, mock.Anything)
+}
@@ -6,31 +6,66 @@ import (
 	"arabic/internal/repository"
 	"arabic/pkg/customError"
 	"arabic/pkg/logger"
+	"arabic/pkg/notify"
 	"arabic/pkg/queryBuilder"
 	"arabic/pkg/security/auth"
+	"arabic/pkg/security/throttle"
+	"arabic/pkg/security/totp"
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"strings"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 type IUserService interface {
 	CreateUser(ctx context.Context, user *dto.UserCreateRequest) error
-	Login(ctx context.Context, email, password string) (*dto.UserGetResponse, string, error)
+	Login(ctx context.Context, email, password string, meta *dto.SessionMeta) (*dto.LoginResult, error)
+	LoginTwoFactor(ctx context.Context, req *dto.TwoFactorLoginRequest, meta *dto.SessionMeta) (*dto.LoginResult, error)
+	SetupTwoFactorLogin(ctx context.Context, challengeToken string) (*dto.TwoFactorSetupResponse, error)
+	Refresh(ctx context.Context, refreshToken string) (*dto.AuthTokens, error)
+	Logout(ctx context.Context, refreshToken string) error
+	LogoutAll(ctx context.Context, userId int64) error
 	GetUser(ctx context.Context, email string) (*dto.UserGetResponse, error)
 	UpdateUserInfo(ctx context.Context, req *dto.UserUpdateRequest) error
 	UpdateUserAddress(cxt context.Context, req *dto.UserAddressUpdateRequest) error
+	ForgotPassword(ctx context.Context, email string, meta *dto.SessionMeta) error
+	ResetPassword(ctx context.Context, req *dto.PasswordResetRequest) error
+	VerifyEmail(ctx context.Context, token string) error
 }
 
 type UserService struct {
-	userRepository repository.IUserRepository
-	jwtConfig      *security.JWTConfig
+	userRepository         repository.IUserRepository
+	sessionRepository      repository.ISessionRepository
+	tokenRepository        repository.IUserTokenRepository
+	notificationRepository repository.INotificationRepository
+	loginAuditRepository   repository.ILoginAuditRepository
+	twoFactorService       ITwoFactorService
+	loginLimiter           *throttle.Limiter
+	// Запросы сброса пароля считаются отдельно от попыток входа
+	resetLimiter    *throttle.Limiter
+	jwtConfig       *security.JWTConfig
+	accountConfig   *security.AccountConfig
+	twoFactorConfig *totp.Config
 }
 
-func NewUserService(userRepo repository.IUserRepository, jwtConfig *security.JWTConfig) *UserService {
+func NewUserService(userRepo repository.IUserRepository, sessionRepo repository.ISessionRepository, tokenRepo repository.IUserTokenRepository, notificationRepo repository.INotificationRepository, loginAuditRepo repository.ILoginAuditRepository, twoFactorService ITwoFactorService, loginLimiter *throttle.Limiter, jwtConfig *security.JWTConfig, accountConfig *security.AccountConfig, twoFactorConfig *totp.Config) *UserService {
 	return &UserService{
-		userRepository: userRepo,
-		jwtConfig:      jwtConfig,
+		userRepository:         userRepo,
+		sessionRepository:      sessionRepo,
+		tokenRepository:        tokenRepo,
+		notificationRepository: notificationRepo,
+		loginAuditRepository:   loginAuditRepo,
+		twoFactorService:       twoFactorService,
+		loginLimiter:           loginLimiter,
+		resetLimiter:           loginLimiter.Scoped("reset"),
+		jwtConfig:              jwtConfig,
+		accountConfig:          accountConfig,
+		twoFactorConfig:        twoFactorConfig,
 	}
 }
 
@@ -44,11 +79,16 @@ func (s *UserService) CreateUser(ctx context.Context, req *dto.UserCreateRequest
 		Password: hashedPassword,
 		Email:    req.Email,
 		Username: req.Username,
+		Language: req.Language,
+	}
+	if user.Language == "" {
+		user.Language = notify.DefaultLanguage
 	}
 
 	err = s.userRepository.Create(ctx, user)
 
 	if err == nil {
+		s.sendRegistration(ctx, user)
 		return nil
 	}
 
@@ -61,33 +101,207 @@ func (s *UserService) CreateUser(ctx context.Context, req *dto.UserCreateRequest
 
 //func Log()
 
-func (s *UserService) Login(ctx context.Context, email, password string) (*dto.UserGetResponse, string, error) {
+// Если у пользователя подключен второй фактор или его роль этого требует,
+// вместо сессии выдается токен для второго шага входа
+func (s *UserService) Login(ctx context.Context, email, password string, meta *dto.SessionMeta) (*dto.LoginResult, error) {
+	attempt, err := s.reserveLoginAttempt(ctx, email, meta)
+	if err != nil {
+		return nil, err
+	}
+
 	user, err := s.userRepository.FindByEmail(ctx, email)
 
 	if err != nil {
 		security.CompareHashAndPassword("Dummy-password-for-time", password)
-		return nil, "", customError.NewServiceError(http.StatusBadRequest, "Invalid username or password", err)
+		s.recordLoginFailure(ctx, email, nil, meta, model.LoginUnknownEmail)
+		return nil, customError.NewServiceError(http.StatusBadRequest, "Invalid username or password", err)
 	}
 
 	ok := security.CompareHashAndPassword(password, user.Password)
 	if !ok {
-		return nil, "", customError.NewServiceError(http.StatusBadRequest, "Invalid username or password", err)
+		s.recordLoginFailure(ctx, email, &user.Id, meta, model.LoginWrongPassword)
+		return nil, customError.NewServiceError(http.StatusBadRequest, "Invalid username or password", err)
+	}
+
+	if s.accountConfig.BlocksLogin() && user.EmailVerifiedAt == nil {
+		return nil, customError.NewServiceError(http.StatusForbidden, customError.ErrorEmailNotVerified, nil)
+	}
+
+	enabled, err := s.twoFactorService.IsEnabled(ctx, user.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Счетчик попыток не сбрасывается до ввода кода, иначе пароль позволял бы перебирать коды без задержек
+	if enabled || s.twoFactorConfig.IsRequired(user.RoleCode) {
+		return s.issueLoginChallenge(ctx, user.Id, !enabled)
+	}
+
+	return s.completeLogin(ctx, user, meta, attempt)
+}
+
+// Второй шаг входа. Если второй фактор обязателен, но еще не подключен,
+// код подтверждает подключение и в ответе приходят коды восстановления
+func (s *UserService) LoginTwoFactor(ctx context.Context, req *dto.TwoFactorLoginRequest, meta *dto.SessionMeta) (*dto.LoginResult, error) {
+	challengeHash := security.HashToken(req.ChallengeToken)
+
+	user, err := s.findChallengeUser(ctx, challengeHash)
+	if err != nil {
+		return nil, err
+	}
+
+	attempt, err := s.reserveLoginAttempt(ctx, user.Email, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	enabled, err := s.twoFactorService.IsEnabled(ctx, user.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	var recoveryCodes []string
+	if enabled {
+		ok, err := s.twoFactorService.Verify(ctx, user.Id, &req.TwoFactorCodeRequest)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			s.recordLoginFailure(ctx, user.Email, &user.Id, meta, model.LoginWrongCode)
+			return nil, customError.NewServiceError(http.StatusBadRequest, customError.ErrorInvalidCode, nil)
+		}
+	} else {
+		codes, err := s.twoFactorService.Enable(ctx, user.Id, req.Code)
+		var serviceErr *customError.ServiceError
+		if errors.As(err, &serviceErr) && serviceErr.Code == http.StatusBadRequest {
+			s.recordLoginFailure(ctx, user.Email, &user.Id, meta, model.LoginWrongCode)
+		}
+		if err != nil {
+			return nil, err
+		}
+		recoveryCodes = codes.RecoveryCodes
+	}
+
+	// Токен расходуется только после верного кода, чтобы опечатка не заставляла вводить пароль заново
+	ok, err := s.tokenRepository.Consume(ctx, challengeHash, model.TokenTwoFactor)
+	if err != nil {
+		logger.Log.Error("UserService -> LoginTwoFactor -> Consume -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+	if !ok {
+		return nil, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorLoginExpired, nil)
 	}
 
-	token, err := security.GenerateJWT(user.Email, user.Id, s.jwtConfig)
+	result, err := s.completeLogin(ctx, user, meta, attempt)
 	if err != nil {
-		return nil, "", customError.NewServiceError(http.StatusInternalServerError, "Something went wrong. pls try later", err)
+		return nil, err
 	}
 
-	resp := &dto.UserGetResponse{
-		Email:      user.Email,
-		SecondName: user.SecondName,
-		FirstName:  user.FirstName,
-		Id:         user.Id,
-		Username:   user.Username,
+	result.RecoveryCodes = recoveryCodes
+	return result, nil
+}
+
+// Секрет для подключения второго фактора во время входа, когда роль его требует
+func (s *UserService) SetupTwoFactorLogin(ctx context.Context, challengeToken string) (*dto.TwoFactorSetupResponse, error) {
+	user, err := s.findChallengeUser(ctx, security.HashToken(challengeToken))
+	if err != nil {
+		return nil, err
 	}
 
-	return resp, token, nil
+	return s.twoFactorService.Setup(ctx, user.Id, user.Email)
+}
+
+// Меняет refresh токен на новую пару токенов той же сессии
+func (s *UserService) Refresh(ctx context.Context, refreshToken string) (*dto.AuthTokens, error) {
+	if refreshToken == "" {
+		return nil, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, nil)
+	}
+
+	newToken, newHash, err := security.GenerateRefreshToken()
+	if err != nil {
+		logger.Log.Error("UserService -> Refresh -> GenerateRefreshToken -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	expiresAt := time.Now().Add(s.jwtConfig.RefreshTTL())
+	session, err := s.sessionRepository.Rotate(ctx, security.HashToken(refreshToken), newHash, expiresAt)
+
+	switch {
+	case errors.Is(err, repository.ErrRefreshTokenReused):
+		logger.Log.Warn(fmt.Sprintf("UserService -> Refresh -> refresh token reuse, session %s of user %d revoked", session.Id, session.UserId))
+		return nil, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, err)
+	case errors.Is(err, repository.ErrRefreshTokenNotFound), errors.Is(err, repository.ErrSessionExpired):
+		return nil, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, err)
+	case err != nil:
+		logger.Log.Error("UserService -> Refresh -> Rotate -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	user, err := s.userRepository.FindById(ctx, session.UserId)
+	if err != nil {
+		logger.Log.Error("UserService -> Refresh -> FindById -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorAuthorize, nil)
+	}
+
+	if err = s.checkSessionTwoFactor(ctx, &user.User); err != nil {
+		return nil, err
+	}
+
+	accessToken, err := security.GenerateJWT(user.Email, user.Id, user.RoleCode, session.Id, s.jwtConfig)
+	if err != nil {
+		logger.Log.Error("UserService -> Refresh -> GenerateJWT -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	return &dto.AuthTokens{
+		AccessToken:      accessToken,
+		AccessExpiresAt:  time.Now().Add(s.jwtConfig.AccessTTL()),
+		RefreshToken:     newToken,
+		RefreshExpiresAt: expiresAt,
+	}, nil
+}
+
+// Сессии, начатые до того, как роли потребовался второй фактор, не продлеваются:
+// все сессии пользователя отзываются, и при входе ему придется подключить второй фактор
+func (s *UserService) checkSessionTwoFactor(ctx context.Context, user *model.User) error {
+	if !s.twoFactorConfig.IsRequired(user.RoleCode) {
+		return nil
+	}
+
+	enabled, err := s.twoFactorService.IsEnabled(ctx, user.Id)
+	if err != nil || enabled {
+		return err
+	}
+
+	if err = s.sessionRepository.RevokeAll(ctx, user.Id); err != nil {
+		logger.Log.Error("UserService -> checkSessionTwoFactor -> RevokeAll -> err -> " + err.Error())
+		return customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	return customError.NewServiceError(http.StatusUnauthorized, customError.ErrorTwoFactorNeeded, nil)
+}
+
+// Отзывает сессию, к которой относится refresh токен
+func (s *UserService) Logout(ctx context.Context, refreshToken string) error {
+	if refreshToken == "" {
+		return nil
+	}
+
+	if err := s.sessionRepository.RevokeByToken(ctx, security.HashToken(refreshToken)); err != nil {
+		logger.Log.Error("UserService -> Logout -> err -> " + err.Error())
+		return customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	return nil
+}
+
+func (s *UserService) LogoutAll(ctx context.Context, userId int64) error {
+	if err := s.sessionRepository.RevokeAll(ctx, userId); err != nil {
+		logger.Log.Error("UserService -> LogoutAll -> err -> " + err.Error())
+		return customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	return nil
 }
 
 func (s *UserService) GetUser(ctx context.Context, email string) (*dto.UserGetResponse, error) {
@@ -98,21 +312,14 @@ func (s *UserService) GetUser(ctx context.Context, email string) (*dto.UserGetRe
 		return nil, customError.NewServiceError(http.StatusBadRequest, "Cant find user by provided email", err)
 	}
 
-	resp := dto.UserGetResponse{
-		Email:      user.Email,
-		Username:   user.Username,
-		FirstName:  user.FirstName,
-		SecondName: user.SecondName,
-		Id:         user.Id,
-	}
-
-	return &resp, nil
+	return newUserResponse(user), nil
 }
 
 func (s *UserService) UpdateUserInfo(ctx context.Context, req *dto.UserUpdateRequest) error {
 	qb := queryBuilder.NewQueryBuilder(true).
 		Set("first_name", req.FirstName).
-		Set("second_name", req.SecondName)
+		Set("second_name", req.SecondName).
+		Set("language", req.Language)
 
 	query, values := qb.BuildUpdateQuery("public.users", "id", req.Id)
 
@@ -136,7 +343,9 @@ func (s *UserService) UpdateUserAddress(cxt context.Context, req *dto.UserAddres
 		Set("city", req.City).
 		Set("street", req.Street).
 		Set("house", req.House).
-		Set("apartment", req.Apartment)
+		Set("apartment", req.Apartment).
+		Set("latitude", req.Latitude).
+		Set("longitude", req.Longitude)
 
 	query, values := qb.BuildUpdateQuery("public.users", "id", req.Id)
 
@@ -159,9 +368,254 @@ func (s *UserService) UpdateUserAddress(cxt context.Context, req *dto.UserAddres
 	return nil
 }
 
+// Ответ не зависит от того, есть ли такой email, чтобы по нему нельзя было перебирать адреса. Запросы ограничиваются по email и IP
+func (s *UserService) ForgotPassword(ctx context.Context, email string, meta *dto.SessionMeta) error {
+	_, wait, err := s.resetLimiter.Reserve(ctx, email, meta.Ip)
+	if err != nil {
+		logger.Log.Error("UserService -> ForgotPassword -> Reserve -> err -> " + err.Error())
+	} else if wait > 0 {
+		message := fmt.Sprintf("Too many password reset requests. Try again in %d seconds", int(math.Ceil(wait.Seconds())))
+		return customError.NewServiceError(http.StatusTooManyRequests, message, nil)
+	}
+
+	// Поиск пользователя и письмо уходят в фон, чтобы по времени ответа нельзя было узнать, зарегистрирован ли email
+	go s.sendPasswordReset(context.WithoutCancel(ctx), email)
+
+	return nil
+}
+
+func (s *UserService) sendPasswordReset(ctx context.Context, email string) {
+	user, err := s.userRepository.FindByEmail(ctx, email)
+	if err != nil {
+		return
+	}
+
+	token, err := s.issueToken(ctx, user.Id, model.TokenPasswordReset, s.accountConfig.ResetTTL())
+	if err != nil {
+		logger.Log.Error("UserService -> sendPasswordReset -> issueToken -> err -> " + err.Error())
+		return
+	}
+
+	data := map[string]any{
+		"username":    user.Username,
+		"link":        s.accountConfig.ResetLink(token),
+		"ttl_minutes": s.accountConfig.ResetTokenTTL,
+	}
+	if err = s.notificationRepository.Enqueue(ctx, user.Id, model.NotificationPasswordReset, data); err != nil {
+		logger.Log.Error("UserService -> sendPasswordReset -> Enqueue -> err -> " + err.Error())
+	}
+}
+
+// Меняет пароль по токену из письма. Все сессии пользователя отзываются
+func (s *UserService) ResetPassword(ctx context.Context, req *dto.PasswordResetRequest) error {
+	hashedPassword, err := security.GenerateHashFromPassword(req.Password)
+	if err != nil {
+		logger.Log.Error("UserService -> ResetPassword -> GenerateHashFromPassword -> err -> " + err.Error())
+		return customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	ok, err := s.tokenRepository.ResetPassword(ctx, security.HashToken(req.Token), hashedPassword)
+	if err != nil {
+		logger.Log.Error("UserService -> ResetPassword -> err -> " + err.Error())
+		return customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	if !ok {
+		return customError.NewServiceError(http.StatusBadRequest, customError.ErrorInvalidToken, nil)
+	}
+
+	return nil
+}
+
+func (s *UserService) VerifyEmail(ctx context.Context, token string) error {
+	if token == "" {
+		return customError.NewServiceError(http.StatusBadRequest, customError.ErrorInvalidToken, nil)
+	}
+
+	ok, err := s.tokenRepository.VerifyEmail(ctx, security.HashToken(token))
+	if err != nil {
+		logger.Log.Error("UserService -> VerifyEmail -> err -> " + err.Error())
+		return customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	if !ok {
+		return customError.NewServiceError(http.StatusBadRequest, customError.ErrorInvalidToken, nil)
+	}
+
+	return nil
+}
+
+// Письмо со ссылкой подтверждения. Оно не должно мешать регистрации, поэтому ошибки
+// только логируются: подтвердить email можно и через сброс пароля
+func (s *UserService) sendRegistration(ctx context.Context, user *model.User) {
+	token, err := s.issueToken(ctx, user.Id, model.TokenVerifyEmail, s.accountConfig.VerifyTTL())
+	if err != nil {
+		logger.Log.Error("UserService -> sendRegistration -> issueToken -> err -> " + err.Error())
+		return
+	}
+
+	data := map[string]any{
+		"username": user.Username,
+		"email":    user.Email,
+		"link":     s.accountConfig.VerifyLink(token),
+	}
+	if err = s.notificationRepository.Enqueue(ctx, user.Id, model.NotificationRegistration, data); err != nil {
+		logger.Log.Error("UserService -> sendRegistration -> Enqueue -> err -> " + err.Error())
+	}
+}
+
+// Засчитывает попытку входа до проверки пароля или кода и отказывает, пока для email
+// или IP действует пауза. Недоступное хранилище счетчиков не должно блокировать вход всем пользователям
+func (s *UserService) reserveLoginAttempt(ctx context.Context, email string, meta *dto.SessionMeta) (*throttle.Attempt, error) {
+	attempt, wait, err := s.loginLimiter.Reserve(ctx, email, meta.Ip)
+	if err != nil {
+		logger.Log.Error("UserService -> reserveLoginAttempt -> Reserve -> err -> " + err.Error())
+		return nil, nil
+	}
+
+	if wait > 0 {
+		s.recordLoginFailure(ctx, email, nil, meta, model.LoginThrottled)
+		message := fmt.Sprintf("Too many login attempts. Try again in %d seconds", int(math.Ceil(wait.Seconds())))
+		return nil, customError.NewServiceError(http.StatusTooManyRequests, message, nil)
+	}
+
+	return attempt, nil
+}
+
+// Сбрасывает счетчик неудачных попыток и открывает сессию
+func (s *UserService) completeLogin(ctx context.Context, user *model.UserFullInfo, meta *dto.SessionMeta, attempt *throttle.Attempt) (*dto.LoginResult, error) {
+	if attempt != nil {
+		if err := s.loginLimiter.Succeed(ctx, attempt); err != nil {
+			logger.Log.Error("UserService -> completeLogin -> Succeed -> err -> " + err.Error())
+		}
+	}
+
+	tokens, err := s.startSession(ctx, &user.User, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.LoginResult{User: newUserResponse(user), Tokens: tokens}, nil
+}
+
+func (s *UserService) issueLoginChallenge(ctx context.Context, userId int64, enrollmentRequired bool) (*dto.LoginResult, error) {
+	token, err := s.issueToken(ctx, userId, model.TokenTwoFactor, s.twoFactorConfig.ChallengeTTL())
+	if err != nil {
+		logger.Log.Error("UserService -> issueLoginChallenge -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+
+	return &dto.LoginResult{Challenge: &dto.TwoFactorChallengeResponse{
+		TwoFactorRequired:  true,
+		ChallengeToken:     token,
+		EnrollmentRequired: enrollmentRequired,
+		ExpiresIn:          int(s.twoFactorConfig.ChallengeTTL().Seconds()),
+	}}, nil
+}
+
+// Пользователь, прошедший первый шаг входа. Токен при этом не расходуется
+func (s *UserService) findChallengeUser(ctx context.Context, challengeHash string) (*model.UserFullInfo, error) {
+	userId, ok, err := s.tokenRepository.FindUser(ctx, challengeHash, model.TokenTwoFactor)
+	if err != nil {
+		logger.Log.Error("UserService -> findChallengeUser -> FindUser -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, customError.Error500, nil)
+	}
+	if !ok {
+		return nil, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorLoginExpired, nil)
+	}
+
+	user, err := s.userRepository.FindById(ctx, userId)
+	if err != nil {
+		logger.Log.Error("UserService -> findChallengeUser -> FindById -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusUnauthorized, customError.ErrorLoginExpired, nil)
+	}
+
+	return user, nil
+}
+
+// Пишет неудачную попытку входа в журнал, в счетчиках она уже учтена при резервировании.
+// Ошибки только логируются, ответ пользователю от них не зависит
+func (s *UserService) recordLoginFailure(ctx context.Context, email string, userId *int64, meta *dto.SessionMeta, reason string) {
+	failure := &model.LoginFailure{
+		Email:     truncateEmail(strings.ToLower(strings.TrimSpace(email))),
+		UserId:    userId,
+		Ip:        meta.Ip,
+		UserAgent: meta.UserAgent,
+		Reason:    reason,
+	}
+	if err := s.loginAuditRepository.Record(ctx, failure); err != nil {
+		logger.Log.Error("UserService -> recordLoginFailure -> Record -> err -> " + err.Error())
+	}
+}
+
+// Выпускает одноразовый токен, прежние токены того же назначения перестают действовать
+func (s *UserService) issueToken(ctx context.Context, userId int64, purpose string, ttl time.Duration) (string, error) {
+	token, hash, err := security.GenerateToken()
+	if err != nil {
+		return "", err
+	}
+
+	return token, s.tokenRepository.Create(ctx, userId, purpose, hash, time.Now().Add(ttl))
+}
+
+// Создает новую сессию и выдает для нее access и refresh токены
+func (s *UserService) startSession(ctx context.Context, user *model.User, meta *dto.SessionMeta) (*dto.AuthTokens, error) {
+	refreshToken, refreshHash, err := security.GenerateRefreshToken()
+	if err != nil {
+		logger.Log.Error("UserService -> startSession -> GenerateRefreshToken -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, "Something went wrong. pls try later", nil)
+	}
+
+	session := &model.UserSession{
+		Id:        uuid.New().String(),
+		UserId:    user.Id,
+		UserAgent: meta.UserAgent,
+		Ip:        meta.Ip,
+		ExpiresAt: time.Now().Add(s.jwtConfig.RefreshTTL()),
+	}
+
+	if err = s.sessionRepository.Create(ctx, session, refreshHash); err != nil {
+		logger.Log.Error("UserService -> startSession -> Create -> err -> " + err.Error())
+		return nil, customError.NewServiceError(http.StatusInternalServerError, "Something went wrong. pls try later", nil)
+	}
+
+	accessToken, err := security.GenerateJWT(user.Email, user.Id, user.RoleCode, session.Id, s.jwtConfig)
+	if err != nil {
+		return nil, customError.NewServiceError(http.StatusInternalServerError, "Something went wrong. pls try later", err)
+	}
+
+	return &dto.AuthTokens{
+		AccessToken:      accessToken,
+		AccessExpiresAt:  time.Now().Add(s.jwtConfig.AccessTTL()),
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: session.ExpiresAt,
+	}, nil
+}
+
 func (s *UserService) handleDuplicateErrorMessage(err error, user *model.User) error {
 	if strings.Contains(err.Error(), "email") {
 		return customError.NewServiceError(http.StatusConflict, fmt.Sprintf("User with this email= [%s] already exists", user.Email), err)
 	}
 	return customError.NewServiceError(http.StatusConflict, fmt.Sprintf("User with this username= [%s] already exists", user.Username), err)
 }
+
+func newUserResponse(user *model.UserFullInfo) *dto.UserGetResponse {
+	return &dto.UserGetResponse{
+		Email:         user.Email,
+		Username:      user.Username,
+		FirstName:     user.FirstName,
+		SecondName:    user.SecondName,
+		Id:            user.Id,
+		Language:      user.Language,
+		EmailVerified: user.EmailVerifiedAt != nil,
+	}
+}
+
+// Длина email ограничена RFC 5321, в журнал длиннее не пишем
+func truncateEmail(email string) string {
+	const maxEmailLength = 320
+	if len(email) <= maxEmailLength {
+		return email
+	}
+	return strings.ToValidUTF8(email[:maxEmailLength], "")
+}
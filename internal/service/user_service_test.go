@@ -0,0 +1,619 @@
+package service_test
+
+import (
+	"arabic/internal/dto"
+	"arabic/internal/model"
+	"arabic/internal/repository"
+	"arabic/internal/service"
+	"arabic/pkg/customError"
+	"arabic/pkg/logger"
+	security "arabic/pkg/security/auth"
+	"arabic/pkg/security/throttle"
+	"arabic/pkg/security/totp"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"strings"
+	"testing"
+	"time"
+)
+
+type MockISessionRepository struct {
+	mock.Mock
+}
+
+func (m *MockISessionRepository) Create(ctx context.Context, session *model.UserSession, tokenHash string) error {
+	args := m.Called(ctx, session, tokenHash)
+	return args.Error(0)
+}
+func (m *MockISessionRepository) Rotate(ctx context.Context, tokenHash, newTokenHash string, expiresAt time.Time) (*model.UserSession, error) {
+	args := m.Called(ctx, tokenHash, newTokenHash, expiresAt)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.UserSession), args.Error(1)
+}
+func (m *MockISessionRepository) RevokeByToken(ctx context.Context, tokenHash string) error {
+	args := m.Called(ctx, tokenHash)
+	return args.Error(0)
+}
+func (m *MockISessionRepository) RevokeAll(ctx context.Context, userId int64) error {
+	args := m.Called(ctx, userId)
+	return args.Error(0)
+}
+func (m *MockISessionRepository) IsSessionActive(ctx context.Context, sessionId string) (bool, error) {
+	args := m.Called(ctx, sessionId)
+	return args.Bool(0), args.Error(1)
+}
+
+type MockIUserTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockIUserTokenRepository) Create(ctx context.Context, userId int64, purpose, tokenHash string, expiresAt time.Time) error {
+	args := m.Called(ctx, userId, purpose, tokenHash, expiresAt)
+	return args.Error(0)
+}
+func (m *MockIUserTokenRepository) VerifyEmail(ctx context.Context, tokenHash string) (bool, error) {
+	args := m.Called(ctx, tokenHash)
+	return args.Bool(0), args.Error(1)
+}
+func (m *MockIUserTokenRepository) ResetPassword(ctx context.Context, tokenHash, passwordHash string) (bool, error) {
+	args := m.Called(ctx, tokenHash, passwordHash)
+	return args.Bool(0), args.Error(1)
+}
+func (m *MockIUserTokenRepository) FindUser(ctx context.Context, tokenHash, purpose string) (int64, bool, error) {
+	args := m.Called(ctx, tokenHash, purpose)
+	return args.Get(0).(int64), args.Bool(1), args.Error(2)
+}
+func (m *MockIUserTokenRepository) Consume(ctx context.Context, tokenHash, purpose string) (bool, error) {
+	args := m.Called(ctx, tokenHash, purpose)
+	return args.Bool(0), args.Error(1)
+}
+
+type MockILoginAuditRepository struct {
+	mock.Mock
+}
+
+func (m *MockILoginAuditRepository) Record(ctx context.Context, failure *model.LoginFailure) error {
+	args := m.Called(ctx, failure)
+	return args.Error(0)
+}
+func (m *MockILoginAuditRepository) Find(ctx context.Context, email, ip string, limit int) ([]*model.LoginFailure, error) {
+	args := m.Called(ctx, email, ip, limit)
+	return args.Get(0).([]*model.LoginFailure), args.Error(1)
+}
+
+func newLoginLimiter() *throttle.Limiter {
+	return throttle.NewLimiter(throttle.NewMemoryStore(), throttle.NewConfig())
+}
+
+func TestUserService_Refresh(t *testing.T) {
+	logger.Init("Error", "./")
+
+	session := &model.UserSession{Id: "7f1c6f5e-8f0a-4c57-9d1c-2d8f7f2a0b11", UserId: 1}
+
+	tests := []struct {
+		name       string
+		token      string
+		session    *model.UserSession
+		mockError  error
+		expectCode int
+	}{
+		{
+			name:    "success",
+			token:   "refresh",
+			session: session,
+		},
+		{
+			name:       "empty token",
+			token:      "",
+			expectCode: 401,
+		},
+		{
+			name:       "unknown token",
+			token:      "refresh",
+			mockError:  repository.ErrRefreshTokenNotFound,
+			expectCode: 401,
+		},
+		{
+			name:       "reused token",
+			token:      "refresh",
+			session:    session,
+			mockError:  repository.ErrRefreshTokenReused,
+			expectCode: 401,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sessionRepo := &MockISessionRepository{}
+			sessionRepo.On("Rotate", mock.Anything, security.HashToken(tc.token), mock.Anything, mock.Anything).Return(tc.session, tc.mockError)
+
+			userRepo := &MockIUserRepository{}
+			userRepo.On("FindById", mock.Anything, int64(1)).Return(&model.UserFullInfo{User: model.User{Id: 1, Email: "a@a.com", RoleCode: "user"}}, nil)
+
+			srv := service.NewUserService(userRepo, sessionRepo, &MockIUserTokenRepository{}, &MockINotificationRepository{}, &MockILoginAuditRepository{}, newTwoFactorStub(), newLoginLimiter(), security.NewJWTConfig(), security.NewAccountConfig(), totp.NewConfig())
+			tokens, err := srv.Refresh(context.Background(), tc.token)
+
+			if tc.expectCode != 0 {
+				var serviceErr *customError.ServiceError
+				assert.ErrorAs(t, err, &serviceErr)
+				assert.Equal(t, tc.expectCode, serviceErr.Code)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.NotEmpty(t, tokens.AccessToken)
+			assert.NotEmpty(t, tokens.RefreshToken)
+			assert.NotEqual(t, tc.token, tokens.RefreshToken)
+		})
+	}
+}
+
+func TestUserService_Refresh_TwoFactorRequired(t *testing.T) {
+	logger.Init("Error", "./")
+
+	session := &model.UserSession{Id: "7f1c6f5e-8f0a-4c57-9d1c-2d8f7f2a0b11", UserId: 1}
+
+	tests := []struct {
+		name       string
+		twoFactor  *model.TwoFactor
+		expectCode int
+	}{
+		{
+			name:       "not enrolled",
+			expectCode: 401,
+		},
+		{
+			name:      "enrolled",
+			twoFactor: newTwoFactor(t, true),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sessionRepo := &MockISessionRepository{}
+			sessionRepo.On("Rotate", mock.Anything, security.HashToken("refresh"), mock.Anything, mock.Anything).Return(session, nil)
+			sessionRepo.On("RevokeAll", mock.Anything, int64(1)).Return(nil)
+
+			userRepo := &MockIUserRepository{}
+			userRepo.On("FindById", mock.Anything, int64(1)).Return(&model.UserFullInfo{User: model.User{Id: 1, Email: "a@a.com", RoleCode: model.RoleAdmin}}, nil)
+
+			twoFactorRepo := &MockITwoFactorRepository{}
+			twoFactorRepo.On("Find", mock.Anything, int64(1)).Return(tc.twoFactor, tc.twoFactor != nil, nil)
+
+			srv := service.NewUserService(userRepo, sessionRepo, &MockIUserTokenRepository{}, &MockINotificationRepository{}, &MockILoginAuditRepository{}, newTwoFactorService(twoFactorRepo), newLoginLimiter(), security.NewJWTConfig(), security.NewAccountConfig(), newTwoFactorConfig())
+			tokens, err := srv.Refresh(context.Background(), "refresh")
+
+			if tc.expectCode != 0 {
+				var serviceErr *customError.ServiceError
+				assert.ErrorAs(t, err, &serviceErr)
+				assert.Equal(t, tc.expectCode, serviceErr.Code)
+				sessionRepo.AssertCalled(t, "RevokeAll", mock.Anything, int64(1))
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.NotEmpty(t, tokens.AccessToken)
+			sessionRepo.AssertNotCalled(t, "RevokeAll", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestUserService_CreateUser(t *testing.T) {
+	logger.Init("Error", "./")
+
+	tests := []struct {
+		name         string
+		language     string
+		expectLang   string
+		enqueueError error
+	}{
+		{
+			name:       "default language",
+			expectLang: "ru",
+		},
+		{
+			name:       "english",
+			language:   "en",
+			expectLang: "en",
+		},
+		{
+			name:         "queue failure does not break registration",
+			expectLang:   "ru",
+			enqueueError: errors.New("connection refused"),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			userRepo := &MockIUserRepository{}
+			userRepo.On("Create", mock.Anything, mock.MatchedBy(func(u *model.User) bool {
+				return u.Language == tc.expectLang
+			})).Run(func(args mock.Arguments) {
+				args.Get(1).(*model.User).Id = 7
+			}).Return(nil)
+
+			// В письмо уходит сам токен, в базу - только его хеш
+			var tokenHash string
+			tokenRepo := &MockIUserTokenRepository{}
+			tokenRepo.On("Create", mock.Anything, int64(7), model.TokenVerifyEmail, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				tokenHash = args.String(3)
+			}).Return(nil)
+
+			notificationRepo := &MockINotificationRepository{}
+			notificationRepo.On("Enqueue", mock.Anything, int64(7), model.NotificationRegistration, mock.MatchedBy(func(data map[string]any) bool {
+				link, _ := data["link"].(string)
+				token, found := strings.CutPrefix(link, security.NewAccountConfig().VerifyURL+"?token=")
+				return found && security.HashToken(token) == tokenHash && data["email"] == "a@a.com"
+			})).Return(tc.enqueueError)
+
+			srv := service.NewUserService(userRepo, &MockISessionRepository{}, tokenRepo, notificationRepo, &MockILoginAuditRepository{}, newTwoFactorStub(), newLoginLimiter(), security.NewJWTConfig(), security.NewAccountConfig(), totp.NewConfig())
+			err := srv.CreateUser(context.Background(), &dto.UserCreateRequest{Email: "a@a.com", Username: "user", Password: "Password1!", Language: tc.language})
+
+			assert.NoError(t, err)
+			userRepo.AssertExpectations(t)
+			tokenRepo.AssertExpectations(t)
+			notificationRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserService_Login_RequireVerifiedEmail(t *testing.T) {
+	logger.Init("Error", "./")
+
+	password, err := security.GenerateHashFromPassword("Password1!")
+	assert.NoError(t, err)
+	verifiedAt := time.Now()
+
+	tests := []struct {
+		name       string
+		mode       string
+		verifiedAt *time.Time
+		expectCode int
+	}{
+		{
+			name:       "blocked until verified",
+			mode:       security.VerifiedEmailLogin,
+			expectCode: 403,
+		},
+		{
+			name:       "verified",
+			mode:       security.VerifiedEmailLogin,
+			verifiedAt: &verifiedAt,
+		},
+		{
+			name: "only checkout is blocked",
+			mode: security.VerifiedEmailCheckout,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			userRepo := &MockIUserRepository{}
+			userRepo.On("FindByEmail", mock.Anything, "a@a.com").Return(&model.UserFullInfo{
+				User: model.User{Id: 1, Email: "a@a.com", Password: password, RoleCode: "user", EmailVerifiedAt: tc.verifiedAt},
+			}, nil)
+
+			sessionRepo := &MockISessionRepository{}
+			sessionRepo.On("Create", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+			account := security.NewAccountConfig()
+			account.RequireVerifiedEmail = tc.mode
+
+			srv := service.NewUserService(userRepo, sessionRepo, &MockIUserTokenRepository{}, &MockINotificationRepository{}, &MockILoginAuditRepository{}, newTwoFactorStub(), newLoginLimiter(), security.NewJWTConfig(), account, totp.NewConfig())
+			result, err := srv.Login(context.Background(), "a@a.com", "Password1!", &dto.SessionMeta{})
+
+			if tc.expectCode != 0 {
+				var serviceErr *customError.ServiceError
+				assert.ErrorAs(t, err, &serviceErr)
+				assert.Equal(t, tc.expectCode, serviceErr.Code)
+				sessionRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.verifiedAt != nil, result.User.EmailVerified)
+		})
+	}
+}
+
+func TestUserService_ResetPassword(t *testing.T) {
+	logger.Init("Error", "./")
+
+	tests := []struct {
+		name       string
+		repoOk     bool
+		expectCode int
+	}{
+		{
+			name:   "success",
+			repoOk: true,
+		},
+		{
+			name:       "used or expired token",
+			repoOk:     false,
+			expectCode: 400,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tokenRepo := &MockIUserTokenRepository{}
+			tokenRepo.On("ResetPassword", mock.Anything, security.HashToken("reset"), mock.MatchedBy(func(hash string) bool {
+				return security.CompareHashAndPassword("NewPassword1!", hash)
+			})).Return(tc.repoOk, nil)
+
+			srv := service.NewUserService(&MockIUserRepository{}, &MockISessionRepository{}, tokenRepo, &MockINotificationRepository{}, &MockILoginAuditRepository{}, newTwoFactorStub(), newLoginLimiter(), security.NewJWTConfig(), security.NewAccountConfig(), totp.NewConfig())
+			err := srv.ResetPassword(context.Background(), &dto.PasswordResetRequest{Token: "reset", Password: "NewPassword1!"})
+
+			if tc.expectCode != 0 {
+				var serviceErr *customError.ServiceError
+				assert.ErrorAs(t, err, &serviceErr)
+				assert.Equal(t, tc.expectCode, serviceErr.Code)
+				return
+			}
+
+			assert.NoError(t, err)
+			tokenRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserService_ForgotPassword(t *testing.T) {
+	logger.Init("Error", "./")
+
+	tests := []struct {
+		name      string
+		email     string
+		expectJob bool
+	}{
+		{name: "known email gets reset link", email: "a@a.com", expectJob: true},
+		{name: "unknown email answers the same", email: "nobody@a.com"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			looked := make(chan struct{})
+			enqueued := make(chan struct{})
+
+			userRepo := &MockIUserRepository{}
+			userRepo.On("FindByEmail", mock.Anything, "a@a.com").Run(func(args mock.Arguments) {
+				close(looked)
+			}).Return(&model.UserFullInfo{User: model.User{Id: 7, Email: "a@a.com", Username: "user"}}, nil)
+			userRepo.On("FindByEmail", mock.Anything, "nobody@a.com").Run(func(args mock.Arguments) {
+				close(looked)
+			}).Return(nil, errors.New("no rows in result set"))
+
+			// Для неизвестного email вызовы ниже не настроены, и мок упадет, если до них дойдет
+			tokenRepo := &MockIUserTokenRepository{}
+			notificationRepo := &MockINotificationRepository{}
+			if tc.expectJob {
+				tokenRepo.On("Create", mock.Anything, int64(7), model.TokenPasswordReset, mock.Anything, mock.Anything).Return(nil)
+				notificationRepo.On("Enqueue", mock.Anything, int64(7), model.NotificationPasswordReset, mock.Anything).Run(func(args mock.Arguments) {
+					close(enqueued)
+				}).Return(nil)
+			}
+
+			srv := service.NewUserService(userRepo, &MockISessionRepository{}, tokenRepo, notificationRepo, &MockILoginAuditRepository{}, newTwoFactorStub(), newLoginLimiter(), security.NewJWTConfig(), security.NewAccountConfig(), totp.NewConfig())
+
+			assert.NoError(t, srv.ForgotPassword(context.Background(), tc.email, &dto.SessionMeta{Ip: "10.0.0.1"}))
+
+			<-looked
+			if tc.expectJob {
+				<-enqueued
+				tokenRepo.AssertExpectations(t)
+			}
+		})
+	}
+}
+
+func TestUserService_ForgotPassword_Throttle(t *testing.T) {
+	logger.Init("Error", "./")
+
+	userRepo := &MockIUserRepository{}
+	userRepo.On("FindByEmail", mock.Anything, "nobody@a.com").Return(nil, errors.New("no rows in result set"))
+
+	config := throttle.NewConfig()
+	config.EmailFreeAttempts = 2
+	config.BaseDelaySeconds = 60
+	limiter := throttle.NewLimiter(throttle.NewMemoryStore(), config)
+
+	srv := service.NewUserService(userRepo, &MockISessionRepository{}, &MockIUserTokenRepository{}, &MockINotificationRepository{}, &MockILoginAuditRepository{}, newTwoFactorStub(), limiter, security.NewJWTConfig(), security.NewAccountConfig(), totp.NewConfig())
+	meta := &dto.SessionMeta{Ip: "10.0.0.1"}
+
+	assert.NoError(t, srv.ForgotPassword(context.Background(), "nobody@a.com", meta))
+	assert.NoError(t, srv.ForgotPassword(context.Background(), "nobody@a.com", meta))
+
+	err := srv.ForgotPassword(context.Background(), "nobody@a.com", meta)
+	var serviceErr *customError.ServiceError
+	assert.ErrorAs(t, err, &serviceErr)
+	assert.Equal(t, 429, serviceErr.Code)
+
+	// Запросы сброса не тратят попытки входа
+	_, wait, err := limiter.Reserve(context.Background(), "nobody@a.com", meta.Ip)
+	assert.NoError(t, err)
+	assert.Zero(t, wait)
+}
+
+func TestUserService_Login_Throttle(t *testing.T) {
+	logger.Init("Error", "./")
+
+	password, err := security.GenerateHashFromPassword("Password1!")
+	assert.NoError(t, err)
+
+	userRepo := &MockIUserRepository{}
+	userRepo.On("FindByEmail", mock.Anything, "a@a.com").Return(&model.UserFullInfo{
+		User: model.User{Id: 1, Email: "a@a.com", Password: password, RoleCode: "user"},
+	}, nil)
+	userRepo.On("FindByEmail", mock.Anything, "nobody@a.com").Return(nil, errors.New("no rows in result set"))
+
+	var reasons []string
+	auditRepo := &MockILoginAuditRepository{}
+	auditRepo.On("Record", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		reasons = append(reasons, args.Get(1).(*model.LoginFailure).Reason)
+	}).Return(nil)
+
+	sessionRepo := &MockISessionRepository{}
+	sessionRepo.On("Create", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	config := throttle.NewConfig()
+	config.EmailFreeAttempts = 2
+	config.BaseDelaySeconds = 60
+	limiter := throttle.NewLimiter(throttle.NewMemoryStore(), config)
+
+	srv := service.NewUserService(userRepo, sessionRepo, &MockIUserTokenRepository{}, &MockINotificationRepository{}, auditRepo, newTwoFactorStub(), limiter, security.NewJWTConfig(), security.NewAccountConfig(), totp.NewConfig())
+	meta := &dto.SessionMeta{Ip: "10.0.0.1"}
+
+	login := func(password string) int {
+		_, err := srv.Login(context.Background(), "a@a.com", password, meta)
+		var serviceErr *customError.ServiceError
+		if errors.As(err, &serviceErr) {
+			return serviceErr.Code
+		}
+		return 200
+	}
+
+	assert.Equal(t, 400, login("wrong"))
+	assert.Equal(t, 400, login("wrong"))
+	// Даже верный пароль не проверяется, пока действует пауза
+	assert.Equal(t, 429, login("Password1!"))
+
+	_, err = srv.Login(context.Background(), "nobody@a.com", "wrong", meta)
+	assert.Error(t, err)
+
+	assert.NoError(t, limiter.Unlock(context.Background(), "a@a.com", ""))
+	assert.Equal(t, 200, login("Password1!"))
+
+	assert.Equal(t, []string{model.LoginWrongPassword, model.LoginWrongPassword, model.LoginThrottled, model.LoginUnknownEmail}, reasons)
+}
+
+func TestUserService_Login_TwoFactor(t *testing.T) {
+	logger.Init("Error", "./")
+
+	password, err := security.GenerateHashFromPassword("Password1!")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name             string
+		role             string
+		twoFactor        *model.TwoFactor
+		expectChallenge  bool
+		expectEnrollment bool
+	}{
+		{
+			name: "not enabled",
+			role: model.RoleUser,
+		},
+		{
+			name:            "enabled",
+			role:            model.RoleUser,
+			twoFactor:       newTwoFactor(t, true),
+			expectChallenge: true,
+		},
+		{
+			name:             "required by role",
+			role:             model.RoleAdmin,
+			expectChallenge:  true,
+			expectEnrollment: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			userRepo := &MockIUserRepository{}
+			userRepo.On("FindByEmail", mock.Anything, "a@a.com").Return(&model.UserFullInfo{
+				User: model.User{Id: 1, Email: "a@a.com", Password: password, RoleCode: tc.role},
+			}, nil)
+
+			sessionRepo := &MockISessionRepository{}
+			sessionRepo.On("Create", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+			tokenRepo := &MockIUserTokenRepository{}
+			tokenRepo.On("Create", mock.Anything, int64(1), model.TokenTwoFactor, mock.Anything, mock.Anything).Return(nil)
+
+			twoFactorRepo := &MockITwoFactorRepository{}
+			twoFactorRepo.On("Find", mock.Anything, int64(1)).Return(tc.twoFactor, tc.twoFactor != nil, nil)
+
+			srv := service.NewUserService(userRepo, sessionRepo, tokenRepo, &MockINotificationRepository{}, &MockILoginAuditRepository{}, newTwoFactorService(twoFactorRepo), newLoginLimiter(), security.NewJWTConfig(), security.NewAccountConfig(), newTwoFactorConfig())
+			result, err := srv.Login(context.Background(), "a@a.com", "Password1!", &dto.SessionMeta{})
+			assert.NoError(t, err)
+
+			if !tc.expectChallenge {
+				assert.Nil(t, result.Challenge)
+				assert.NotNil(t, result.Tokens)
+				return
+			}
+
+			assert.Nil(t, result.Tokens)
+			assert.NotEmpty(t, result.Challenge.ChallengeToken)
+			assert.Equal(t, tc.expectEnrollment, result.Challenge.EnrollmentRequired)
+			sessionRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestUserService_LoginTwoFactor(t *testing.T) {
+	logger.Init("Error", "./")
+
+	userRepo := &MockIUserRepository{}
+	userRepo.On("FindById", mock.Anything, int64(1)).Return(&model.UserFullInfo{
+		User: model.User{Id: 1, Email: "a@a.com", RoleCode: model.RoleUser},
+	}, nil)
+
+	sessionRepo := &MockISessionRepository{}
+	sessionRepo.On("Create", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	challengeHash := security.HashToken("challenge")
+	tokenRepo := &MockIUserTokenRepository{}
+	tokenRepo.On("FindUser", mock.Anything, challengeHash, model.TokenTwoFactor).Return(int64(1), true, nil)
+	tokenRepo.On("FindUser", mock.Anything, mock.Anything, model.TokenTwoFactor).Return(int64(0), false, nil)
+	tokenRepo.On("Consume", mock.Anything, challengeHash, model.TokenTwoFactor).Return(true, nil)
+
+	twoFactorRepo := &MockITwoFactorRepository{}
+	twoFactorRepo.On("Find", mock.Anything, int64(1)).Return(newTwoFactor(t, true), true, nil)
+	twoFactorRepo.On("UseStep", mock.Anything, int64(1), mock.Anything).Return(true, nil)
+
+	var reasons []string
+	auditRepo := &MockILoginAuditRepository{}
+	auditRepo.On("Record", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		reasons = append(reasons, args.Get(1).(*model.LoginFailure).Reason)
+	}).Return(nil)
+
+	srv := service.NewUserService(userRepo, sessionRepo, tokenRepo, &MockINotificationRepository{}, auditRepo, newTwoFactorService(twoFactorRepo), newLoginLimiter(), security.NewJWTConfig(), security.NewAccountConfig(), newTwoFactorConfig())
+	meta := &dto.SessionMeta{Ip: "10.0.0.1"}
+
+	login := func(token, code string) (*dto.LoginResult, int) {
+		req := &dto.TwoFactorLoginRequest{}
+		req.ChallengeToken = token
+		req.Code = code
+
+		result, err := srv.LoginTwoFactor(context.Background(), req, meta)
+		var serviceErr *customError.ServiceError
+		if errors.As(err, &serviceErr) {
+			return nil, serviceErr.Code
+		}
+		return result, 200
+	}
+
+	wrongCode := "000000"
+	if currentCode(t) == wrongCode {
+		wrongCode = "111111"
+	}
+
+	_, code := login("expired", currentCode(t))
+	assert.Equal(t, 401, code)
+
+	_, code = login("challenge", wrongCode)
+	assert.Equal(t, 400, code)
+	tokenRepo.AssertNotCalled(t, "Consume", mock.Anything, mock.Anything, mock.Anything)
+
+	result, code := login("challenge", currentCode(t))
+	assert.Equal(t, 200, code)
+	assert.NotNil(t, result.Tokens)
+	assert.Equal(t, int64(1), result.User.Id)
+
+	assert.Equal(t, []string{model.LoginWrongCode}, reasons)
+}
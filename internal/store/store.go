@@ -7,12 +7,29 @@ import (
 )
 
 type Store struct {
-	config             *Config
-	db                 *pgxpool.Pool
-	userRepository     *repository.UserRepository
-	tagRepository      *repository.TagRepository
-	categoryRepository *repository.CategoryRepository
-	catalogRepository  *repository.CatalogRepository
+	config                 *Config
+	db                     *pgxpool.Pool
+	userRepository         *repository.UserRepository
+	tagRepository          *repository.TagRepository
+	categoryRepository     *repository.CategoryRepository
+	catalogRepository      *repository.CatalogRepository
+	cartRepository         *repository.CartRepository
+	orderRepository        *repository.OrderRepository
+	sessionRepository      *repository.SessionRepository
+	deliveryRepository     *repository.DeliveryRepository
+	dispatchRepository     *repository.DispatchRepository
+	pickingRepository      *repository.PickingRepository
+	paymentRepository      *repository.PaymentRepository
+	promotionRepository    *repository.PromotionRepository
+	loyaltyRepository      *repository.LoyaltyRepository
+	reviewRepository       *repository.ReviewRepository
+	favoriteRepository     *repository.FavoriteRepository
+	subscriptionRepository *repository.SubscriptionRepository
+	notificationRepository *repository.NotificationRepository
+	userTokenRepository    *repository.UserTokenRepository
+	loginAttemptRepository *repository.LoginAttemptRepository
+	loginAuditRepository   *repository.LoginAuditRepository
+	twoFactorRepository    *repository.TwoFactorRepository
 }
 
 func New(config *Config) *Store {
@@ -72,3 +89,122 @@ func (s *Store) CatalogRepository() *repository.CatalogRepository {
 	}
 	return s.catalogRepository
 }
+
+func (s *Store) CartRepository() *repository.CartRepository {
+	if s.cartRepository == nil {
+		s.cartRepository = repository.NewCartRepository(s.db)
+	}
+	return s.cartRepository
+}
+
+func (s *Store) OrderRepository() *repository.OrderRepository {
+	if s.orderRepository == nil {
+		s.orderRepository = repository.NewOrderRepository(s.db)
+	}
+	return s.orderRepository
+}
+
+func (s *Store) SessionRepository() *repository.SessionRepository {
+	if s.sessionRepository == nil {
+		s.sessionRepository = repository.NewSessionRepository(s.db)
+	}
+	return s.sessionRepository
+}
+
+func (s *Store) DeliveryRepository() *repository.DeliveryRepository {
+	if s.deliveryRepository == nil {
+		s.deliveryRepository = repository.NewDeliveryRepository(s.db)
+	}
+	return s.deliveryRepository
+}
+
+func (s *Store) DispatchRepository() *repository.DispatchRepository {
+	if s.dispatchRepository == nil {
+		s.dispatchRepository = repository.NewDispatchRepository(s.db)
+	}
+	return s.dispatchRepository
+}
+
+func (s *Store) PickingRepository() *repository.PickingRepository {
+	if s.pickingRepository == nil {
+		s.pickingRepository = repository.NewPickingRepository(s.db)
+	}
+	return s.pickingRepository
+}
+
+func (s *Store) PaymentRepository() *repository.PaymentRepository {
+	if s.paymentRepository == nil {
+		s.paymentRepository = repository.NewPaymentRepository(s.db)
+	}
+	return s.paymentRepository
+}
+
+func (s *Store) PromotionRepository() *repository.PromotionRepository {
+	if s.promotionRepository == nil {
+		s.promotionRepository = repository.NewPromotionRepository(s.db)
+	}
+	return s.promotionRepository
+}
+
+func (s *Store) LoyaltyRepository() *repository.LoyaltyRepository {
+	if s.loyaltyRepository == nil {
+		s.loyaltyRepository = repository.NewLoyaltyRepository(s.db)
+	}
+	return s.loyaltyRepository
+}
+
+func (s *Store) ReviewRepository() *repository.ReviewRepository {
+	if s.reviewRepository == nil {
+		s.reviewRepository = repository.NewReviewRepository(s.db)
+	}
+	return s.reviewRepository
+}
+
+func (s *Store) FavoriteRepository() *repository.FavoriteRepository {
+	if s.favoriteRepository == nil {
+		s.favoriteRepository = repository.NewFavoriteRepository(s.db)
+	}
+	return s.favoriteRepository
+}
+
+func (s *Store) SubscriptionRepository() *repository.SubscriptionRepository {
+	if s.subscriptionRepository == nil {
+		s.subscriptionRepository = repository.NewSubscriptionRepository(s.db)
+	}
+	return s.subscriptionRepository
+}
+
+func (s *Store) NotificationRepository() *repository.NotificationRepository {
+	if s.notificationRepository == nil {
+		s.notificationRepository = repository.NewNotificationRepository(s.db)
+	}
+	return s.notificationRepository
+}
+
+func (s *Store) UserTokenRepository() *repository.UserTokenRepository {
+	if s.userTokenRepository == nil {
+		s.userTokenRepository = repository.NewUserTokenRepository(s.db)
+	}
+	return s.userTokenRepository
+}
+
+func (s *Store) LoginAttemptRepository() *repository.LoginAttemptRepository {
+	if s.loginAttemptRepository == nil {
+		s.loginAttemptRepository = repository.NewLoginAttemptRepository(s.db)
+	}
+	return s.loginAttemptRepository
+}
+
+func (s *Store) LoginAuditRepository() *repository.LoginAuditRepository {
+	if s.loginAuditRepository == nil {
+		s.loginAuditRepository = repository.NewLoginAuditRepository(s.db)
+	}
+	return s.loginAuditRepository
+}
+
+func (s *Store) TwoFactorRepository() *repository.TwoFactorRepository {
+	if s.twoFactorRepository == nil {
+		s.twoFactorRepository = repository.NewTwoFactorRepository(s.db)
+	}
+	return s.twoFactorRepository
+}
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS public.idx_catalogs_search_vector;
+ALTER TABLE public.catalogs DROP COLUMN IF EXISTS search_vector;
@@ -0,0 +1,10 @@
+-- ========================================
+-- Полнотекстовый поиск по каталогу
+-- ========================================
+ALTER TABLE public.catalogs
+    ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('russian', coalesce(name, '')), 'A') ||
+        setweight(to_tsvector('russian', coalesce(description, '')), 'B')
+    ) STORED;
+
+CREATE INDEX idx_catalogs_search_vector ON public.catalogs USING GIN (search_vector);
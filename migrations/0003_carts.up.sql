@@ -0,0 +1,36 @@
+-- ========================================
+-- Корзины пользователей
+-- ========================================
+CREATE TABLE public.carts
+(
+    id BIGSERIAL PRIMARY KEY,
+    -- Корзина авторизованного пользователя
+    user_id BIGINT UNIQUE,
+    -- Корзина анонимного пользователя, сливается с корзиной пользователя при логине
+    token UUID UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_user
+        FOREIGN KEY (user_id)
+            REFERENCES users(id)
+            ON DELETE CASCADE,
+    CONSTRAINT chk_cart_owner CHECK (user_id IS NOT NULL OR token IS NOT NULL)
+);
+
+CREATE TABLE public.cart_items
+(
+    cart_id BIGINT NOT NULL,
+    catalog_id BIGINT NOT NULL,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (cart_id, catalog_id),
+    CONSTRAINT fk_cart
+        FOREIGN KEY (cart_id)
+            REFERENCES carts(id)
+            ON DELETE CASCADE,
+    CONSTRAINT fk_catalog
+        FOREIGN KEY (catalog_id)
+            REFERENCES catalogs(id)
+            ON DELETE CASCADE
+);
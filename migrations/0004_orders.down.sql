@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS public.order_items;
+DROP TABLE IF EXISTS public.orders;
@@ -0,0 +1,59 @@
+-- ========================================
+-- Заказы
+-- ========================================
+CREATE TABLE public.orders
+(
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    status VARCHAR(32) NOT NULL DEFAULT 'created',
+
+    -- Суммы фиксируются на момент оформления
+    subtotal DECIMAL(10,2) NOT NULL,
+    discount DECIMAL(10,2) NOT NULL DEFAULT 0.00,
+    total DECIMAL(10,2) NOT NULL,
+    total_weight DECIMAL(10,2) NOT NULL DEFAULT 0.00,
+
+    -- Адрес доставки копируется из профиля пользователя
+    apartment VARCHAR(50) NOT NULL DEFAULT '',
+    house VARCHAR(50) NOT NULL,
+    street VARCHAR(255) NOT NULL,
+    city VARCHAR(100) NOT NULL,
+    region VARCHAR(100) NOT NULL DEFAULT '',
+
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_user
+        FOREIGN KEY (user_id)
+            REFERENCES users(id)
+            ON DELETE RESTRICT
+);
+
+CREATE INDEX idx_orders_user_id ON public.orders (user_id);
+CREATE INDEX idx_orders_status ON public.orders (status);
+
+CREATE TABLE public.order_items
+(
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL,
+    catalog_id BIGINT,
+
+    -- Снимок товара на момент оформления
+    name VARCHAR(50) NOT NULL,
+    sku VARCHAR(64),
+    price DECIMAL(8,2) NOT NULL,
+    discount_percent DECIMAL(5,2) NOT NULL DEFAULT 0.00,
+    unit_price DECIMAL(8,2) NOT NULL,
+    weight DECIMAL(8,2) NOT NULL,
+    quantity INT NOT NULL CHECK (quantity > 0),
+
+    CONSTRAINT fk_order
+        FOREIGN KEY (order_id)
+            REFERENCES orders(id)
+            ON DELETE CASCADE,
+    CONSTRAINT fk_catalog
+        FOREIGN KEY (catalog_id)
+            REFERENCES catalogs(id)
+            ON DELETE SET NULL
+);
+
+CREATE INDEX idx_order_items_order_id ON public.order_items (order_id);
@@ -0,0 +1,27 @@
+-- ========================================
+-- История смены статусов заказа
+-- ========================================
+CREATE TABLE public.order_status_history
+(
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL,
+    from_status VARCHAR(32),
+    to_status VARCHAR(32) NOT NULL,
+    changed_by BIGINT,
+    comment TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_order
+        FOREIGN KEY (order_id)
+            REFERENCES orders(id)
+            ON DELETE CASCADE,
+    CONSTRAINT fk_changed_by
+        FOREIGN KEY (changed_by)
+            REFERENCES users(id)
+            ON DELETE SET NULL
+);
+
+CREATE INDEX idx_order_status_history_order_id ON public.order_status_history (order_id, id);
+
+-- Начальная запись для уже созданных заказов
+INSERT INTO public.order_status_history (order_id, from_status, to_status, changed_by, created_at)
+SELECT id, NULL, status, user_id, created_at FROM public.orders;
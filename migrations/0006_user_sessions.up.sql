@@ -0,0 +1,37 @@
+-- ========================================
+-- Сессии пользователей и refresh токены
+-- ========================================
+CREATE TABLE public.user_sessions
+(
+    -- Совпадает с jti в access токене
+    id UUID PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    user_agent VARCHAR(255) NOT NULL DEFAULT '',
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    last_used_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_user
+        FOREIGN KEY (user_id)
+            REFERENCES users(id)
+            ON DELETE CASCADE
+);
+
+CREATE INDEX idx_user_sessions_user_id ON public.user_sessions (user_id);
+
+-- Все выданные в рамках сессии refresh токены. Хранится только sha256 хеш.
+-- Повторное использование уже ротированного токена отзывает всю сессию
+CREATE TABLE public.user_refresh_tokens
+(
+    token_hash VARCHAR(64) PRIMARY KEY,
+    session_id UUID NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_session
+        FOREIGN KEY (session_id)
+            REFERENCES user_sessions(id)
+            ON DELETE CASCADE
+);
+
+CREATE INDEX idx_user_refresh_tokens_session_id ON public.user_refresh_tokens (session_id);
@@ -0,0 +1,4 @@
+ALTER TABLE public.orders DROP COLUMN IF EXISTS delivery_slot_id;
+DROP TABLE IF EXISTS public.delivery_slots;
+DROP TABLE IF EXISTS public.delivery_slot_templates;
+DROP TABLE IF EXISTS public.delivery_zones;
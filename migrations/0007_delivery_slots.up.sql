@@ -0,0 +1,67 @@
+-- ========================================
+-- Зоны доставки
+-- ========================================
+CREATE TABLE public.delivery_zones
+(
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- ========================================
+-- Шаблоны слотов: по ним на каждый день недели генерируются слоты зоны
+-- ========================================
+CREATE TABLE public.delivery_slot_templates
+(
+    id BIGSERIAL PRIMARY KEY,
+    zone_id BIGINT NOT NULL,
+    -- 0 - воскресенье, как в EXTRACT(DOW)
+    weekday SMALLINT NOT NULL CHECK (weekday BETWEEN 0 AND 6),
+    start_time TIME NOT NULL,
+    end_time TIME NOT NULL,
+    capacity INT NOT NULL CHECK (capacity > 0),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_template_time CHECK (start_time < end_time),
+    CONSTRAINT uq_template UNIQUE (zone_id, weekday, start_time),
+    CONSTRAINT fk_zone
+        FOREIGN KEY (zone_id)
+            REFERENCES delivery_zones(id)
+            ON DELETE CASCADE
+);
+
+-- ========================================
+-- Слоты конкретного дня. Создаются из шаблонов при первом запросе дня,
+-- дальнейшие изменения шаблона на уже созданные слоты не влияют
+-- ========================================
+CREATE TABLE public.delivery_slots
+(
+    id BIGSERIAL PRIMARY KEY,
+    zone_id BIGINT NOT NULL,
+    template_id BIGINT,
+    date DATE NOT NULL,
+    start_time TIME NOT NULL,
+    end_time TIME NOT NULL,
+    capacity INT NOT NULL CHECK (capacity > 0),
+    -- Защита от овербукинга на уровне БД
+    reserved INT NOT NULL DEFAULT 0 CHECK (reserved >= 0 AND reserved <= capacity),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT uq_slot UNIQUE (zone_id, date, start_time),
+    CONSTRAINT fk_zone
+        FOREIGN KEY (zone_id)
+            REFERENCES delivery_zones(id)
+            ON DELETE CASCADE,
+    CONSTRAINT fk_template
+        FOREIGN KEY (template_id)
+            REFERENCES delivery_slot_templates(id)
+            ON DELETE SET NULL
+);
+
+CREATE INDEX idx_delivery_slots_date ON public.delivery_slots (date);
+
+ALTER TABLE public.orders
+    ADD COLUMN delivery_slot_id BIGINT
+        CONSTRAINT fk_delivery_slot
+            REFERENCES delivery_slots(id)
+            ON DELETE SET NULL;
@@ -0,0 +1,17 @@
+ALTER TABLE public.orders
+    DROP COLUMN IF EXISTS delivery_fee,
+    DROP COLUMN IF EXISTS delivery_zone_id,
+    DROP COLUMN IF EXISTS longitude,
+    DROP COLUMN IF EXISTS latitude;
+
+ALTER TABLE public.users
+    DROP COLUMN IF EXISTS longitude,
+    DROP COLUMN IF EXISTS latitude;
+
+ALTER TABLE public.delivery_zones
+    DROP COLUMN IF EXISTS eta_minutes,
+    DROP COLUMN IF EXISTS per_kg_fee,
+    DROP COLUMN IF EXISTS free_delivery_threshold,
+    DROP COLUMN IF EXISTS base_fee,
+    DROP COLUMN IF EXISTS min_order_amount,
+    DROP COLUMN IF EXISTS polygon;
@@ -0,0 +1,29 @@
+-- ========================================
+-- Границы и тарифы зон доставки
+-- ========================================
+ALTER TABLE public.delivery_zones
+    -- GeoJSON геометрия Polygon или MultiPolygon, координаты [lon, lat]
+    ADD COLUMN polygon JSONB,
+    ADD COLUMN min_order_amount DECIMAL(10,2) NOT NULL DEFAULT 0.00,
+    ADD COLUMN base_fee DECIMAL(10,2) NOT NULL DEFAULT 0.00,
+    -- 0 - бесплатной доставки нет
+    ADD COLUMN free_delivery_threshold DECIMAL(10,2) NOT NULL DEFAULT 0.00,
+    -- Доплата за каждый килограмм веса заказа (catalogs.weight в граммах)
+    ADD COLUMN per_kg_fee DECIMAL(10,2) NOT NULL DEFAULT 0.00,
+    ADD COLUMN eta_minutes INT NOT NULL DEFAULT 60 CHECK (eta_minutes > 0);
+
+-- ========================================
+-- Координаты адреса
+-- ========================================
+ALTER TABLE public.users
+    ADD COLUMN latitude DOUBLE PRECISION,
+    ADD COLUMN longitude DOUBLE PRECISION;
+
+ALTER TABLE public.orders
+    ADD COLUMN latitude DOUBLE PRECISION,
+    ADD COLUMN longitude DOUBLE PRECISION,
+    ADD COLUMN delivery_zone_id BIGINT
+        CONSTRAINT fk_delivery_zone
+            REFERENCES delivery_zones(id)
+            ON DELETE SET NULL,
+    ADD COLUMN delivery_fee DECIMAL(10,2) NOT NULL DEFAULT 0.00;
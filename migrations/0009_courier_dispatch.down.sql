@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS public.delivery_route_stops;
+DROP TABLE IF EXISTS public.delivery_routes;
+DROP TABLE IF EXISTS public.courier_profiles;
@@ -0,0 +1,67 @@
+-- ========================================
+-- Вместимость курьеров
+-- ========================================
+CREATE TABLE public.courier_profiles
+(
+    user_id BIGINT PRIMARY KEY,
+    max_orders INT NOT NULL DEFAULT 10 CHECK (max_orders > 0),
+    -- Килограммы, вес заказов (orders.total_weight) хранится в граммах
+    max_weight DECIMAL(8,2) NOT NULL DEFAULT 30.00 CHECK (max_weight > 0),
+    is_available BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_user
+        FOREIGN KEY (user_id)
+            REFERENCES users(id)
+            ON DELETE CASCADE
+);
+
+-- ========================================
+-- Маршруты доставки. Маршрут активен, пока в нем есть заказы в статусе out_for_delivery
+-- ========================================
+CREATE TABLE public.delivery_routes
+(
+    id BIGSERIAL PRIMARY KEY,
+    courier_id BIGINT NOT NULL,
+    zone_id BIGINT,
+    delivery_slot_id BIGINT,
+    total_weight DECIMAL(10,2) NOT NULL DEFAULT 0.00,
+    -- Длина маршрута от склада в метрах
+    distance DOUBLE PRECISION NOT NULL DEFAULT 0,
+    created_by BIGINT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_courier
+        FOREIGN KEY (courier_id)
+            REFERENCES users(id)
+            ON DELETE RESTRICT,
+    CONSTRAINT fk_zone
+        FOREIGN KEY (zone_id)
+            REFERENCES delivery_zones(id)
+            ON DELETE SET NULL,
+    CONSTRAINT fk_delivery_slot
+        FOREIGN KEY (delivery_slot_id)
+            REFERENCES delivery_slots(id)
+            ON DELETE SET NULL,
+    CONSTRAINT fk_created_by
+        FOREIGN KEY (created_by)
+            REFERENCES users(id)
+            ON DELETE SET NULL
+);
+
+CREATE INDEX idx_delivery_routes_courier_id ON public.delivery_routes (courier_id);
+
+CREATE TABLE public.delivery_route_stops
+(
+    route_id BIGINT NOT NULL,
+    position INT NOT NULL,
+    -- Заказ может попасть только в один маршрут
+    order_id BIGINT NOT NULL UNIQUE,
+    PRIMARY KEY (route_id, position),
+    CONSTRAINT fk_route
+        FOREIGN KEY (route_id)
+            REFERENCES delivery_routes(id)
+            ON DELETE CASCADE,
+    CONSTRAINT fk_order
+        FOREIGN KEY (order_id)
+            REFERENCES orders(id)
+            ON DELETE CASCADE
+);
@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS public.order_pick_events;
+
+DELETE FROM public.order_items WHERE substitutes_item_id IS NOT NULL;
+DELETE FROM public.order_items WHERE quantity = 0;
+ALTER TABLE public.order_items
+    DROP COLUMN IF EXISTS substitutes_item_id,
+    DROP COLUMN IF EXISTS ordered_quantity,
+    DROP COLUMN IF EXISTS pick_status,
+    DROP CONSTRAINT IF EXISTS order_items_quantity_check;
+ALTER TABLE public.order_items ADD CONSTRAINT order_items_quantity_check CHECK (quantity > 0);
+
+DROP INDEX IF EXISTS idx_orders_picker_id;
+ALTER TABLE public.orders DROP COLUMN IF EXISTS picker_id;
@@ -0,0 +1,66 @@
+-- ========================================
+-- Сборка заказов. Строка сборки - позиция заказа, quantity уменьшается при недовложении
+-- ========================================
+ALTER TABLE public.orders
+    ADD COLUMN picker_id BIGINT,
+    ADD CONSTRAINT fk_picker
+        FOREIGN KEY (picker_id)
+            REFERENCES users(id)
+            ON DELETE SET NULL;
+
+-- Отклоненная покупателем позиция остается в заказе с нулевым количеством
+ALTER TABLE public.order_items DROP CONSTRAINT order_items_quantity_check;
+ALTER TABLE public.order_items
+    ADD CONSTRAINT order_items_quantity_check CHECK (quantity >= 0),
+    -- pending, picked, short, substituted
+    ADD COLUMN pick_status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    -- Количество на момент оформления
+    ADD COLUMN ordered_quantity INT,
+    -- Позиция-замена ссылается на замененную позицию
+    ADD COLUMN substitutes_item_id BIGINT,
+    ADD CONSTRAINT fk_substitutes_item
+        FOREIGN KEY (substitutes_item_id)
+            REFERENCES order_items(id)
+            ON DELETE CASCADE;
+
+UPDATE public.order_items SET ordered_quantity = quantity;
+ALTER TABLE public.order_items ALTER COLUMN ordered_quantity SET NOT NULL;
+
+-- ========================================
+-- События сборки, которые покупатель подтверждает или отклоняет
+-- ========================================
+CREATE TABLE public.order_pick_events
+(
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL,
+    -- short_pick, substitution
+    type VARCHAR(16) NOT NULL,
+    order_item_id BIGINT NOT NULL,
+    substitute_item_id BIGINT,
+    ordered_quantity INT NOT NULL,
+    picked_quantity INT NOT NULL,
+    -- pending, approved, rejected
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    created_by BIGINT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    resolved_at TIMESTAMP,
+    CONSTRAINT fk_order
+        FOREIGN KEY (order_id)
+            REFERENCES orders(id)
+            ON DELETE CASCADE,
+    CONSTRAINT fk_order_item
+        FOREIGN KEY (order_item_id)
+            REFERENCES order_items(id)
+            ON DELETE CASCADE,
+    CONSTRAINT fk_substitute_item
+        FOREIGN KEY (substitute_item_id)
+            REFERENCES order_items(id)
+            ON DELETE CASCADE,
+    CONSTRAINT fk_created_by
+        FOREIGN KEY (created_by)
+            REFERENCES users(id)
+            ON DELETE SET NULL
+);
+
+CREATE INDEX idx_order_pick_events_order_id ON public.order_pick_events (order_id);
+CREATE INDEX idx_orders_picker_id ON public.orders (picker_id);
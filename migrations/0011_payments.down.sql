@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS public.payment_webhook_events;
+DROP TABLE IF EXISTS public.payments;
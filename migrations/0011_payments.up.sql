@@ -0,0 +1,38 @@
+-- ========================================
+-- Платежи заказов. Суммы хранятся в минимальных единицах валюты (копейках)
+-- ========================================
+CREATE TABLE public.payments
+(
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL,
+    provider VARCHAR(32) NOT NULL,
+    intent_id VARCHAR(128) NOT NULL UNIQUE,
+    client_secret VARCHAR(255) NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    authorized_amount BIGINT NOT NULL DEFAULT 0,
+    captured_amount BIGINT NOT NULL DEFAULT 0,
+    refunded_amount BIGINT NOT NULL DEFAULT 0,
+    -- pending, authorized, failed, captured, refunded, canceled
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_order
+        FOREIGN KEY (order_id)
+            REFERENCES orders(id)
+            ON DELETE CASCADE
+);
+
+CREATE INDEX idx_payments_order_id ON public.payments (order_id);
+
+-- ========================================
+-- Принятые вебхуки шлюза. Повторная доставка того же события не обрабатывается
+-- ========================================
+CREATE TABLE public.payment_webhook_events
+(
+    event_id VARCHAR(128) PRIMARY KEY,
+    type VARCHAR(64) NOT NULL,
+    intent_id VARCHAR(128) NOT NULL,
+    payload JSONB NOT NULL,
+    received_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
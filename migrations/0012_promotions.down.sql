@@ -0,0 +1,8 @@
+ALTER TABLE public.orders
+    DROP COLUMN IF EXISTS promo_discount,
+    DROP COLUMN IF EXISTS promo_code;
+
+DROP TABLE IF EXISTS public.promotion_redemptions;
+DROP TABLE IF EXISTS public.promotion_tags;
+DROP TABLE IF EXISTS public.promotion_categories;
+DROP TABLE IF EXISTS public.promotions;
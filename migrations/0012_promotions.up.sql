@@ -0,0 +1,94 @@
+-- ========================================
+-- Акции и промокоды. Акция без кода применяется автоматически
+-- ========================================
+CREATE TABLE public.promotions
+(
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    -- Хранится в верхнем регистре, NULL - автоматическое правило
+    code VARCHAR(32) UNIQUE,
+    -- percent, fixed, buy_x_get_y, free_delivery
+    type VARCHAR(16) NOT NULL,
+    -- Процент для percent, сумма для fixed
+    value DECIMAL(10,2) NOT NULL DEFAULT 0.00,
+    -- Для buy_x_get_y: при покупке buy_quantity + free_quantity одного товара free_quantity бесплатно
+    buy_quantity INT NOT NULL DEFAULT 0,
+    free_quantity INT NOT NULL DEFAULT 0,
+    min_order_amount DECIMAL(10,2) NOT NULL DEFAULT 0.00,
+    -- NULL - без ограничений
+    usage_limit INT CHECK (usage_limit > 0),
+    per_user_limit INT CHECK (per_user_limit > 0),
+    used_count INT NOT NULL DEFAULT 0,
+    starts_at TIMESTAMP,
+    ends_at TIMESTAMP,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (starts_at IS NULL OR ends_at IS NULL OR starts_at < ends_at)
+);
+
+-- Ограничения акции: товар подходит, если его категория или один из тегов есть в списках.
+-- Без ограничений акция действует на все товары
+CREATE TABLE public.promotion_categories
+(
+    promotion_id BIGINT NOT NULL,
+    category_id BIGINT NOT NULL,
+    PRIMARY KEY (promotion_id, category_id),
+    CONSTRAINT fk_promotion
+        FOREIGN KEY (promotion_id)
+            REFERENCES promotions(id)
+            ON DELETE CASCADE,
+    CONSTRAINT fk_category
+        FOREIGN KEY (category_id)
+            REFERENCES categories(id)
+            ON DELETE CASCADE
+);
+
+CREATE TABLE public.promotion_tags
+(
+    promotion_id BIGINT NOT NULL,
+    tag_id BIGINT NOT NULL,
+    PRIMARY KEY (promotion_id, tag_id),
+    CONSTRAINT fk_promotion
+        FOREIGN KEY (promotion_id)
+            REFERENCES promotions(id)
+            ON DELETE CASCADE,
+    CONSTRAINT fk_tag
+        FOREIGN KEY (tag_id)
+            REFERENCES tags(id)
+            ON DELETE CASCADE
+);
+
+-- ========================================
+-- Применения акций в заказах. Отмена заказа удаляет запись и возвращает использование
+-- ========================================
+CREATE TABLE public.promotion_redemptions
+(
+    id BIGSERIAL PRIMARY KEY,
+    promotion_id BIGINT NOT NULL,
+    order_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    discount DECIMAL(10,2) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (promotion_id, order_id),
+    -- Использованную акцию нельзя удалить, только отключить
+    CONSTRAINT fk_promotion
+        FOREIGN KEY (promotion_id)
+            REFERENCES promotions(id),
+    CONSTRAINT fk_order
+        FOREIGN KEY (order_id)
+            REFERENCES orders(id)
+            ON DELETE CASCADE,
+    CONSTRAINT fk_user
+        FOREIGN KEY (user_id)
+            REFERENCES users(id)
+            ON DELETE CASCADE
+);
+
+CREATE INDEX idx_promotion_redemptions_user ON public.promotion_redemptions (promotion_id, user_id);
+CREATE INDEX idx_promotion_redemptions_order_id ON public.promotion_redemptions (order_id);
+
+-- Скидка акций на товары фиксируется при оформлении и не пересчитывается при сборке
+ALTER TABLE public.orders
+    ADD COLUMN promo_code VARCHAR(32),
+    ADD COLUMN promo_discount DECIMAL(10,2) NOT NULL DEFAULT 0.00;
@@ -0,0 +1,6 @@
+ALTER TABLE public.orders
+    DROP COLUMN IF EXISTS loyalty_earn_rate,
+    DROP COLUMN IF EXISTS loyalty_discount,
+    DROP COLUMN IF EXISTS loyalty_points;
+
+DROP TABLE IF EXISTS public.loyalty_ledger;
@@ -0,0 +1,36 @@
+-- ========================================
+-- Бонусные баллы. Двойная запись: каждая проводка - две строки с общим entry_id,
+-- сумма points которых равна 0. Баланс покупателя - сумма по счету customer, отдельно не хранится
+-- ========================================
+CREATE TABLE public.loyalty_ledger
+(
+    id BIGSERIAL PRIMARY KEY,
+    entry_id UUID NOT NULL,
+    -- customer - баллы покупателя, issued - начисленные магазином, redeemed - потраченные на заказы
+    account VARCHAR(16) NOT NULL,
+    user_id BIGINT NOT NULL,
+    order_id BIGINT,
+    -- accrual, redemption, accrual_reversal, redemption_reversal
+    type VARCHAR(32) NOT NULL,
+    points BIGINT NOT NULL CHECK (points <> 0),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_user
+        FOREIGN KEY (user_id)
+            REFERENCES users(id)
+            ON DELETE CASCADE,
+    CONSTRAINT fk_order
+        FOREIGN KEY (order_id)
+            REFERENCES orders(id)
+            ON DELETE CASCADE
+);
+
+-- Не больше одной проводки каждого типа на заказ: повторная смена статуса не начислит баллы дважды
+CREATE UNIQUE INDEX idx_loyalty_ledger_order_type ON public.loyalty_ledger (order_id, type, account);
+CREATE INDEX idx_loyalty_ledger_user ON public.loyalty_ledger (user_id, account, id);
+CREATE INDEX idx_loyalty_ledger_entry ON public.loyalty_ledger (entry_id);
+
+-- Списанные при оформлении баллы и ставка начисления на момент покупки
+ALTER TABLE public.orders
+    ADD COLUMN loyalty_points BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN loyalty_discount DECIMAL(10,2) NOT NULL DEFAULT 0.00,
+    ADD COLUMN loyalty_earn_rate DECIMAL(8,4) NOT NULL DEFAULT 0;
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS public.review_photos;
+DROP TABLE IF EXISTS public.reviews;
+
+ALTER TABLE public.catalogs
+    ALTER COLUMN rating DROP NOT NULL,
+    ALTER COLUMN reviews_count DROP NOT NULL;
@@ -0,0 +1,54 @@
+-- Рейтинг пересчитывается из одобренных отзывов, NULL больше не нужен
+UPDATE public.catalogs SET rating = 0 WHERE rating IS NULL;
+UPDATE public.catalogs SET reviews_count = 0 WHERE reviews_count IS NULL;
+
+ALTER TABLE public.catalogs
+    ALTER COLUMN rating SET NOT NULL,
+    ALTER COLUMN reviews_count SET NOT NULL;
+
+-- ========================================
+-- Отзывы покупателей. В рейтинг товара попадают только одобренные модератором
+-- ========================================
+CREATE TABLE public.reviews
+(
+    id BIGSERIAL PRIMARY KEY,
+    catalog_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    score SMALLINT NOT NULL CHECK (score BETWEEN 1 AND 5),
+    text TEXT NOT NULL DEFAULT '',
+    -- pending, approved, rejected
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    moderated_by BIGINT,
+    moderation_comment TEXT NOT NULL DEFAULT '',
+    moderated_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    -- Один отзыв покупателя на товар
+    UNIQUE (catalog_id, user_id),
+    CONSTRAINT fk_catalog
+        FOREIGN KEY (catalog_id)
+            REFERENCES catalogs(id)
+            ON DELETE CASCADE,
+    CONSTRAINT fk_user
+        FOREIGN KEY (user_id)
+            REFERENCES users(id)
+            ON DELETE CASCADE,
+    CONSTRAINT fk_moderator
+        FOREIGN KEY (moderated_by)
+            REFERENCES users(id)
+            ON DELETE SET NULL
+);
+
+CREATE INDEX idx_reviews_catalog_status ON public.reviews (catalog_id, status, id);
+CREATE INDEX idx_reviews_pending ON public.reviews (id) WHERE status = 'pending';
+
+CREATE TABLE public.review_photos
+(
+    review_id BIGINT NOT NULL,
+    position SMALLINT NOT NULL,
+    image_url TEXT NOT NULL,
+    PRIMARY KEY (review_id, position),
+    CONSTRAINT fk_review
+        FOREIGN KEY (review_id)
+            REFERENCES reviews(id)
+            ON DELETE CASCADE
+);
@@ -0,0 +1,20 @@
+-- ========================================
+-- Избранные товары покупателя
+-- ========================================
+CREATE TABLE public.user_favorites
+(
+    user_id BIGINT NOT NULL,
+    catalog_id BIGINT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, catalog_id),
+    CONSTRAINT fk_user
+        FOREIGN KEY (user_id)
+            REFERENCES users(id)
+            ON DELETE CASCADE,
+    CONSTRAINT fk_catalog
+        FOREIGN KEY (catalog_id)
+            REFERENCES catalogs(id)
+            ON DELETE CASCADE
+);
+
+CREATE INDEX idx_user_favorites_list ON public.user_favorites (user_id, created_at DESC);
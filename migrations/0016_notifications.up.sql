@@ -0,0 +1,47 @@
+-- ========================================
+-- Подписки покупателей на поступление товара и снижение цены
+-- ========================================
+CREATE TABLE public.catalog_subscriptions
+(
+    user_id BIGINT NOT NULL,
+    catalog_id BIGINT NOT NULL,
+    -- back_in_stock - одноразовая, удаляется после уведомления; price_drop - постоянная
+    type VARCHAR(16) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, catalog_id, type),
+    CONSTRAINT fk_user
+        FOREIGN KEY (user_id)
+            REFERENCES users(id)
+            ON DELETE CASCADE,
+    CONSTRAINT fk_catalog
+        FOREIGN KEY (catalog_id)
+            REFERENCES catalogs(id)
+            ON DELETE CASCADE
+);
+
+CREATE INDEX idx_catalog_subscriptions_catalog ON public.catalog_subscriptions (catalog_id, type);
+
+-- ========================================
+-- Очередь уведомлений. Пишется в транзакции изменения товара, доставляется фоновым обработчиком
+-- ========================================
+CREATE TABLE public.notifications
+(
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    type VARCHAR(32) NOT NULL,
+    subject TEXT NOT NULL,
+    text TEXT NOT NULL,
+    -- pending, sent, failed
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    -- Обработчик сдвигает время на период аренды, чтобы упавший процесс не потерял уведомление
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    sent_at TIMESTAMP,
+    CONSTRAINT fk_user
+        FOREIGN KEY (user_id)
+            REFERENCES users(id)
+            ON DELETE CASCADE
+);
+
+CREATE INDEX idx_notifications_pending ON public.notifications (next_attempt_at) WHERE status = 'pending';
@@ -0,0 +1,9 @@
+ALTER TABLE public.notifications
+    ADD COLUMN subject TEXT NOT NULL DEFAULT '',
+    ADD COLUMN text TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE public.notifications
+    DROP COLUMN IF EXISTS attempts,
+    DROP COLUMN IF EXISTS data;
+
+ALTER TABLE public.users DROP COLUMN IF EXISTS language;
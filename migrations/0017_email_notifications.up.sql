@@ -0,0 +1,17 @@
+-- Язык писем пользователю
+ALTER TABLE public.users ADD COLUMN language VARCHAR(2) NOT NULL DEFAULT 'ru';
+
+-- ========================================
+-- Текст уведомления собирается из шаблона при отправке на языке получателя,
+-- неудачная отправка повторяется с растущей паузой
+-- ========================================
+ALTER TABLE public.notifications
+    ADD COLUMN data JSONB NOT NULL DEFAULT '{}',
+    ADD COLUMN attempts INT NOT NULL DEFAULT 0;
+
+-- Для уведомлений, поставленных до шаблонов, нет данных, их уже не отправить
+UPDATE public.notifications SET status = 'failed', error = 'created before templates' WHERE status = 'pending';
+
+ALTER TABLE public.notifications
+    DROP COLUMN subject,
+    DROP COLUMN text;
@@ -0,0 +1,26 @@
+ALTER TABLE public.users ADD COLUMN email_verified_at TIMESTAMP;
+
+-- Уже зарегистрированные пользователи считаются подтвержденными,
+-- иначе включение проверки заблокирует их без предупреждения
+UPDATE public.users SET email_verified_at = created_at;
+
+-- ========================================
+-- Одноразовые токены из писем: подтверждение email и сброс пароля.
+-- Хранится только sha256 хеш, использованный или просроченный токен недействителен
+-- ========================================
+CREATE TABLE public.user_tokens
+(
+    token_hash VARCHAR(64) PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    -- verify_email, password_reset
+    purpose VARCHAR(32) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_user
+        FOREIGN KEY (user_id)
+            REFERENCES users(id)
+            ON DELETE CASCADE
+);
+
+CREATE INDEX idx_user_tokens_user_id ON public.user_tokens (user_id, purpose) WHERE used_at IS NULL;
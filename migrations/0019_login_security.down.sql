@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS public.login_audit;
+DROP TABLE IF EXISTS public.login_attempts;
@@ -0,0 +1,35 @@
+-- ========================================
+-- Счетчики неудачных входов для ограничения попыток, общие для всех экземпляров.
+-- Ключ - email или IP, записи старше окна удаляются при следующей попытке по ключу
+-- ========================================
+CREATE TABLE public.login_attempts
+(
+    id BIGSERIAL PRIMARY KEY,
+    key VARCHAR(320) NOT NULL,
+    attempted_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_login_attempts_key ON public.login_attempts (key, attempted_at);
+
+-- ========================================
+-- Журнал неудачных входов для разбора администратором
+-- ========================================
+CREATE TABLE public.login_audit
+(
+    id BIGSERIAL PRIMARY KEY,
+    email VARCHAR(320) NOT NULL,
+    -- NULL, если такого пользователя нет
+    user_id BIGINT,
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    user_agent VARCHAR(255) NOT NULL DEFAULT '',
+    -- unknown_email, wrong_password, throttled
+    reason VARCHAR(32) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_user
+        FOREIGN KEY (user_id)
+            REFERENCES users(id)
+            ON DELETE SET NULL
+);
+
+CREATE INDEX idx_login_audit_email ON public.login_audit (email, created_at DESC);
+CREATE INDEX idx_login_audit_ip ON public.login_audit (ip, created_at DESC);
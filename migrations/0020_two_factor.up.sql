@@ -0,0 +1,35 @@
+-- ========================================
+-- Второй фактор входа: TOTP из приложения-аутентификатора.
+-- Секрет зашифрован ключом из конфигурации сервера. Пока enabled_at пустой,
+-- подключение не подтверждено кодом и при входе не спрашивается
+-- ========================================
+CREATE TABLE public.user_two_factor
+(
+    user_id BIGINT PRIMARY KEY,
+    secret TEXT NOT NULL,
+    enabled_at TIMESTAMP,
+    -- Последний принятый шаг TOTP, код того же или более раннего шага повторно не принимается
+    last_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_user
+        FOREIGN KEY (user_id)
+            REFERENCES users(id)
+            ON DELETE CASCADE
+);
+
+-- ========================================
+-- Одноразовые коды восстановления на случай потери телефона, хранится sha256 хеш
+-- ========================================
+CREATE TABLE public.user_recovery_codes
+(
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_user
+        FOREIGN KEY (user_id)
+            REFERENCES users(id)
+            ON DELETE CASCADE,
+    CONSTRAINT uq_user_recovery_code UNIQUE (user_id, code_hash)
+);
@@ -0,0 +1,3 @@
+COMMENT ON COLUMN public.courier_profiles.max_weight IS NULL;
+COMMENT ON COLUMN public.orders.total_weight IS NULL;
+COMMENT ON COLUMN public.catalogs.weight IS NULL;
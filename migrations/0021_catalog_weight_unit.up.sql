@@ -0,0 +1,6 @@
+-- ========================================
+-- Единицы веса, от которых зависят тарифы доставки и загрузка курьеров
+-- ========================================
+COMMENT ON COLUMN public.catalogs.weight IS 'Вес единицы товара в граммах';
+COMMENT ON COLUMN public.orders.total_weight IS 'Вес заказа в граммах';
+COMMENT ON COLUMN public.courier_profiles.max_weight IS 'Грузоподъемность курьера в килограммах';
@@ -1,12 +1,17 @@
 package customError
 
 const (
-	Error500           = "Something went wrong, try later..."
-	ErrorParse         = "Cant parse data, please check provided data"
-	ErrorWrongPayload  = "Check provided data"
-	ErrorNotFoundById  = "Nothing found, please check the provided id"
-	ErrorGetQueryParam = "Please check provided params"
-	ErrorAuthorize     = "Cannot authorize with this token. Please log in again."
+	Error500              = "Something went wrong, try later..."
+	ErrorParse            = "Cant parse data, please check provided data"
+	ErrorWrongPayload     = "Check provided data"
+	ErrorNotFoundById     = "Nothing found, please check the provided id"
+	ErrorGetQueryParam    = "Please check provided params"
+	ErrorAuthorize        = "Cannot authorize with this token. Please log in again."
+	ErrorEmailNotVerified = "Please confirm your email using the link from the registration email"
+	ErrorInvalidToken     = "The link is invalid or has expired"
+	ErrorInvalidCode      = "Invalid two-factor authentication code"
+	ErrorLoginExpired     = "Login session has expired, please sign in again"
+	ErrorTwoFactorNeeded  = "Two-factor authentication is required for your role, please sign in again"
 )
 
 type ServiceError struct {
@@ -0,0 +1,20 @@
+package events
+
+import "time"
+
+type Config struct {
+	// Сколько событий копится для медленного клиента, прежде чем его отключить
+	BufferSize       int `toml:"buffer_size"`
+	HeartbeatSeconds int `toml:"heartbeat_seconds"`
+}
+
+func NewConfig() *Config {
+	return &Config{
+		BufferSize:       16,
+		HeartbeatSeconds: 15,
+	}
+}
+
+func (c *Config) Heartbeat() time.Duration {
+	return time.Duration(c.HeartbeatSeconds) * time.Second
+}
@@ -0,0 +1,116 @@
+package events
+
+import "sync"
+
+// Событие для подписчиков темы. Id = 0 - событие без идентификатора, по нему нельзя продолжить поток
+type Event struct {
+	Id   int64
+	Type string
+	Data []byte
+}
+
+type Publisher interface {
+	Publish(topic string, event Event)
+}
+
+// In-process pub/sub. Публикация не блокируется: подписчик с переполненным буфером
+// отключается и должен переподключиться с Last-Event-ID
+type Hub struct {
+	mu         sync.Mutex
+	bufferSize int
+	topics     map[string]map[*Subscription]struct{}
+	closed     bool
+}
+
+type Subscription struct {
+	hub    *Hub
+	topic  string
+	events chan Event
+	once   sync.Once
+}
+
+func NewHub(config *Config) *Hub {
+	bufferSize := config.BufferSize
+	if bufferSize < 1 {
+		bufferSize = 1
+	}
+
+	return &Hub{
+		bufferSize: bufferSize,
+		topics:     make(map[string]map[*Subscription]struct{}),
+	}
+}
+
+func (h *Hub) Subscribe(topic string) *Subscription {
+	sub := &Subscription{hub: h, topic: topic, events: make(chan Event, h.bufferSize)}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		sub.closeChannel()
+		return sub
+	}
+
+	if h.topics[topic] == nil {
+		h.topics[topic] = make(map[*Subscription]struct{})
+	}
+	h.topics[topic][sub] = struct{}{}
+
+	return sub
+}
+
+func (h *Hub) Publish(topic string, event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.topics[topic] {
+		select {
+		case sub.events <- event:
+		default:
+			h.remove(sub)
+			sub.closeChannel()
+		}
+	}
+}
+
+// Отключает всех подписчиков, используется при остановке сервера
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for _, subs := range h.topics {
+		for sub := range subs {
+			sub.closeChannel()
+		}
+	}
+	h.topics = make(map[string]map[*Subscription]struct{})
+}
+
+func (h *Hub) remove(sub *Subscription) {
+	subs := h.topics[sub.topic]
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(h.topics, sub.topic)
+	}
+}
+
+// Канал закрывается при отписке, переполнении буфера или остановке хаба
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	s.hub.remove(s)
+	s.closeChannel()
+}
+
+func (s *Subscription) closeChannel() {
+	s.once.Do(func() {
+		close(s.events)
+	})
+}
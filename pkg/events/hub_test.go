@@ -0,0 +1,95 @@
+package events_test
+
+import (
+	"arabic/pkg/events"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHub_Publish(t *testing.T) {
+	hub := events.NewHub(&events.Config{BufferSize: 2})
+
+	order := hub.Subscribe("order:1")
+	other := hub.Subscribe("order:2")
+	defer other.Close()
+
+	hub.Publish("order:1", events.Event{Id: 1, Type: "status"})
+	hub.Publish("order:1", events.Event{Id: 2, Type: "status"})
+
+	assert.Equal(t, int64(1), (<-order.Events()).Id)
+	assert.Equal(t, int64(2), (<-order.Events()).Id)
+	assert.Len(t, other.Events(), 0)
+
+	order.Close()
+	_, ok := <-order.Events()
+	assert.False(t, ok)
+
+	// Публикация после отписки не паникует
+	hub.Publish("order:1", events.Event{Id: 3})
+}
+
+func TestHub_SlowSubscriberIsDropped(t *testing.T) {
+	hub := events.NewHub(&events.Config{BufferSize: 1})
+	sub := hub.Subscribe("order:1")
+
+	hub.Publish("order:1", events.Event{Id: 1})
+	hub.Publish("order:1", events.Event{Id: 2})
+
+	event, ok := <-sub.Events()
+	assert.True(t, ok)
+	assert.Equal(t, int64(1), event.Id)
+
+	_, ok = <-sub.Events()
+	assert.False(t, ok)
+
+	sub.Close()
+}
+
+func TestHub_Close(t *testing.T) {
+	hub := events.NewHub(events.NewConfig())
+	sub := hub.Subscribe("order:1")
+
+	hub.Close()
+	_, ok := <-sub.Events()
+	assert.False(t, ok)
+
+	late := hub.Subscribe("order:1")
+	_, ok = <-late.Events()
+	assert.False(t, ok)
+}
+
+func TestWriteEvent(t *testing.T) {
+	tests := []struct {
+		name   string
+		event  events.Event
+		expect string
+	}{
+		{
+			name:   "with id",
+			event:  events.Event{Id: 7, Type: "status", Data: []byte(`{"status":"packed"}`)},
+			expect: "id: 7\nevent: status\ndata: {\"status\":\"packed\"}\n\n",
+		},
+		{
+			name:   "multiline without id",
+			event:  events.Event{Type: "eta", Data: []byte("a\nb")},
+			expect: "event: eta\ndata: a\ndata: b\n\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			assert.NoError(t, events.WriteEvent(&buf, tc.event))
+			assert.Equal(t, tc.expect, buf.String())
+		})
+	}
+}
+
+func TestParseLastEventId(t *testing.T) {
+	assert.Equal(t, int64(42), events.ParseLastEventId("42"))
+	assert.Equal(t, int64(0), events.ParseLastEventId(""))
+	assert.Equal(t, int64(0), events.ParseLastEventId("abc"))
+	assert.Equal(t, int64(0), events.ParseLastEventId("-5"))
+}
@@ -0,0 +1,44 @@
+package events
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Пишет событие в формате text/event-stream. Многострочные данные разбиваются на несколько полей data
+func WriteEvent(w io.Writer, event Event) error {
+	var buf bytes.Buffer
+
+	if event.Id != 0 {
+		buf.WriteString("id: " + strconv.FormatInt(event.Id, 10) + "\n")
+	}
+	if event.Type != "" {
+		buf.WriteString("event: " + event.Type + "\n")
+	}
+	for _, line := range bytes.Split(event.Data, []byte("\n")) {
+		buf.WriteString("data: ")
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Комментарий держит соединение открытым через прокси
+func WriteHeartbeat(w io.Writer) error {
+	_, err := fmt.Fprint(w, ": ping\n\n")
+	return err
+}
+
+// Разбирает заголовок Last-Event-ID, некорректное значение означает поток с начала
+func ParseLastEventId(value string) int64 {
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 0 {
+		return 0
+	}
+	return id
+}
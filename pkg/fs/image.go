@@ -20,7 +20,7 @@ type IFileSystemImage interface {
 	GetImageExtension(base64Image *string) (string, error)
 	IsSupportingExtension(extension string) bool
 	SafeImageToStorage(extension string, base64Image *string) (string, error)
-	//DeleteImage(filename string) bool
+	DeleteImage(filename string) bool
 	GetPath() string
 }
 
@@ -112,3 +112,9 @@ func (i *Image) SafeImageToStorage(extension string, base64Image *string) (strin
 
 	return filename, nil
 }
+
+// Удаляет сохраненное изображение. Уже отсутствующий файл считается удаленным
+func (i *Image) DeleteImage(filename string) bool {
+	err := os.Remove(i.config.Path + filename)
+	return err == nil || errors.Is(err, os.ErrNotExist)
+}
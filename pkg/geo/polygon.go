@@ -0,0 +1,119 @@
+package geo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+type Point struct {
+	Lat float64 `json:"latitude"`
+	Lon float64 `json:"longitude"`
+}
+
+// Замкнутый контур. Точки хранятся как в GeoJSON: [lon, lat]
+type Ring [][2]float64
+
+// Первый контур - внешняя граница, остальные - дырки
+type Polygon []Ring
+
+type MultiPolygon []Polygon
+
+type geometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// Разбирает GeoJSON геометрию типа Polygon или MultiPolygon
+func ParseGeoJSON(raw []byte) (MultiPolygon, error) {
+	var g geometry
+	if err := json.Unmarshal(raw, &g); err != nil {
+		return nil, err
+	}
+
+	var result MultiPolygon
+	switch g.Type {
+	case "Polygon":
+		var polygon Polygon
+		if err := json.Unmarshal(g.Coordinates, &polygon); err != nil {
+			return nil, err
+		}
+		result = MultiPolygon{polygon}
+	case "MultiPolygon":
+		if err := json.Unmarshal(g.Coordinates, &result); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported geometry type %q, expected Polygon or MultiPolygon", g.Type)
+	}
+
+	if err := result.validate(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (m MultiPolygon) validate() error {
+	if len(m) == 0 {
+		return errors.New("geometry has no polygons")
+	}
+
+	for _, polygon := range m {
+		if len(polygon) == 0 {
+			return errors.New("polygon has no rings")
+		}
+		for _, ring := range polygon {
+			if len(ring) < 4 || ring[0] != ring[len(ring)-1] {
+				return errors.New("polygon ring must be closed and have at least 4 positions")
+			}
+			for _, position := range ring {
+				if position[0] < -180 || position[0] > 180 || position[1] < -90 || position[1] > 90 {
+					return fmt.Errorf("position [%g, %g] is out of range", position[0], position[1])
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (m MultiPolygon) Contains(p Point) bool {
+	for _, polygon := range m {
+		if polygon.Contains(p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (pg Polygon) Contains(p Point) bool {
+	if len(pg) == 0 || !pg[0].contains(p) {
+		return false
+	}
+
+	for _, hole := range pg[1:] {
+		if hole.contains(p) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Ray casting: считаем пересечения горизонтального луча из точки с ребрами контура.
+// Для зон доставки в пределах города плоское приближение достаточно точное
+func (r Ring) contains(p Point) bool {
+	inside := false
+
+	for i, j := 0, len(r)-1; i < len(r); j, i = i, i+1 {
+		xi, yi := r[i][0], r[i][1]
+		xj, yj := r[j][0], r[j][1]
+
+		if (yi > p.Lat) != (yj > p.Lat) && p.Lon < (xj-xi)*(p.Lat-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+
+	return inside
+}
@@ -0,0 +1,57 @@
+package geo_test
+
+import (
+	"arabic/pkg/geo"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultiPolygon_Contains(t *testing.T) {
+	// Квадрат вокруг центра Грозного с дыркой в середине
+	raw := []byte(`{
+		"type": "Polygon",
+		"coordinates": [
+			[[45.60, 43.25], [45.80, 43.25], [45.80, 43.40], [45.60, 43.40], [45.60, 43.25]],
+			[[45.69, 43.31], [45.71, 43.31], [45.71, 43.33], [45.69, 43.33], [45.69, 43.31]]
+		]
+	}`)
+
+	zone, err := geo.ParseGeoJSON(raw)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		point  geo.Point
+		expect bool
+	}{
+		{name: "inside", point: geo.Point{Lat: 43.30, Lon: 45.65}, expect: true},
+		{name: "outside", point: geo.Point{Lat: 43.50, Lon: 45.65}, expect: false},
+		{name: "in hole", point: geo.Point{Lat: 43.32, Lon: 45.70}, expect: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, zone.Contains(tc.point))
+		})
+	}
+}
+
+func TestParseGeoJSON_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+	}{
+		{name: "point", raw: `{"type": "Point", "coordinates": [45.6, 43.2]}`},
+		{name: "not closed", raw: `{"type": "Polygon", "coordinates": [[[45.6, 43.2], [45.8, 43.2], [45.8, 43.4], [45.6, 43.4]]]}`},
+		{name: "out of range", raw: `{"type": "Polygon", "coordinates": [[[200, 43.2], [45.8, 43.2], [45.8, 43.4], [200, 43.2]]]}`},
+		{name: "broken json", raw: `{"type": "Polygon"`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := geo.ParseGeoJSON([]byte(tc.raw))
+			assert.Error(t, err)
+		})
+	}
+}
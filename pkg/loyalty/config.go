@@ -0,0 +1,18 @@
+package loyalty
+
+type Config struct {
+	// Баллов за единицу суммы, оплаченной за товары доставленного заказа
+	EarnRate float64 `toml:"earn_rate"`
+	// Сколько стоит один балл при оплате заказа
+	PointValue float64 `toml:"point_value"`
+	// Какую часть суммы товаров можно оплатить баллами, в процентах
+	MaxRedeemPercent float64 `toml:"max_redeem_percent"`
+}
+
+func NewConfig() *Config {
+	return &Config{
+		EarnRate:         0.05,
+		PointValue:       1,
+		MaxRedeemPercent: 30,
+	}
+}
@@ -0,0 +1,23 @@
+package loyalty
+
+import "math"
+
+// Баллы за оплаченную сумму по ставке rate, дробная часть отбрасывается
+func Earned(amount float64, rate float64) int64 {
+	if amount <= 0 || rate <= 0 {
+		return 0
+	}
+	return int64(math.Floor(amount*rate + 1e-9))
+}
+
+// Сколько из requested баллов можно списать на заказ с суммой товаров amount и на какую сумму
+func (c *Config) Redeemable(amount float64, requested int64) (int64, float64) {
+	if requested <= 0 || amount <= 0 || c.PointValue <= 0 {
+		return 0, 0
+	}
+
+	limit := int64(math.Floor(amount*c.MaxRedeemPercent/100/c.PointValue + 1e-9))
+	points := min(requested, limit)
+
+	return points, math.Round(float64(points)*c.PointValue*100) / 100
+}
@@ -0,0 +1,64 @@
+package loyalty_test
+
+import (
+	"arabic/pkg/loyalty"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEarned(t *testing.T) {
+	assert.Equal(t, int64(24), loyalty.Earned(480, 0.05))
+	assert.Equal(t, int64(0), loyalty.Earned(19.99, 0.05))
+	assert.Equal(t, int64(1), loyalty.Earned(20, 0.05))
+	assert.Equal(t, int64(0), loyalty.Earned(-10, 0.05))
+}
+
+func TestConfig_Redeemable(t *testing.T) {
+	tests := []struct {
+		name           string
+		config         *loyalty.Config
+		amount         float64
+		requested      int64
+		expectPoints   int64
+		expectDiscount float64
+	}{
+		{
+			name:           "within cap",
+			config:         loyalty.NewConfig(),
+			amount:         1000,
+			requested:      100,
+			expectPoints:   100,
+			expectDiscount: 100,
+		},
+		{
+			name:           "capped by percent of items",
+			config:         loyalty.NewConfig(),
+			amount:         1000,
+			requested:      500,
+			expectPoints:   300,
+			expectDiscount: 300,
+		},
+		{
+			name:           "point worth half",
+			config:         &loyalty.Config{PointValue: 0.5, MaxRedeemPercent: 10},
+			amount:         99,
+			requested:      100,
+			expectPoints:   19,
+			expectDiscount: 9.5,
+		},
+		{
+			name:   "nothing requested",
+			config: loyalty.NewConfig(),
+			amount: 1000,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			points, discount := tc.config.Redeemable(tc.amount, tc.requested)
+			assert.Equal(t, tc.expectPoints, points)
+			assert.Equal(t, tc.expectDiscount, discount)
+		})
+	}
+}
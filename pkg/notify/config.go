@@ -0,0 +1,79 @@
+package notify
+
+import "time"
+
+const (
+	DriverLog    = "log"
+	DriverMemory = "memory"
+	DriverSMTP   = "smtp"
+)
+
+type Config struct {
+	// log - пишет уведомления в stdout, memory - хранит в памяти процесса, smtp - отправляет почтой
+	Driver string `toml:"driver"`
+	// Как часто обработчик забирает уведомления из очереди и сколько за раз
+	PollIntervalSeconds int `toml:"poll_interval_seconds"`
+	BatchSize           int `toml:"batch_size"`
+	// На сколько уведомление скрывается от других обработчиков, пока идет отправка
+	LeaseSeconds int `toml:"lease_seconds"`
+	// Повторы неудачной отправки: пауза удваивается от retry_base до retry_max
+	MaxAttempts      int         `toml:"max_attempts"`
+	RetryBaseSeconds int         `toml:"retry_base_seconds"`
+	RetryMaxSeconds  int         `toml:"retry_max_seconds"`
+	SMTP             *SMTPConfig `toml:"smtp"`
+}
+
+type SMTPConfig struct {
+	Host     string `toml:"host"`
+	Port     int    `toml:"port"`
+	Username string `toml:"username"`
+	Password string `toml:"password"`
+	From     string `toml:"from"`
+	FromName string `toml:"from_name"`
+	// Требовать STARTTLS. Без него шифрование включается, только если сервер его предлагает
+	RequireTLS     bool `toml:"require_tls"`
+	TimeoutSeconds int  `toml:"timeout_seconds"`
+}
+
+func NewConfig() *Config {
+	return &Config{
+		Driver:              DriverLog,
+		PollIntervalSeconds: 5,
+		BatchSize:           50,
+		LeaseSeconds:        60,
+		MaxAttempts:         5,
+		RetryBaseSeconds:    30,
+		RetryMaxSeconds:     3600,
+		SMTP: &SMTPConfig{
+			Host:           "localhost",
+			Port:           1025,
+			From:           "noreply@arabic.local",
+			FromName:       "Arabic",
+			TimeoutSeconds: 10,
+		},
+	}
+}
+
+func (c *Config) PollInterval() time.Duration {
+	return time.Duration(c.PollIntervalSeconds) * time.Second
+}
+
+func (c *Config) Lease() time.Duration {
+	return time.Duration(c.LeaseSeconds) * time.Second
+}
+
+// Пауза перед следующей попыткой после attempt неудачных
+func (c *Config) Backoff(attempt int) time.Duration {
+	delay := time.Duration(c.RetryBaseSeconds) * time.Second
+	limit := time.Duration(c.RetryMaxSeconds) * time.Second
+
+	for i := 1; i < attempt && delay < limit; i++ {
+		delay *= 2
+	}
+
+	return min(delay, limit)
+}
+
+func (s *SMTPConfig) Timeout() time.Duration {
+	return time.Duration(s.TimeoutSeconds) * time.Second
+}
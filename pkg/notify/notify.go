@@ -0,0 +1,86 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+)
+
+type Message struct {
+	// Адрес получателя, для почтовых каналов - email
+	Recipient string
+	Subject   string
+	Text      string
+	// HTML-версия письма, необязательна
+	HTML string
+}
+
+// Канал доставки уведомлений. Ошибка Send означает, что сообщение нужно отправить повторно
+type Notifier interface {
+	Name() string
+	Send(ctx context.Context, msg *Message) error
+}
+
+func NewNotifier(config *Config) (Notifier, error) {
+	switch config.Driver {
+	case DriverLog:
+		return NewLogNotifier(os.Stdout), nil
+	case DriverMemory:
+		return NewMemoryNotifier(), nil
+	case DriverSMTP:
+		return NewSMTPNotifier(config.SMTP), nil
+	default:
+		return nil, fmt.Errorf("unknown notify driver %q", config.Driver)
+	}
+}
+
+// Для локальной разработки: вместо отправки печатает сообщение
+type LogNotifier struct {
+	logger *log.Logger
+}
+
+func NewLogNotifier(out io.Writer) *LogNotifier {
+	return &LogNotifier{logger: log.New(out, "[notify] ", log.LstdFlags)}
+}
+
+func (l *LogNotifier) Name() string {
+	return DriverLog
+}
+
+func (l *LogNotifier) Send(ctx context.Context, msg *Message) error {
+	l.logger.Printf("to=%s subject=%q text=%q", msg.Recipient, msg.Subject, msg.Text)
+	return nil
+}
+
+// Хранит отправленные сообщения в памяти, удобен в тестах
+type MemoryNotifier struct {
+	mu       sync.Mutex
+	messages []*Message
+}
+
+func NewMemoryNotifier() *MemoryNotifier {
+	return &MemoryNotifier{}
+}
+
+func (m *MemoryNotifier) Name() string {
+	return DriverMemory
+}
+
+func (m *MemoryNotifier) Send(ctx context.Context, msg *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	copied := *msg
+	m.messages = append(m.messages, &copied)
+	return nil
+}
+
+func (m *MemoryNotifier) Messages() []*Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]*Message(nil), m.messages...)
+}
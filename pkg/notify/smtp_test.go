@@ -0,0 +1,159 @@
+package notify_test
+
+import (
+	"arabic/pkg/notify"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sinkMessage struct {
+	from string
+	to   []string
+	data string
+}
+
+// Минимальный SMTP-сервер, который принимает любые письма и складывает их в канал
+func startSMTPSink(t *testing.T) (*notify.SMTPConfig, <-chan *sinkMessage) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	messages := make(chan *sinkMessage, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSMTP(conn, messages)
+		}
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	config := notify.NewConfig().SMTP
+	config.Host = addr.IP.String()
+	config.Port = addr.Port
+	return config, messages
+}
+
+func serveSMTP(conn net.Conn, messages chan<- *sinkMessage) {
+	defer conn.Close()
+
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 sink ESMTP")
+
+	msg := &sinkMessage{}
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+
+		command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch command {
+		case "EHLO", "HELO":
+			tp.PrintfLine("250 sink")
+		case "MAIL":
+			msg.from = strings.TrimPrefix(line, "MAIL FROM:")
+			tp.PrintfLine("250 OK")
+		case "RCPT":
+			msg.to = append(msg.to, strings.TrimPrefix(line, "RCPT TO:"))
+			tp.PrintfLine("250 OK")
+		case "DATA":
+			tp.PrintfLine("354 Go ahead")
+			data, err := io.ReadAll(tp.DotReader())
+			if err != nil {
+				return
+			}
+			msg.data = string(data)
+			messages <- msg
+			msg = &sinkMessage{}
+			tp.PrintfLine("250 OK")
+		case "QUIT":
+			tp.PrintfLine("221 Bye")
+			return
+		default:
+			tp.PrintfLine("502 Not implemented")
+		}
+	}
+}
+
+func TestSMTPNotifier_Send(t *testing.T) {
+	config, messages := startSMTPSink(t)
+
+	err := notify.NewSMTPNotifier(config).Send(context.Background(), &notify.Message{
+		Recipient: "buyer@example.com",
+		Subject:   "Заказ №15 доставлен",
+		Text:      "Заказ №15 доставлен.",
+		HTML:      "<p>Заказ №15 доставлен.</p>",
+	})
+	require.NoError(t, err)
+
+	sent := <-messages
+	assert.Equal(t, "<noreply@arabic.local>", sent.from)
+	assert.Equal(t, []string{"<buyer@example.com>"}, sent.to)
+
+	parsed, err := mail.ReadMessage(strings.NewReader(sent.data))
+	require.NoError(t, err)
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "Заказ №15 доставлен", subject)
+	assert.Equal(t, "<buyer@example.com>", parsed.Header.Get("To"))
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	// Части декодируются из quoted-printable при чтении
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+	var parts []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		content, err := io.ReadAll(part)
+		require.NoError(t, err)
+		parts = append(parts, string(content))
+	}
+
+	assert.Equal(t, []string{"Заказ №15 доставлен.", "<p>Заказ №15 доставлен.</p>"}, parts)
+}
+
+func TestSMTPNotifier_SendRequireTLS(t *testing.T) {
+	config, messages := startSMTPSink(t)
+	config.RequireTLS = true
+
+	err := notify.NewSMTPNotifier(config).Send(context.Background(), &notify.Message{
+		Recipient: "buyer@example.com",
+		Subject:   "Subject",
+		Text:      "Text",
+	})
+
+	assert.Error(t, err)
+	assert.Empty(t, messages)
+}
+
+func TestConfig_Backoff(t *testing.T) {
+	config := notify.NewConfig()
+	config.RetryBaseSeconds = 30
+	config.RetryMaxSeconds = 100
+
+	assert.Equal(t, 30, int(config.Backoff(1).Seconds()))
+	assert.Equal(t, 60, int(config.Backoff(2).Seconds()))
+	assert.Equal(t, 100, int(config.Backoff(3).Seconds()))
+	assert.Equal(t, 100, int(config.Backoff(50).Seconds()))
+}
@@ -0,0 +1,121 @@
+package notify
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Язык писем по умолчанию, на него же откатываемся, если шаблона на языке получателя нет
+const DefaultLanguage = "ru"
+
+var Languages = []string{"ru", "en"}
+
+// Шаблоны лежат в templates/<язык>/<имя>.txt и необязательный <имя>.html.
+// В .txt блок subject задает тему письма, остальное - текстовая версия
+//
+//go:embed templates
+var templates embed.FS
+
+var templateFuncs = map[string]any{
+	"money": money,
+}
+
+type Renderer struct {
+	text map[string]*texttemplate.Template
+	html map[string]*htmltemplate.Template
+}
+
+func NewRenderer() (*Renderer, error) {
+	r := &Renderer{
+		text: map[string]*texttemplate.Template{},
+		html: map[string]*htmltemplate.Template{},
+	}
+
+	for _, lang := range Languages {
+		files, err := fs.Glob(templates, "templates/"+lang+"/*.txt")
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range files {
+			name := strings.TrimSuffix(path.Base(file), ".txt")
+
+			text, err := texttemplate.New(path.Base(file)).Funcs(templateFuncs).Option("missingkey=error").ParseFS(templates, file)
+			if err != nil {
+				return nil, err
+			}
+			if text.Lookup("subject") == nil {
+				return nil, fmt.Errorf("template %s has no subject", file)
+			}
+			r.text[templateKey(lang, name)] = text
+
+			htmlFile := strings.TrimSuffix(file, ".txt") + ".html"
+			if _, err = fs.Stat(templates, htmlFile); err != nil {
+				continue
+			}
+
+			html, err := htmltemplate.New(path.Base(htmlFile)).Funcs(templateFuncs).Option("missingkey=error").ParseFS(templates, htmlFile)
+			if err != nil {
+				return nil, err
+			}
+			r.html[templateKey(lang, name)] = html
+		}
+	}
+
+	return r, nil
+}
+
+// Собирает письмо по шаблону name на языке lang
+func (r *Renderer) Render(name, lang string, data any) (*Message, error) {
+	if _, ok := r.text[templateKey(lang, name)]; !ok {
+		lang = DefaultLanguage
+	}
+
+	text, ok := r.text[templateKey(lang, name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown template %q", name)
+	}
+
+	var subject, body bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, err
+	}
+	if err := text.Execute(&body, data); err != nil {
+		return nil, err
+	}
+
+	msg := &Message{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimSpace(body.String()) + "\n",
+	}
+
+	if html, ok := r.html[templateKey(lang, name)]; ok {
+		var buf bytes.Buffer
+		if err := html.Execute(&buf, data); err != nil {
+			return nil, err
+		}
+		msg.HTML = strings.TrimSpace(buf.String())
+	}
+
+	return msg, nil
+}
+
+func templateKey(lang, name string) string {
+	return lang + "/" + name
+}
+
+// Сумма с копейками. Числа из JSON приходят как json.Number или float64, из кода - любыми числовыми типами
+func money(v any) (string, error) {
+	amount, err := strconv.ParseFloat(fmt.Sprint(v), 64)
+	if err != nil {
+		return "", fmt.Errorf("money: %w", err)
+	}
+	return strconv.FormatFloat(amount, 'f', 2, 64), nil
+}
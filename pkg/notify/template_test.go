@@ -0,0 +1,112 @@
+package notify_test
+
+import (
+	"arabic/pkg/notify"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderer_Render(t *testing.T) {
+	renderer, err := notify.NewRenderer()
+	require.NoError(t, err)
+
+	// Так данные приходят из очереди: числа в виде json.Number
+	order := map[string]any{
+		"order_id":     json.Number("1000000"),
+		"status":       "delivered",
+		"total":        json.Number("450.5"),
+		"delivery_fee": json.Number("150"),
+		"items": []any{
+			map[string]any{"name": "Финики", "quantity": json.Number("2"), "unit_price": json.Number("150.25")},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		template      string
+		language      string
+		data          map[string]any
+		expectSubject string
+		expectText    []string
+		expectError   bool
+	}{
+		{
+			name:          "order status ru",
+			template:      "order_status",
+			language:      "ru",
+			data:          order,
+			expectSubject: "Заказ №1000000 доставлен",
+			expectText:    []string{"Финики x 2 - 150.25 руб.", "Итого: 450.50 руб."},
+		},
+		{
+			name:          "order status en",
+			template:      "order_status",
+			language:      "en",
+			data:          order,
+			expectSubject: "Order #1000000 has been delivered",
+			expectText:    []string{"Total: 450.50 RUB"},
+		},
+		{
+			name:          "unknown language falls back to ru",
+			template:      "registration",
+			language:      "de",
+			data:          map[string]any{"username": "user", "email": "a@a.com", "link": "http://localhost/verify?token=abc"},
+			expectSubject: "Добро пожаловать, user!",
+			expectText:    []string{"a@a.com", "http://localhost/verify?token=abc"},
+		},
+		{
+			name:          "price drop",
+			template:      "price_drop",
+			language:      "en",
+			data:          map[string]any{"name": "Dates", "price": float32(90), "old_price": float32(100)},
+			expectSubject: "Dates price drop",
+			expectText:    []string{"from 100.00 to 90.00 RUB"},
+		},
+		{
+			name:        "unknown template",
+			template:    "unknown",
+			language:    "ru",
+			data:        map[string]any{},
+			expectError: true,
+		},
+		{
+			name:        "missing data",
+			template:    "back_in_stock",
+			language:    "ru",
+			data:        map[string]any{"name": "Dates"},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			msg, err := renderer.Render(tc.template, tc.language, tc.data)
+
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectSubject, msg.Subject)
+			assert.NotEmpty(t, msg.HTML)
+			for _, text := range tc.expectText {
+				assert.Contains(t, msg.Text, text)
+			}
+		})
+	}
+}
+
+func TestRenderer_RenderEscapesHTML(t *testing.T) {
+	renderer, err := notify.NewRenderer()
+	require.NoError(t, err)
+
+	msg, err := renderer.Render("registration", "en", map[string]any{"username": "<b>x</b>", "email": "a@a.com", "link": "http://localhost/verify?token=abc"})
+	require.NoError(t, err)
+
+	assert.Contains(t, msg.Text, "<b>x</b>")
+	assert.Contains(t, msg.HTML, "&lt;b&gt;x&lt;/b&gt;")
+}
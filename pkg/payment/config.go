@@ -0,0 +1,44 @@
+package payment
+
+import (
+	"errors"
+	"time"
+)
+
+const ProviderFake = "fake"
+
+type Config struct {
+	Provider      string `toml:"provider"`
+	Currency      string `toml:"currency"`
+	WebhookSecret string `toml:"webhook_secret"`
+	// Куда fake-шлюз отправляет вебхуки
+	WebhookURL string `toml:"webhook_url"`
+	// Допустимое расхождение времени подписи вебхука
+	WebhookToleranceSeconds int `toml:"webhook_tolerance_seconds"`
+	// Открытые ручки имитации оплаты fake-шлюза, только для локальной разработки
+	EnableFakeSimulator bool `toml:"enable_fake_simulator"`
+}
+
+func NewConfig() *Config {
+	return &Config{
+		Provider:                ProviderFake,
+		Currency:                "RUB",
+		WebhookURL:              "http://localhost:8080/api/v1/payments/webhook",
+		WebhookToleranceSeconds: 300,
+	}
+}
+
+// С пустым секретом подпись вебхука может посчитать кто угодно
+func (c *Config) Validate() error {
+	if c.WebhookSecret == "" {
+		return errors.New("payment webhook_secret is required")
+	}
+	if c.EnableFakeSimulator && c.Provider != ProviderFake {
+		return errors.New("payment enable_fake_simulator requires provider=fake")
+	}
+	return nil
+}
+
+func (c *Config) WebhookTolerance() time.Duration {
+	return time.Duration(c.WebhookToleranceSeconds) * time.Second
+}
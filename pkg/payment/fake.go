@@ -0,0 +1,212 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	fakeStatusRequiresPayment = "requires_payment"
+	fakeStatusAuthorized      = "authorized"
+	fakeStatusFailed          = "failed"
+	fakeStatusCaptured        = "captured"
+	fakeStatusCanceled        = "canceled"
+)
+
+// Локальный шлюз для разработки: хранит платежи в памяти, а оплату покупателем
+// имитирует Simulator, который отправляет подписанный вебхук на WebhookURL
+type FakeProvider struct {
+	mu         sync.Mutex
+	intents    map[string]*fakeIntent
+	secret     string
+	webhookURL string
+	tolerance  time.Duration
+	client     *http.Client
+}
+
+type fakeIntent struct {
+	status   string
+	amount   int64
+	captured int64
+	refunded int64
+}
+
+func NewFakeProvider(config *Config) *FakeProvider {
+	return &FakeProvider{
+		intents:    make(map[string]*fakeIntent),
+		secret:     config.WebhookSecret,
+		webhookURL: config.WebhookURL,
+		tolerance:  config.WebhookTolerance(),
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (f *FakeProvider) Name() string {
+	return ProviderFake
+}
+
+func (f *FakeProvider) CreateIntent(ctx context.Context, req *IntentRequest) (*Intent, error) {
+	if req.Amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	id := "pi_fake_" + uuid.NewString()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.intents[id] = &fakeIntent{status: fakeStatusRequiresPayment, amount: req.Amount}
+
+	return &Intent{
+		Id:           id,
+		ClientSecret: id + "_secret_" + uuid.NewString(),
+		Amount:       req.Amount,
+		Currency:     req.Currency,
+	}, nil
+}
+
+func (f *FakeProvider) Capture(ctx context.Context, intentId string, amount int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	intent, ok := f.intents[intentId]
+	if !ok || intent.status != fakeStatusAuthorized {
+		return ErrIntentNotFound
+	}
+
+	if amount <= 0 || amount > intent.amount {
+		return ErrInvalidAmount
+	}
+
+	intent.status = fakeStatusCaptured
+	intent.captured = amount
+	return nil
+}
+
+func (f *FakeProvider) Refund(ctx context.Context, intentId string, amount int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	intent, ok := f.intents[intentId]
+	if !ok {
+		return ErrIntentNotFound
+	}
+
+	switch intent.status {
+	case fakeStatusAuthorized:
+		intent.status = fakeStatusCanceled
+		return nil
+	case fakeStatusCaptured:
+		if amount <= 0 || intent.refunded+amount > intent.captured {
+			return ErrInvalidAmount
+		}
+		intent.refunded += amount
+		return nil
+	default:
+		return ErrIntentNotFound
+	}
+}
+
+func (f *FakeProvider) VerifyWebhook(payload []byte, signature string) (*WebhookEvent, error) {
+	if err := VerifySignature(f.secret, signature, payload, time.Now(), f.tolerance); err != nil {
+		return nil, err
+	}
+
+	event := &WebhookEvent{}
+	if err := json.Unmarshal(payload, event); err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+type simulatorResponse struct {
+	EventId string `json:"event_id"`
+	Type    string `json:"type"`
+	// Коды ответа на каждую доставку вебхука
+	Deliveries []int `json:"deliveries"`
+}
+
+// HTTP-имитатор действий покупателя на стороне шлюза:
+// POST /{intentId}/authorize и POST /{intentId}/fail. Параметр repeat=N отправляет
+// один и тот же вебхук N раз, как это делают настоящие шлюзы при повторах
+func (f *FakeProvider) Simulator() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /{intentId}/authorize", f.simulate(fakeStatusAuthorized, EventAuthorized))
+	mux.HandleFunc("POST /{intentId}/fail", f.simulate(fakeStatusFailed, EventFailed))
+	return mux
+}
+
+func (f *FakeProvider) simulate(status, eventType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		intentId := r.PathValue("intentId")
+
+		repeat, err := strconv.Atoi(r.URL.Query().Get("repeat"))
+		if err != nil || repeat < 1 {
+			repeat = 1
+		}
+		repeat = min(repeat, 5)
+
+		f.mu.Lock()
+		intent, ok := f.intents[intentId]
+		if !ok || intent.status != fakeStatusRequiresPayment {
+			f.mu.Unlock()
+			http.Error(w, "payment intent not found or already processed", http.StatusConflict)
+			return
+		}
+		intent.status = status
+		amount := intent.amount
+		f.mu.Unlock()
+
+		event := &WebhookEvent{
+			Id:        "evt_fake_" + uuid.NewString(),
+			Type:      eventType,
+			IntentId:  intentId,
+			Amount:    amount,
+			CreatedAt: time.Now().UTC(),
+		}
+
+		resp := &simulatorResponse{EventId: event.Id, Type: event.Type}
+		for range repeat {
+			code, err := f.sendWebhook(r.Context(), event)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadGateway)
+				return
+			}
+			resp.Deliveries = append(resp.Deliveries, code)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// Лок не держится во время отправки: обработчик вебхука может вызвать Refund
+func (f *FakeProvider) sendWebhook(ctx context.Context, event *WebhookEvent) (int, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.webhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(f.secret, payload, time.Now()))
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode, nil
+}
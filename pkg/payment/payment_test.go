@@ -0,0 +1,116 @@
+package payment_test
+
+import (
+	"arabic/pkg/payment"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifySignature(t *testing.T) {
+	payload := []byte(`{"id":"evt_1"}`)
+	now := time.Unix(1_700_000_000, 0)
+	header := payment.Sign("secret", payload, now)
+
+	tests := []struct {
+		name    string
+		secret  string
+		header  string
+		payload []byte
+		now     time.Time
+		valid   bool
+	}{
+		{name: "valid", secret: "secret", header: header, payload: payload, now: now, valid: true},
+		{name: "wrong secret", secret: "other", header: header, payload: payload, now: now},
+		{name: "tampered payload", secret: "secret", header: header, payload: []byte(`{"id":"evt_2"}`), now: now},
+		{name: "expired", secret: "secret", header: header, payload: payload, now: now.Add(10 * time.Minute)},
+		{name: "malformed", secret: "secret", header: "v1=abc", payload: payload, now: now},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := payment.VerifySignature(tc.secret, tc.header, tc.payload, tc.now, 5*time.Minute)
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, payment.ErrInvalidSignature)
+			}
+		})
+	}
+}
+
+func TestFakeProvider_Flow(t *testing.T) {
+	var received []*payment.WebhookEvent
+	var provider *payment.FakeProvider
+
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, _ := io.ReadAll(r.Body)
+		event, err := provider.VerifyWebhook(payload, r.Header.Get(payment.SignatureHeader))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received = append(received, event)
+	}))
+	defer webhook.Close()
+
+	provider = payment.NewFakeProvider(&payment.Config{WebhookSecret: "secret", WebhookURL: webhook.URL, WebhookToleranceSeconds: 60})
+	ctx := context.Background()
+
+	intent, err := provider.CreateIntent(ctx, &payment.IntentRequest{OrderId: 1, Amount: 10_000, Currency: "RUB"})
+	assert.NoError(t, err)
+
+	// До авторизации списать нельзя
+	assert.ErrorIs(t, provider.Capture(ctx, intent.Id, 10_000), payment.ErrIntentNotFound)
+
+	simulator := httptest.NewServer(provider.Simulator())
+	defer simulator.Close()
+
+	resp, err := http.Post(simulator.URL+"/"+intent.Id+"/authorize?repeat=2", "application/json", nil)
+	assert.NoError(t, err)
+	var result struct {
+		Deliveries []int `json:"deliveries"`
+	}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	resp.Body.Close()
+
+	assert.Equal(t, []int{200, 200}, result.Deliveries)
+	assert.Len(t, received, 2)
+	assert.Equal(t, received[0].Id, received[1].Id)
+	assert.Equal(t, payment.EventAuthorized, received[0].Type)
+	assert.Equal(t, int64(10_000), received[0].Amount)
+
+	// Списывается не больше авторизованной суммы, возвращается не больше списанной
+	assert.ErrorIs(t, provider.Capture(ctx, intent.Id, 12_000), payment.ErrInvalidAmount)
+	assert.NoError(t, provider.Capture(ctx, intent.Id, 8_000))
+	assert.ErrorIs(t, provider.Refund(ctx, intent.Id, 9_000), payment.ErrInvalidAmount)
+	assert.NoError(t, provider.Refund(ctx, intent.Id, 8_000))
+}
+
+func TestMinorUnits(t *testing.T) {
+	assert.Equal(t, int64(12345), payment.MinorUnits(123.45))
+	assert.Equal(t, int64(10), payment.MinorUnits(0.1))
+	assert.Equal(t, 123.45, payment.FromMinorUnits(12345))
+}
+
+func TestConfig_Validate(t *testing.T) {
+	config := payment.NewConfig()
+	assert.False(t, config.EnableFakeSimulator)
+	assert.Error(t, config.Validate())
+
+	config.WebhookSecret = "secret"
+	assert.NoError(t, config.Validate())
+
+	config.EnableFakeSimulator = true
+	assert.NoError(t, config.Validate())
+
+	// Имитатор без fake-шлюза подтверждал бы платежи, которых нет у настоящего шлюза
+	config.Provider = "stripe"
+	assert.Error(t, config.Validate())
+}
@@ -0,0 +1,71 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Типы событий вебхука
+const (
+	EventAuthorized = "payment.authorized"
+	EventFailed     = "payment.failed"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrIntentNotFound   = errors.New("payment intent not found")
+	ErrInvalidAmount    = errors.New("invalid payment amount")
+)
+
+// Платежный шлюз. Суммы передаются в минимальных единицах валюты (копейках).
+// Оплата двухэтапная: покупатель авторизует сумму, магазин списывает фактическую сумму после сборки
+type PaymentProvider interface {
+	Name() string
+	CreateIntent(ctx context.Context, req *IntentRequest) (*Intent, error)
+	Capture(ctx context.Context, intentId string, amount int64) error
+	// Возврат списанных денег или отмена авторизации, если списания не было
+	Refund(ctx context.Context, intentId string, amount int64) error
+	VerifyWebhook(payload []byte, signature string) (*WebhookEvent, error)
+}
+
+type IntentRequest struct {
+	OrderId  int64
+	Amount   int64
+	Currency string
+}
+
+type Intent struct {
+	Id string
+	// Передается клиенту для подтверждения оплаты на стороне шлюза
+	ClientSecret string
+	Amount       int64
+	Currency     string
+}
+
+type WebhookEvent struct {
+	Id        string    `json:"id"`
+	Type      string    `json:"type"`
+	IntentId  string    `json:"intent_id"`
+	Amount    int64     `json:"amount"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewProvider(config *Config) (PaymentProvider, error) {
+	switch config.Provider {
+	case ProviderFake:
+		return NewFakeProvider(config), nil
+	default:
+		return nil, fmt.Errorf("unknown payment provider %q", config.Provider)
+	}
+}
+
+func MinorUnits(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+func FromMinorUnits(amount int64) float64 {
+	return float64(amount) / 100
+}
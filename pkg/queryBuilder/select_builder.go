@@ -0,0 +1,101 @@
+package queryBuilder
+
+import (
+	"fmt"
+	"strings"
+)
+
+type SelectBuilder struct {
+	table        string
+	columns      []string
+	whereParts   []string
+	values       []interface{}
+	orderParts   []string
+	limit        int
+	offset       int
+	withNilCheck bool
+}
+
+func NewSelectBuilder(table string, withNilCheck bool, columns ...string) *SelectBuilder {
+	return &SelectBuilder{
+		table:        table,
+		columns:      columns,
+		withNilCheck: withNilCheck,
+	}
+}
+
+// Добавляет условие в WHERE. Плейсхолдеры "?" заменяются на $n по порядку values.
+// При withNilCheck условие пропускается, если хотя бы одно значение nil
+func (sb *SelectBuilder) Where(condition string, values ...any) *SelectBuilder {
+	if sb.withNilCheck {
+		for _, value := range values {
+			if isNil(value) {
+				return sb
+			}
+		}
+	}
+
+	for _, value := range values {
+		sb.values = append(sb.values, value)
+		condition = strings.Replace(condition, "?", fmt.Sprintf("$%d", len(sb.values)), 1)
+	}
+
+	sb.whereParts = append(sb.whereParts, condition)
+	return sb
+}
+
+// Добавляет условие в WHERE только если cond == true
+func (sb *SelectBuilder) WhereIf(cond bool, condition string, values ...any) *SelectBuilder {
+	if !cond {
+		return sb
+	}
+	return sb.Where(condition, values...)
+}
+
+func (sb *SelectBuilder) OrderBy(expression string, desc bool) *SelectBuilder {
+	direction := "ASC"
+	if desc {
+		direction = "DESC"
+	}
+	sb.orderParts = append(sb.orderParts, fmt.Sprintf("%s %s", expression, direction))
+	return sb
+}
+
+func (sb *SelectBuilder) Limit(limit int) *SelectBuilder {
+	sb.limit = limit
+	return sb
+}
+
+func (sb *SelectBuilder) Offset(offset int) *SelectBuilder {
+	sb.offset = offset
+	return sb
+}
+
+func (sb *SelectBuilder) BuildSelectQuery() (string, []interface{}) {
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(sb.columns, ", "), sb.table) + sb.whereClause()
+
+	if len(sb.orderParts) > 0 {
+		query += " ORDER BY " + strings.Join(sb.orderParts, ", ")
+	}
+	if sb.limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", sb.limit)
+	}
+	if sb.offset > 0 {
+		query += fmt.Sprintf(" OFFSET %d", sb.offset)
+	}
+
+	return query, sb.values
+}
+
+// Строит запрос на подсчет строк с теми же условиями, без сортировки и пагинации
+func (sb *SelectBuilder) BuildCountQuery() (string, []interface{}) {
+	values := append([]interface{}{}, sb.values...)
+	return fmt.Sprintf("SELECT COUNT(*) FROM %s", sb.table) + sb.whereClause(), values
+}
+
+func (sb *SelectBuilder) whereClause() string {
+	if len(sb.whereParts) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(sb.whereParts, " AND ")
+}
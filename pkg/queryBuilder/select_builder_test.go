@@ -0,0 +1,32 @@
+package queryBuilder_test
+
+import (
+	"arabic/pkg/queryBuilder"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSelectBuilder_BuildSelectQuery(t *testing.T) {
+	var categoryId *uint
+	minPrice := float32(10)
+
+	sb := queryBuilder.NewSelectBuilder("public.catalogs", true, "id", "name").
+		Where("category_id = ?", categoryId).
+		Where("price >= ?", &minPrice).
+		WhereIf(true, "amount > 0").
+		WhereIf(false, "discount_percent > 0").
+		OrderBy("price", true).
+		Limit(10).
+		Offset(20)
+
+	countQuery, countValues := sb.BuildCountQuery()
+	assert.Equal(t, "SELECT COUNT(*) FROM public.catalogs WHERE price >= $1 AND amount > 0", countQuery)
+	assert.Len(t, countValues, 1)
+
+	sb.Where("(price, id) > (SELECT price, id FROM public.catalogs WHERE id = ?)", 5)
+
+	query, values := sb.BuildSelectQuery()
+	assert.Equal(t, "SELECT id, name FROM public.catalogs WHERE price >= $1 AND amount > 0 AND (price, id) > (SELECT price, id FROM public.catalogs WHERE id = $2) ORDER BY price DESC LIMIT 10 OFFSET 20", query)
+	assert.Len(t, values, 2)
+	assert.Len(t, countValues, 1)
+}
@@ -0,0 +1,31 @@
+package routing
+
+import (
+	"arabic/pkg/geo"
+	"time"
+)
+
+// Склад, с которого курьеры начинают маршрут, и параметры оценки времени доставки
+type Config struct {
+	DepotLatitude  float64 `toml:"depot_latitude"`
+	DepotLongitude float64 `toml:"depot_longitude"`
+	// Средняя скорость курьера по городу
+	AverageSpeedKmh float64 `toml:"average_speed_kmh"`
+	// Время на передачу заказа на каждой остановке
+	StopMinutes int `toml:"stop_minutes"`
+}
+
+func NewConfig() *Config {
+	return &Config{
+		AverageSpeedKmh: 25,
+		StopMinutes:     5,
+	}
+}
+
+func (c *Config) Depot() geo.Point {
+	return geo.Point{Lat: c.DepotLatitude, Lon: c.DepotLongitude}
+}
+
+func (c *Config) StopDuration() time.Duration {
+	return time.Duration(c.StopMinutes) * time.Minute
+}
@@ -0,0 +1,30 @@
+package routing
+
+import (
+	"arabic/pkg/geo"
+	"time"
+)
+
+// Время прибытия на каждую остановку при объезде по порядку из точки from, начиная со start.
+// Расстояние считается по прямой, на каждой предыдущей остановке курьер тратит StopDuration
+func (c *Config) EstimateArrivals(from geo.Point, stops []geo.Point, start time.Time) []time.Time {
+	metersPerSecond := c.AverageSpeedKmh * 1000 / 3600
+	if metersPerSecond <= 0 {
+		metersPerSecond = 1
+	}
+
+	arrivals := make([]time.Time, 0, len(stops))
+	at, current := start, from
+
+	for i, stop := range stops {
+		if i > 0 {
+			at = at.Add(c.StopDuration())
+		}
+		travel := geo.Distance(current, stop) / metersPerSecond
+		at = at.Add(time.Duration(travel * float64(time.Second)))
+		arrivals = append(arrivals, at)
+		current = stop
+	}
+
+	return arrivals
+}
@@ -0,0 +1,31 @@
+package routing_test
+
+import (
+	"arabic/pkg/geo"
+	"arabic/pkg/routing"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_EstimateArrivals(t *testing.T) {
+	config := &routing.Config{AverageSpeedKmh: 36, StopMinutes: 5}
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	depot := geo.Point{Lat: 43.30, Lon: 45.60}
+
+	// ~1.1 км между соседними точками по широте, 36 км/ч = 10 м/с
+	stops := []geo.Point{
+		{Lat: 43.31, Lon: 45.60},
+		{Lat: 43.32, Lon: 45.60},
+	}
+
+	arrivals := config.EstimateArrivals(depot, stops, start)
+
+	assert.Len(t, arrivals, 2)
+	leg := time.Duration(geo.Distance(depot, stops[0]) / 10 * float64(time.Second))
+	assert.InDelta(t, float64(leg), float64(arrivals[0].Sub(start)), float64(time.Second))
+	assert.InDelta(t, float64(2*leg+5*time.Minute), float64(arrivals[1].Sub(start)), float64(2*time.Second))
+
+	assert.Empty(t, config.EstimateArrivals(depot, nil, start))
+}
@@ -0,0 +1,92 @@
+package routing
+
+import "arabic/pkg/geo"
+
+// Ограничение на число проходов 2-opt, чтобы большой маршрут не считался бесконечно
+const maxTwoOptPasses = 50
+
+// Порядок объезда точек из depot без возврата на склад:
+// жадный nearest-neighbor, затем улучшение 2-opt. Возвращает индексы stops
+func PlanRoute(depot geo.Point, stops []geo.Point) []int {
+	order := nearestNeighbor(depot, stops)
+	return twoOpt(depot, stops, order)
+}
+
+// Длина маршрута в метрах
+func Distance(depot geo.Point, stops []geo.Point, order []int) float64 {
+	total := 0.0
+	prev := depot
+	for _, idx := range order {
+		total += geo.Distance(prev, stops[idx])
+		prev = stops[idx]
+	}
+	return total
+}
+
+func nearestNeighbor(depot geo.Point, stops []geo.Point) []int {
+	visited := make([]bool, len(stops))
+	order := make([]int, 0, len(stops))
+	current := depot
+
+	for range stops {
+		best, bestDistance := -1, 0.0
+		for i, stop := range stops {
+			if visited[i] {
+				continue
+			}
+			if d := geo.Distance(current, stop); best == -1 || d < bestDistance {
+				best, bestDistance = i, d
+			}
+		}
+
+		visited[best] = true
+		order = append(order, best)
+		current = stops[best]
+	}
+
+	return order
+}
+
+// Разворачивает отрезки маршрута, пока это сокращает путь
+func twoOpt(depot geo.Point, stops []geo.Point, order []int) []int {
+	point := func(pos int) geo.Point {
+		if pos < 0 {
+			return depot
+		}
+		return stops[order[pos]]
+	}
+
+	for pass := 0; pass < maxTwoOptPasses; pass++ {
+		improved := false
+
+		for i := 0; i < len(order)-1; i++ {
+			for k := i + 1; k < len(order); k++ {
+				before := geo.Distance(point(i-1), point(i))
+				after := geo.Distance(point(i-1), point(k))
+
+				// Маршрут открытый: у последней точки нет следующего ребра
+				if k+1 < len(order) {
+					before += geo.Distance(point(k), point(k+1))
+					after += geo.Distance(point(i), point(k+1))
+				}
+
+				if after < before-1e-9 {
+					reverse(order[i : k+1])
+					improved = true
+				}
+			}
+		}
+
+		if !improved {
+			break
+		}
+	}
+
+	return order
+}
+
+func reverse(s []int) {
+	for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
+		s[i], s[j] = s[j], s[i]
+	}
+}
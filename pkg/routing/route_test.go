@@ -0,0 +1,67 @@
+package routing_test
+
+import (
+	"arabic/pkg/geo"
+	"arabic/pkg/routing"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanRoute(t *testing.T) {
+	depot := geo.Point{Lat: 43.30, Lon: 45.60}
+
+	tests := []struct {
+		name  string
+		stops []geo.Point
+	}{
+		{name: "empty", stops: nil},
+		{name: "single", stops: []geo.Point{{Lat: 43.31, Lon: 45.61}}},
+		{
+			// Точки на одной линии в перемешанном порядке - оптимальный путь идет по возрастанию
+			name: "line",
+			stops: []geo.Point{
+				{Lat: 43.30, Lon: 45.64},
+				{Lat: 43.30, Lon: 45.61},
+				{Lat: 43.30, Lon: 45.65},
+				{Lat: 43.30, Lon: 45.62},
+				{Lat: 43.30, Lon: 45.63},
+			},
+		},
+		{
+			name: "grid",
+			stops: []geo.Point{
+				{Lat: 43.32, Lon: 45.62},
+				{Lat: 43.30, Lon: 45.62},
+				{Lat: 43.32, Lon: 45.60},
+				{Lat: 43.31, Lon: 45.61},
+				{Lat: 43.33, Lon: 45.63},
+				{Lat: 43.29, Lon: 45.64},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			order := routing.PlanRoute(depot, tc.stops)
+
+			assert.Len(t, order, len(tc.stops))
+			assert.ElementsMatch(t, identity(len(tc.stops)), order)
+			// 2-opt не может сделать маршрут длиннее исходного порядка
+			assert.LessOrEqual(t, routing.Distance(depot, tc.stops, order), routing.Distance(depot, tc.stops, identity(len(tc.stops)))+1e-6)
+		})
+	}
+
+	t.Run("line is visited in order", func(t *testing.T) {
+		stops := []geo.Point{{Lat: 43.30, Lon: 45.64}, {Lat: 43.30, Lon: 45.61}, {Lat: 43.30, Lon: 45.65}, {Lat: 43.30, Lon: 45.62}, {Lat: 43.30, Lon: 45.63}}
+		assert.Equal(t, []int{1, 3, 4, 0, 2}, routing.PlanRoute(depot, stops))
+	})
+}
+
+func identity(n int) []int {
+	result := make([]int, n)
+	for i := range result {
+		result[i] = i
+	}
+	return result
+}
@@ -0,0 +1,55 @@
+package security
+
+import "time"
+
+// Что запрещено пользователю с неподтвержденным email
+const (
+	VerifiedEmailOptional = "off"
+	VerifiedEmailLogin    = "login"
+	VerifiedEmailCheckout = "checkout"
+)
+
+type AccountConfig struct {
+	// off - ничего, login - вход, checkout - оформление заказа
+	RequireVerifiedEmail string `toml:"require_verified_email"`
+	VerifyTokenTTL       int    `toml:"verify_token_ttl_hours"`
+	ResetTokenTTL        int    `toml:"reset_token_ttl_minutes"`
+	// Адреса для ссылок из писем, токен добавляется параметром ?token=
+	VerifyURL string `toml:"verify_url"`
+	ResetURL  string `toml:"reset_url"`
+}
+
+func NewAccountConfig() *AccountConfig {
+	return &AccountConfig{
+		RequireVerifiedEmail: VerifiedEmailOptional,
+		VerifyTokenTTL:       48,
+		ResetTokenTTL:        60,
+		VerifyURL:            "http://localhost:8080/api/v1/user/verify",
+		ResetURL:             "http://localhost:3000/password/reset",
+	}
+}
+
+func (a *AccountConfig) VerifyTTL() time.Duration {
+	return time.Duration(a.VerifyTokenTTL) * time.Hour
+}
+
+func (a *AccountConfig) ResetTTL() time.Duration {
+	return time.Duration(a.ResetTokenTTL) * time.Minute
+}
+
+func (a *AccountConfig) VerifyLink(token string) string {
+	return a.VerifyURL + "?token=" + token
+}
+
+func (a *AccountConfig) ResetLink(token string) string {
+	return a.ResetURL + "?token=" + token
+}
+
+// Проверка email на входе включает и проверку при оформлении заказа
+func (a *AccountConfig) BlocksLogin() bool {
+	return a.RequireVerifiedEmail == VerifiedEmailLogin
+}
+
+func (a *AccountConfig) BlocksCheckout() bool {
+	return a.RequireVerifiedEmail == VerifiedEmailLogin || a.RequireVerifiedEmail == VerifiedEmailCheckout
+}
@@ -0,0 +1,95 @@
+package security
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Cookie с access токеном
+const AccessTokenCookie = "token"
+
+// Откуда middleware берет access токен
+const (
+	TokenSourceHeader = "header"
+	TokenSourceCookie = "cookie"
+)
+
+// Атрибуты cookie с токенами. Max-Age берется из времени жизни токенов в [jwt]
+type CookieConfig struct {
+	// Только по HTTPS. Safari не передает такие cookie на http://localhost
+	Secure bool `toml:"secure"`
+	// lax, strict или none. none нужен фронтенду на другом домене и требует secure
+	SameSite string `toml:"same_site"`
+	// То же для cookie с refresh токеном. Она нужна только запросам фронтенда к /user/refresh и /user/logout
+	RefreshSameSite string `toml:"refresh_same_site"`
+	// Пусто - cookie только для домена API
+	Domain string `toml:"domain"`
+}
+
+func NewCookieConfig() *CookieConfig {
+	return &CookieConfig{
+		Secure:          true,
+		SameSite:        "lax",
+		RefreshSameSite: "strict",
+	}
+}
+
+func (c *CookieConfig) Validate() error {
+	if err := c.validateSameSite("same_site", c.SameSite); err != nil {
+		return err
+	}
+	return c.validateSameSite("refresh_same_site", c.RefreshSameSite)
+}
+
+func (c *CookieConfig) validateSameSite(name, value string) error {
+	switch value {
+	case "lax", "strict":
+	case "none":
+		if !c.Secure {
+			return fmt.Errorf("cookie %s=none requires secure=true", name)
+		}
+	default:
+		return fmt.Errorf("unknown cookie %s %q", name, value)
+	}
+	return nil
+}
+
+func (c *CookieConfig) SameSiteMode() http.SameSite {
+	return sameSiteMode(c.SameSite)
+}
+
+func (c *CookieConfig) RefreshSameSiteMode() http.SameSite {
+	return sameSiteMode(c.RefreshSameSite)
+}
+
+// Cookie с общими атрибутами. maxAge < 0 удаляет cookie
+func (c *CookieConfig) New(name, value, path string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   c.Domain,
+		MaxAge:   maxAge,
+		Secure:   c.Secure,
+		HttpOnly: true,
+		SameSite: c.SameSiteMode(),
+	}
+}
+
+// Cookie с refresh токеном, SameSite из refresh_same_site
+func (c *CookieConfig) NewRefresh(name, value, path string, maxAge int) *http.Cookie {
+	cookie := c.New(name, value, path, maxAge)
+	cookie.SameSite = c.RefreshSameSiteMode()
+	return cookie
+}
+
+func sameSiteMode(value string) http.SameSite {
+	switch value {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
@@ -0,0 +1,42 @@
+package security_test
+
+import (
+	security "arabic/pkg/security/auth"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCookieConfig(t *testing.T) {
+	config := security.NewCookieConfig()
+	config.Domain = "arabic.com"
+	assert.NoError(t, config.Validate())
+
+	cookie := config.New(security.AccessTokenCookie, "value", "/", 900)
+	assert.True(t, cookie.Secure)
+	assert.True(t, cookie.HttpOnly)
+	assert.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+	assert.Equal(t, "arabic.com", cookie.Domain)
+	assert.Equal(t, 900, cookie.MaxAge)
+
+	// Refresh токен по умолчанию не уходит с запросами с других сайтов
+	refresh := config.NewRefresh("refresh_token", "value", "/api/v1/user", 3600)
+	assert.Equal(t, http.SameSiteStrictMode, refresh.SameSite)
+	assert.Equal(t, "arabic.com", refresh.Domain)
+
+	config.SameSite = "none"
+	assert.NoError(t, config.Validate())
+	assert.Equal(t, http.SameSiteNoneMode, config.SameSiteMode())
+
+	// Браузеры отбрасывают SameSite=None без Secure
+	config.Secure = false
+	assert.Error(t, config.Validate())
+
+	config.SameSite = "unknown"
+	assert.Error(t, config.Validate())
+
+	config.SameSite = "lax"
+	config.RefreshSameSite = "none"
+	assert.Error(t, config.Validate())
+}
@@ -1,9 +1,12 @@
 package security
 
 import (
+	"arabic/pkg/logger"
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	jwtmiddleware "github.com/auth0/go-jwt-middleware/v2"
@@ -12,22 +15,122 @@ import (
 )
 
 type JWTConfig struct {
-	SecretJWTKey string `toml:"jwt_secret_key"`
-	Audience     string `toml:"jwt_audience"`
-	Issuer       string `toml:"jwt_issuer"`
+	// Секрет HS256, используется, только если не заданы keys
+	SecretJWTKey    string `toml:"jwt_secret_key"`
+	Audience        string `toml:"jwt_audience"`
+	Issuer          string `toml:"jwt_issuer"`
+	AccessTokenTTL  int    `toml:"access_token_ttl_minutes"`
+	RefreshTokenTTL int    `toml:"refresh_token_ttl_hours"`
+	// Ключи RS256/EdDSA. Подписывает самый новый из начавших действовать, проверяются все непросроченные
+	Keys []*JWTKey `toml:"keys"`
+	// Сколько проверяющие сервисы могут кешировать JWKS. Новый ключ должен быть опубликован
+	// хотя бы на это время раньше, чем начнет подписывать
+	JWKSCacheSeconds int `toml:"jwks_cache_seconds"`
+	// Где искать access токен и в каком порядке: header - Authorization: Bearer, cookie - cookie token
+	TokenSources []string `toml:"token_sources"`
+
+	keySet *KeySet
 }
 
 func NewJWTConfig() *JWTConfig {
-	return &JWTConfig{}
+	return &JWTConfig{
+		AccessTokenTTL:   15,
+		RefreshTokenTTL:  30 * 24,
+		JWKSCacheSeconds: 300,
+		TokenSources:     []string{TokenSourceHeader, TokenSourceCookie},
+	}
+}
+
+func (j *JWTConfig) Validate() error {
+	if len(j.TokenSources) == 0 {
+		return errors.New("jwt token_sources is empty")
+	}
+
+	for _, source := range j.TokenSources {
+		if source != TokenSourceHeader && source != TokenSourceCookie {
+			return fmt.Errorf("unknown jwt token source %q", source)
+		}
+	}
+
+	return nil
+}
+
+// Берет токен из первого источника, где он есть
+func (j *JWTConfig) tokenExtractor() jwtmiddleware.TokenExtractor {
+	var extractors []jwtmiddleware.TokenExtractor
+
+	for _, source := range j.TokenSources {
+		switch source {
+		case TokenSourceHeader:
+			extractors = append(extractors, tokenFromHeader)
+		case TokenSourceCookie:
+			extractors = append(extractors, jwtmiddleware.CookieTokenExtractor(AccessTokenCookie))
+		}
+	}
+
+	return jwtmiddleware.MultiTokenExtractor(extractors...)
+}
+
+// Читает ключи из файлов при старте сервера, чтобы ошибка в конфигурации не всплыла на первом входе
+func (j *JWTConfig) LoadKeys() error {
+	if len(j.Keys) == 0 {
+		if j.SecretJWTKey == "" {
+			return errors.New("jwt_secret_key or jwt.keys must be set")
+		}
+		j.keySet = newSecretKeySet(j.SecretJWTKey)
+		return nil
+	}
+
+	keySet, err := loadKeySet(j.Keys)
+	if err != nil {
+		return err
+	}
+	if _, err = keySet.signer(time.Now()); err != nil {
+		return err
+	}
+
+	j.keySet = keySet
+	return nil
+}
+
+func (j *JWTConfig) keys() (*KeySet, error) {
+	if j.keySet != nil {
+		return j.keySet, nil
+	}
+	if len(j.Keys) == 0 {
+		return newSecretKeySet(j.SecretJWTKey), nil
+	}
+	return nil, errors.New("jwt keys are not loaded")
+}
+
+func (j *JWTConfig) AccessTTL() time.Duration {
+	return time.Duration(j.AccessTokenTTL) * time.Minute
+}
+
+func (j *JWTConfig) RefreshTTL() time.Duration {
+	return time.Duration(j.RefreshTokenTTL) * time.Hour
 }
 
-func (j *JWTConfig) emptyFunc(context.Context) (any, error) {
-	return []byte(j.SecretJWTKey), nil
+// Проверяет, что сессия, к которой привязан токен (jti), не отозвана
+type SessionChecker interface {
+	IsSessionActive(ctx context.Context, sessionId string) (bool, error)
+}
+
+type verificationKey struct{}
+
+// Ключ выбирается по kid до проверки и передается валидатору через контекст
+func keyFromContext(ctx context.Context) (any, error) {
+	key := ctx.Value(verificationKey{})
+	if key == nil {
+		return nil, errors.New("verification key not found")
+	}
+	return key, nil
 }
 
 type CustomClaims struct {
 	UserEmail string `json:"email"`
 	Id        int64  `json:"id"`
+	Role      string `json:"role"`
 	jwt.RegisteredClaims
 }
 
@@ -38,33 +141,99 @@ func (c *CustomClaims) Validate(ctx context.Context) error {
 	return nil
 }
 
-func NewJwtMiddleware(config *JWTConfig) *jwtmiddleware.JWTMiddleware {
-	var jwtValidator, err = validator.New(
-		config.emptyFunc,
-		validator.HS256,
-		config.Issuer,
-		[]string{config.Audience},
-		validator.WithCustomClaims(func() validator.CustomClaims {
-			return &CustomClaims{}
-		}),
+func NewJwtMiddleware(config *JWTConfig, sessions SessionChecker) *jwtmiddleware.JWTMiddleware {
+	return jwtmiddleware.New(
+		newTokenValidator(config, sessions),
+		jwtmiddleware.WithTokenExtractor(config.tokenExtractor()),
 	)
+}
+
+// Middleware для публичных роутов: валидный токен кладется в контекст так же, как в CheckJWT,
+// а без токена или с невалидным токеном запрос проходит анонимно
+func NewOptionalJwtMiddleware(config *JWTConfig, sessions SessionChecker) func(http.Handler) http.Handler {
+	validateToken := newTokenValidator(config, sessions)
+	extractToken := config.tokenExtractor()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token, err := extractToken(r); err == nil && token != "" {
+				if claims, err := validateToken(r.Context(), token); err == nil {
+					r = r.WithContext(context.WithValue(r.Context(), jwtmiddleware.ContextKey{}, claims))
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
 
+// Валидатор проверяет один алгоритм, поэтому на каждый алгоритм из набора ключей свой
+func newTokenValidator(config *JWTConfig, sessions SessionChecker) jwtmiddleware.ValidateToken {
+	keys, err := config.keys()
 	if err != nil {
-		println("Something went wrong while configuring JWT middleware", err.Error())
+		logger.Log.Error("JWT -> newTokenValidator -> keys -> err -> " + err.Error())
+		keys = &KeySet{}
 	}
 
-	return jwtmiddleware.New(
-		jwtValidator.ValidateToken,
-		// Вытаскиваем токен из кук
-		jwtmiddleware.WithTokenExtractor(func(r *http.Request) (string, error) {
-			cookie, err := r.Cookie("token")
-			if err != nil {
-				return "", err
-			}
-			return cookie.Value, nil
-		}),
-	)
+	validators := map[string]*validator.Validator{}
+	for _, algorithm := range keys.algorithms() {
+		jwtValidator, err := validator.New(
+			keyFromContext,
+			validator.SignatureAlgorithm(algorithm),
+			config.Issuer,
+			[]string{config.Audience},
+			validator.WithCustomClaims(func() validator.CustomClaims {
+				return &CustomClaims{}
+			}),
+		)
+		if err != nil {
+			logger.Log.Error("JWT -> newTokenValidator -> validator.New -> err -> " + err.Error())
+			continue
+		}
+		validators[algorithm] = jwtValidator
+	}
 
+	// Подпись и срок жизни проверяет валидатор, отзыв сессии - проверка jti в БД
+	return func(ctx context.Context, token string) (any, error) {
+		key, err := keys.verifier(token, time.Now())
+		if err != nil {
+			return nil, err
+		}
+
+		jwtValidator, ok := validators[key.algorithm]
+		if !ok {
+			return nil, errors.New("unsupported signing algorithm")
+		}
+
+		claims, err := jwtValidator.ValidateToken(context.WithValue(ctx, verificationKey{}, key.public), token)
+		if err != nil {
+			return nil, err
+		}
+
+		customClaims, ok := claims.(*validator.ValidatedClaims).CustomClaims.(*CustomClaims)
+		if !ok || customClaims.ID == "" {
+			return nil, errors.New("token is not bound to a session")
+		}
+
+		active, err := sessions.IsSessionActive(ctx, customClaims.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !active {
+			return nil, errors.New("session revoked")
+		}
+
+		return claims, nil
+	}
+}
+
+// Заголовок с другой схемой, например Basic от прокси, не мешает взять токен из cookie
+func tokenFromHeader(r *http.Request) (string, error) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", nil
+	}
+	return strings.TrimSpace(token), nil
 }
 
 func GetClaimsFromContext(r *http.Request) (*CustomClaims, error) {
@@ -87,18 +256,33 @@ func GetClaimsFromContext(r *http.Request) (*CustomClaims, error) {
 	return customClaims, nil
 }
 
-func GenerateJWT(email string, id int64, jwtConfig *JWTConfig) (string, error) {
+// sessionId попадает в jti, по нему middleware проверяет, что сессия не отозвана
+func GenerateJWT(email string, id int64, role, sessionId string, jwtConfig *JWTConfig) (string, error) {
 	claims := CustomClaims{
 		UserEmail: email,
 		Id:        id,
+		Role:      role,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(2 * time.Hour)),
+			ID:        sessionId,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(jwtConfig.AccessTTL())),
 			Issuer:    jwtConfig.Issuer,
 			Audience:  []string{jwtConfig.Audience},
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	keys, err := jwtConfig.keys()
+	if err != nil {
+		return "", err
+	}
+	key, err := keys.signer(time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(key.method, claims)
+	if key.id != "" {
+		token.Header["kid"] = key.id
+	}
 
-	return token.SignedString([]byte(jwtConfig.SecretJWTKey))
+	return token.SignedString(key.private)
 }
@@ -0,0 +1,324 @@
+package security_test
+
+import (
+	security "arabic/pkg/security/auth"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubSessions struct {
+	active bool
+}
+
+func (s *stubSessions) IsSessionActive(ctx context.Context, sessionId string) (bool, error) {
+	return s.active, nil
+}
+
+func TestOptionalJwtMiddleware(t *testing.T) {
+	config := security.NewJWTConfig()
+	config.SecretJWTKey = "secret"
+	config.Issuer = "arabic"
+	config.Audience = "arabic-api"
+
+	token, err := security.GenerateJWT("a@a.com", 7, "user", "session-1", config)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		cookie   string
+		active   bool
+		expectId int64
+	}{
+		{
+			name:     "valid token",
+			cookie:   token,
+			active:   true,
+			expectId: 7,
+		},
+		{
+			name:   "anonymous request",
+			active: true,
+		},
+		{
+			name:   "malformed token",
+			cookie: "not-a-jwt",
+			active: true,
+		},
+		{
+			name:   "revoked session",
+			cookie: token,
+			active: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var userId int64
+			handler := security.NewOptionalJwtMiddleware(config, &stubSessions{active: tc.active})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if claims, err := security.GetClaimsFromContext(r); err == nil {
+					userId = claims.Id
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/catalog/all", nil)
+			if tc.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "token", Value: tc.cookie})
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tc.expectId, userId)
+		})
+	}
+}
+
+// Пишет закрытый ключ в PEM PKCS#8 во временный каталог теста
+func writeKey(t *testing.T, key any) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "key.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+	return path
+}
+
+func newKeyConfig(keys ...*security.JWTKey) *security.JWTConfig {
+	config := security.NewJWTConfig()
+	config.Issuer = "arabic"
+	config.Audience = "arabic-api"
+	config.Keys = keys
+	return config
+}
+
+// Id пользователя из токена, 0 - токен не принят
+func validate(t *testing.T, config *security.JWTConfig, token string) int64 {
+	var userId int64
+	handler := security.NewOptionalJwtMiddleware(config, &stubSessions{active: true})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if claims, err := security.GetClaimsFromContext(r); err == nil {
+			userId = claims.Id
+		}
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/catalog/all", nil)
+	req.AddCookie(&http.Cookie{Name: "token", Value: token})
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	return userId
+}
+
+func TestJwtKeyRotation(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, retiredKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	now := time.Now()
+	oldKey := &security.JWTKey{Id: "old", PrivateKeyFile: writeKey(t, rsaKey), SignFrom: now.Add(-48 * time.Hour), VerifyUntil: now.Add(time.Hour)}
+	newKey := &security.JWTKey{Id: "new", PrivateKeyFile: writeKey(t, edKey), SignFrom: now.Add(-time.Minute)}
+	nextKey := &security.JWTKey{Id: "next", PrivateKeyFile: writeKey(t, rsaKey), SignFrom: now.Add(time.Hour)}
+	expired := &security.JWTKey{Id: "retired", PrivateKeyFile: writeKey(t, retiredKey), SignFrom: now.Add(-72 * time.Hour), VerifyUntil: now.Add(-time.Hour)}
+
+	config := newKeyConfig(oldKey, newKey, nextKey, expired)
+	require.NoError(t, config.LoadKeys())
+
+	// Подписывает самый новый из начавших действовать ключей
+	token, err := security.GenerateJWT("a@a.com", 7, "user", "session-1", config)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), validate(t, config, token))
+	assert.Contains(t, decodeHeader(t, token), `"kid":"new"`)
+	assert.Contains(t, decodeHeader(t, token), `"alg":"EdDSA"`)
+
+	// Токен старого ключа действует до verify_until
+	oldConfig := newKeyConfig(&security.JWTKey{Id: "old", PrivateKeyFile: oldKey.PrivateKeyFile})
+	require.NoError(t, oldConfig.LoadKeys())
+	oldToken, err := security.GenerateJWT("a@a.com", 8, "user", "session-1", oldConfig)
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), validate(t, config, oldToken))
+
+	retiredConfig := newKeyConfig(&security.JWTKey{Id: "retired", PrivateKeyFile: expired.PrivateKeyFile})
+	require.NoError(t, retiredConfig.LoadKeys())
+	retiredToken, err := security.GenerateJWT("a@a.com", 9, "user", "session-1", retiredConfig)
+	require.NoError(t, err)
+	assert.Zero(t, validate(t, config, retiredToken))
+
+	// HS256 без kid не принимается, когда заданы асимметричные ключи
+	secretConfig := security.NewJWTConfig()
+	secretConfig.SecretJWTKey = "secret"
+	secretConfig.Issuer = "arabic"
+	secretConfig.Audience = "arabic-api"
+	secretToken, err := security.GenerateJWT("a@a.com", 10, "user", "session-1", secretConfig)
+	require.NoError(t, err)
+	assert.Zero(t, validate(t, config, secretToken))
+}
+
+func TestJWKSHandler(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	now := time.Now()
+	config := newKeyConfig(
+		&security.JWTKey{Id: "rsa", PrivateKeyFile: writeKey(t, rsaKey), SignFrom: now.Add(-time.Hour)},
+		&security.JWTKey{Id: "ed", PrivateKeyFile: writeKey(t, edKey), SignFrom: now.Add(time.Hour)},
+		&security.JWTKey{Id: "retired", PrivateKeyFile: writeKey(t, edKey), VerifyUntil: now.Add(-time.Hour)},
+	)
+	require.NoError(t, config.LoadKeys())
+
+	rec := httptest.NewRecorder()
+	security.JWKSHandler(config)(rec, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+	assert.Equal(t, "public, max-age=300", rec.Header().Get("Cache-Control"))
+
+	var jwks security.JWKS
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&jwks))
+	require.Len(t, jwks.Keys, 2)
+
+	assert.Equal(t, "rsa", jwks.Keys[0].Kid)
+	assert.Equal(t, "RSA", jwks.Keys[0].Kty)
+	assert.Equal(t, "AQAB", jwks.Keys[0].E)
+	assert.Empty(t, jwks.Keys[0].X)
+
+	// Ключ, который еще не подписывает, уже опубликован
+	assert.Equal(t, "ed", jwks.Keys[1].Kid)
+	assert.Equal(t, "OKP", jwks.Keys[1].Kty)
+	assert.Equal(t, "Ed25519", jwks.Keys[1].Crv)
+}
+
+func TestJWTConfig_LoadKeys(t *testing.T) {
+	assert.Error(t, security.NewJWTConfig().LoadKeys())
+
+	smallKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	assert.Error(t, newKeyConfig(&security.JWTKey{Id: "small", PrivateKeyFile: writeKey(t, smallKey)}).LoadKeys())
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	path := writeKey(t, edKey)
+	assert.Error(t, newKeyConfig(&security.JWTKey{Id: "a", PrivateKeyFile: path}, &security.JWTKey{Id: "a", PrivateKeyFile: path}).LoadKeys())
+
+	// Нет ни одного ключа, который уже подписывает
+	assert.Error(t, newKeyConfig(&security.JWTKey{Id: "future", PrivateKeyFile: path, SignFrom: time.Now().Add(time.Hour)}).LoadKeys())
+}
+
+func decodeHeader(t *testing.T, token string) string {
+	header, _, _ := strings.Cut(token, ".")
+	raw, err := base64.RawURLEncoding.DecodeString(header)
+	require.NoError(t, err)
+	return string(raw)
+}
+
+func TestJwtMiddleware_TokenSources(t *testing.T) {
+	config := security.NewJWTConfig()
+	config.SecretJWTKey = "secret"
+	config.Issuer = "arabic"
+	config.Audience = "arabic-api"
+
+	cookieToken, err := security.GenerateJWT("a@a.com", 1, "user", "session-1", config)
+	require.NoError(t, err)
+	headerToken, err := security.GenerateJWT("b@a.com", 2, "courier", "session-2", config)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		sources    []string
+		header     string
+		cookie     string
+		expectCode int
+		expectId   int64
+	}{
+		{
+			name:       "bearer header",
+			sources:    []string{security.TokenSourceHeader, security.TokenSourceCookie},
+			header:     "Bearer " + headerToken,
+			expectCode: http.StatusOK,
+			expectId:   2,
+		},
+		{
+			name:       "header takes precedence",
+			sources:    []string{security.TokenSourceHeader, security.TokenSourceCookie},
+			header:     "Bearer " + headerToken,
+			cookie:     cookieToken,
+			expectCode: http.StatusOK,
+			expectId:   2,
+		},
+		{
+			name:       "cookie takes precedence",
+			sources:    []string{security.TokenSourceCookie, security.TokenSourceHeader},
+			header:     "Bearer " + headerToken,
+			cookie:     cookieToken,
+			expectCode: http.StatusOK,
+			expectId:   1,
+		},
+		{
+			name:       "header disabled",
+			sources:    []string{security.TokenSourceCookie},
+			header:     "Bearer " + headerToken,
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			name:       "no token",
+			sources:    []string{security.TokenSourceHeader, security.TokenSourceCookie},
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			name:       "not a bearer header",
+			sources:    []string{security.TokenSourceHeader, security.TokenSourceCookie},
+			header:     "Basic dXNlcjpwYXNz",
+			cookie:     cookieToken,
+			expectCode: http.StatusOK,
+			expectId:   1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			config.TokenSources = tc.sources
+			require.NoError(t, config.Validate())
+
+			var userId int64
+			handler := security.NewJwtMiddleware(config, &stubSessions{active: true}).CheckJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if claims, err := security.GetClaimsFromContext(r); err == nil {
+					userId = claims.Id
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/user", nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			if tc.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: security.AccessTokenCookie, Value: tc.cookie})
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectCode, rec.Code)
+			assert.Equal(t, tc.expectId, userId)
+		})
+	}
+
+	config.TokenSources = []string{"query"}
+	assert.Error(t, config.Validate())
+}
@@ -0,0 +1,258 @@
+package security
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Алгоритмы подписи access токенов
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// Ключ подписи из [[jwt.keys]]. Алгоритм определяется типом ключа: RSA - RS256, Ed25519 - EdDSA
+type JWTKey struct {
+	Id string `toml:"kid"`
+	// PEM с закрытым ключом в PKCS#8 (RSA или Ed25519) или PKCS#1 (RSA)
+	PrivateKeyFile string `toml:"private_key_file"`
+	// С этого момента ключ подписывает токены. До него ключ уже опубликован в JWKS,
+	// чтобы проверяющие сервисы успели обновить кеш
+	SignFrom time.Time `toml:"sign_from"`
+	// После этого момента токены с ключом не принимаются и он пропадает из JWKS.
+	// Должен быть позже окончания подписи хотя бы на время жизни access токена
+	VerifyUntil time.Time `toml:"verify_until"`
+}
+
+type signingKey struct {
+	id          string
+	algorithm   string
+	method      jwt.SigningMethod
+	private     any
+	public      any
+	signFrom    time.Time
+	verifyUntil time.Time
+}
+
+func (k *signingKey) canVerify(at time.Time) bool {
+	return k.verifyUntil.IsZero() || at.Before(k.verifyUntil)
+}
+
+func (k *signingKey) canSign(at time.Time) bool {
+	return !at.Before(k.signFrom) && k.canVerify(at)
+}
+
+// Набор ключей подписи с ротацией. Без [[jwt.keys]] - один ключ HS256 из jwt_secret_key
+type KeySet struct {
+	keys []*signingKey
+}
+
+func newSecretKeySet(secret string) *KeySet {
+	return &KeySet{keys: []*signingKey{{
+		algorithm: AlgorithmHS256,
+		method:    jwt.SigningMethodHS256,
+		private:   []byte(secret),
+		public:    []byte(secret),
+	}}}
+}
+
+func loadKeySet(configs []*JWTKey) (*KeySet, error) {
+	set := &KeySet{}
+	ids := map[string]bool{}
+
+	for _, config := range configs {
+		if config.Id == "" {
+			return nil, errors.New("jwt key kid is empty")
+		}
+		if ids[config.Id] {
+			return nil, fmt.Errorf("duplicate jwt key kid %q", config.Id)
+		}
+		ids[config.Id] = true
+
+		key, err := loadKey(config)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %q: %w", config.Id, err)
+		}
+		set.keys = append(set.keys, key)
+	}
+
+	sort.SliceStable(set.keys, func(i, j int) bool {
+		return set.keys[i].signFrom.Before(set.keys[j].signFrom)
+	})
+
+	return set, nil
+}
+
+func loadKey(config *JWTKey) (*signingKey, error) {
+	data, err := os.ReadFile(config.PrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var private any
+	if block.Type == "RSA PRIVATE KEY" {
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	} else {
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &signingKey{
+		id:          config.Id,
+		private:     private,
+		signFrom:    config.SignFrom,
+		verifyUntil: config.VerifyUntil,
+	}
+
+	switch private := private.(type) {
+	case *rsa.PrivateKey:
+		if private.N.BitLen() < 2048 {
+			return nil, errors.New("rsa key must be at least 2048 bits")
+		}
+		key.algorithm, key.method, key.public = AlgorithmRS256, jwt.SigningMethodRS256, &private.PublicKey
+	case ed25519.PrivateKey:
+		key.algorithm, key.method, key.public = AlgorithmEdDSA, jwt.SigningMethodEdDSA, private.Public()
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", private)
+	}
+
+	return key, nil
+}
+
+// Самый новый из ключей, которые уже подписывают
+func (s *KeySet) signer(at time.Time) (*signingKey, error) {
+	for i := len(s.keys) - 1; i >= 0; i-- {
+		if s.keys[i].canSign(at) {
+			return s.keys[i], nil
+		}
+	}
+	return nil, errors.New("no active jwt signing key")
+}
+
+// Ключ из заголовка токена. Алгоритм токена должен совпадать с алгоритмом ключа,
+// иначе открытый ключ RSA можно было бы подставить как секрет HS256
+func (s *KeySet) verifier(token string, at time.Time) (*signingKey, error) {
+	header, _, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, errors.New("malformed token")
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(header)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields struct {
+		Kid string `json:"kid"`
+		Alg string `json:"alg"`
+	}
+	if err = json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+
+	for _, key := range s.keys {
+		if key.id == fields.Kid && key.algorithm == fields.Alg && key.canVerify(at) {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown jwt key %q", fields.Kid)
+}
+
+func (s *KeySet) algorithms() []string {
+	var algorithms []string
+	seen := map[string]bool{}
+
+	for _, key := range s.keys {
+		if !seen[key.algorithm] {
+			seen[key.algorithm] = true
+			algorithms = append(algorithms, key.algorithm)
+		}
+	}
+
+	return algorithms
+}
+
+// JSON Web Key Set, RFC 7517
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// Открытые ключи, которыми можно проверить токены в момент at. Секрет HS256 не публикуется
+func (s *KeySet) JWKS(at time.Time) *JWKS {
+	jwks := &JWKS{Keys: []JWK{}}
+
+	for _, key := range s.keys {
+		if !key.canVerify(at) {
+			continue
+		}
+
+		jwk := JWK{Kid: key.id, Use: "sig", Alg: key.algorithm}
+		switch public := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		default:
+			continue
+		}
+
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	return jwks
+}
+
+// GET /.well-known/jwks.json для сервисов, которые проверяют наши токены сами
+func JWKSHandler(config *JWTConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		keys, err := config.keys()
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", config.JWKSCacheSeconds))
+		json.NewEncoder(w).Encode(keys.JWKS(time.Now()))
+	}
+}
@@ -0,0 +1,29 @@
+package security
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// Генерирует непрозрачный refresh токен и его sha256 хеш для хранения в БД
+func GenerateRefreshToken() (token string, hash string, err error) {
+	return GenerateToken()
+}
+
+// Случайный токен для передачи пользователю и его хеш. Используется для refresh токенов и ссылок из писем
+func GenerateToken() (token string, hash string, err error) {
+	raw := make([]byte, 32)
+	if _, err = rand.Read(raw); err != nil {
+		return "", "", err
+	}
+
+	token = base64.RawURLEncoding.EncodeToString(raw)
+	return token, HashToken(token), nil
+}
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
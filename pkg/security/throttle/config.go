@@ -0,0 +1,66 @@
+package throttle
+
+import "time"
+
+const (
+	DriverMemory   = "memory"
+	DriverPostgres = "postgres"
+)
+
+type Config struct {
+	// memory - счетчики в памяти процесса, postgres - общие для всех экземпляров приложения
+	Driver string `toml:"driver"`
+	// Учитываются неудачные попытки за последние window_minutes
+	WindowMinutes int `toml:"window_minutes"`
+	// Столько попыток можно сделать без задержки, дальше пауза удваивается от base_delay до max_delay.
+	// За одним IP бывает много пользователей, поэтому пороги для него выше
+	EmailFreeAttempts int `toml:"email_free_attempts"`
+	IPFreeAttempts    int `toml:"ip_free_attempts"`
+	BaseDelaySeconds  int `toml:"base_delay_seconds"`
+	MaxDelaySeconds   int `toml:"max_delay_seconds"`
+	// После стольких попыток вход блокируется на lockout_minutes
+	EmailLockoutAttempts int `toml:"email_lockout_attempts"`
+	IPLockoutAttempts    int `toml:"ip_lockout_attempts"`
+	LockoutMinutes       int `toml:"lockout_minutes"`
+}
+
+func NewConfig() *Config {
+	return &Config{
+		Driver:               DriverMemory,
+		WindowMinutes:        15,
+		EmailFreeAttempts:    3,
+		IPFreeAttempts:       20,
+		BaseDelaySeconds:     1,
+		MaxDelaySeconds:      30,
+		EmailLockoutAttempts: 10,
+		IPLockoutAttempts:    50,
+		LockoutMinutes:       15,
+	}
+}
+
+func (c *Config) Window() time.Duration {
+	return time.Duration(c.WindowMinutes) * time.Minute
+}
+
+func (c *Config) Lockout() time.Duration {
+	return time.Duration(c.LockoutMinutes) * time.Minute
+}
+
+// Пауза после count неудачных попыток: free без задержки, с lockout - блокировка
+func (c *Config) Delay(count, free, lockout int) time.Duration {
+	if count >= lockout {
+		return c.Lockout()
+	}
+	if count < free {
+		return 0
+	}
+
+	delay := time.Duration(c.BaseDelaySeconds) * time.Second
+	limit := time.Duration(c.MaxDelaySeconds) * time.Second
+
+	for i := free; i < count && delay < limit; i++ {
+		delay *= 2
+	}
+
+	return min(delay, limit)
+}
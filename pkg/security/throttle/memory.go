@@ -0,0 +1,90 @@
+package throttle
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Раз в столько записей из памяти удаляются ключи без попыток в окне
+const memorySweepEvery = 1000
+
+// Счетчики в памяти процесса. Подходит для одного экземпляра приложения
+type MemoryStore struct {
+	mu       sync.Mutex
+	attempts map[string][]time.Time
+	adds     int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{attempts: map[string][]time.Time{}}
+}
+
+func (m *MemoryStore) Reserve(ctx context.Context, key string, at time.Time, window time.Duration, delay DelayFunc) (Window, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.adds++
+	if m.adds%memorySweepEvery == 0 {
+		m.sweep(at, window)
+	}
+
+	attempts := prune(m.attempts[key], at, window)
+	current := windowOf(attempts)
+	if current.Count > 0 && at.Before(current.Last.Add(delay(current.Count))) {
+		return current, false, nil
+	}
+
+	m.attempts[key] = append(attempts, at)
+	return current, true, nil
+}
+
+func (m *MemoryStore) Release(ctx context.Context, key string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	attempts := m.attempts[key]
+	for i := len(attempts) - 1; i >= 0; i-- {
+		if attempts[i].Equal(at) {
+			m.attempts[key] = append(attempts[:i], attempts[i+1:]...)
+			break
+		}
+	}
+
+	return nil
+}
+
+func (m *MemoryStore) Reset(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.attempts, key)
+	return nil
+}
+
+func (m *MemoryStore) sweep(at time.Time, window time.Duration) {
+	for key, attempts := range m.attempts {
+		if attempts = prune(attempts, at, window); len(attempts) == 0 {
+			delete(m.attempts, key)
+		} else {
+			m.attempts[key] = attempts
+		}
+	}
+}
+
+// Попытки хранятся по возрастанию времени, отбрасываем вышедшие из окна
+func prune(attempts []time.Time, at time.Time, window time.Duration) []time.Time {
+	from := at.Add(-window)
+	i := 0
+	for i < len(attempts) && !attempts[i].After(from) {
+		i++
+	}
+	return attempts[i:]
+}
+
+func windowOf(attempts []time.Time) Window {
+	if len(attempts) == 0 {
+		return Window{}
+	}
+	return Window{Count: len(attempts), Last: attempts[len(attempts)-1]}
+}
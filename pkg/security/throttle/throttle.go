@@ -0,0 +1,149 @@
+package throttle
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Попытки по ключу в скользящем окне
+type Window struct {
+	Count int
+	Last  time.Time
+}
+
+// Пауза после count попыток в окне
+type DelayFunc func(count int) time.Duration
+
+// Хранилище счетчиков попыток. Реализация на Postgres нужна, когда экземпляров приложения несколько
+type Store interface {
+	// Записывает попытку в момент at, если пауза после попыток в окне, заканчивающемся в at, истекла.
+	// Проверка и запись атомарны. Возвращает окно до этой попытки и признак, что она записана
+	Reserve(ctx context.Context, key string, at time.Time, window time.Duration, delay DelayFunc) (Window, bool, error)
+	// Удаляет одну попытку, записанную в момент at
+	Release(ctx context.Context, key string, at time.Time) error
+	Reset(ctx context.Context, key string) error
+}
+
+// Ключ не длиннее колонки login_attempts.key, у слишком длинного email отбрасывается хвост
+const maxKeyLength = 320
+
+type key struct {
+	name    string
+	free    int
+	lockout int
+}
+
+// Попытка входа, засчитанная до проверки пароля
+type Attempt struct {
+	email string
+	ip    string
+	at    time.Time
+}
+
+// Ограничивает попытки входа по email и по IP
+type Limiter struct {
+	store  Store
+	config *Config
+	// Префикс ключей, чтобы счетчики разных действий не смешивались
+	scope string
+}
+
+func NewLimiter(store Store, config *Config) *Limiter {
+	return &Limiter{store: store, config: config}
+}
+
+// Лимитер с теми же хранилищем и порогами, но отдельными счетчиками, например для сброса пароля
+func (l *Limiter) Scoped(scope string) *Limiter {
+	return &Limiter{store: l.store, config: l.config, scope: scope + ":"}
+}
+
+// Засчитывает попытку входа до проверки пароля, чтобы параллельные запросы не проходили
+// проверку разом. Если для email или IP действует пауза, возвращает ее и ничего не записывает
+func (l *Limiter) Reserve(ctx context.Context, email, ip string) (*Attempt, time.Duration, error) {
+	now := time.Now()
+
+	var reserved []string
+	for _, k := range l.keys(email, ip) {
+		delay := l.delay(k)
+
+		window, ok, err := l.store.Reserve(ctx, k.name, now, l.config.Window(), delay)
+		if err == nil && ok {
+			reserved = append(reserved, k.name)
+			continue
+		}
+
+		// Отказ из-за паузы не продлевает ее
+		for _, name := range reserved {
+			if releaseErr := l.store.Release(ctx, name, now); releaseErr != nil && err == nil {
+				err = releaseErr
+			}
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+
+		return nil, window.Last.Add(delay(window.Count)).Sub(now), nil
+	}
+
+	return &Attempt{email: email, ip: ip, at: now}, 0, nil
+}
+
+// Успешный вход сбрасывает счетчик email и не засчитывается для IP. Прежние неудачи с IP
+// остаются: с одного адреса могут подбирать пароли к разным аккаунтам, зная пароль от своего
+func (l *Limiter) Succeed(ctx context.Context, attempt *Attempt) error {
+	if attempt.email != "" {
+		if err := l.store.Reset(ctx, l.emailKey(attempt.email)); err != nil {
+			return err
+		}
+	}
+
+	if attempt.ip != "" {
+		return l.store.Release(ctx, l.ipKey(attempt.ip), attempt.at)
+	}
+
+	return nil
+}
+
+// Снимает блокировку вручную. Пустые значения пропускаются
+func (l *Limiter) Unlock(ctx context.Context, email, ip string) error {
+	for _, k := range l.keys(email, ip) {
+		if err := l.store.Reset(ctx, k.name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (l *Limiter) keys(email, ip string) []key {
+	var keys []key
+	if email != "" {
+		keys = append(keys, key{name: l.emailKey(email), free: l.config.EmailFreeAttempts, lockout: l.config.EmailLockoutAttempts})
+	}
+	if ip != "" {
+		keys = append(keys, key{name: l.ipKey(ip), free: l.config.IPFreeAttempts, lockout: l.config.IPLockoutAttempts})
+	}
+	return keys
+}
+
+func (l *Limiter) delay(k key) DelayFunc {
+	return func(count int) time.Duration {
+		return l.config.Delay(count, k.free, k.lockout)
+	}
+}
+
+func (l *Limiter) emailKey(email string) string {
+	return truncateKey(l.scope + "email:" + strings.ToLower(strings.TrimSpace(email)))
+}
+
+func (l *Limiter) ipKey(ip string) string {
+	return truncateKey(l.scope + "ip:" + ip)
+}
+
+func truncateKey(key string) string {
+	if len(key) <= maxKeyLength {
+		return key
+	}
+	return strings.ToValidUTF8(key[:maxKeyLength], "")
+}
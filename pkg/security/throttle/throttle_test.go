@@ -0,0 +1,207 @@
+package throttle_test
+
+import (
+	"arabic/pkg/security/throttle"
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Delay(t *testing.T) {
+	config := throttle.NewConfig()
+	config.BaseDelaySeconds = 1
+	config.MaxDelaySeconds = 30
+	config.LockoutMinutes = 15
+
+	assert.Equal(t, time.Duration(0), config.Delay(2, 3, 10))
+	assert.Equal(t, time.Second, config.Delay(3, 3, 10))
+	assert.Equal(t, 2*time.Second, config.Delay(4, 3, 10))
+	assert.Equal(t, 16*time.Second, config.Delay(7, 3, 10))
+	assert.Equal(t, 30*time.Second, config.Delay(9, 3, 10))
+	assert.Equal(t, 15*time.Minute, config.Delay(10, 3, 10))
+}
+
+func TestMemoryStore_Reserve(t *testing.T) {
+	store := throttle.NewMemoryStore()
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// Две попытки без паузы, дальше минута после последней
+	delay := func(count int) time.Duration {
+		if count < 2 {
+			return 0
+		}
+		return time.Minute
+	}
+
+	for i := range 2 {
+		_, ok, err := store.Reserve(ctx, "email:a@a.com", start.Add(time.Duration(i)*time.Second), 5*time.Minute, delay)
+		require.NoError(t, err)
+		assert.True(t, ok)
+	}
+
+	window, ok, err := store.Reserve(ctx, "email:a@a.com", start.Add(30*time.Second), 5*time.Minute, delay)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 2, window.Count)
+	assert.Equal(t, start.Add(time.Second), window.Last)
+
+	// Отпущенная попытка не считается
+	require.NoError(t, store.Release(ctx, "email:a@a.com", start.Add(time.Second)))
+	window, ok, _ = store.Reserve(ctx, "email:a@a.com", start.Add(30*time.Second), 5*time.Minute, delay)
+	assert.True(t, ok)
+	assert.Equal(t, 1, window.Count)
+
+	// Первые попытки вышли из окна
+	window, ok, _ = store.Reserve(ctx, "email:a@a.com", start.Add(5*time.Minute+40*time.Second), 5*time.Minute, delay)
+	assert.True(t, ok)
+	assert.Equal(t, 0, window.Count)
+
+	require.NoError(t, store.Reset(ctx, "email:a@a.com"))
+	window, _, _ = store.Reserve(ctx, "email:a@a.com", start.Add(6*time.Minute), 5*time.Minute, delay)
+	assert.Equal(t, 0, window.Count)
+}
+
+func TestLimiter(t *testing.T) {
+	ctx := context.Background()
+
+	config := throttle.NewConfig()
+	config.EmailFreeAttempts = 2
+	config.IPFreeAttempts = 5
+	config.BaseDelaySeconds = 10
+	config.MaxDelaySeconds = 60
+	config.EmailLockoutAttempts = 2
+	config.IPLockoutAttempts = 100
+	config.LockoutMinutes = 15
+
+	limiter := throttle.NewLimiter(throttle.NewMemoryStore(), config)
+
+	for range 2 {
+		attempt, wait, err := limiter.Reserve(ctx, "a@a.com", "10.0.0.1")
+		require.NoError(t, err)
+		assert.NotNil(t, attempt)
+		assert.Zero(t, wait)
+	}
+
+	// Третья попытка уже после блокировки, email сравнивается без учета регистра, другой IP не спасает
+	attempt, wait, err := limiter.Reserve(ctx, " A@a.com", "10.0.0.2")
+	require.NoError(t, err)
+	assert.Nil(t, attempt)
+	assert.InDelta(t, 15*time.Minute, wait, float64(time.Second))
+
+	// Отказ не продлевает паузу и не засчитывается для IP
+	for range 10 {
+		_, _, _ = limiter.Reserve(ctx, "a@a.com", "10.0.0.3")
+	}
+	attempt, _, _ = limiter.Reserve(ctx, "b@b.com", "10.0.0.3")
+	assert.NotNil(t, attempt)
+
+	require.NoError(t, limiter.Unlock(ctx, "a@a.com", ""))
+	attempt, wait, _ = limiter.Reserve(ctx, "a@a.com", "10.0.0.4")
+	assert.NotNil(t, attempt)
+	assert.Zero(t, wait)
+}
+
+func TestLimiter_LongEmail(t *testing.T) {
+	ctx := context.Background()
+
+	store := &recordingStore{MemoryStore: throttle.NewMemoryStore()}
+	limiter := throttle.NewLimiter(store, throttle.NewConfig())
+
+	_, _, err := limiter.Reserve(ctx, strings.Repeat("a", 400)+"@a.com", "10.0.0.1")
+	require.NoError(t, err)
+
+	require.Len(t, store.keys, 2)
+	for _, key := range store.keys {
+		assert.LessOrEqual(t, len(key), 320)
+	}
+}
+
+// Запоминает ключи, с которыми лимитер обращается к хранилищу
+type recordingStore struct {
+	*throttle.MemoryStore
+	keys []string
+}
+
+func (r *recordingStore) Reserve(ctx context.Context, key string, at time.Time, window time.Duration, delay throttle.DelayFunc) (throttle.Window, bool, error) {
+	r.keys = append(r.keys, key)
+	return r.MemoryStore.Reserve(ctx, key, at, window, delay)
+}
+
+func TestLimiter_Succeed(t *testing.T) {
+	ctx := context.Background()
+
+	config := throttle.NewConfig()
+	config.EmailFreeAttempts = 2
+	config.IPFreeAttempts = 2
+
+	limiter := throttle.NewLimiter(throttle.NewMemoryStore(), config)
+
+	// Успешные входы не копятся ни для email, ни для IP
+	for range 5 {
+		attempt, wait, err := limiter.Reserve(ctx, "a@a.com", "10.0.0.1")
+		require.NoError(t, err)
+		require.Zero(t, wait)
+		require.NoError(t, limiter.Succeed(ctx, attempt))
+	}
+}
+
+func TestLimiter_ConcurrentBurst(t *testing.T) {
+	ctx := context.Background()
+
+	config := throttle.NewConfig()
+	config.EmailFreeAttempts = 3
+	config.EmailLockoutAttempts = 10
+
+	limiter := throttle.NewLimiter(throttle.NewMemoryStore(), config)
+
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if attempt, _, err := limiter.Reserve(ctx, "a@a.com", ""); err == nil && attempt != nil {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Одновременные запросы проходят проверку только в пределах попыток без задержки
+	assert.Equal(t, int32(3), allowed.Load())
+}
+
+func TestLimiter_IPAcrossAccounts(t *testing.T) {
+	ctx := context.Background()
+
+	config := throttle.NewConfig()
+	config.IPFreeAttempts = 3
+	config.IPLockoutAttempts = 5
+
+	limiter := throttle.NewLimiter(throttle.NewMemoryStore(), config)
+
+	// Перебор паролей по разным аккаунтам с одного адреса, четвертая попытка - только после паузы
+	for _, email := range []string{"a@a.com", "b@b.com", "c@c.com"} {
+		attempt, wait, err := limiter.Reserve(ctx, email, "10.0.0.1")
+		require.NoError(t, err)
+		require.NotNil(t, attempt)
+		require.Zero(t, wait)
+	}
+
+	attempt, wait, err := limiter.Reserve(ctx, "d@d.com", "10.0.0.1")
+	require.NoError(t, err)
+	assert.Nil(t, attempt)
+	assert.Greater(t, wait, time.Duration(0))
+
+	require.NoError(t, limiter.Unlock(ctx, "", "10.0.0.1"))
+	attempt, wait, _ = limiter.Reserve(ctx, "f@f.com", "10.0.0.1")
+	assert.NotNil(t, attempt)
+	assert.Zero(t, wait)
+}
@@ -0,0 +1,37 @@
+package totp
+
+import (
+	"slices"
+	"time"
+)
+
+type Config struct {
+	// Название в приложении-аутентификаторе
+	Issuer string `toml:"issuer"`
+	// Ключ шифрования секретов в БД. После смены ключа подключенные приложения перестанут работать
+	EncryptionKey string `toml:"encryption_key"`
+	// Роли, которым вход без второго фактора запрещен
+	RequiredRoles []string `toml:"required_roles"`
+	// Сколько живет токен между вводом пароля и кода
+	ChallengeTTLMinutes int `toml:"challenge_ttl_minutes"`
+	RecoveryCodes       int `toml:"recovery_codes"`
+	// Допуск рассинхронизации часов, в шагах по 30 секунд
+	Skew int `toml:"skew"`
+}
+
+func NewConfig() *Config {
+	return &Config{
+		Issuer:              "Arabic",
+		ChallengeTTLMinutes: 5,
+		RecoveryCodes:       10,
+		Skew:                1,
+	}
+}
+
+func (c *Config) IsRequired(role string) bool {
+	return slices.Contains(c.RequiredRoles, role)
+}
+
+func (c *Config) ChallengeTTL() time.Duration {
+	return time.Duration(c.ChallengeTTLMinutes) * time.Minute
+}
@@ -0,0 +1,42 @@
+package totp
+
+import (
+	"crypto/rand"
+	"strings"
+)
+
+// Алфавит без похожих символов: 0/o, 1/l
+const recoveryAlphabet = "23456789abcdefghijkmnpqrstuvwxyz"
+
+// Одноразовые коды восстановления вида xxxxx-xxxxx на случай потери телефона
+func GenerateRecoveryCodes(count int) ([]string, error) {
+	codes := make([]string, 0, count)
+	raw := make([]byte, 10)
+
+	for range count {
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+
+		var b strings.Builder
+		for i, c := range raw {
+			if i == 5 {
+				b.WriteByte('-')
+			}
+			b.WriteByte(recoveryAlphabet[int(c)%len(recoveryAlphabet)])
+		}
+		codes = append(codes, b.String())
+	}
+
+	return codes, nil
+}
+
+// Код сравнивается без учета регистра, пробелов и дефиса
+func NormalizeRecoveryCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(code)))
+}
@@ -0,0 +1,93 @@
+package totp_test
+
+import (
+	"arabic/pkg/security/totp"
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тестовый секрет из RFC 6238, приложение B
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCode_RFC6238(t *testing.T) {
+	// Последние 6 цифр 8-значных кодов SHA1 из RFC
+	tests := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+
+	for unix, expected := range tests {
+		code, err := totp.Code(rfcSecret, time.Unix(unix, 0))
+		require.NoError(t, err)
+		assert.Equal(t, expected, code, "T=%d", unix)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	at := time.Unix(1111111111, 0)
+	code, err := totp.Code(rfcSecret, at)
+	require.NoError(t, err)
+
+	step, ok := totp.Validate(rfcSecret, code, at, 1)
+	assert.True(t, ok)
+	assert.Equal(t, at.Unix()/totp.Period, step)
+
+	// Соседний шаг в пределах допуска
+	step, ok = totp.Validate(rfcSecret, code, at.Add(totp.Period*time.Second), 1)
+	assert.True(t, ok)
+	assert.Equal(t, at.Unix()/totp.Period, step)
+
+	_, ok = totp.Validate(rfcSecret, code, at.Add(2*totp.Period*time.Second), 1)
+	assert.False(t, ok)
+
+	_, ok = totp.Validate(rfcSecret, "12345", at, 1)
+	assert.False(t, ok)
+}
+
+func TestURI(t *testing.T) {
+	uri := totp.URI("Arabic", "admin@a.com", "JBSWY3DPEHPK3PXP")
+
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/Arabic:admin@a.com?"))
+	assert.Contains(t, uri, "secret=JBSWY3DPEHPK3PXP")
+	assert.Contains(t, uri, "issuer=Arabic")
+	assert.Contains(t, uri, "digits=6")
+}
+
+func TestCipher(t *testing.T) {
+	c, err := totp.NewCipher("secret-key")
+	require.NoError(t, err)
+
+	encrypted, err := c.Encrypt("JBSWY3DPEHPK3PXP")
+	require.NoError(t, err)
+	assert.NotContains(t, encrypted, "JBSWY3DPEHPK3PXP")
+
+	plain, err := c.Decrypt(encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", plain)
+
+	other, err := totp.NewCipher("other-key")
+	require.NoError(t, err)
+	_, err = other.Decrypt(encrypted)
+	assert.Error(t, err)
+
+	_, err = totp.NewCipher("")
+	assert.Error(t, err)
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := totp.GenerateRecoveryCodes(10)
+	require.NoError(t, err)
+	assert.Len(t, codes, 10)
+	assert.Regexp(t, `^[a-z2-9]{5}-[a-z2-9]{5}$`, codes[0])
+
+	assert.Equal(t, totp.NormalizeRecoveryCode(codes[0]), totp.NormalizeRecoveryCode(" "+strings.ToUpper(codes[0])+" "))
+}